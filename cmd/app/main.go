@@ -8,17 +8,35 @@ import (
 	userQueue "github.com/nakiner/faceit/pkg/queue/user"
 	natsCl "github.com/nakiner/faceit/pkg/store/nats"
 	"github.com/nakiner/faceit/pkg/user"
+	"io"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/go-kit/kit/log/level"
 	"github.com/nakiner/faceit/configs"
 	"github.com/nakiner/faceit/internal/server"
+	"github.com/nakiner/faceit/tools/auth"
+	"github.com/nakiner/faceit/tools/features"
+	"github.com/nakiner/faceit/tools/lockout"
 	"github.com/nakiner/faceit/tools/logging"
+	"github.com/nakiner/faceit/tools/mailer"
 	"github.com/nakiner/faceit/tools/metrics"
+	"github.com/nakiner/faceit/tools/password"
+	"github.com/nakiner/faceit/tools/retry"
 	"github.com/nakiner/faceit/tools/sentry"
 	"github.com/nakiner/faceit/tools/tracing"
+	"github.com/nakiner/faceit/tools/workers"
+	"github.com/nats-io/nats.go"
+	"github.com/opentracing/opentracing-go"
+	"gorm.io/gorm"
 
+	apikeyRepository "github.com/nakiner/faceit/internal/repository/apikey"
+	attemptRepository "github.com/nakiner/faceit/internal/repository/attempt"
+	roleRepository "github.com/nakiner/faceit/internal/repository/role"
+	tokenRepository "github.com/nakiner/faceit/internal/repository/token"
 	userRepository "github.com/nakiner/faceit/internal/repository/user"
 )
 
@@ -32,7 +50,11 @@ func main() {
 		fmt.Fprintf(os.Stderr, "read config: %s", err)
 		os.Exit(1)
 	}
-	// Print config
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+	// Print config, secrets are redacted
 	if err := cfg.Print(); err != nil {
 		fmt.Fprintf(os.Stderr, "read config: %s", err)
 		os.Exit(1)
@@ -45,12 +67,36 @@ func main() {
 	}
 	ctx = logging.WithContext(ctx, logger)
 
+	watcher, err := configs.NewWatcher(ctx, cfg)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to init config watcher", "err", err)
+		os.Exit(1)
+	}
+	defer watcher.Close()
+	watcher.Subscribe(func(c *configs.Config) {
+		if err := logger.SetLevel(c.Logger.Level); err != nil {
+			level.Error(logger).Log("msg", "failed to apply logger level", "err", err)
+		}
+	})
+
+	flags := features.New(cfg.Features)
+	ctx = features.WithContext(ctx, flags)
+	watcher.Subscribe(func(c *configs.Config) {
+		flags.Set(c.Features)
+	})
+
 	if cfg.Tracer.Enabled {
-		tracer, closer, err := tracing.NewJaegerTracer(
-			ctx,
-			fmt.Sprintf("%s:%d", cfg.Tracer.Host, cfg.Tracer.Port),
-			cfg.Tracer.Name,
-		)
+		sampler, err := tracing.NewSampler(cfg.Tracer.SampleRate)
+		if err != nil {
+			level.Error(logger).Log("err", err, "msg", "failed to init tracer sampler")
+			os.Exit(1)
+		}
+		watcher.Subscribe(func(c *configs.Config) {
+			if err := sampler.SetRate(c.Tracer.SampleRate); err != nil {
+				level.Error(logger).Log("msg", "failed to apply tracer sample rate", "err", err)
+			}
+		})
+		tracer, closer, err := initTracer(ctx, cfg, sampler)
 		if err != nil {
 			level.Error(logger).Log("err", err, "msg", "failed to init tracer")
 		}
@@ -63,59 +109,128 @@ func main() {
 		}
 	}
 
+	var metric *metrics.Metrics
 	if cfg.Metrics.Enabled {
-		ctx = metrics.WithContext(ctx)
+		// buckets are checked by config validation
+		buckets, _ := metrics.ParseBuckets(cfg.Metrics.Buckets)
+		ctx = metrics.WithContext(ctx, metrics.SetBuckets(buckets))
+		metric = metrics.Instance(ctx)
+		if err := metric.RegisterBuildInfo(health.Version, health.Commit, health.BuildTime); err != nil {
+			level.Error(logger).Log("msg", "failed to register build info", "err", err)
+		}
 	}
 
-	db, err := database.Connect(ctx, cfg)
-	if err != nil {
-		level.Error(logger).Log("msg", "failed to init db", "err", err)
-		os.Exit(1)
+	// dependencies are connected lazily, startup waits for them while readiness fails
+	var db *database.Connection
+	if cfg.Storage.Driver == configs.StoragePostgres {
+		db, err = database.Connect(ctx, cfg)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to init db", "err", err)
+			os.Exit(1)
+		}
+	} else {
+		level.Warn(logger).Log("msg", "users are kept in memory and lost on exit", "storage", cfg.Storage.Driver)
 	}
 
-	defer db.Close()
+	var (
+		nc          *nats.Conn
+		broker      *userQueue.MemoryBroker
+		userNatsPub userQueue.Publisher
+		invalidator *userQueue.Invalidator
+	)
+	if cfg.Queue.Driver == configs.QueueNATS {
+		nc, err = natsCl.NewClient(&cfg.Nats, natsCl.LogState(logger)...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to init nats: %s", err)
+			os.Exit(1)
+		}
+
+		ec, err := natsCl.NewEncodedClient(nc)
+		if err != nil {
+			level.Error(logger).Log("msg", "err init nats NewEncodedConn", "err", err)
+			os.Exit(1)
+		}
 
-	nc, err := natsCl.NewClient(&cfg.Nats)
+		userNatsPub, err = userQueue.NewPublisher(ec)
+		if err != nil {
+			level.Error(logger).Log("msg", "err init userQueue.Publisher", "err", err)
+			os.Exit(1)
+		}
+		invalidator = userQueue.NewInvalidator(nc)
+	} else {
+		level.Warn(logger).Log("msg", "user events are delivered within process only", "queue", cfg.Queue.Driver)
+		broker = userQueue.NewMemoryBroker(ctx)
+		userNatsPub = userQueue.NewMemoryPublisher(broker)
+		invalidator = userQueue.NewMemoryInvalidator(broker)
+	}
+
+	if cfg.Metrics.Enabled {
+		if err := initStoreMetrics(metric, db, nc); err != nil {
+			level.Error(logger).Log("msg", "failed to register store metrics", "err", err)
+		}
+		userNatsPub = userQueue.NewMetricsPublisher(ctx, userNatsPub)
+	}
+
+	userRepo, err := initUserRepository(ctx, db, invalidator, cfg)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to init nats: %s", err)
+		level.Error(logger).Log("msg", "err init user repository", "err", err)
 		os.Exit(1)
 	}
-	defer nc.Close()
 
-	ec, err := natsCl.NewEncodedClient(nc)
+	monitor := initHealthMonitor(ctx, cfg, db, nc)
+	monitor.Starting()
+	monitor.Start(ctx)
+	defer monitor.Stop()
+
+	healthService := initHealthService(ctx, monitor)
+	userWorkers := workers.NewGroup()
+	roleRepo := initRoleRepository(db)
+	keyRepo := initAPIKeyRepository(db)
+	tokenRepo := initTokenRepository(db)
+	attemptRepo := initAttemptRepository(db)
+	mail, templates, err := initMailer(ctx, cfg)
 	if err != nil {
-		level.Error(logger).Log("msg", "err init nats NewEncodedConn", "err", err)
+		level.Error(logger).Log("msg", "err init mailer", "err", err)
 		os.Exit(1)
 	}
-	defer ec.Close()
-
-	userRepo := initUserRepository(ctx, db, cfg)
-	userNatsPub, err := userQueue.NewPublisher(ec)
+	passwords, err := initPasswordPolicy(ctx, cfg)
 	if err != nil {
-		level.Error(logger).Log("msg", "err init userQueue.Publisher", "err", err)
+		level.Error(logger).Log("msg", "err init password policy", "err", err)
 		os.Exit(1)
 	}
-
-	healthService := initHealthService(ctx, userRepo, userNatsPub)
-	userService := initUserService(ctx, cfg, userRepo, userNatsPub)
+	userService := initUserService(ctx, cfg, userRepo, roleRepo, keyRepo, tokenRepo, attemptRepo, userNatsPub, userWorkers,
+		user.SetMailer(mail, templates),
+		user.SetPasswordPolicy(passwords),
+	)
+	authenticator, superAdmins, userMiddlewares := initAuth(ctx, cfg, userRepo, roleRepo, keyRepo)
 
 	s, err := server.NewServer(
 		server.SetConfig(cfg),
 		server.SetLogger(logger),
+		server.SetMetrics(metric),
+		server.SetAuthenticator(authenticator),
+		server.SetPriorityAuthenticator(superAdmins),
 		server.SetHandler(
 			map[string]http.Handler{
 				"":     health.MakeHTTPHandler(ctx, healthService),
-				"user": user.MakeHTTPHandler(ctx, userService),
+				"user": user.MakeHTTPHandler(ctx, userService, userMiddlewares...),
 			}),
 		server.SetGRPC(
-			user.JoinGRPC(ctx, userService),
+			health.JoinGRPC(ctx, healthService),
+			user.JoinGRPC(ctx, userService, userMiddlewares...),
+			// standard health protocol should be joined last to cover services registered above
+			health.JoinGRPCHealth(monitor),
 		),
+		server.SetOnShutdown(monitor.Drain),
+		// workers are stopped before queue is drained, so their pending publishes are flushed
+		server.SetTeardown(initTeardown(userWorkers, db, nc, broker)...),
 	)
 	if err != nil {
 		level.Error(logger).Log("init", "server", "err", err)
 		os.Exit(1)
 	}
 	defer s.Close()
+	watcher.Subscribe(s.Apply)
 
 	if err := s.AddHTTP(); err != nil {
 		level.Error(logger).Log("err", err)
@@ -132,19 +247,167 @@ func main() {
 		os.Exit(1)
 	}
 
+	s.AddStartup(func(startCtx context.Context) error {
+		if err := awaitDependencies(logging.WithContext(startCtx, logger), cfg, db, nc); err != nil {
+			return err
+		}
+		level.Info(logger).Log("msg", "dependencies are reachable, service is ready")
+		monitor.Started(ctx)
+		return nil
+	})
+
 	s.AddSignalHandler()
 	s.Run()
 }
 
-func initHealthService(ctx context.Context, userRep userRepository.Repository, userNatsPub userQueue.Publisher) health.Service {
+// awaitDependencies retries postgres master and nats in use concurrently until both are reachable
+// or startup deadline passes
+func awaitDependencies(ctx context.Context, cfg *configs.Config, db *database.Connection, nc *nats.Conn) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(cfg.Startup.TimeoutSec))
+	defer cancel()
+	backoff := retry.SetBackoff(
+		time.Millisecond*time.Duration(cfg.Startup.InitialBackoffMsec),
+		time.Millisecond*time.Duration(cfg.Startup.MaxBackoffMsec),
+	)
+
+	deps := map[string]func(ctx context.Context) error{}
+	if db != nil {
+		deps["postgres-master"] = db.PingMaster
+	}
+	if nc != nil {
+		deps["nats"] = natsCl.AwaitConn(nc)
+	}
+	errs := make(chan error, len(deps))
+	for name, probe := range deps {
+		go func(name string, probe func(ctx context.Context) error) {
+			errs <- retry.Do(ctx, name, probe, backoff)
+		}(name, probe)
+	}
+	var res error
+	for range deps {
+		if err := <-errs; err != nil && res == nil {
+			res = err
+		}
+	}
+	return res
+}
+
+func initTracer(ctx context.Context, cfg *configs.Config, sampler *tracing.Sampler) (opentracing.Tracer, io.Closer, error) {
+	if cfg.Tracer.Provider == tracing.ProviderOTLP {
+		return tracing.NewOTelTracer(
+			ctx,
+			cfg.Tracer.OTLP.Endpoint,
+			cfg.Tracer.Name,
+			tracing.SetSampler(sampler),
+			tracing.SetInsecure(cfg.Tracer.OTLP.Insecure),
+		)
+	}
+	return tracing.NewJaegerTracer(
+		ctx,
+		fmt.Sprintf("%s:%d", cfg.Tracer.Host, cfg.Tracer.Port),
+		cfg.Tracer.Name,
+		tracing.SetSampler(sampler),
+	)
+}
+
+// initStoreMetrics exports stats of postgres pools and nats connection, nil ones are not used
+func initStoreMetrics(m *metrics.Metrics, db *database.Connection, nc *nats.Conn) error {
+	pools := map[string]*gorm.DB{}
+	if db != nil {
+		pools["master"] = db.Master
+		for _, r := range db.Replicas {
+			pools[r.Name] = r.DB
+		}
+	}
+	for pool, conn := range pools {
+		sqlDB, err := conn.DB()
+		if err != nil {
+			return err
+		}
+		if err := m.RegisterDB(pool, sqlDB); err != nil {
+			return err
+		}
+	}
+	if nc == nil {
+		return nil
+	}
+	return m.RegisterNATS(nc)
+}
+
+// initTeardown returns shutdown phases closing dependencies in use, nil ones are not used
+func initTeardown(group *workers.Group, db *database.Connection, nc *nats.Conn, broker *userQueue.MemoryBroker) []server.Phase {
+	phases := []server.Phase{{Name: "workers", Run: group.Wait}}
+	if nc != nil {
+		phases = append(phases, server.Phase{Name: "nats", Run: func(ctx context.Context) error {
+			return natsCl.Drain(ctx, nc)
+		}})
+	}
+	if broker != nil {
+		phases = append(phases, server.Phase{Name: "queue", Run: broker.Drain})
+	}
+	if db != nil {
+		phases = append(phases, server.Phase{Name: "database", Run: func(context.Context) error {
+			return db.Close()
+		}})
+	}
+	return phases
+}
+
+func initHealthMonitor(ctx context.Context, cfg *configs.Config, db *database.Connection, nc *nats.Conn) *health.Monitor {
+	monitor := health.NewMonitor(
+		ctx,
+		health.SetInterval(time.Second*time.Duration(cfg.Health.IntervalSec)),
+		health.SetTimeout(time.Millisecond*time.Duration(cfg.Health.TimeoutMsec)),
+	)
+	if db != nil {
+		monitor.Register("postgres-master", health.SeverityCritical, health.CheckerFunc(db.PingMaster))
+		monitor.Register("postgres-replica", health.SeverityDegraded, health.CheckerFunc(db.PingReplica))
+	}
+	if nc != nil {
+		monitor.Register("nats", health.SeverityDegraded, health.CheckerFunc(natsCl.CheckConn(nc)))
+	}
+	return monitor
+}
+
+func initHealthService(ctx context.Context, monitor *health.Monitor) health.Service {
 	var healthService health.Service
-	healthService = health.NewHealthService(userRep, userNatsPub)
+	healthService = health.NewHealthService(monitor)
 	healthService = health.NewLoggingService(ctx, healthService)
 	return healthService
 }
 
-func initUserService(ctx context.Context, cfg *configs.Config, repo userRepository.Repository, ncPub userQueue.Publisher) user.Service {
-	userService := user.NewUserService(repo, ncPub)
+func initUserService(ctx context.Context, cfg *configs.Config, repo userRepository.Repository, roles roleRepository.Repository, keys apikeyRepository.Repository, tokens tokenRepository.Repository, attempts attemptRepository.Repository, ncPub userQueue.Publisher, group *workers.Group, opts ...user.ServiceOption) user.Service {
+	opts = append([]user.ServiceOption{
+		user.SetRotationGrace(time.Second * time.Duration(cfg.Auth.APIKeys.RotationGraceSec)),
+		user.SetVerification(time.Second*time.Duration(cfg.EmailVerification.TokenTTLSec), cfg.EmailVerification.URL),
+		user.SetPasswordReset(
+			time.Second*time.Duration(cfg.PasswordReset.TokenTTLSec),
+			cfg.PasswordReset.URL,
+			time.Second*time.Duration(cfg.PasswordReset.IntervalSec),
+		),
+	}, opts...)
+	if cfg.Lockout.Enabled {
+		window := time.Second * time.Duration(cfg.Lockout.WindowSec)
+		opts = append(opts, user.SetLockout(
+			lockout.Policy{
+				Window:    window,
+				Threshold: cfg.Lockout.Account.Threshold,
+				Duration:  time.Second * time.Duration(cfg.Lockout.Account.DurationSec),
+				Delay:     time.Millisecond * time.Duration(cfg.Lockout.Account.DelayMsec),
+				MaxDelay:  time.Millisecond * time.Duration(cfg.Lockout.Account.MaxDelayMsec),
+			},
+			lockout.Policy{
+				Window:    window,
+				Threshold: cfg.Lockout.IP.Threshold,
+				Duration:  time.Second * time.Duration(cfg.Lockout.IP.DurationSec),
+			},
+			attempts,
+		))
+	}
+	if cfg.Metrics.Enabled {
+		opts = append(opts, user.SetMetrics(metrics.Instance(ctx)))
+	}
+	userService := user.NewUserService(repo, roles, keys, tokens, ncPub, group, opts...)
 	if cfg.Metrics.Enabled {
 		userService = user.NewMetricsService(ctx, userService)
 	}
@@ -158,13 +421,128 @@ func initUserService(ctx context.Context, cfg *configs.Config, repo userReposito
 	return userService
 }
 
-func initUserRepository(ctx context.Context, db *database.Connection, cfg *configs.Config) userRepository.Repository {
-	repo := userRepository.NewRepository(db)
+func initRoleRepository(db *database.Connection) roleRepository.Repository {
+	if db != nil {
+		return roleRepository.NewRepository(db)
+	}
+	return roleRepository.NewMemoryRepository()
+}
+
+func initAPIKeyRepository(db *database.Connection) apikeyRepository.Repository {
+	if db != nil {
+		return apikeyRepository.NewRepository(db)
+	}
+	return apikeyRepository.NewMemoryRepository()
+}
+
+func initTokenRepository(db *database.Connection) tokenRepository.Repository {
+	if db != nil {
+		return tokenRepository.NewRepository(db)
+	}
+	return tokenRepository.NewMemoryRepository()
+}
+
+func initAttemptRepository(db *database.Connection) attemptRepository.Repository {
+	if db != nil {
+		return attemptRepository.NewRepository(db)
+	}
+	return attemptRepository.NewMemoryRepository()
+}
+
+// initMailer returns mailer of emails sent to users and their templates
+func initMailer(ctx context.Context, cfg *configs.Config) (mailer.Mailer, *mailer.Templates, error) {
+	templates, err := mailer.LoadTemplates(cfg.Mailer.TemplatesDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch cfg.Mailer.Driver {
+	case configs.MailerSMTP:
+		opts := []mailer.SMTPOption{
+			mailer.SetStartTLS(cfg.Mailer.SMTP.StartTLS),
+			mailer.SetTimeout(time.Millisecond * time.Duration(cfg.Mailer.SMTP.TimeoutMsec)),
+		}
+		if cfg.Mailer.SMTP.Username != "" {
+			opts = append(opts, mailer.SetAuth(cfg.Mailer.SMTP.Username, cfg.Mailer.SMTP.Password.Reveal()))
+		}
+		addr := net.JoinHostPort(cfg.Mailer.SMTP.Host, strconv.Itoa(cfg.Mailer.SMTP.Port))
+		m, err := mailer.NewSMTP(addr, cfg.Mailer.From, opts...)
+		return m, templates, err
+	case configs.MailerOutbox:
+		level.Warn(logging.FromContext(ctx)).Log("msg", "emails are not delivered, they are written to outbox", "dir", cfg.Mailer.Outbox.Dir)
+		m, err := mailer.NewOutbox(cfg.Mailer.Outbox.Dir, cfg.Mailer.From)
+		return m, templates, err
+	default:
+		level.Warn(logging.FromContext(ctx)).Log("msg", "emails are not delivered, they are written to log")
+		return mailer.NewLog(), templates, nil
+	}
+}
+
+// initPasswordPolicy returns rules of new passwords, with breached passwords loaded from file when it is set
+func initPasswordPolicy(ctx context.Context, cfg *configs.Config) (*password.Policy, error) {
+	policy := &password.Policy{
+		MinLength:      cfg.Password.MinLength,
+		MaxLength:      cfg.Password.MaxLength,
+		MinClasses:     cfg.Password.MinClasses,
+		ForbidPersonal: cfg.Password.ForbidPersonal,
+	}
+	if cfg.Password.Breached.File == "" {
+		return policy, nil
+	}
+	begin := time.Now()
+	breached, err := password.LoadBreached(cfg.Password.Breached.File, cfg.Password.Breached.FalsePositiveRate)
+	if err != nil {
+		return nil, err
+	}
+	level.Info(logging.FromContext(ctx)).Log("msg", "loaded breached passwords", "count", password.Len(breached), "took", time.Since(begin))
+	policy.Breached = breached
+	return policy, nil
+}
+
+// initAuth returns authenticator of callers and authorizer of user methods, none when auth is disabled.
+// Credentials looking like API keys are checked against keys, others are verified as JWT. Bare JWT verification
+// is returned too, it recognizes superadmins without storage while server is overloaded.
+func initAuth(ctx context.Context, cfg *configs.Config, users userRepository.Repository, roles roleRepository.Repository, keys apikeyRepository.Repository) (auth.Authenticator, auth.Authenticator, []user.EndpointMiddleware) {
+	if !cfg.Auth.Enabled {
+		level.Warn(logging.FromContext(ctx)).Log("msg", "auth is disabled, user methods are allowed to anonymous callers")
+		return nil, nil, nil
+	}
+	jwt := auth.NewJWT(
+		[]byte(cfg.Auth.JWT.Secret.Reveal()),
+		auth.SetIssuer(cfg.Auth.JWT.Issuer),
+		auth.SetAudience(cfg.Auth.JWT.Audience),
+		auth.SetLeeway(time.Second*time.Duration(cfg.Auth.JWT.LeewaySec)),
+	)
+	// sessions issued before password change or reset are revoked
+	tokens := auth.WithRevocation(jwt, userRepository.NewRevoker(users))
+	authenticator := auth.Route(apikeyRepository.Marker, apikeyRepository.NewAuthenticator(keys), tokens)
+	return authenticator, jwt, []user.EndpointMiddleware{user.NewAuthorizer(roles)}
+}
+
+func initUserRepository(ctx context.Context, db *database.Connection, inv userRepository.Invalidator, cfg *configs.Config) (userRepository.Repository, error) {
+	var repo userRepository.Repository
+	if db != nil {
+		repo = userRepository.NewRepository(db)
+	} else {
+		repo = userRepository.NewMemoryRepository()
+	}
+	if cfg.Cache.Enabled {
+		var err error
+		repo, err = userRepository.NewCachingRepository(
+			ctx,
+			repo,
+			inv,
+			userRepository.SetCacheSize(cfg.Cache.Size),
+			userRepository.SetCacheTTL(time.Second*time.Duration(cfg.Cache.TTLSec)),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if cfg.Tracer.Enabled {
 		repo = userRepository.NewTracingRepository(ctx, repo)
 	}
 	if cfg.Sentry.Enabled {
 		repo = userRepository.NewSentryService(repo)
 	}
-	return repo
+	return repo, nil
 }
@@ -5,9 +5,12 @@ import (
 	"flag"
 	"fmt"
 	"github.com/nakiner/faceit/pkg/store/nats"
+	"github.com/nakiner/faceit/tools/secrets"
+	"github.com/nakiner/faceit/tools/tlsconfig"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"io/ioutil"
 	"os"
 	"strings"
 )
@@ -15,93 +18,349 @@ import (
 // ServiceName Used to define service prefix
 const ServiceName = "faceit"
 
+// Drivers of storage and queue, memory ones keep data in process and are meant for local development and tests
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+	QueueNATS       = "nats"
+	QueueMemory     = "memory"
+)
+
+// Drivers of mailer, log and outbox ones do not deliver emails and are meant for local development and tests
+const (
+	MailerSMTP   = "smtp"
+	MailerLog    = "log"
+	MailerOutbox = "outbox"
+)
+
+// fileSuffix is appended to secret option name to read its value from file
+const fileSuffix = "_file"
+
 // options slice to map all values into configuration
 var options = []option{
 	{"config", "string", "", "config file"},
 
+	{"server.mode", "string", "split", "Serving mode: split serves HTTP and GRPC on their own ports, single multiplexes both on http port"},
 	{"server.http.port", "int", 8080, "server http port"},
 	{"server.http.timeout_sec", "int", 86400, "server http connection timeout"},
 	{"server.grpc.port", "int", 9194, "server grpc port"},
 	{"server.grpc.timeout_sec", "int", 86400, "server grpc connection timeout"},
+	{"server.http.tls.enabled", "bool", false, "Enables TLS on http server"},
+	{"server.http.tls.cert_file", "string", "", "http server TLS certificate file, reloaded on change"},
+	{"server.http.tls.key_file", "string", "", "http server TLS private key file, reloaded on change"},
+	{"server.http.tls.ca_file", "string", "", "http server CA bundle used to verify client certificates"},
+	{"server.http.tls.min_version", "string", "1.2", "http server minimal TLS version: 1.2 or 1.3"},
+	{"server.http.tls.client_auth", "string", "none", "http server client certificate policy: none, request, require, verify_if_given, require_and_verify"},
+	{"server.grpc.tls.enabled", "bool", false, "Enables TLS on grpc server"},
+	{"server.grpc.tls.cert_file", "string", "", "grpc server TLS certificate file, reloaded on change"},
+	{"server.grpc.tls.key_file", "string", "", "grpc server TLS private key file, reloaded on change"},
+	{"server.grpc.tls.ca_file", "string", "", "grpc server CA bundle used to verify client certificates"},
+	{"server.grpc.tls.min_version", "string", "1.2", "grpc server minimal TLS version: 1.2 or 1.3"},
+	{"server.grpc.tls.client_auth", "string", "none", "grpc server client certificate policy: none, request, require, verify_if_given, require_and_verify"},
+
+	{"storage.driver", "string", StoragePostgres, "Users storage: postgres or memory, memory is not persisted and is meant for local development"},
+	{"queue.driver", "string", QueueNATS, "User events queue: nats or memory, memory delivers events in process only"},
+
+	{"cache.enabled", "bool", false, "Enables in-process cache of users read by id, invalidated on all instances through the queue"},
+	{"cache.size", "int", 10000, "Maximum number of cached users, least recently used are evicted"},
+	{"cache.ttl_sec", "int", 60, "Cached user is read again after TTL, bounds staleness when invalidation is lost"},
+
+	{"tenant.default", "string", "default", "Tenant of requests without X-Tenant-ID header or tenant claim, empty requires one"},
+
+	{"auth.enabled", "bool", false, "Enables bearer token authentication and role-based access control of user methods"},
+	{"auth.jwt.secret", "secret", "", "HS256 key tokens are signed with, required when auth is enabled"},
+	{"auth.jwt.issuer", "string", "", "Required iss claim of tokens, empty accepts any"},
+	{"auth.jwt.audience", "string", "", "Required aud claim of tokens, empty accepts any"},
+	{"auth.jwt.leeway_sec", "int", 30, "Allowed clock skew when checking exp and nbf claims"},
+	{"auth.api_keys.rotation_grace_sec", "int", 86400, "How long rotated API keys stay valid when rotation does not set grace period"},
+
+	{"mailer.driver", "string", MailerLog, "Delivery of emails: smtp, log or outbox, log writes them to log and outbox to files"},
+	{"mailer.from", "string", "Faceit <noreply@localhost>", "Sender of emails"},
+	{"mailer.templates_dir", "string", "", "Directory with <name>.tmpl files replacing built-in email templates"},
+	{"mailer.smtp.host", "string", "localhost", "SMTP server host"},
+	{"mailer.smtp.port", "int", 587, "SMTP server port"},
+	{"mailer.smtp.username", "string", "", "SMTP user, empty disables authentication"},
+	{"mailer.smtp.password", "secret", "", "SMTP password"},
+	{"mailer.smtp.starttls", "bool", true, "Upgrades connection to TLS when server supports it"},
+	{"mailer.smtp.timeout_msec", "int", 10000, "How long sending of an email may take"},
+	{"mailer.outbox.dir", "string", "outbox", "Directory emails are written to as .eml files"},
+
+	{"email_verification.token_ttl_sec", "int", 86400, "How long email verification links stay valid"},
+	{"email_verification.url", "string", "", "Page verifying email, token is appended as query parameter; empty sends token alone"},
+	{"password.min_length", "int", 8, "Minimal number of characters of new passwords"},
+	{"password.max_length", "int", 64, "Maximal number of characters of new passwords, at most 64"},
+	{"password.min_classes", "int", 0, "How many of lowercase, uppercase, digit and other characters new passwords must contain, 0-4"},
+	{"password.forbid_personal", "bool", true, "Reject new passwords containing nickname or email of user"},
+	{"password.breached.file", "string", "", "File of SHA-1 hashes of breached passwords rejected as new ones, empty disables screening"},
+	{"password.breached.false_positive_rate", "float64", 0.0, "Above 0 loads breached passwords into bloom filter of that false positive rate, 0 keeps exact list"},
+
+	{"password_reset.token_ttl_sec", "int", 3600, "How long password reset links stay valid"},
+	{"password_reset.url", "string", "", "Page resetting password, token is appended as query parameter; empty sends token alone"},
+	{"password_reset.interval_sec", "int", 60, "Minimal interval between password reset emails sent to one user"},
+	{"lockout.enabled", "bool", true, "Refuse password checks after failed ones of account or client ip"},
+	{"lockout.window_sec", "int", 900, "Period failed password checks are counted in"},
+	{"lockout.account.threshold", "int", 10, "Failed checks of account in window locking it, 0 disables account lockout"},
+	{"lockout.account.duration_sec", "int", 900, "How long account stays locked after its latest failed check"},
+	{"lockout.account.delay_msec", "int", 1000, "Delay of checks of account after failed one, doubled per further failure, 0 disables delays"},
+	{"lockout.account.max_delay_msec", "int", 30000, "Maximal delay of checks of account"},
+	{"lockout.ip.threshold", "int", 100, "Failed checks from client ip in window locking it, 0 disables ip lockout"},
+	{"lockout.ip.duration_sec", "int", 3600, "How long client ip stays locked after its latest failed check"},
+
+	{"postgres.slow_query_threshold_msec", "int", 200, "Statements slower than threshold are logged, 0 disables"},
+	{"postgres.max_replica_lag_msec", "int", 5000, "Replicas lagging behind master longer are not read from, 0 disables lag check"},
+	{"postgres.read_your_writes", "bool", true, "Reads of a request go to master after it has written"},
 
 	{"postgres.master.host", "string", "localhost", "postgres master host"},
 	{"postgres.master.port", "int", 5432, "postgres master port"},
 	{"postgres.master.user", "string", "postgres", "postgres master user"},
-	{"postgres.master.password", "string", "postgres", "postgres master password"},
+	{"postgres.master.password", "secret", "postgres", "postgres master password"},
 	{"postgres.master.database_name", "string", "faceit", "postgres master database name"},
 	{"postgres.master.secure", "string", "disable", "postgres master SSL support"},
+	{"postgres.master.max_open_conns", "int", 20, "postgres master maximum of open connections, 0 is unlimited"},
+	{"postgres.master.max_idle_conns", "int", 5, "postgres master maximum of idle connections"},
+	{"postgres.master.conn_max_lifetime_sec", "int", 1800, "postgres master connection is closed after lifetime, 0 keeps it forever"},
+	{"postgres.master.conn_max_idle_time_sec", "int", 300, "postgres master idle connection is closed after idle time, 0 keeps it forever"},
+	{"postgres.master.statement_timeout_msec", "int", 0, "postgres master statement_timeout, 0 disables"},
 
 	{"postgres.replica.host", "string", "localhost", "postgres replica host"},
 	{"postgres.replica.port", "int", 5432, "postgres replica port"},
 	{"postgres.replica.user", "string", "postgres", "postgres replica user"},
-	{"postgres.replica.password", "string", "postgres", "postgres replica password"},
+	{"postgres.replica.password", "secret", "postgres", "postgres replica password"},
 	{"postgres.replica.database_name", "string", "faceit", "postgres replica database name"},
 	{"postgres.replica.secure", "string", "disable", "postgres replica SSL support"},
+	{"postgres.replica.max_open_conns", "int", 20, "postgres replica maximum of open connections, 0 is unlimited"},
+	{"postgres.replica.max_idle_conns", "int", 5, "postgres replica maximum of idle connections"},
+	{"postgres.replica.conn_max_lifetime_sec", "int", 1800, "postgres replica connection is closed after lifetime, 0 keeps it forever"},
+	{"postgres.replica.conn_max_idle_time_sec", "int", 300, "postgres replica idle connection is closed after idle time, 0 keeps it forever"},
+	{"postgres.replica.statement_timeout_msec", "int", 0, "postgres replica statement_timeout, 0 disables"},
 
 	{"nats.host", "string", "127.0.0.1", "The nats host"},
 	{"nats.port", "int", 4222, "The nats port"},
 	{"nats.username", "string", "", "The nats user login"},
-	{"nats.password", "string", "", "The nats user password"},
+	{"nats.password", "secret", "", "The nats user password"},
 	{"nats.request_timeout_msec", "int", 500000, "The nats connection timeout in msec"},
 	{"nats.retry_limit", "int", 5, "Reconnection limit to the nats"},
 	{"nats.reconnect_time_wait_msec", "int", 500, "Reconnect time wait to the nats in msec"},
 
+	{"startup.timeout_sec", "int", 60, "Deadline for postgres and nats to become reachable on startup, service stops when it passes"},
+	{"startup.initial_backoff_msec", "int", 250, "Delay before second connect attempt on startup, doubles on every failure"},
+	{"startup.max_backoff_msec", "int", 5000, "Upper bound of delay between connect attempts on startup"},
+
+	{"shutdown.pre_stop_delay_sec", "int", 5, "Delay between failing readiness and stopping transports, lets load balancers notice"},
+	{"shutdown.timeout_sec", "int", 30, "Deadline for in-flight HTTP and GRPC requests to finish on shutdown"},
+	{"shutdown.teardown_timeout_sec", "int", 10, "Deadline of every dependency teardown phase on shutdown"},
+
+	{"health.interval_sec", "int", 10, "Period between dependency health probes"},
+	{"health.timeout_msec", "int", 2000, "Timeout of a single dependency health probe"},
+
 	{"logger.level", "string", "emerg", "Level of logging. A string that correspond to the following levels: emerg, alert, crit, err, warning, notice, info, debug"},
 	{"logger.time_format", "string", "2006-01-02T15:04:05.999999999", "Date format in logs"},
 
 	{"sentry.enabled", "bool", false, "Enables or disables sentry"},
-	{"sentry.dsn", "string", "https://95d1f24a6486440fb38d19ee6a63d32a@sentry.dev.kubedev.ru/18", "Data source name. Sentry addr"},
+	{"sentry.dsn", "secret", "", "Data source name. Sentry addr, required when sentry is enabled"},
 	{"sentry.environment", "string", "local", "The environment to be sent with events."},
 
 	{"tracer.enabled", "bool", false, "Enables or disables tracing"},
 	{"tracer.host", "string", "127.0.0.1", "The tracer host"},
 	{"tracer.port", "int", 5775, "The tracer port"},
 	{"tracer.name", "string", "export", "The tracer name"},
+	{"tracer.sample_rate", "float64", 1.0, "Ratio of sampled traces between 0 and 1, applied live on reload"},
+	{"tracer.provider", "string", "jaeger", "Tracer implementation: jaeger agent or OpenTelemetry otlp"},
+	{"tracer.otlp.endpoint", "string", "127.0.0.1:4317", "The OTLP/gRPC collector endpoint"},
+	{"tracer.otlp.insecure", "bool", true, "Disables TLS to OTLP collector"},
+
+	{"cors.allowed_origins", "string", "*", "Comma separated origins allowed by CORS, * allows any"},
+	{"cors.allowed_methods", "string", "GET, POST, OPTIONS, PUT, DELETE, UPDATE, PATCH", "Methods allowed by CORS"},
+	{"cors.allowed_headers", "string", "Origin, Content-Type, Authorization, X-Tenant-ID", "Headers allowed by CORS"},
 
 	{"metrics.enabled", "bool", false, "Enables or disables metrics"},
 	{"metrics.port", "int", 9153, "server http port"},
+	{"metrics.buckets", "string", "0.005,0.01,0.025,0.05,0.1,0.25,0.5,1,2.5,5,10", "Comma separated latency histogram buckets in seconds"},
+	{"metrics.tls.enabled", "bool", false, "Enables TLS on metrics server"},
+	{"metrics.tls.cert_file", "string", "", "metrics server TLS certificate file, reloaded on change"},
+	{"metrics.tls.key_file", "string", "", "metrics server TLS private key file, reloaded on change"},
+	{"metrics.tls.ca_file", "string", "", "metrics server CA bundle used to verify client certificates"},
+	{"metrics.tls.min_version", "string", "1.2", "metrics server minimal TLS version: 1.2 or 1.3"},
+	{"metrics.tls.client_auth", "string", "none", "metrics server client certificate policy: none, request, require, verify_if_given, require_and_verify"},
 
 	{"limiter.enabled", "bool", false, "Enables or disables limiter"},
 	{"limiter.limit", "float64", 10000.0, "Limit tokens per second"},
+	{"limiter.burst", "int", 0, "Bucket size per client, defaults to limit"},
+	{"limiter.trusted_proxies", "string", "", "Comma separated CIDRs of proxies allowed to set X-Forwarded-For"},
+	{"limiter.max_keys", "int", 10000, "Max number of tracked client keys"},
+	{"limiter.idle_timeout_sec", "int", 600, "Client key is evicted after being idle for this time"},
+
+	{"concurrency.enabled", "bool", false, "Enables or disables adaptive concurrency limiter"},
+	{"concurrency.initial_limit", "int", 20, "Initial concurrency limit"},
+	{"concurrency.min_limit", "int", 5, "Lower bound of concurrency limit"},
+	{"concurrency.max_limit", "int", 500, "Upper bound of concurrency limit"},
+	{"concurrency.latency_target_msec", "int", 250, "Latency above which concurrency limit is decreased"},
+	{"concurrency.backoff", "float64", 0.9, "Multiplier applied to concurrency limit on overload"},
+	{"concurrency.priority", "string", "/liveness,/readiness,/version,/faceitpb.HealthService/,/grpc.health.v1.Health/", "Comma separated path prefixes never shed by concurrency limiter"},
 }
 
 type Config struct {
 	Server struct {
+		Mode string
 		GRPC struct {
 			Port       int
 			TimeoutSec int `mapstructure:"timeout_sec"`
+			TLS        tlsconfig.Config
 		}
 		HTTP struct {
 			Port       int
 			TimeoutSec int `mapstructure:"timeout_sec"`
+			TLS        tlsconfig.Config
 		}
 	}
+	Startup struct {
+		TimeoutSec         int `mapstructure:"timeout_sec"`
+		InitialBackoffMsec int `mapstructure:"initial_backoff_msec"`
+		MaxBackoffMsec     int `mapstructure:"max_backoff_msec"`
+	}
+	Shutdown struct {
+		PreStopDelaySec    int `mapstructure:"pre_stop_delay_sec"`
+		TimeoutSec         int `mapstructure:"timeout_sec"`
+		TeardownTimeoutSec int `mapstructure:"teardown_timeout_sec"`
+	}
+	Health struct {
+		IntervalSec int `mapstructure:"interval_sec"`
+		TimeoutMsec int `mapstructure:"timeout_msec"`
+	}
 	Logger struct {
 		Level      string
 		TimeFormat string `mapstructure:"time_format"`
 	}
 	Sentry struct {
 		Enabled     bool
-		Dsn         string
+		Dsn         secrets.String
 		Environment string
 	}
 	Tracer struct {
-		Enabled bool
-		Host    string
-		Port    int
-		Name    string
+		Enabled    bool
+		Host       string
+		Port       int
+		Name       string
+		SampleRate float64 `mapstructure:"sample_rate"`
+		Provider   string
+		OTLP       struct {
+			Endpoint string
+			Insecure bool
+		}
 	}
-	Metrics struct {
+	CORS struct {
+		AllowedOrigins string `mapstructure:"allowed_origins"`
+		AllowedMethods string `mapstructure:"allowed_methods"`
+		AllowedHeaders string `mapstructure:"allowed_headers"`
+	}
+	Features map[string]bool
+	Metrics  struct {
 		Enabled bool
 		Port    int
+		Buckets string
+		TLS     tlsconfig.Config
 	}
 	Limiter struct {
+		Enabled        bool
+		Limit          float64
+		Burst          int
+		TrustedProxies string `mapstructure:"trusted_proxies"`
+		MaxKeys        int    `mapstructure:"max_keys"`
+		IdleTimeoutSec int    `mapstructure:"idle_timeout_sec"`
+		Routes         []LimiterRoute
+	}
+	Concurrency struct {
+		Enabled           bool
+		InitialLimit      int `mapstructure:"initial_limit"`
+		MinLimit          int `mapstructure:"min_limit"`
+		MaxLimit          int `mapstructure:"max_limit"`
+		LatencyTargetMsec int `mapstructure:"latency_target_msec"`
+		Backoff           float64
+		Priority          string
+	}
+	Storage struct {
+		Driver string
+	}
+	Queue struct {
+		Driver string
+	}
+	Cache struct {
 		Enabled bool
-		Limit   float64
+		Size    int
+		TTLSec  int `mapstructure:"ttl_sec"`
+	}
+	Tenant struct {
+		Default string
+	}
+	Auth struct {
+		Enabled bool
+		JWT     struct {
+			Secret    secrets.String
+			Issuer    string
+			Audience  string
+			LeewaySec int `mapstructure:"leeway_sec"`
+		}
+		APIKeys struct {
+			RotationGraceSec int `mapstructure:"rotation_grace_sec"`
+		} `mapstructure:"api_keys"`
+	}
+	Mailer struct {
+		Driver       string
+		From         string
+		TemplatesDir string `mapstructure:"templates_dir"`
+		SMTP         struct {
+			Host        string
+			Port        int
+			Username    string
+			Password    secrets.String
+			StartTLS    bool `mapstructure:"starttls"`
+			TimeoutMsec int  `mapstructure:"timeout_msec"`
+		}
+		Outbox struct {
+			Dir string
+		}
+	}
+	EmailVerification struct {
+		TokenTTLSec int `mapstructure:"token_ttl_sec"`
+		URL         string
+	} `mapstructure:"email_verification"`
+	Password struct {
+		MinLength      int  `mapstructure:"min_length"`
+		MaxLength      int  `mapstructure:"max_length"`
+		MinClasses     int  `mapstructure:"min_classes"`
+		ForbidPersonal bool `mapstructure:"forbid_personal"`
+		Breached       struct {
+			File              string
+			FalsePositiveRate float64 `mapstructure:"false_positive_rate"`
+		}
+	}
+	PasswordReset struct {
+		TokenTTLSec int `mapstructure:"token_ttl_sec"`
+		URL         string
+		IntervalSec int `mapstructure:"interval_sec"`
+	} `mapstructure:"password_reset"`
+	Lockout struct {
+		Enabled   bool
+		WindowSec int `mapstructure:"window_sec"`
+		Account   struct {
+			Threshold    int
+			DurationSec  int `mapstructure:"duration_sec"`
+			DelayMsec    int `mapstructure:"delay_msec"`
+			MaxDelayMsec int `mapstructure:"max_delay_msec"`
+		}
+		IP struct {
+			Threshold   int
+			DurationSec int `mapstructure:"duration_sec"`
+		} `mapstructure:"ip"`
 	}
 	Postgres struct {
 		Master  Database
 		Replica Database
+		// Replicas are read from in addition to Replica
+		Replicas               []Database
+		SlowQueryThresholdMsec int  `mapstructure:"slow_query_threshold_msec"`
+		MaxReplicaLagMsec      int  `mapstructure:"max_replica_lag_msec"`
+		ReadYourWrites         bool `mapstructure:"read_your_writes"`
 	}
 	Nats nats.Config
 }
@@ -110,9 +369,29 @@ type Database struct {
 	Host         string
 	Port         int
 	User         string
-	Password     string
+	Password     secrets.String
+	PasswordFile string `mapstructure:"password_file"`
 	DatabaseName string `mapstructure:"database_name"`
 	Secure       string
+
+	MaxOpenConns         int `mapstructure:"max_open_conns"`
+	MaxIdleConns         int `mapstructure:"max_idle_conns"`
+	ConnMaxLifetimeSec   int `mapstructure:"conn_max_lifetime_sec"`
+	ConnMaxIdleTimeSec   int `mapstructure:"conn_max_idle_time_sec"`
+	StatementTimeoutMsec int `mapstructure:"statement_timeout_msec"`
+}
+
+// ReplicaNodes returns every replica reads are routed to.
+func (c *Config) ReplicaNodes() []Database {
+	return append([]Database{c.Postgres.Replica}, c.Postgres.Replicas...)
+}
+
+// LimiterRoute overrides limiter quota for requests matching method and path prefix.
+type LimiterRoute struct {
+	Method string
+	Path   string
+	Limit  float64
+	Burst  int
 }
 
 type option struct {
@@ -137,6 +416,9 @@ func (c *Config) Read() error {
 		switch o.typing {
 		case "string":
 			pflag.String(o.name, o.value.(string), o.description)
+		case "secret":
+			pflag.String(o.name, o.value.(string), o.description)
+			pflag.String(o.name+fileSuffix, "", "file to read "+o.description+" from")
 		case "int":
 			pflag.Int(o.name, o.value.(int), o.description)
 		case "bool":
@@ -161,9 +443,48 @@ func (c *Config) Read() error {
 		}
 	}
 
+	return c.unmarshal()
+}
+
+// unmarshal resolves secrets given by files and decodes viper state into c.
+func (c *Config) unmarshal() error {
+	if err := readSecretFiles(); err != nil {
+		return err
+	}
 	if err := viper.Unmarshal(c); err != nil {
 		return errors.Wrap(err, "failed to unmarshal")
 	}
+	// additional replicas are listed in file only, so their secret files are read here
+	for i, r := range c.Postgres.Replicas {
+		if r.PasswordFile == "" {
+			continue
+		}
+		b, err := ioutil.ReadFile(r.PasswordFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read postgres.replicas[%d].password_file", i)
+		}
+		c.Postgres.Replicas[i].Password = secrets.String(strings.TrimRight(string(b), "\r\n"))
+	}
+	return nil
+}
+
+// readSecretFiles reads secrets whose <name>_file flag, env or file setting points to a file,
+// e.g. FACEIT_POSTGRES_MASTER_PASSWORD_FILE, file content overrides plain value.
+func readSecretFiles() error {
+	for _, o := range options {
+		if o.typing != "secret" {
+			continue
+		}
+		path := viper.GetString(o.name + fileSuffix)
+		if path == "" {
+			continue
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s%s", o.name, fileSuffix)
+		}
+		viper.Set(o.name, strings.TrimRight(string(b), "\r\n"))
+	}
 	return nil
 }
 
@@ -1,9 +1,15 @@
 package configs
 
 import (
+	"encoding/json"
 	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -20,3 +26,67 @@ func TestConfig_Read(t *testing.T) {
 	c.Read()
 	assert.Equal(t, exp.Metrics.Port, c.Metrics.Port)
 }
+
+func TestConfig_Validate(t *testing.T) {
+	c := NewConfig()
+	for _, o := range options {
+		viper.SetDefault(o.name, o.value)
+	}
+	defer viper.Reset()
+	assert.NoError(t, viper.Unmarshal(c))
+	assert.NoError(t, c.Validate())
+
+	c.Server.Mode = "mixed"
+	c.Postgres.Master.Host = ""
+	c.Queue.Driver = "kafka"
+	c.Mailer.Driver = "sendmail"
+	c.Password.MaxLength = 128
+	c.Lockout.Account.MaxDelayMsec = 0
+	c.Sentry.Enabled = true
+	c.Metrics.Enabled = true
+	c.Metrics.TLS.Enabled = true
+	c.Tracer.Enabled = true
+	c.Tracer.Provider = "zipkin"
+	c.Startup.MaxBackoffMsec = 100
+	c.Limiter.TrustedProxies = "10.0.0.0/8, 10.0.0.0/33"
+	c.Tenant.Default = "brand.a"
+	c.Auth.Enabled = true
+	err := c.Validate()
+	var verr *ValidationError
+	assert.True(t, errors.As(err, &verr))
+	assert.Equal(t, []string{
+		`server.mode must be split or single, got "mixed"`,
+		"postgres.master.host is required",
+		`queue.driver must be nats or memory, got "kafka"`,
+		`mailer.driver must be smtp, log or outbox, got "sendmail"`,
+		"password.min_length and password.max_length must satisfy 0 <= min_length <= max_length <= 64, got 8 and 128",
+		"lockout.account.delay_msec and lockout.account.max_delay_msec must satisfy 0 <= delay_msec <= max_delay_msec, got 1000 and 0",
+		`tenant.default: tenant id must be 1-64 letters, digits, '-' or '_', got "brand.a"`,
+		"auth.jwt.secret is required when auth is enabled",
+		"sentry.dsn is required when sentry is enabled",
+		`tracer.provider must be jaeger or otlp, got "zipkin"`,
+		"metrics.tls.cert_file is required",
+		"metrics.tls.key_file is required",
+		"startup.max_backoff_msec must not be less than startup.initial_backoff_msec",
+		"limiter.trusted_proxies: invalid CIDR address: 10.0.0.0/33",
+	}, verr.Problems)
+}
+
+func TestConfig_SecretFile(t *testing.T) {
+	defer viper.Reset()
+	fileName := filepath.Join(t.TempDir(), "password")
+	assert.NoError(t, ioutil.WriteFile(fileName, []byte("s3cr3t\n"), 0600))
+	os.Setenv("FACEIT_POSTGRES_MASTER_PASSWORD_FILE", fileName)
+	defer os.Unsetenv("FACEIT_POSTGRES_MASTER_PASSWORD_FILE")
+	viper.SetEnvPrefix(ServiceName)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	viper.AutomaticEnv()
+
+	c := NewConfig()
+	assert.NoError(t, c.unmarshal())
+	assert.Equal(t, "s3cr3t", c.Postgres.Master.Password.Reveal())
+
+	b, err := json.Marshal(c)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "s3cr3t")
+}
@@ -0,0 +1,263 @@
+package configs
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/nakiner/faceit/tools/limiting"
+	"github.com/nakiner/faceit/tools/logging"
+	"github.com/nakiner/faceit/tools/metrics"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/nakiner/faceit/tools/tlsconfig"
+	"github.com/nakiner/faceit/tools/tracing"
+)
+
+// maxPasswordLength is size of password column
+const maxPasswordLength = 64
+
+// ValidationError lists every invalid setting found in configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+type validator struct {
+	problems []string
+}
+
+func (v *validator) addf(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+func (v *validator) required(key, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.addf("%s is required", key)
+	}
+}
+
+func (v *validator) port(key string, value int) {
+	if value < 1 || value > 65535 {
+		v.addf("%s must be between 1 and 65535, got %d", key, value)
+	}
+}
+
+func (v *validator) tls(key string, cfg tlsconfig.Config) {
+	if !cfg.Enabled {
+		return
+	}
+	v.required(key+".cert_file", cfg.CertFile)
+	v.required(key+".key_file", cfg.KeyFile)
+}
+
+func (v *validator) database(key string, db Database) {
+	v.required(key+".host", db.Host)
+	v.port(key+".port", db.Port)
+	v.required(key+".user", db.User)
+	v.required(key+".database_name", db.DatabaseName)
+	for _, setting := range []struct {
+		name  string
+		value int
+	}{
+		{"max_open_conns", db.MaxOpenConns},
+		{"max_idle_conns", db.MaxIdleConns},
+		{"conn_max_lifetime_sec", db.ConnMaxLifetimeSec},
+		{"conn_max_idle_time_sec", db.ConnMaxIdleTimeSec},
+		{"statement_timeout_msec", db.StatementTimeoutMsec},
+	} {
+		if setting.value < 0 {
+			v.addf("%s.%s must not be negative", key, setting.name)
+		}
+	}
+}
+
+// Validate checks required and dependent settings, all problems are reported at once.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	if c.Server.Mode != "split" && c.Server.Mode != "single" {
+		v.addf("server.mode must be split or single, got %q", c.Server.Mode)
+	}
+	v.port("server.http.port", c.Server.HTTP.Port)
+	v.tls("server.http.tls", c.Server.HTTP.TLS)
+	if c.Server.Mode != "single" {
+		v.port("server.grpc.port", c.Server.GRPC.Port)
+		v.tls("server.grpc.tls", c.Server.GRPC.TLS)
+	}
+
+	switch c.Storage.Driver {
+	case StoragePostgres:
+		v.database("postgres.master", c.Postgres.Master)
+		v.database("postgres.replica", c.Postgres.Replica)
+		for i, r := range c.Postgres.Replicas {
+			v.database(fmt.Sprintf("postgres.replicas[%d]", i), r)
+		}
+		if c.Postgres.SlowQueryThresholdMsec < 0 {
+			v.addf("postgres.slow_query_threshold_msec must not be negative")
+		}
+		if c.Postgres.MaxReplicaLagMsec < 0 {
+			v.addf("postgres.max_replica_lag_msec must not be negative")
+		}
+	case StorageMemory:
+	default:
+		v.addf("storage.driver must be postgres or memory, got %q", c.Storage.Driver)
+	}
+
+	switch c.Queue.Driver {
+	case QueueNATS:
+		v.required("nats.host", c.Nats.Host)
+		v.port("nats.port", c.Nats.Port)
+	case QueueMemory:
+	default:
+		v.addf("queue.driver must be nats or memory, got %q", c.Queue.Driver)
+	}
+
+	switch c.Mailer.Driver {
+	case MailerSMTP:
+		v.required("mailer.smtp.host", c.Mailer.SMTP.Host)
+		v.port("mailer.smtp.port", c.Mailer.SMTP.Port)
+	case MailerOutbox:
+		v.required("mailer.outbox.dir", c.Mailer.Outbox.Dir)
+	case MailerLog:
+	default:
+		v.addf("mailer.driver must be smtp, log or outbox, got %q", c.Mailer.Driver)
+	}
+	if _, err := mail.ParseAddress(c.Mailer.From); err != nil {
+		v.addf("mailer.from must be email address, got %q", c.Mailer.From)
+	}
+	if c.EmailVerification.TokenTTLSec <= 0 {
+		v.addf("email_verification.token_ttl_sec must be positive")
+	}
+	if c.EmailVerification.URL != "" {
+		if u, err := url.Parse(c.EmailVerification.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			v.addf("email_verification.url must be absolute URL, got %q", c.EmailVerification.URL)
+		}
+	}
+	if c.Password.MinLength < 0 || c.Password.MaxLength < c.Password.MinLength || c.Password.MaxLength > maxPasswordLength {
+		v.addf("password.min_length and password.max_length must satisfy 0 <= min_length <= max_length <= %d, got %d and %d",
+			maxPasswordLength, c.Password.MinLength, c.Password.MaxLength)
+	}
+	if c.Password.MinClasses < 0 || c.Password.MinClasses > 4 {
+		v.addf("password.min_classes must be 0-4, got %d", c.Password.MinClasses)
+	}
+	if rate := c.Password.Breached.FalsePositiveRate; rate < 0 || rate >= 1 {
+		v.addf("password.breached.false_positive_rate must be in [0, 1), got %v", rate)
+	}
+	if c.PasswordReset.TokenTTLSec <= 0 {
+		v.addf("password_reset.token_ttl_sec must be positive")
+	}
+	if c.PasswordReset.IntervalSec < 0 {
+		v.addf("password_reset.interval_sec must not be negative, got %d", c.PasswordReset.IntervalSec)
+	}
+	if c.PasswordReset.URL != "" {
+		if u, err := url.Parse(c.PasswordReset.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			v.addf("password_reset.url must be absolute URL, got %q", c.PasswordReset.URL)
+		}
+	}
+
+	if c.Lockout.Enabled {
+		if c.Lockout.WindowSec <= 0 {
+			v.addf("lockout.window_sec must be positive, got %d", c.Lockout.WindowSec)
+		}
+		if c.Lockout.Account.Threshold < 0 || c.Lockout.IP.Threshold < 0 {
+			v.addf("lockout.account.threshold and lockout.ip.threshold must not be negative, got %d and %d",
+				c.Lockout.Account.Threshold, c.Lockout.IP.Threshold)
+		}
+		if c.Lockout.Account.DurationSec < 0 || c.Lockout.IP.DurationSec < 0 {
+			v.addf("lockout.account.duration_sec and lockout.ip.duration_sec must not be negative, got %d and %d",
+				c.Lockout.Account.DurationSec, c.Lockout.IP.DurationSec)
+		}
+		if c.Lockout.Account.DelayMsec < 0 || c.Lockout.Account.MaxDelayMsec < c.Lockout.Account.DelayMsec {
+			v.addf("lockout.account.delay_msec and lockout.account.max_delay_msec must satisfy 0 <= delay_msec <= max_delay_msec, got %d and %d",
+				c.Lockout.Account.DelayMsec, c.Lockout.Account.MaxDelayMsec)
+		}
+	}
+
+	if c.Cache.Enabled {
+		if c.Cache.Size <= 0 {
+			v.addf("cache.size must be positive, got %d", c.Cache.Size)
+		}
+		if c.Cache.TTLSec <= 0 {
+			v.addf("cache.ttl_sec must be positive, got %d", c.Cache.TTLSec)
+		}
+	}
+
+	if c.Tenant.Default != "" {
+		if err := tenant.Validate(c.Tenant.Default); err != nil {
+			v.addf("tenant.default: %s, got %q", err, c.Tenant.Default)
+		}
+	}
+
+	if c.Auth.Enabled {
+		if c.Auth.JWT.Secret == "" {
+			v.addf("auth.jwt.secret is required when auth is enabled")
+		}
+		if c.Auth.JWT.LeewaySec < 0 {
+			v.addf("auth.jwt.leeway_sec must not be negative")
+		}
+	}
+	if c.Auth.APIKeys.RotationGraceSec < 0 {
+		v.addf("auth.api_keys.rotation_grace_sec must not be negative")
+	}
+
+	if err := logging.CheckLevel(c.Logger.Level); err != nil {
+		v.addf("logger.level: %s", err)
+	}
+
+	if c.Sentry.Enabled {
+		if c.Sentry.Dsn == "" {
+			v.addf("sentry.dsn is required when sentry is enabled")
+		} else if u, err := url.Parse(c.Sentry.Dsn.Reveal()); err != nil || u.Scheme == "" || u.Host == "" {
+			v.addf("sentry.dsn must be a valid URL")
+		}
+	}
+
+	if c.Tracer.Enabled {
+		switch c.Tracer.Provider {
+		case tracing.ProviderJaeger:
+			v.required("tracer.host", c.Tracer.Host)
+			v.port("tracer.port", c.Tracer.Port)
+		case tracing.ProviderOTLP:
+			v.required("tracer.otlp.endpoint", c.Tracer.OTLP.Endpoint)
+		default:
+			v.addf("tracer.provider must be jaeger or otlp, got %q", c.Tracer.Provider)
+		}
+	}
+	if c.Tracer.SampleRate < 0 || c.Tracer.SampleRate > 1 {
+		v.addf("tracer.sample_rate must be between 0 and 1, got %v", c.Tracer.SampleRate)
+	}
+
+	if c.Metrics.Enabled {
+		v.port("metrics.port", c.Metrics.Port)
+		if _, err := metrics.ParseBuckets(c.Metrics.Buckets); err != nil {
+			v.addf("metrics.buckets: %s", err)
+		}
+		v.tls("metrics.tls", c.Metrics.TLS)
+	}
+
+	if c.Startup.TimeoutSec <= 0 {
+		v.addf("startup.timeout_sec must be positive, got %d", c.Startup.TimeoutSec)
+	}
+	if c.Startup.InitialBackoffMsec <= 0 {
+		v.addf("startup.initial_backoff_msec must be positive, got %d", c.Startup.InitialBackoffMsec)
+	} else if c.Startup.MaxBackoffMsec < c.Startup.InitialBackoffMsec {
+		v.addf("startup.max_backoff_msec must not be less than startup.initial_backoff_msec")
+	}
+
+	if c.Limiter.Enabled && c.Limiter.Limit <= 0 {
+		v.addf("limiter.limit must be positive, got %v", c.Limiter.Limit)
+	}
+	// client ip is resolved with them even when limiter is disabled
+	if _, err := limiting.ParseCIDRs(strings.Split(c.Limiter.TrustedProxies, ",")); err != nil {
+		v.addf("limiter.trusted_proxies: %s", err)
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package configs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/nakiner/faceit/tools/logging"
+	"github.com/nakiner/faceit/tools/secrets"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// liveSettings are key prefixes applied without restart by subscribed components.
+var liveSettings = []string{
+	"logger.level",
+	"limiter.limit",
+	"limiter.burst",
+	"cors",
+	"features",
+	"tracer.sample_rate",
+}
+
+// debounce groups burst of file events (editors and kubernetes configmap swaps) into single reload
+const debounce = 100 * time.Millisecond
+
+// Change describes setting changed by reload.
+type Change struct {
+	Key  string
+	Old  interface{}
+	New  interface{}
+	Live bool
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %v -> %v", c.Key, c.Old, c.New)
+}
+
+// Watcher re-reads configuration when config file changes or SIGHUP is received
+// and notifies subscribers with new configuration.
+type Watcher struct {
+	mu      sync.Mutex
+	current *Config
+	subs    []func(cfg *Config)
+	logger  log.Logger
+
+	fsw    *fsnotify.Watcher
+	signal chan os.Signal
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewWatcher starts watching config file given by "config" option (if any) and SIGHUP.
+func NewWatcher(ctx context.Context, cfg *Config) (*Watcher, error) {
+	logger := logging.FromContext(ctx)
+	logger = log.With(logger, "component", "config watcher")
+	w := &Watcher{
+		current: cfg,
+		logger:  logger,
+		signal:  make(chan os.Signal, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	var events chan fsnotify.Event
+	if fileName := viper.GetString("config"); fileName != "" {
+		fsw, err := fsnotify.NewWatcher()
+		if err != nil {
+			return nil, errors.Wrap(err, "watch config")
+		}
+		// directory is watched since editors and kubernetes replace file instead of writing it
+		if err := fsw.Add(filepath.Dir(fileName)); err != nil {
+			fsw.Close()
+			return nil, errors.Wrap(err, "watch config")
+		}
+		w.fsw = fsw
+		events = fsw.Events
+	}
+	signal.Notify(w.signal, syscall.SIGHUP)
+
+	go w.run(events)
+	return w, nil
+}
+
+// Subscribe registers fn called with new configuration after every reload.
+// Subscribers are expected to apply only live settings, the rest take effect after restart.
+func (w *Watcher) Subscribe(fn func(cfg *Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs = append(w.subs, fn)
+}
+
+// Reload reads configuration, logs its diff with current one and notifies subscribers.
+func (w *Watcher) Reload() error {
+	next := NewConfig()
+	if fileName := viper.GetString("config"); fileName != "" {
+		if err := viper.ReadInConfig(); err != nil {
+			return errors.Wrap(err, "failed to read from file")
+		}
+	}
+	if err := next.unmarshal(); err != nil {
+		return err
+	}
+	if err := next.Validate(); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	changes := Diff(w.current, next)
+	w.current = next
+	subs := make([]func(*Config), len(w.subs))
+	copy(subs, w.subs)
+	w.mu.Unlock()
+
+	if len(changes) == 0 {
+		level.Info(w.logger).Log("msg", "config reloaded, nothing changed")
+		return nil
+	}
+
+	var live, restart []string
+	for _, c := range changes {
+		if c.Live {
+			live = append(live, c.String())
+		} else {
+			restart = append(restart, c.String())
+		}
+	}
+	level.Info(w.logger).Log("msg", "config reloaded", "applied", strings.Join(live, "; "))
+	if len(restart) > 0 {
+		level.Warn(w.logger).Log("msg", "changed settings require restart", "restart_required", strings.Join(restart, "; "))
+	}
+
+	for _, fn := range subs {
+		fn(next)
+	}
+	return nil
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	signal.Stop(w.signal)
+	close(w.stop)
+	<-w.done
+	if w.fsw != nil {
+		return w.fsw.Close()
+	}
+	return nil
+}
+
+func (w *Watcher) run(events chan fsnotify.Event) {
+	defer close(w.done)
+	var timer <-chan time.Time
+	for {
+		select {
+		case <-events:
+			timer = time.After(debounce)
+		case <-w.signal:
+			level.Info(w.logger).Log("msg", "SIGHUP received")
+			w.reload()
+		case <-timer:
+			timer = nil
+			w.reload()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+func (w *Watcher) reload() {
+	if err := w.Reload(); err != nil {
+		level.Error(w.logger).Log("msg", "config reload failed, keeping current", "err", err)
+	}
+}
+
+// Diff returns settings which differ between configurations sorted by key.
+func Diff(old, new *Config) []Change {
+	before := make(map[string]interface{})
+	after := make(map[string]interface{})
+	flatten("", reflect.ValueOf(old).Elem(), before)
+	flatten("", reflect.ValueOf(new).Elem(), after)
+
+	keys := make(map[string]struct{})
+	for k := range before {
+		keys[k] = struct{}{}
+	}
+	for k := range after {
+		keys[k] = struct{}{}
+	}
+
+	var changes []Change
+	for k := range keys {
+		o, n := before[k], after[k]
+		if reflect.DeepEqual(o, n) {
+			continue
+		}
+		if isSecret(o) || isSecret(n) {
+			o, n = secrets.Redacted, secrets.Redacted
+		}
+		changes = append(changes, Change{Key: k, Old: o, New: n, Live: isLive(k)})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// flatten collects leaf values of configuration keyed the same way as options, e.g. server.http.port.
+func flatten(prefix string, v reflect.Value, out map[string]interface{}) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := f.Tag.Get("mapstructure")
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			flatten(join(prefix, name), v.Field(i), out)
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			flatten(join(prefix, fmt.Sprint(k.Interface())), v.MapIndex(k), out)
+		}
+	default:
+		out[prefix] = v.Interface()
+	}
+}
+
+func join(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func isLive(key string) bool {
+	for _, p := range liveSettings {
+		if key == p || strings.HasPrefix(key, p+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func isSecret(value interface{}) bool {
+	_, ok := value.(secrets.String)
+	return ok
+}
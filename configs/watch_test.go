@@ -0,0 +1,76 @@
+package configs
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nakiner/faceit/tools/secrets"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	old := NewConfig()
+	old.Logger.Level = "err"
+	old.Server.HTTP.Port = 8080
+	old.Postgres.Master.Password = "old"
+
+	next := NewConfig()
+	next.Logger.Level = "debug"
+	next.Server.HTTP.Port = 8081
+	next.Postgres.Master.Password = "new"
+	next.Features = map[string]bool{"beta": true}
+
+	assert.Equal(t, []Change{
+		{Key: "features.beta", Old: nil, New: true, Live: true},
+		{Key: "logger.level", Old: "err", New: "debug", Live: true},
+		{Key: "postgres.master.password", Old: secrets.Redacted, New: secrets.Redacted},
+		{Key: "server.http.port", Old: 8080, New: 8081},
+	}, Diff(old, next))
+}
+
+func TestWatcher(t *testing.T) {
+	defer viper.Reset()
+	for _, o := range options {
+		viper.SetDefault(o.name, o.value)
+	}
+	fileName := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, ioutil.WriteFile(fileName, []byte("[logger]\nlevel = \"err\"\n"), 0600))
+	viper.Set("config", fileName)
+	viper.SetConfigFile(fileName)
+	viper.SetConfigType("toml")
+	require.NoError(t, viper.ReadInConfig())
+
+	cfg := NewConfig()
+	require.NoError(t, viper.Unmarshal(cfg))
+
+	w, err := NewWatcher(context.Background(), cfg)
+	require.NoError(t, err)
+	defer w.Close()
+
+	var mu sync.Mutex
+	var level string
+	w.Subscribe(func(c *Config) {
+		mu.Lock()
+		defer mu.Unlock()
+		level = c.Logger.Level
+	})
+
+	require.NoError(t, ioutil.WriteFile(fileName, []byte("[logger]\nlevel = \"debug\"\n"), 0600))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return level == "debug"
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// broken or invalid file keeps current configuration
+	require.NoError(t, ioutil.WriteFile(fileName, []byte("[logger"), 0600))
+	assert.Error(t, w.Reload())
+	require.NoError(t, ioutil.WriteFile(fileName, []byte("[logger]\nlevel = \"verbose\"\n"), 0600))
+	assert.Error(t, w.Reload())
+}
@@ -0,0 +1,60 @@
+package apikey
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/nakiner/faceit/tools/auth"
+	"github.com/nakiner/faceit/tools/logging"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/pkg/errors"
+)
+
+// touchInterval bounds how often last use of key is written
+const touchInterval = time.Minute
+
+type authenticator struct {
+	repo Repository
+	now  func() time.Time
+}
+
+// NewAuthenticator returns auth.Authenticator of keys kept in repo. Keys are looked up across tenants,
+// callers are limited to tenant of key, get roles of its scopes and its rate limit.
+func NewAuthenticator(repo Repository) auth.Authenticator {
+	return &authenticator{repo: repo, now: time.Now}
+}
+
+func (a *authenticator) Authenticate(ctx context.Context, credentials string) (*auth.Principal, error) {
+	prefix := PrefixOf(credentials)
+	if prefix == "" {
+		return nil, errors.Wrap(auth.ErrUnauthenticated, "malformed api key")
+	}
+	// tenant is not known before key is found
+	key, err := a.repo.FindByPrefix(tenant.WithSuperAdmin(ctx), prefix)
+	if errors.Is(err, ErrNotFound) {
+		return nil, errors.Wrap(auth.ErrUnauthenticated, "unknown api key")
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := a.now()
+	if !Matches(credentials, key.Hash) {
+		return nil, errors.Wrap(auth.ErrUnauthenticated, "invalid api key")
+	}
+	if !key.Active(now) {
+		return nil, errors.Wrap(auth.ErrUnauthenticated, "api key is revoked or expired")
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= touchInterval {
+		if err := a.repo.Touch(tenant.WithContext(ctx, key.TenantID), key.ID, now); err != nil {
+			level.Warn(logging.FromContext(ctx)).Log("msg", "could not record api key use", "key", key.Prefix, "err", err)
+		}
+	}
+	return &auth.Principal{
+		Subject:   key.Prefix,
+		Tenant:    key.TenantID,
+		Roles:     strings.Fields(key.Scopes),
+		RateLimit: key.RateLimit,
+	}, nil
+}
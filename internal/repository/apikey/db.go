@@ -0,0 +1,115 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nakiner/faceit/internal/store/database"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRowsAffectedEmpty = errors.New("result.RowsAffected is empty")
+	ErrNotFound          = errors.New("api key not found")
+)
+
+type keyDBRepository struct {
+	db *database.Connection
+}
+
+// NewRepository creates repository of API keys kept in database.
+func NewRepository(db *database.Connection) Repository {
+	return &keyDBRepository{db: db}
+}
+
+// IsReady checks availability of database
+func (r *keyDBRepository) IsReady() bool {
+	return r.db.CheckConn() == nil
+}
+
+func (r *keyDBRepository) Create(ctx context.Context, key *Key) error {
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return errors.Wrap(err, "keyDBRepository generate uuid err")
+	}
+	key.ID = id.String()
+	if err := r.db.GetMasterConn(ctx).Create(key).Error; err != nil {
+		return errors.Wrap(err, "keyDBRepository Create err")
+	}
+	return nil
+}
+
+func (r *keyDBRepository) List(ctx context.Context) ([]Key, error) {
+	var keys []Key
+	if err := r.db.GetReplicaConn(ctx).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, errors.Wrap(err, "keyDBRepository List err")
+	}
+	return keys, nil
+}
+
+func (r *keyDBRepository) Get(ctx context.Context, id string) (*Key, error) {
+	return r.first(r.db.GetMasterConn(ctx).Where("id = ?", id), "keyDBRepository Get err")
+}
+
+// FindByPrefix reads from master, so revoked keys are rejected right away
+func (r *keyDBRepository) FindByPrefix(ctx context.Context, prefix string) (*Key, error) {
+	return r.first(r.db.GetMasterConn(ctx).Where("prefix = ?", prefix), "keyDBRepository FindByPrefix err")
+}
+
+func (r *keyDBRepository) first(db *gorm.DB, msg string) (*Key, error) {
+	var key Key
+	err := db.Take(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.Wrap(ErrNotFound, msg)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, msg)
+	}
+	return &key, nil
+}
+
+func (r *keyDBRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	result := r.db.GetMasterConn(ctx).Model(&Key{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", at)
+	if err := result.Error; err != nil {
+		return errors.Wrap(err, "keyDBRepository Revoke err")
+	}
+	if result.RowsAffected < 1 {
+		return errors.Wrap(ErrRowsAffectedEmpty, "keyDBRepository Revoke err")
+	}
+	return nil
+}
+
+func (r *keyDBRepository) Rotate(ctx context.Context, id string, next *Key, until time.Time) error {
+	uid, err := uuid.NewUUID()
+	if err != nil {
+		return errors.Wrap(err, "keyDBRepository generate uuid err")
+	}
+	next.ID = uid.String()
+	err = r.db.GetMasterConn(ctx).Transaction(func(tx *gorm.DB) error {
+		// LEAST ignores NULL, so key keeps earlier expiry
+		result := tx.Model(&Key{}).
+			Where("id = ? AND revoked_at IS NULL", id).
+			Update("expires_at", gorm.Expr("LEAST(expires_at, ?)", until))
+		if result.Error != nil {
+			return result.Error
+		}
+		// key was revoked or deleted meanwhile
+		if result.RowsAffected < 1 {
+			return ErrNotFound
+		}
+		return tx.Create(next).Error
+	})
+	if err != nil {
+		return errors.Wrap(err, "keyDBRepository Rotate err")
+	}
+	return nil
+}
+
+func (r *keyDBRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	if err := r.db.GetMasterConn(ctx).Model(&Key{}).Where("id = ?", id).Update("last_used_at", at).Error; err != nil {
+		return errors.Wrap(err, "keyDBRepository Touch err")
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package apikey
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/nakiner/faceit/internal/store/database"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockRepository(t *testing.T) (Repository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	DB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	require.NoError(t, database.UseTenantScope(DB))
+	return NewRepository(database.NewConnection(DB, DB)), mock
+}
+
+func TestKeyDBRepository_FindByPrefix(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	// keys are looked up across tenants before caller is known
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "api_keys" WHERE prefix = $1 LIMIT 1`)).
+		WithArgs("fk_0123abcd").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "prefix"}))
+
+	_, err := repo.FindByPrefix(tenant.WithSuperAdmin(context.Background()), "fk_0123abcd")
+	assert.ErrorIs(t, err, ErrNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestKeyDBRepository_Revoke(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	repo, mock := newMockRepository(t)
+	at := time.Now()
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "api_keys" SET "revoked_at"=$1 WHERE (id = $2 AND revoked_at IS NULL) AND "tenant_id" = $3`)).
+		WithArgs(at, "key-1", "brand-a").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.Revoke(ctx, "key-1", at)
+	assert.ErrorIs(t, err, ErrRowsAffectedEmpty)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestKeyDBRepository_Rotate(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	repo, mock := newMockRepository(t)
+	until := time.Now().Add(time.Hour)
+
+	retire := regexp.QuoteMeta(`UPDATE "api_keys" SET "expires_at"=LEAST(expires_at, $1) WHERE (id = $2 AND revoked_at IS NULL) AND "tenant_id" = $3`)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(retire).
+		WithArgs(until, "key-1", "brand-a").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "api_keys"`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	next := &Key{Name: "matchmaking", Prefix: "fk_0123abcd", Hash: Hash("fk_0123abcd_secret")}
+	require.NoError(t, repo.Rotate(ctx, "key-1", next, until))
+	assert.NotEmpty(t, next.ID)
+	assert.Equal(t, "brand-a", next.TenantID)
+
+	// revoked meanwhile, next key is rolled back
+	mock.ExpectBegin()
+	mock.ExpectExec(retire).
+		WithArgs(until, "key-1", "brand-a").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	err := repo.Rotate(ctx, "key-1", &Key{Name: "matchmaking"}, until)
+	assert.True(t, errors.Is(err, ErrNotFound), "got %v", err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
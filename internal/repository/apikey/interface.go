@@ -0,0 +1,26 @@
+package apikey
+
+import (
+	"context"
+	"time"
+)
+
+// Repository keeps API keys of tenant of context.
+type Repository interface {
+	IsReady() bool
+	// Create stores key, its ID is generated.
+	Create(ctx context.Context, key *Key) error
+	// List returns keys, newest first.
+	List(ctx context.Context) ([]Key, error)
+	// Get returns key with id, ErrNotFound is returned when there is none.
+	Get(ctx context.Context, id string) (*Key, error)
+	// FindByPrefix returns key with prefix, ErrNotFound is returned when there is none.
+	FindByPrefix(ctx context.Context, prefix string) (*Key, error)
+	// Revoke invalidates key at, ErrRowsAffectedEmpty is returned when there is no active key with id.
+	Revoke(ctx context.Context, id string, at time.Time) error
+	// Rotate stores next key and makes key with id expire at until, unless it expires earlier.
+	// ErrNotFound is returned and next is not stored when there is no unrevoked key with id.
+	Rotate(ctx context.Context, id string, next *Key, until time.Time) error
+	// Touch records key was used at.
+	Touch(ctx context.Context, id string, at time.Time) error
+}
@@ -0,0 +1,50 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+)
+
+// Marker starts every key, so keys are told from tokens and found by secret scanners.
+const Marker = "fk_"
+
+const (
+	// prefix is marker followed by hex id
+	prefixLength = len(Marker) + 8
+	secretBytes  = 32
+)
+
+// Generate returns new key together with its prefix and hash, which are stored instead of it.
+func Generate() (key, prefix, hash string, err error) {
+	b := make([]byte, 4+secretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", errors.Wrap(err, "generate api key")
+	}
+	prefix = Marker + hex.EncodeToString(b[:4])
+	key = prefix + "_" + base64.RawURLEncoding.EncodeToString(b[4:])
+	return key, prefix, Hash(key), nil
+}
+
+// Hash returns digest of key, keys are random so a plain SHA-256 is enough.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// PrefixOf returns prefix of key, empty for malformed keys.
+func PrefixOf(key string) string {
+	if len(key) <= prefixLength+1 || key[:len(Marker)] != Marker || key[prefixLength] != '_' {
+		return ""
+	}
+	return key[:prefixLength]
+}
+
+// Matches reports whether key hashes to hash, in constant time.
+func Matches(key, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(Hash(key)), []byte(hash)) == 1
+}
@@ -0,0 +1,160 @@
+package apikey
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/pkg/errors"
+)
+
+type keyMemoryRepository struct {
+	mu   sync.RWMutex
+	keys map[string]Key
+}
+
+// NewMemoryRepository creates thread-safe repository keeping API keys in process,
+// for local development and tests.
+func NewMemoryRepository() Repository {
+	return &keyMemoryRepository{keys: make(map[string]Key)}
+}
+
+func (r *keyMemoryRepository) IsReady() bool {
+	return true
+}
+
+func (r *keyMemoryRepository) Create(ctx context.Context, key *Key) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.create(ctx, key); err != nil {
+		return errors.Wrap(err, "keyMemoryRepository Create err")
+	}
+	return nil
+}
+
+// create assigns id and tenant of key and stores it, mu is held
+func (r *keyMemoryRepository) create(ctx context.Context, key *Key) error {
+	if id := tenant.FromContext(ctx); id != "" {
+		key.TenantID = id
+	} else if !tenant.IsSuperAdmin(ctx) || key.TenantID == "" {
+		return tenant.ErrMissing
+	}
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return err
+	}
+	key.ID = id.String()
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+	r.keys[key.ID] = *key
+	return nil
+}
+
+func (r *keyMemoryRepository) List(ctx context.Context) ([]Key, error) {
+	id, all, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "keyMemoryRepository List err")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := []Key{}
+	for _, k := range r.keys {
+		if all || k.TenantID == id {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+func (r *keyMemoryRepository) Get(ctx context.Context, id string) (*Key, error) {
+	return r.find(ctx, "keyMemoryRepository Get err", func(k *Key) bool { return k.ID == id })
+}
+
+func (r *keyMemoryRepository) FindByPrefix(ctx context.Context, prefix string) (*Key, error) {
+	return r.find(ctx, "keyMemoryRepository FindByPrefix err", func(k *Key) bool { return k.Prefix == prefix })
+}
+
+func (r *keyMemoryRepository) find(ctx context.Context, msg string, match func(k *Key) bool) (*Key, error) {
+	id, all, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, msg)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, k := range r.keys {
+		if match(&k) && (all || k.TenantID == id) {
+			return &k, nil
+		}
+	}
+	return nil, errors.Wrap(ErrNotFound, msg)
+}
+
+func (r *keyMemoryRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	err := r.update(ctx, id, func(k *Key) bool {
+		if k.RevokedAt != nil {
+			return false
+		}
+		k.RevokedAt = &at
+		return true
+	})
+	if err != nil {
+		return errors.Wrap(err, "keyMemoryRepository Revoke err")
+	}
+	return nil
+}
+
+func (r *keyMemoryRepository) Rotate(ctx context.Context, id string, next *Key, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.create(ctx, next); err != nil {
+		return errors.Wrap(err, "keyMemoryRepository Rotate err")
+	}
+	k, ok := r.keys[id]
+	if !ok || k.TenantID != next.TenantID || k.RevokedAt != nil {
+		delete(r.keys, next.ID)
+		return errors.Wrap(ErrNotFound, "keyMemoryRepository Rotate err")
+	}
+	if k.ExpiresAt == nil || k.ExpiresAt.After(until) {
+		k.ExpiresAt = &until
+		r.keys[id] = k
+	}
+	return nil
+}
+
+func (r *keyMemoryRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	err := r.update(ctx, id, func(k *Key) bool {
+		k.LastUsedAt = &at
+		return true
+	})
+	if err != nil && !errors.Is(err, ErrRowsAffectedEmpty) {
+		return errors.Wrap(err, "keyMemoryRepository Touch err")
+	}
+	return nil
+}
+
+// update applies change to key with id in scope of ctx, ErrRowsAffectedEmpty is returned when
+// there is none or change is not applied
+func (r *keyMemoryRepository) update(ctx context.Context, id string, change func(k *Key) bool) error {
+	tenantID, all, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k, ok := r.keys[id]
+	if !ok || !all && k.TenantID != tenantID || !change(&k) {
+		return ErrRowsAffectedEmpty
+	}
+	r.keys[id] = k
+	return nil
+}
@@ -0,0 +1,110 @@
+package apikey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nakiner/faceit/tools/auth"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newKey(t *testing.T, ctx context.Context, repo Repository) (string, *Key) {
+	plain, prefix, hash, err := Generate()
+	require.NoError(t, err)
+	k := &Key{Name: "matchmaking", Prefix: prefix, Hash: hash, Scopes: "service", RateLimit: 5}
+	require.NoError(t, repo.Create(ctx, k))
+	return plain, k
+}
+
+func TestMemoryRepository(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+
+	_, k := newKey(t, ctx, repo)
+	assert.Equal(t, "brand-a", k.TenantID)
+	got, err := repo.FindByPrefix(ctx, k.Prefix)
+	require.NoError(t, err)
+	assert.Equal(t, k.ID, got.ID)
+
+	// keys are scoped to tenant
+	other := tenant.WithContext(context.Background(), "brand-b")
+	keys, err := repo.List(other)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	_, err = repo.Get(other, k.ID)
+	assert.True(t, errors.Is(err, ErrNotFound), "got %v", err)
+	err = repo.Revoke(other, k.ID, time.Now())
+	assert.True(t, errors.Is(err, ErrRowsAffectedEmpty), "got %v", err)
+
+	require.NoError(t, repo.Revoke(ctx, k.ID, time.Now()))
+	err = repo.Revoke(ctx, k.ID, time.Now())
+	assert.True(t, errors.Is(err, ErrRowsAffectedEmpty), "got %v", err)
+
+	_, err = repo.List(context.Background())
+	assert.True(t, errors.Is(err, tenant.ErrMissing), "got %v", err)
+}
+
+func TestAuthenticator(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	now := time.Now()
+	a := &authenticator{repo: repo, now: func() time.Time { return now }}
+
+	plain, k := newKey(t, ctx, repo)
+	p, err := a.Authenticate(context.Background(), plain)
+	require.NoError(t, err)
+	assert.Equal(t, &auth.Principal{Subject: k.Prefix, Tenant: "brand-a", Roles: []string{"service"}, RateLimit: 5}, p)
+	got, err := repo.Get(ctx, k.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.LastUsedAt)
+	assert.True(t, got.LastUsedAt.Equal(now))
+
+	for name, credentials := range map[string]string{
+		"malformed":    "token",
+		"unknown":      "fk_ffffffff_secret",
+		"other secret": k.Prefix + "_secret",
+	} {
+		_, err := a.Authenticate(context.Background(), credentials)
+		assert.True(t, errors.Is(err, auth.ErrUnauthenticated), "%s: got %v", name, err)
+	}
+
+	// old and new keys are both valid during grace period
+	nextPlain, prefix, hash, err := Generate()
+	require.NoError(t, err)
+	next := &Key{Name: k.Name, Prefix: prefix, Hash: hash, Scopes: k.Scopes}
+	require.NoError(t, repo.Rotate(ctx, k.ID, next, now.Add(time.Hour)))
+	_, err = a.Authenticate(context.Background(), plain)
+	assert.NoError(t, err)
+	_, err = a.Authenticate(context.Background(), nextPlain)
+	assert.NoError(t, err)
+
+	now = now.Add(2 * time.Hour)
+	_, err = a.Authenticate(context.Background(), plain)
+	assert.True(t, errors.Is(err, auth.ErrUnauthenticated), "got %v", err)
+	_, err = a.Authenticate(context.Background(), nextPlain)
+	assert.NoError(t, err)
+
+	require.NoError(t, repo.Revoke(ctx, next.ID, now))
+	_, err = a.Authenticate(context.Background(), nextPlain)
+	assert.True(t, errors.Is(err, auth.ErrUnauthenticated), "got %v", err)
+
+	// revoked key is not replaced
+	err = repo.Rotate(ctx, next.ID, &Key{Name: k.Name, Prefix: "fk_00000000"}, now.Add(time.Hour))
+	assert.True(t, errors.Is(err, ErrNotFound), "got %v", err)
+	_, err = repo.FindByPrefix(ctx, "fk_00000000")
+	assert.True(t, errors.Is(err, ErrNotFound), "got %v", err)
+}
+
+func TestPrefixOf(t *testing.T) {
+	plain, prefix, hash, err := Generate()
+	require.NoError(t, err)
+	assert.Equal(t, prefix, PrefixOf(plain))
+	assert.True(t, Matches(plain, hash))
+	assert.False(t, Matches(plain+"x", hash))
+	assert.Empty(t, PrefixOf("fk_0123"))
+	assert.Empty(t, PrefixOf("xx_0123abcd_secret"))
+}
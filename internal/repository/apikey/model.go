@@ -0,0 +1,30 @@
+package apikey
+
+import "time"
+
+// Key is API key of service caller within tenant, only hash of its secret is stored.
+type Key struct {
+	ID       string `gorm:"primaryKey;size:64"`
+	TenantID string `gorm:"size:64"`
+	Name     string `gorm:"size:64"`
+	// Prefix is public part of key identifying it in lists and logs.
+	Prefix string `gorm:"size:16"`
+	Hash   string `gorm:"size:64"`
+	// Scopes are space separated roles granted to callers using key.
+	Scopes string `gorm:"size:255"`
+	// RateLimit is requests per second allowed to key, 0 uses default limit.
+	RateLimit  float64
+	ExpiresAt  *time.Time `gorm:"type:timestamp"`
+	LastUsedAt *time.Time `gorm:"type:timestamp"`
+	RevokedAt  *time.Time `gorm:"type:timestamp"`
+	CreatedAt  time.Time  `gorm:"type:timestamp"`
+}
+
+func (Key) TableName() string {
+	return "api_keys"
+}
+
+// Active reports whether key is neither revoked nor expired at now.
+func (k *Key) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
@@ -0,0 +1,76 @@
+package attempt
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nakiner/faceit/internal/store/database"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type attemptDBRepository struct {
+	db *database.Connection
+}
+
+// NewRepository creates repository of failed credential checks kept in database.
+func NewRepository(db *database.Connection) Repository {
+	return &attemptDBRepository{db: db}
+}
+
+// IsReady checks availability of database
+func (r *attemptDBRepository) IsReady() bool {
+	return r.db.CheckConn() == nil
+}
+
+// Reserve counts failures and records attempt in one transaction holding lock of subject, so concurrent
+// attempts are serialized and each one sees the previous ones
+func (r *attemptDBRepository) Reserve(ctx context.Context, subject string, at, since time.Time) (string, int, time.Time, error) {
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return "", 0, time.Time{}, errors.Wrap(err, "attemptDBRepository generate uuid err")
+	}
+	var stats struct {
+		Count int
+		Last  *time.Time
+	}
+	err = r.db.GetMasterConn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", tenant.FromContext(ctx)+"/"+subject).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subject = ? AND created_at < ?", subject, since).Delete(&Failure{}).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&Failure{}).
+			Select("COUNT(*) AS count, MAX(created_at) AS last").
+			Where("subject = ? AND created_at >= ?", subject, since).
+			Scan(&stats).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&Failure{ID: id.String(), Subject: subject, CreatedAt: at}).Error
+	})
+	if err != nil {
+		return "", 0, time.Time{}, errors.Wrap(err, "attemptDBRepository Reserve err")
+	}
+	if stats.Last == nil {
+		return id.String(), stats.Count, time.Time{}, nil
+	}
+	return id.String(), stats.Count, *stats.Last, nil
+}
+
+func (r *attemptDBRepository) Release(ctx context.Context, subject, id string) error {
+	if err := r.db.GetMasterConn(ctx).Where("subject = ? AND id = ?", subject, id).Delete(&Failure{}).Error; err != nil {
+		return errors.Wrap(err, "attemptDBRepository Release err")
+	}
+	return nil
+}
+
+func (r *attemptDBRepository) Clear(ctx context.Context, subject string) error {
+	if err := r.db.GetMasterConn(ctx).Where("subject = ?", subject).Delete(&Failure{}).Error; err != nil {
+		return errors.Wrap(err, "attemptDBRepository Clear err")
+	}
+	return nil
+}
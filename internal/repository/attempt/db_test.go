@@ -0,0 +1,103 @@
+package attempt
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/nakiner/faceit/internal/store/database"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockRepository(t *testing.T) (Repository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	DB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	require.NoError(t, database.UseTenantScope(DB))
+	return NewRepository(database.NewConnection(DB, DB)), mock
+}
+
+func TestAttemptDBRepository_Reserve(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	repo, mock := newMockRepository(t)
+	now := time.Now()
+	since := now.Add(-time.Hour)
+	last := now.Add(-time.Minute)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock(hashtext($1))`)).
+		WithArgs("brand-a/account:user-1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "login_failures" WHERE (subject = $1 AND created_at < $2) AND "tenant_id" = $3`)).
+		WithArgs("account:user-1", since, "brand-a").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) AS count, MAX(created_at) AS last FROM "login_failures" WHERE (subject = $1 AND created_at >= $2) AND "tenant_id" = $3`)).
+		WithArgs("account:user-1", since, "brand-a").
+		WillReturnRows(sqlmock.NewRows([]string{"count", "last"}).AddRow(2, last))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "login_failures" ("id","tenant_id","subject","created_at") VALUES ($1,$2,$3,$4)`)).
+		WithArgs(sqlmock.AnyArg(), "brand-a", "account:user-1", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	id, count, at, err := repo.Reserve(ctx, Account("user-1"), now, since)
+	require.NoError(t, err)
+	assert.NotEmpty(t, id)
+	assert.Equal(t, 2, count)
+	assert.True(t, at.Equal(last))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAttemptDBRepository_ReserveFirst(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	repo, mock := newMockRepository(t)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock(hashtext($1))`)).
+		WithArgs("brand-a/ip:1.2.3.4").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "login_failures"`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) AS count, MAX(created_at) AS last FROM "login_failures"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "last"}).AddRow(0, nil))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "login_failures"`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, count, last, err := repo.Reserve(ctx, IP("1.2.3.4"), now, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	assert.True(t, last.IsZero())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAttemptDBRepository_Release(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "login_failures" WHERE (subject = $1 AND id = $2) AND "tenant_id" = $3`)).
+		WithArgs("ip:1.2.3.4", "attempt-1", "brand-a").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, repo.Release(ctx, IP("1.2.3.4"), "attempt-1"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAttemptDBRepository_Clear(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "login_failures" WHERE subject = $1 AND "tenant_id" = $2`)).
+		WithArgs("account:user-1", "brand-a").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	require.NoError(t, repo.Clear(ctx, Account("user-1")))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
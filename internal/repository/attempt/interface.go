@@ -0,0 +1,19 @@
+package attempt
+
+import (
+	"context"
+	"time"
+)
+
+// Repository keeps failed credential checks of subjects, like account or client ip, counted in sliding windows.
+type Repository interface {
+	IsReady() bool
+	// Reserve records attempt of subject at as failure until it is released, forgets failures before since
+	// and returns id of attempt, number of failures since before it and when the latest of them was, zero time
+	// when none was. Reservations of subject are serialized, so concurrent attempts count each other.
+	Reserve(ctx context.Context, subject string, at, since time.Time) (id string, failures int, last time.Time, err error)
+	// Release forgets attempt of subject with id, e.g. when it was refused or check did not fail.
+	Release(ctx context.Context, subject, id string) error
+	// Clear forgets failures of subject, e.g. after successful check or unlock.
+	Clear(ctx context.Context, subject string) error
+}
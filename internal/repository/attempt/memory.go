@@ -0,0 +1,108 @@
+package attempt
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/pkg/errors"
+)
+
+// memoryFailure is failure kept in process, or attempt reserved as one
+type memoryFailure struct {
+	id string
+	at time.Time
+}
+
+type attemptMemoryRepository struct {
+	mu sync.Mutex
+	// failures are kept by tenant and subject in order of recording
+	failures map[string]map[string][]memoryFailure
+}
+
+// NewMemoryRepository creates thread-safe repository keeping failures in process, for local development and tests.
+func NewMemoryRepository() Repository {
+	return &attemptMemoryRepository{failures: make(map[string]map[string][]memoryFailure)}
+}
+
+func (r *attemptMemoryRepository) IsReady() bool {
+	return true
+}
+
+func (r *attemptMemoryRepository) Reserve(ctx context.Context, subject string, at, since time.Time) (string, int, time.Time, error) {
+	tid := tenant.FromContext(ctx)
+	if tid == "" {
+		return "", 0, time.Time{}, errors.Wrap(tenant.ErrMissing, "attemptMemoryRepository Reserve err")
+	}
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return "", 0, time.Time{}, errors.Wrap(err, "attemptMemoryRepository generate uuid err")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failures[tid] == nil {
+		r.failures[tid] = make(map[string][]memoryFailure)
+	}
+	kept := after(r.failures[tid][subject], since)
+	var last time.Time
+	for _, f := range kept {
+		if f.at.After(last) {
+			last = f.at
+		}
+	}
+	r.failures[tid][subject] = append(kept, memoryFailure{id: id.String(), at: at})
+	return id.String(), len(kept), last, nil
+}
+
+func (r *attemptMemoryRepository) Release(ctx context.Context, subject, id string) error {
+	tid, all, err := tenant.Scope(ctx)
+	if err != nil {
+		return errors.Wrap(err, "attemptMemoryRepository Release err")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for t, subjects := range r.failures {
+		if !all && t != tid {
+			continue
+		}
+		var kept []memoryFailure
+		for _, f := range subjects[subject] {
+			if f.id != id {
+				kept = append(kept, f)
+			}
+		}
+		subjects[subject] = kept
+	}
+	return nil
+}
+
+func (r *attemptMemoryRepository) Clear(ctx context.Context, subject string) error {
+	id, all, err := tenant.Scope(ctx)
+	if err != nil {
+		return errors.Wrap(err, "attemptMemoryRepository Clear err")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for tid, subjects := range r.failures {
+		if all || tid == id {
+			delete(subjects, subject)
+		}
+	}
+	return nil
+}
+
+// after returns failures at or after since
+func after(failures []memoryFailure, since time.Time) []memoryFailure {
+	var res []memoryFailure
+	for _, f := range failures {
+		if !f.at.Before(since) {
+			res = append(res, f)
+		}
+	}
+	return res
+}
@@ -0,0 +1,56 @@
+package attempt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	now := time.Now()
+	subject := Account("user-1")
+
+	for i := 3; i > 0; i-- {
+		_, _, _, err := repo.Reserve(ctx, subject, now.Add(-time.Duration(i)*time.Minute), now.Add(-time.Hour))
+		require.NoError(t, err)
+	}
+	// failures before window are forgotten, reservation itself is not counted
+	id, count, last, err := repo.Reserve(ctx, subject, now, now.Add(-150*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.True(t, last.Equal(now.Add(-time.Minute)))
+
+	// reservation counts as failure until released
+	_, count, last, err = repo.Reserve(ctx, subject, now, now.Add(-150*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.True(t, last.Equal(now))
+	require.NoError(t, repo.Release(ctx, subject, id))
+	_, count, _, err = repo.Reserve(ctx, subject, now, now.Add(-150*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// subjects and tenants are counted apart
+	_, count, _, err = repo.Reserve(ctx, IP("1.2.3.4"), now, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	_, count, _, err = repo.Reserve(tenant.WithContext(context.Background(), "brand-b"), subject, now, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	require.NoError(t, repo.Clear(ctx, subject))
+	_, count, last, err = repo.Reserve(ctx, subject, now, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	assert.True(t, last.IsZero())
+
+	_, _, _, err = repo.Reserve(context.Background(), subject, now, now.Add(-time.Hour))
+	assert.ErrorIs(t, err, tenant.ErrMissing)
+	assert.ErrorIs(t, repo.Release(context.Background(), subject, id), tenant.ErrMissing)
+}
@@ -0,0 +1,31 @@
+package attempt
+
+import (
+	"net"
+	"time"
+)
+
+// Failure is failed credential check of subject.
+type Failure struct {
+	ID        string    `gorm:"primaryKey;size:64"`
+	TenantID  string    `gorm:"size:64"`
+	Subject   string    `gorm:"size:128"`
+	CreatedAt time.Time `gorm:"type:timestamp"`
+}
+
+func (Failure) TableName() string {
+	return "login_failures"
+}
+
+// Account returns subject of failures of user account.
+func Account(userID string) string {
+	return "account:" + userID
+}
+
+// IP returns subject of failures from client ip, ip is normalized so different notations of it match.
+func IP(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil {
+		ip = parsed.String()
+	}
+	return "ip:" + ip
+}
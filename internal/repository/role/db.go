@@ -0,0 +1,56 @@
+package role
+
+import (
+	"context"
+
+	"github.com/nakiner/faceit/internal/store/database"
+	"github.com/pkg/errors"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrRowsAffectedEmpty = errors.New("result.RowsAffected is empty")
+)
+
+type roleDBRepository struct {
+	db *database.Connection
+}
+
+// NewRepository creates repository of role assignments kept in database.
+func NewRepository(db *database.Connection) Repository {
+	return &roleDBRepository{db: db}
+}
+
+// IsReady checks availability of database
+func (r *roleDBRepository) IsReady() bool {
+	return r.db.CheckConn() == nil
+}
+
+// Get reads roles from master, so role changes take effect right away
+func (r *roleDBRepository) Get(ctx context.Context, userID string) ([]string, error) {
+	var roles []string
+	err := r.db.GetMasterConn(ctx).Model(&Assignment{}).Where("user_id = ?", userID).Order("role").Pluck("role", &roles).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "roleDBRepository Get err")
+	}
+	return roles, nil
+}
+
+func (r *roleDBRepository) Assign(ctx context.Context, userID, role string) error {
+	err := r.db.GetMasterConn(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&Assignment{UserID: userID, Role: role}).Error
+	if err != nil {
+		return errors.Wrap(err, "roleDBRepository Assign err")
+	}
+	return nil
+}
+
+func (r *roleDBRepository) Revoke(ctx context.Context, userID, role string) error {
+	result := r.db.GetMasterConn(ctx).Delete(&Assignment{}, "user_id = ? AND role = ?", userID, role)
+	if err := result.Error; err != nil {
+		return errors.Wrap(err, "roleDBRepository Revoke err")
+	}
+	if result.RowsAffected < 1 {
+		return errors.Wrap(ErrRowsAffectedEmpty, "roleDBRepository Revoke err")
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package role
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/nakiner/faceit/internal/store/database"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockRepository(t *testing.T) (Repository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	DB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	require.NoError(t, database.UseTenantScope(DB))
+	return NewRepository(database.NewConnection(DB, DB)), mock
+}
+
+func TestRoleDBRepository_Get(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "role" FROM "user_roles" WHERE user_id = $1 AND "tenant_id" = $2 ORDER BY role`)).
+		WithArgs("user-1", "brand-a").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("admin").AddRow("support"))
+
+	roles, err := repo.Get(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin", "support"}, roles)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRoleDBRepository_Assign(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "user_roles" ("tenant_id","user_id","role","created_at") VALUES ($1,$2,$3,$4) ON CONFLICT DO NOTHING`)).
+		WithArgs("brand-a", "user-1", "admin", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, repo.Assign(ctx, "user-1", "admin"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRoleDBRepository_Revoke(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "user_roles" WHERE (user_id = $1 AND role = $2) AND "tenant_id" = $3`)).
+		WithArgs("user-1", "admin", "brand-a").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.Revoke(ctx, "user-1", "admin")
+	assert.ErrorIs(t, err, ErrRowsAffectedEmpty)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,14 @@
+package role
+
+import "context"
+
+// Repository keeps roles assigned to users of tenant of context.
+type Repository interface {
+	IsReady() bool
+	// Get returns roles of user, sorted by name.
+	Get(ctx context.Context, userID string) ([]string, error)
+	// Assign grants role to user, granting assigned role again is not an error.
+	Assign(ctx context.Context, userID, role string) error
+	// Revoke takes role from user, ErrRowsAffectedEmpty is returned when it is not assigned.
+	Revoke(ctx context.Context, userID, role string) error
+}
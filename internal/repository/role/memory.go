@@ -0,0 +1,76 @@
+package role
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/pkg/errors"
+)
+
+type roleMemoryRepository struct {
+	mu    sync.RWMutex
+	roles map[Assignment]struct{}
+}
+
+// NewMemoryRepository creates thread-safe repository keeping role assignments in process,
+// for local development and tests.
+func NewMemoryRepository() Repository {
+	return &roleMemoryRepository{roles: make(map[Assignment]struct{})}
+}
+
+func (r *roleMemoryRepository) IsReady() bool {
+	return true
+}
+
+func (r *roleMemoryRepository) Get(ctx context.Context, userID string) ([]string, error) {
+	id, all, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "roleMemoryRepository Get err")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var roles []string
+	for a := range r.roles {
+		if a.UserID == userID && (all || a.TenantID == id) {
+			roles = append(roles, a.Role)
+		}
+	}
+	sort.Strings(roles)
+	return roles, nil
+}
+
+func (r *roleMemoryRepository) Assign(ctx context.Context, userID, role string) error {
+	id := tenant.FromContext(ctx)
+	if id == "" {
+		return errors.Wrap(tenant.ErrMissing, "roleMemoryRepository Assign err")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.roles[Assignment{TenantID: id, UserID: userID, Role: role}] = struct{}{}
+	return nil
+}
+
+func (r *roleMemoryRepository) Revoke(ctx context.Context, userID, role string) error {
+	id, all, err := tenant.Scope(ctx)
+	if err != nil {
+		return errors.Wrap(err, "roleMemoryRepository Revoke err")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var revoked bool
+	for a := range r.roles {
+		if a.UserID == userID && a.Role == role && (all || a.TenantID == id) {
+			delete(r.roles, a)
+			revoked = true
+		}
+	}
+	if !revoked {
+		return errors.Wrap(ErrRowsAffectedEmpty, "roleMemoryRepository Revoke err")
+	}
+	return nil
+}
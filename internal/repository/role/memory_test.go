@@ -0,0 +1,42 @@
+package role
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+
+	require.NoError(t, repo.Assign(ctx, "user-1", "support"))
+	require.NoError(t, repo.Assign(ctx, "user-1", "admin"))
+	// assigning again is not an error
+	require.NoError(t, repo.Assign(ctx, "user-1", "admin"))
+	roles, err := repo.Get(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin", "support"}, roles)
+
+	// roles are scoped to tenant
+	other := tenant.WithContext(context.Background(), "brand-b")
+	roles, err = repo.Get(other, "user-1")
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+	err = repo.Revoke(other, "user-1", "admin")
+	assert.True(t, errors.Is(err, ErrRowsAffectedEmpty), "got %v", err)
+
+	require.NoError(t, repo.Revoke(ctx, "user-1", "admin"))
+	roles, err = repo.Get(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"support"}, roles)
+	err = repo.Revoke(ctx, "user-1", "admin")
+	assert.True(t, errors.Is(err, ErrRowsAffectedEmpty), "got %v", err)
+
+	_, err = repo.Get(context.Background(), "user-1")
+	assert.True(t, errors.Is(err, tenant.ErrMissing), "got %v", err)
+}
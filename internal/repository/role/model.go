@@ -0,0 +1,15 @@
+package role
+
+import "time"
+
+// Assignment is role granted to user within tenant.
+type Assignment struct {
+	TenantID  string    `gorm:"primaryKey;size:64"`
+	UserID    string    `gorm:"primaryKey;size:64"`
+	Role      string    `gorm:"primaryKey;size:32"`
+	CreatedAt time.Time `gorm:"type:timestamp"`
+}
+
+func (Assignment) TableName() string {
+	return "user_roles"
+}
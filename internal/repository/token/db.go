@@ -0,0 +1,102 @@
+package token
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nakiner/faceit/internal/store/database"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotFound = errors.New("token not found")
+)
+
+type tokenDBRepository struct {
+	db *database.Connection
+}
+
+// NewRepository creates repository of one-time tokens kept in database.
+func NewRepository(db *database.Connection) Repository {
+	return &tokenDBRepository{db: db}
+}
+
+// IsReady checks availability of database
+func (r *tokenDBRepository) IsReady() bool {
+	return r.db.CheckConn() == nil
+}
+
+func (r *tokenDBRepository) Create(ctx context.Context, token *Token) error {
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return errors.Wrap(err, "tokenDBRepository generate uuid err")
+	}
+	token.ID = id.String()
+	if err := r.db.GetMasterConn(ctx).Create(token).Error; err != nil {
+		return errors.Wrap(err, "tokenDBRepository Create err")
+	}
+	return nil
+}
+
+func (r *tokenDBRepository) Find(ctx context.Context, purpose, hash string, now time.Time) (*Token, error) {
+	var token Token
+	err := r.db.GetMasterConn(ctx).
+		Where("hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", hash, purpose, now).
+		Take(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.Wrap(ErrNotFound, "tokenDBRepository Find err")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "tokenDBRepository Find err")
+	}
+	return &token, nil
+}
+
+// Consume marks token used with conditional update, so concurrent requests may not use it twice
+func (r *tokenDBRepository) Consume(ctx context.Context, purpose, hash string, now time.Time) (*Token, error) {
+	var token Token
+	err := r.db.GetMasterConn(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Token{}).
+			Where("hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", hash, purpose, now).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected < 1 {
+			return ErrNotFound
+		}
+		return tx.Where("hash = ? AND purpose = ?", hash, purpose).Take(&token).Error
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "tokenDBRepository Consume err")
+	}
+	return &token, nil
+}
+
+func (r *tokenDBRepository) Revoke(ctx context.Context, userID, purpose string, at time.Time) error {
+	err := r.db.GetMasterConn(ctx).Model(&Token{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", at).Error
+	if err != nil {
+		return errors.Wrap(err, "tokenDBRepository Revoke err")
+	}
+	return nil
+}
+
+// LastIssued reads master, token issued moments ago may not be on replica yet and a second one would be sent
+func (r *tokenDBRepository) LastIssued(ctx context.Context, userID, purpose string) (time.Time, error) {
+	var token Token
+	err := r.db.GetMasterConn(ctx).
+		Where("user_id = ? AND purpose = ?", userID, purpose).
+		Order("created_at DESC").
+		Take(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "tokenDBRepository LastIssued err")
+	}
+	return token.CreatedAt, nil
+}
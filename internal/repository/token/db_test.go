@@ -0,0 +1,100 @@
+package token
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/nakiner/faceit/internal/store/database"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockRepository(t *testing.T) (Repository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	DB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	require.NoError(t, database.UseTenantScope(DB))
+	return NewRepository(database.NewConnection(DB, DB)), mock
+}
+
+func TestTokenDBRepository_Consume(t *testing.T) {
+	ctx := tenant.WithSuperAdmin(context.Background())
+	repo, mock := newMockRepository(t)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "user_tokens" SET "used_at"=$1 WHERE hash = $2 AND purpose = $3 AND used_at IS NULL AND expires_at > $4`)).
+		WithArgs(now, "hash", PurposeVerifyEmail, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_tokens" WHERE hash = $1 AND purpose = $2 LIMIT 1`)).
+		WithArgs("hash", PurposeVerifyEmail).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "user_id", "email"}).AddRow("token-1", "brand-a", "user-1", "alice@example.com"))
+	mock.ExpectCommit()
+
+	got, err := repo.Consume(ctx, PurposeVerifyEmail, "hash", now)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", got.UserID)
+	assert.Equal(t, "brand-a", got.TenantID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTokenDBRepository_ConsumeUsed(t *testing.T) {
+	ctx := tenant.WithSuperAdmin(context.Background())
+	repo, mock := newMockRepository(t)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "user_tokens" SET "used_at"=$1`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, err := repo.Consume(ctx, PurposeVerifyEmail, "hash", now)
+	assert.ErrorIs(t, err, ErrNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTokenDBRepository_LastIssued(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	repo, mock := newMockRepository(t)
+	at := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_tokens" WHERE (user_id = $1 AND purpose = $2) AND "tenant_id" = $3 ORDER BY created_at DESC LIMIT 1`)).
+		WithArgs("user-1", PurposeResetPassword, "brand-a").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("token-1", at))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_tokens"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}))
+
+	got, err := repo.LastIssued(ctx, "user-1", PurposeResetPassword)
+	require.NoError(t, err)
+	assert.True(t, got.Equal(at))
+	got, err = repo.LastIssued(ctx, "user-2", PurposeResetPassword)
+	require.NoError(t, err)
+	assert.True(t, got.IsZero())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTokenDBRepository_Find(t *testing.T) {
+	ctx := tenant.WithSuperAdmin(context.Background())
+	repo, mock := newMockRepository(t)
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_tokens" WHERE hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3 LIMIT 1`)).
+		WithArgs("hash", PurposeResetPassword, now).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "user_id"}).AddRow("token-1", "brand-a", "user-1"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_tokens"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	got, err := repo.Find(ctx, PurposeResetPassword, "hash", now)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", got.UserID)
+	_, err = repo.Find(ctx, PurposeResetPassword, "other", now)
+	assert.ErrorIs(t, err, ErrNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
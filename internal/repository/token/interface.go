@@ -0,0 +1,23 @@
+package token
+
+import (
+	"context"
+	"time"
+)
+
+// Repository keeps one-time tokens sent to users, e.g. in email verification links.
+type Repository interface {
+	IsReady() bool
+	// Create stores token, its ID is generated.
+	Create(ctx context.Context, token *Token) error
+	// Find returns valid token of purpose with hash without using it, ErrNotFound is returned
+	// when there is no such token or it is used or expired.
+	Find(ctx context.Context, purpose, hash string, now time.Time) (*Token, error)
+	// Consume marks token of purpose with hash used at now and returns it,
+	// ErrNotFound is returned when there is no such token or it is used or expired.
+	Consume(ctx context.Context, purpose, hash string, now time.Time) (*Token, error)
+	// Revoke marks unused tokens of purpose sent to user used at, so only the latest one works.
+	Revoke(ctx context.Context, userID, purpose string, at time.Time) error
+	// LastIssued returns when latest token of purpose was sent to user, zero time when none was.
+	LastIssued(ctx context.Context, userID, purpose string) (time.Time, error)
+}
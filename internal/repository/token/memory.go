@@ -0,0 +1,126 @@
+package token
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/pkg/errors"
+)
+
+type tokenMemoryRepository struct {
+	mu     sync.Mutex
+	tokens map[string]Token
+}
+
+// NewMemoryRepository creates thread-safe repository keeping tokens in process, for local development and tests.
+func NewMemoryRepository() Repository {
+	return &tokenMemoryRepository{tokens: make(map[string]Token)}
+}
+
+func (r *tokenMemoryRepository) IsReady() bool {
+	return true
+}
+
+func (r *tokenMemoryRepository) Create(ctx context.Context, token *Token) error {
+	if id := tenant.FromContext(ctx); id != "" {
+		token.TenantID = id
+	} else if !tenant.IsSuperAdmin(ctx) || token.TenantID == "" {
+		return errors.Wrap(tenant.ErrMissing, "tokenMemoryRepository Create err")
+	}
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return errors.Wrap(err, "tokenMemoryRepository generate uuid err")
+	}
+	token.ID = id.String()
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens[token.ID] = *token
+	return nil
+}
+
+func (r *tokenMemoryRepository) Find(ctx context.Context, purpose, hash string, now time.Time) (*Token, error) {
+	scope, err := scopeOf(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "tokenMemoryRepository Find err")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.tokens {
+		if t.Hash == hash && t.Purpose == purpose && scope(&t) && t.Valid(now) {
+			return &t, nil
+		}
+	}
+	return nil, errors.Wrap(ErrNotFound, "tokenMemoryRepository Find err")
+}
+
+func (r *tokenMemoryRepository) Consume(ctx context.Context, purpose, hash string, now time.Time) (*Token, error) {
+	scope, err := scopeOf(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "tokenMemoryRepository Consume err")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, t := range r.tokens {
+		if t.Hash != hash || t.Purpose != purpose || !scope(&t) || !t.Valid(now) {
+			continue
+		}
+		t.UsedAt = &now
+		r.tokens[id] = t
+		return &t, nil
+	}
+	return nil, errors.Wrap(ErrNotFound, "tokenMemoryRepository Consume err")
+}
+
+func (r *tokenMemoryRepository) Revoke(ctx context.Context, userID, purpose string, at time.Time) error {
+	scope, err := scopeOf(ctx)
+	if err != nil {
+		return errors.Wrap(err, "tokenMemoryRepository Revoke err")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, t := range r.tokens {
+		if t.UserID == userID && t.Purpose == purpose && t.UsedAt == nil && scope(&t) {
+			t.UsedAt = &at
+			r.tokens[id] = t
+		}
+	}
+	return nil
+}
+
+func (r *tokenMemoryRepository) LastIssued(ctx context.Context, userID, purpose string) (time.Time, error) {
+	scope, err := scopeOf(ctx)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "tokenMemoryRepository LastIssued err")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var last time.Time
+	for _, t := range r.tokens {
+		if t.UserID == userID && t.Purpose == purpose && scope(&t) && t.CreatedAt.After(last) {
+			last = t.CreatedAt
+		}
+	}
+	return last, nil
+}
+
+// scopeOf returns filter of tokens visible in tenant scope of ctx
+func scopeOf(ctx context.Context) (func(t *Token) bool, error) {
+	id, all, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return func(t *Token) bool {
+		return all || t.TenantID == id
+	}, nil
+}
@@ -0,0 +1,72 @@
+package token
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newToken(t *testing.T, ctx context.Context, repo Repository, userID string, ttl time.Duration) string {
+	plain, hash, err := Generate()
+	require.NoError(t, err)
+	require.NoError(t, repo.Create(ctx, &Token{
+		UserID:    userID,
+		Purpose:   PurposeVerifyEmail,
+		Hash:      hash,
+		Email:     "alice@example.com",
+		ExpiresAt: time.Now().Add(ttl),
+	}))
+	return plain
+}
+
+func TestMemoryRepository(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	now := time.Now()
+
+	plain := newToken(t, ctx, repo, "user-1", time.Hour)
+	// finding token does not use it
+	found, err := repo.Find(tenant.WithSuperAdmin(context.Background()), PurposeVerifyEmail, Hash(plain), now)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", found.UserID)
+	// tokens are looked up across tenants before user is known
+	got, err := repo.Consume(tenant.WithSuperAdmin(context.Background()), PurposeVerifyEmail, Hash(plain), now)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", got.UserID)
+	assert.Equal(t, "brand-a", got.TenantID)
+
+	// tokens are single-use
+	_, err = repo.Consume(ctx, PurposeVerifyEmail, Hash(plain), now)
+	assert.True(t, errors.Is(err, ErrNotFound), "got %v", err)
+	_, err = repo.Find(ctx, PurposeVerifyEmail, Hash(plain), now)
+	assert.True(t, errors.Is(err, ErrNotFound), "got %v", err)
+
+	expired := newToken(t, ctx, repo, "user-1", time.Hour)
+	_, err = repo.Consume(ctx, PurposeVerifyEmail, Hash(expired), now.Add(2*time.Hour))
+	assert.True(t, errors.Is(err, ErrNotFound), "got %v", err)
+
+	other := newToken(t, ctx, repo, "user-1", time.Hour)
+	_, err = repo.Consume(ctx, "other", Hash(other), now)
+	assert.True(t, errors.Is(err, ErrNotFound), "got %v", err)
+	_, err = repo.Consume(tenant.WithContext(context.Background(), "brand-b"), PurposeVerifyEmail, Hash(other), now)
+	assert.True(t, errors.Is(err, ErrNotFound), "got %v", err)
+
+	require.NoError(t, repo.Revoke(ctx, "user-1", PurposeVerifyEmail, now))
+	_, err = repo.Consume(ctx, PurposeVerifyEmail, Hash(other), now)
+	assert.True(t, errors.Is(err, ErrNotFound), "got %v", err)
+
+	last, err := repo.LastIssued(ctx, "user-1", PurposeVerifyEmail)
+	require.NoError(t, err)
+	assert.False(t, last.Before(now))
+	last, err = repo.LastIssued(ctx, "user-1", PurposeResetPassword)
+	require.NoError(t, err)
+	assert.True(t, last.IsZero())
+
+	err = repo.Create(context.Background(), &Token{})
+	assert.True(t, errors.Is(err, tenant.ErrMissing), "got %v", err)
+}
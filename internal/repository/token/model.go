@@ -0,0 +1,32 @@
+package token
+
+import "time"
+
+// Purposes of tokens, token of one purpose is not accepted for another.
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+)
+
+// Token is one-time token sent to user, only hash of it is stored.
+type Token struct {
+	ID       string `gorm:"primaryKey;size:64"`
+	TenantID string `gorm:"size:64"`
+	UserID   string `gorm:"size:64"`
+	Purpose  string `gorm:"size:32"`
+	Hash     string `gorm:"size:64"`
+	// Email is address token was sent to, token is not valid once user changes it
+	Email     string     `gorm:"size:64"`
+	ExpiresAt time.Time  `gorm:"type:timestamp"`
+	UsedAt    *time.Time `gorm:"type:timestamp"`
+	CreatedAt time.Time  `gorm:"type:timestamp"`
+}
+
+func (Token) TableName() string {
+	return "user_tokens"
+}
+
+// Valid reports whether token is neither used nor expired at now.
+func (t *Token) Valid(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
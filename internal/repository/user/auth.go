@@ -0,0 +1,36 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/nakiner/faceit/tools/auth"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/pkg/errors"
+)
+
+type revoker struct {
+	repo Repository
+}
+
+// NewRevoker returns auth.Revoker of sessions issued to users before they changed or reset password.
+// Callers which are not users stored in repo are never revoked.
+func NewRevoker(repo Repository) auth.Revoker {
+	return &revoker{repo: repo}
+}
+
+func (r *revoker) Revoked(ctx context.Context, p *auth.Principal) (bool, error) {
+	ctx = tenant.WithContext(ctx, p.Tenant)
+	if p.Tenant == "" {
+		ctx = tenant.WithSuperAdmin(ctx)
+	}
+	users, err := r.repo.Get(ctx, Conditions{"id": p.Subject}, 1, 1)
+	if err != nil {
+		return false, errors.Wrap(err, "get user")
+	}
+	if len(users) == 0 || users[0].PasswordChangedAt == nil {
+		return false, nil
+	}
+	// iat has second precision, tokens issued within second of change are kept
+	return p.IssuedAt.Before(users[0].PasswordChangedAt.Truncate(time.Second)), nil
+}
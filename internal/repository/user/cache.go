@@ -0,0 +1,194 @@
+package user
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/nakiner/faceit/tools/cache"
+	"github.com/nakiner/faceit/tools/logging"
+	"github.com/nakiner/faceit/tools/metrics"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+)
+
+// cacheName labels metrics of users cache
+const cacheName = "users"
+
+// Invalidator broadcasts ids of changed users to all instances, including this one.
+type Invalidator interface {
+	Invalidate(ctx context.Context, id string) error
+	OnInvalidate(fn func(id string)) error
+}
+
+type cacheOptions struct {
+	size int
+	ttl  time.Duration
+}
+
+type CacheOption func(*cacheOptions)
+
+// SetCacheSize sets maximum number of cached users.
+func SetCacheSize(size int) CacheOption {
+	return func(o *cacheOptions) {
+		o.size = size
+	}
+}
+
+// SetCacheTTL sets how long user is served from cache, it bounds staleness when invalidation is lost.
+func SetCacheTTL(ttl time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.ttl = ttl
+	}
+}
+
+// NewCachingRepository caches users read by id in process, concurrent misses of the same id are collapsed
+// into one read. Writes drop changed user from cache of every instance through invalidator.
+func NewCachingRepository(ctx context.Context, r Repository, inv Invalidator, opts ...CacheOption) (Repository, error) {
+	o := &cacheOptions{size: 10000, ttl: time.Minute}
+	for _, opt := range opts {
+		opt(o)
+	}
+	c := &cachingRepository{
+		Repository: r,
+		inv:        inv,
+		metrics:    metrics.Instance(ctx),
+	}
+	var cacheOpts []cache.Option
+	if c.metrics != nil {
+		cacheOpts = append(cacheOpts, cache.SetOnEvict(func(reason string) {
+			c.metrics.ObserveCacheEviction(cacheName, reason)
+		}))
+	}
+	c.cache = cache.NewLRU(o.size, o.ttl, cacheOpts...)
+
+	if err := inv.OnInvalidate(c.evict); err != nil {
+		return nil, errors.Wrap(err, "subscribe to users invalidation")
+	}
+	return c, nil
+}
+
+type cachingRepository struct {
+	// epoch changes on every invalidation, reads started before are not cached as they may be stale.
+	// It is first to be 64-bit aligned for atomic operations
+	epoch uint64
+	Repository
+	inv     Invalidator
+	cache   *cache.LRU
+	group   singleflight.Group
+	metrics *metrics.Metrics
+}
+
+func (r *cachingRepository) IsReady() bool {
+	return r.Repository.IsReady()
+}
+
+// Create needs no invalidation, user with new id could not be cached
+func (r *cachingRepository) Create(ctx context.Context, data *User) (string, error) {
+	return r.Repository.Create(ctx, data)
+}
+
+func (r *cachingRepository) Delete(ctx context.Context, id string) error {
+	err := r.Repository.Delete(ctx, id)
+	r.invalidate(ctx, id)
+	return err
+}
+
+func (r *cachingRepository) Update(ctx context.Context, data *User) error {
+	err := r.Repository.Update(ctx, data)
+	r.invalidate(ctx, data.ID)
+	return err
+}
+
+func (r *cachingRepository) VerifyEmail(ctx context.Context, id, email string, at time.Time) error {
+	err := r.Repository.VerifyEmail(ctx, id, email, at)
+	r.invalidate(ctx, id)
+	return err
+}
+
+func (r *cachingRepository) SetPassword(ctx context.Context, id, password string, at time.Time) error {
+	err := r.Repository.SetPassword(ctx, id, password, at)
+	r.invalidate(ctx, id)
+	return err
+}
+
+// Get serves reads of single user by id from cache, other queries go to repository
+func (r *cachingRepository) Get(ctx context.Context, conditions Conditions, limit uint32, offset uint32) ([]*User, error) {
+	id, ok := conditions.byID(limit, offset)
+	if !ok {
+		return r.Repository.Get(ctx, conditions, limit, offset)
+	}
+
+	tenantID, all, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "cachingRepository Get err")
+	}
+
+	// users are cached by id, tenant is checked on every hit
+	if v, ok := r.cache.Get(id); ok && (all || v.(*User).TenantID == tenantID) {
+		r.observe(true)
+		u := *v.(*User)
+		return []*User{&u}, nil
+	}
+	r.observe(false)
+
+	epoch := atomic.LoadUint64(&r.epoch)
+	// reads of different tenants are not collapsed as they see different users,
+	// nor reads started after invalidation with ones which may be stale
+	key := strconv.FormatUint(epoch, 10) + "/" + tenantID + "/" + id
+	v, err, _ := r.group.Do(key, func() (interface{}, error) {
+		users, err := r.Repository.Get(ctx, conditions, limit, offset)
+		if err != nil {
+			return nil, err
+		}
+		if len(users) == 1 && atomic.LoadUint64(&r.epoch) == epoch {
+			u := *users[0]
+			r.cache.Set(id, &u)
+		}
+		return users, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// result is shared between collapsed reads, each one gets own copies
+	shared := v.([]*User)
+	users := make([]*User, 0, len(shared))
+	for _, u := range shared {
+		u := *u
+		users = append(users, &u)
+	}
+	return users, nil
+}
+
+// invalidate drops user locally right away and asks other instances to drop it too,
+// it is done even when write failed as it may have been applied
+func (r *cachingRepository) invalidate(ctx context.Context, id string) {
+	r.evict(id)
+	if err := r.inv.Invalidate(ctx, id); err != nil {
+		level.Error(logging.FromContext(ctx)).Log("msg", "failed to broadcast user invalidation", "id", id, "err", err)
+	}
+}
+
+func (r *cachingRepository) evict(id string) {
+	atomic.AddUint64(&r.epoch, 1)
+	r.cache.Delete(id)
+}
+
+func (r *cachingRepository) observe(hit bool) {
+	if r.metrics != nil {
+		r.metrics.ObserveCacheLookup(cacheName, hit)
+	}
+}
+
+// byID returns id when conditions select first page of single user by id
+func (c Conditions) byID(limit, offset uint32) (string, bool) {
+	if len(c) != 1 || limit < 1 || offset != 1 {
+		return "", false
+	}
+	id, ok := c["id"].(string)
+	return id, ok
+}
@@ -0,0 +1,171 @@
+package user
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRepository counts reads and blocks them until release is closed
+type countingRepository struct {
+	Repository
+	reads   int32
+	release chan struct{}
+}
+
+func (r *countingRepository) Get(ctx context.Context, conditions Conditions, limit uint32, offset uint32) ([]*User, error) {
+	atomic.AddInt32(&r.reads, 1)
+	<-r.release
+	return r.Repository.Get(ctx, conditions, limit, offset)
+}
+
+type localInvalidator struct {
+	mu  sync.Mutex
+	ids []string
+	fn  func(id string)
+}
+
+func (i *localInvalidator) Invalidate(ctx context.Context, id string) error {
+	i.mu.Lock()
+	i.ids = append(i.ids, id)
+	i.mu.Unlock()
+	return nil
+}
+
+func (i *localInvalidator) OnInvalidate(fn func(id string)) error {
+	i.fn = fn
+	return nil
+}
+
+func newCachingRepository(t *testing.T) (Repository, *countingRepository, *localInvalidator) {
+	release := make(chan struct{})
+	close(release)
+	counting := &countingRepository{Repository: NewMemoryRepository(), release: release}
+	inv := &localInvalidator{}
+	repo, err := NewCachingRepository(context.Background(), counting, inv, SetCacheSize(10), SetCacheTTL(time.Minute))
+	require.NoError(t, err)
+	return repo, counting, inv
+}
+
+func TestCachingRepository_Get(t *testing.T) {
+	repo, counting, _ := newCachingRepository(t)
+	ctx := tenant.WithContext(context.Background(), "default")
+	id, err := repo.Create(ctx, &User{Nickname: "sample"})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		users, err := repo.Get(ctx, Conditions{"id": id}, 50, 1)
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, "sample", users[0].Nickname)
+		// callers get copies of cached user
+		users[0].Nickname = "changed"
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&counting.reads))
+
+	// other queries are not cached
+	for i := 0; i < 2; i++ {
+		_, err := repo.Get(ctx, Conditions{"nickname": "sample"}, 50, 1)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&counting.reads))
+}
+
+func TestCachingRepository_Invalidation(t *testing.T) {
+	repo, counting, inv := newCachingRepository(t)
+	ctx := tenant.WithContext(context.Background(), "default")
+	id, err := repo.Create(ctx, &User{Nickname: "sample"})
+	require.NoError(t, err)
+	_, err = repo.Get(ctx, Conditions{"id": id}, 50, 1)
+	require.NoError(t, err)
+
+	// write drops user locally and broadcasts its id
+	require.NoError(t, repo.Update(ctx, &User{ID: id, Nickname: "renamed"}))
+	assert.Equal(t, []string{id}, inv.ids)
+	users, err := repo.Get(ctx, Conditions{"id": id}, 50, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", users[0].Nickname)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&counting.reads))
+
+	// invalidation broadcast by another instance
+	inv.fn(id)
+	_, err = repo.Get(ctx, Conditions{"id": id}, 50, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&counting.reads))
+}
+
+func TestCachingRepository_CollapsesMisses(t *testing.T) {
+	repo, counting, _ := newCachingRepository(t)
+	ctx := tenant.WithContext(context.Background(), "default")
+	id, err := repo.Create(ctx, &User{Nickname: "sample"})
+	require.NoError(t, err)
+	counting.release = make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			users, err := repo.Get(ctx, Conditions{"id": id}, 50, 1)
+			assert.NoError(t, err)
+			assert.Len(t, users, 1)
+		}()
+	}
+	// let concurrent misses join the first read
+	time.Sleep(50 * time.Millisecond)
+	close(counting.release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&counting.reads))
+}
+
+func TestCachingRepository_StaleReadNotCached(t *testing.T) {
+	repo, counting, inv := newCachingRepository(t)
+	ctx := tenant.WithContext(context.Background(), "default")
+	id, err := repo.Create(ctx, &User{Nickname: "sample"})
+	require.NoError(t, err)
+	counting.release = make(chan struct{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := repo.Get(ctx, Conditions{"id": id}, 50, 1)
+		assert.NoError(t, err)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	// user changes while it is being read
+	inv.fn(id)
+	close(counting.release)
+	<-done
+
+	_, err = repo.Get(ctx, Conditions{"id": id}, 50, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&counting.reads))
+}
+
+func TestCachingRepository_TenantScope(t *testing.T) {
+	repo, _, _ := newCachingRepository(t)
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	id, err := repo.Create(ctx, &User{Nickname: "sample"})
+	require.NoError(t, err)
+	users, err := repo.Get(ctx, Conditions{"id": id}, 50, 1)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+
+	// cached user is not served to other tenant
+	users, err = repo.Get(tenant.WithContext(context.Background(), "brand-b"), Conditions{"id": id}, 50, 1)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	users, err = repo.Get(tenant.WithSuperAdmin(context.Background()), Conditions{"id": id}, 50, 1)
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	_, err = repo.Get(context.Background(), Conditions{"id": id}, 50, 1)
+	assert.ErrorIs(t, err, tenant.ErrMissing)
+}
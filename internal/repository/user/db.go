@@ -2,11 +2,12 @@ package user
 
 import (
 	"context"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/nakiner/faceit/internal/store/database"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
-	"time"
 )
 
 var (
@@ -14,27 +15,17 @@ var (
 )
 
 type userDBRepository struct {
-	db    *database.Connection
-	ready bool
+	db *database.Connection
 }
 
 // NewRepository creates new interfaced repository with CRUD to User entity
 func NewRepository(db *database.Connection) Repository {
-	rep := userDBRepository{db: db, ready: true}
-	go func() {
-		tic := time.Tick(time.Minute * 1)
-		for range tic {
-			if rep.db.CheckConn() != nil {
-				rep.ready = false
-			}
-		}
-	}()
-	return &rep
+	return &userDBRepository{db: db}
 }
 
-// IsReady used in Readiness checks to check availability of CRUD operations
+// IsReady checks availability of CRUD operations by pinging master and replica
 func (r *userDBRepository) IsReady() bool {
-	return r.ready
+	return r.db.CheckConn() == nil
 }
 
 // Create creates a new User entity in database
@@ -72,11 +63,28 @@ func (r *userDBRepository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
-// Update updates a User entity with given id and User payload
+// Update updates a User entity with given id and User payload, changed email is no longer verified
 func (r *userDBRepository) Update(ctx context.Context, data *User) error {
 	conn := r.db.GetMasterConn(ctx)
 
-	result := conn.Model(data).Updates(data)
+	var result *gorm.DB
+	if data.Email == "" {
+		result = conn.Model(data).Updates(data)
+	} else {
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			err := tx.Model(&User{}).
+				Where("id = ? AND email <> ?", data.ID, data.Email).
+				Update("email_verified_at", nil).Error
+			if err != nil {
+				return err
+			}
+			result = tx.Model(data).Updates(data)
+			return result.Error
+		})
+		if err != nil {
+			return errors.Wrap(err, "userDBRepository Update err")
+		}
+	}
 	if err := result.Error; err != nil {
 		return errors.Wrap(err, "userDBRepository Update err")
 	}
@@ -88,6 +96,41 @@ func (r *userDBRepository) Update(ctx context.Context, data *User) error {
 	return nil
 }
 
+// VerifyEmail marks email verified unless it was changed after verification was requested
+func (r *userDBRepository) VerifyEmail(ctx context.Context, id, email string, at time.Time) error {
+	conn := r.db.GetMasterConn(ctx)
+
+	result := conn.Model(&User{}).Where("id = ? AND email = ?", id, email).Update("email_verified_at", at)
+	if err := result.Error; err != nil {
+		return errors.Wrap(err, "userDBRepository VerifyEmail err")
+	}
+
+	if count := result.RowsAffected; count < 1 {
+		return errors.Wrap(ErrRowsAffectedEmpty, "userDBRepository VerifyEmail err")
+	}
+
+	return nil
+}
+
+// SetPassword replaces password and records when it was changed
+func (r *userDBRepository) SetPassword(ctx context.Context, id, password string, at time.Time) error {
+	conn := r.db.GetMasterConn(ctx)
+
+	result := conn.Model(&User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"password":            password,
+		"password_changed_at": at,
+	})
+	if err := result.Error; err != nil {
+		return errors.Wrap(err, "userDBRepository SetPassword err")
+	}
+
+	if count := result.RowsAffected; count < 1 {
+		return errors.Wrap(ErrRowsAffectedEmpty, "userDBRepository SetPassword err")
+	}
+
+	return nil
+}
+
 // Get performs select from database with set of conditions to fetch User collection
 // Conditions parsed into database prepared conditions to take only required data
 // Empty rowset does not handled to evade 404 behavior on transport layer.
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/nakiner/faceit/internal/store/database"
+	"github.com/nakiner/faceit/tools/tenant"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gorm.io/driver/postgres"
@@ -14,6 +15,7 @@ import (
 )
 
 func TestUserDBRepository_Create(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 
@@ -21,13 +23,11 @@ func TestUserDBRepository_Create(t *testing.T) {
 		Conn: db,
 	}), &gorm.Config{})
 	require.NoError(t, err)
+	require.NoError(t, database.UseTenantScope(DB))
 
-	dbpool := database.Connection{
-		Master:  DB,
-		Replica: DB,
-	}
+	dbpool := database.NewConnection(DB, DB)
 
-	repo := NewRepository(&dbpool)
+	repo := NewRepository(dbpool)
 	u := User{
 		FirstName: "firstname",
 		LastName:  "lastname",
@@ -38,19 +38,21 @@ func TestUserDBRepository_Create(t *testing.T) {
 	}
 
 	mock.ExpectBegin()
-	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "users" ("id","first_name","last_name","nickname","password","email","country","created_at","updated_at") 
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`)).
-		WithArgs(sqlmock.AnyArg(), u.FirstName, u.LastName, u.Nickname, u.Password, u.Email, u.Country, sqlmock.AnyArg(), sqlmock.AnyArg()).
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "users" ("id","tenant_id","first_name","last_name","nickname","password","email","country","created_at","updated_at","email_verified_at","password_changed_at") 
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)`)).
+		WithArgs(sqlmock.AnyArg(), "brand-a", u.FirstName, u.LastName, u.Nickname, u.Password, u.Email, u.Country, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
 
-	res, err := repo.Create(context.Background(), &u)
+	res, err := repo.Create(ctx, &u)
 	require.NoError(t, err)
 
 	assert.Equal(t, u.ID, res)
+	assert.Equal(t, "brand-a", u.TenantID)
 }
 
 func TestUserDBRepository_Delete(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 
@@ -58,26 +60,25 @@ func TestUserDBRepository_Delete(t *testing.T) {
 		Conn: db,
 	}), &gorm.Config{})
 	require.NoError(t, err)
+	require.NoError(t, database.UseTenantScope(DB))
 
-	dbpool := database.Connection{
-		Master:  DB,
-		Replica: DB,
-	}
+	dbpool := database.NewConnection(DB, DB)
 
 	id := "testid"
 
 	mock.ExpectBegin()
-	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "users" WHERE id = $1`)).
-		WithArgs(id).
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "users" WHERE id = $1 AND "tenant_id" = $2`)).
+		WithArgs(id, "brand-a").
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
 
-	repo := NewRepository(&dbpool)
-	err = repo.Delete(context.Background(), id)
+	repo := NewRepository(dbpool)
+	err = repo.Delete(ctx, id)
 	require.NoError(t, err)
 }
 
 func TestUserDBRepository_Update(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 
@@ -85,13 +86,11 @@ func TestUserDBRepository_Update(t *testing.T) {
 		Conn: db,
 	}), &gorm.Config{})
 	require.NoError(t, err)
+	require.NoError(t, database.UseTenantScope(DB))
 
-	dbpool := database.Connection{
-		Master:  DB,
-		Replica: DB,
-	}
+	dbpool := database.NewConnection(DB, DB)
 
-	repo := NewRepository(&dbpool)
+	repo := NewRepository(dbpool)
 
 	u := User{
 		ID:       "testid",
@@ -100,16 +99,71 @@ func TestUserDBRepository_Update(t *testing.T) {
 	}
 
 	mock.ExpectBegin()
-	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "nickname"=$1,"country"=$2,"updated_at"=$3 WHERE "id" = $4`)).
-		WithArgs(u.Nickname, u.Country, sqlmock.AnyArg(), u.ID).
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "nickname"=$1,"country"=$2,"updated_at"=$3 WHERE "tenant_id" = $4 AND "id" = $5`)).
+		WithArgs(u.Nickname, u.Country, sqlmock.AnyArg(), "brand-a", u.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = repo.Update(ctx, &u)
+	require.NoError(t, err)
+}
+
+func TestUserDBRepository_UpdateEmail(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	DB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	require.NoError(t, database.UseTenantScope(DB))
+
+	repo := NewRepository(database.NewConnection(DB, DB))
+
+	u := User{
+		ID:    "testid",
+		Email: "new@example.com",
+	}
+
+	// changed email is no longer verified
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "email_verified_at"=$1,"updated_at"=$2 WHERE (id = $3 AND email <> $4) AND "tenant_id" = $5`)).
+		WithArgs(nil, sqlmock.AnyArg(), u.ID, u.Email, "brand-a").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "email"=$1,"updated_at"=$2 WHERE "tenant_id" = $3 AND "id" = $4`)).
+		WithArgs(u.Email, sqlmock.AnyArg(), "brand-a", u.ID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
 
-	err = repo.Update(context.Background(), &u)
+	require.NoError(t, repo.Update(ctx, &u))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserDBRepository_SetPassword(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
+
+	DB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	require.NoError(t, database.UseTenantScope(DB))
+
+	repo := NewRepository(database.NewConnection(DB, DB))
+
+	at := time.Now()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "password"=$1,"password_changed_at"=$2,"updated_at"=$3 WHERE id = $4 AND "tenant_id" = $5`)).
+		WithArgs("changed", at, sqlmock.AnyArg(), "testid", "brand-a").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, repo.SetPassword(ctx, "testid", "changed", at))
+	require.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestUserDBRepository_Get(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 
@@ -119,18 +173,17 @@ func TestUserDBRepository_Get(t *testing.T) {
 		SkipDefaultTransaction: true,
 	})
 	require.NoError(t, err)
+	require.NoError(t, database.UseTenantScope(DB))
 
-	dbpool := database.Connection{
-		Master:  DB,
-		Replica: DB,
-	}
+	dbpool := database.NewConnection(DB, DB)
 
-	repo := NewRepository(&dbpool)
+	repo := NewRepository(dbpool)
 
 	tm := time.Now()
 
 	u := User{
 		ID:        "test",
+		TenantID:  "brand-a",
 		FirstName: "firstname",
 		LastName:  "lastname",
 		Nickname:  "nickname",
@@ -142,18 +195,18 @@ func TestUserDBRepository_Get(t *testing.T) {
 	}
 
 	rows := sqlmock.
-		NewRows([]string{"id", "first_name", "last_name", "nickname", "password", "email", "country", "created_at", "updated_at"}).
-		AddRow(u.ID, u.FirstName, u.LastName, u.Nickname, u.Password, u.Email, u.Country, u.CreatedAt, u.UpdatedAt)
+		NewRows([]string{"id", "tenant_id", "first_name", "last_name", "nickname", "password", "email", "country", "created_at", "updated_at"}).
+		AddRow(u.ID, u.TenantID, u.FirstName, u.LastName, u.Nickname, u.Password, u.Email, u.Country, u.CreatedAt, u.UpdatedAt)
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE id = $1 AND country = $2 LIMIT 50`)).
-		WithArgs(u.ID, u.Country).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE id = $1 AND country = $2 AND "tenant_id" = $3 LIMIT 50`)).
+		WithArgs(u.ID, u.Country, "brand-a").
 		WillReturnRows(rows)
 
 	conds := Conditions{}
 	conds["id"] = u.ID
 	conds["country"] = u.Country
 
-	res, err := repo.Get(context.Background(), conds, 50, 1)
+	res, err := repo.Get(ctx, conds, 50, 1)
 	require.NoError(t, err)
 
 	var exp []*User
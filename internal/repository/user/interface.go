@@ -1,6 +1,9 @@
 package user
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type Repository interface {
 	IsReady() bool
@@ -8,4 +11,10 @@ type Repository interface {
 	Delete(ctx context.Context, id string) error
 	Update(ctx context.Context, data *User) error
 	Get(ctx context.Context, conditions Conditions, limit uint32, offset uint32) ([]*User, error)
+	// VerifyEmail marks email of user with id verified at, ErrRowsAffectedEmpty is returned
+	// when there is no such user or its email is no longer email.
+	VerifyEmail(ctx context.Context, id, email string, at time.Time) error
+	// SetPassword replaces password of user with id and records it was changed at, ErrRowsAffectedEmpty
+	// is returned when there is no such user.
+	SetPassword(ctx context.Context, id, password string, at time.Time) error
 }
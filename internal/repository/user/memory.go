@@ -0,0 +1,231 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/pkg/errors"
+)
+
+type userMemoryRepository struct {
+	mu    sync.RWMutex
+	users map[string]*User
+	// order keeps ids in insertion order, so pages are stable
+	order []string
+}
+
+// NewMemoryRepository creates thread-safe repository keeping users in process, for local development and tests.
+// It follows semantics of database repository: zero fields are not updated, offset starts from 1,
+// operations are scoped to tenant of context.
+func NewMemoryRepository() Repository {
+	return &userMemoryRepository{users: make(map[string]*User)}
+}
+
+// IsReady is always true, there is nothing to connect to
+func (r *userMemoryRepository) IsReady() bool {
+	return true
+}
+
+// Create stores a copy of User entity with generated id in tenant of context
+func (r *userMemoryRepository) Create(ctx context.Context, data *User) (string, error) {
+	tenantID, all, err := tenant.Scope(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "userMemoryRepository Create err")
+	}
+	if !all {
+		data.TenantID = tenantID
+	} else if data.TenantID == "" {
+		return "", errors.Wrap(tenant.ErrMissing, "userMemoryRepository Create err")
+	}
+
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return "", errors.Wrap(err, "userMemoryRepository generate uuid err")
+	}
+	now := time.Now()
+	data.ID = id.String()
+	data.CreatedAt = now
+	data.UpdatedAt = now
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := *data
+	r.users[u.ID] = &u
+	r.order = append(r.order, u.ID)
+	return data.ID, nil
+}
+
+// Delete deletes a User entity with given id
+func (r *userMemoryRepository) Delete(ctx context.Context, id string) error {
+	scope, err := scopeOf(ctx)
+	if err != nil {
+		return errors.Wrap(err, "userMemoryRepository Delete err")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.users[id]; !ok || !scope(u) {
+		return errors.Wrap(ErrRowsAffectedEmpty, "userMemoryRepository Delete err")
+	}
+	delete(r.users, id)
+	for i, v := range r.order {
+		if v == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Update updates non-zero fields of User entity with given id
+func (r *userMemoryRepository) Update(ctx context.Context, data *User) error {
+	scope, err := scopeOf(ctx)
+	if err != nil {
+		return errors.Wrap(err, "userMemoryRepository Update err")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[data.ID]
+	if !ok || !scope(u) {
+		return errors.Wrap(ErrRowsAffectedEmpty, "userMemoryRepository Update err")
+	}
+	if data.Email != "" && data.Email != u.Email {
+		u.EmailVerifiedAt = nil
+	}
+	for _, f := range []struct {
+		dst *string
+		src string
+	}{
+		{&u.FirstName, data.FirstName},
+		{&u.LastName, data.LastName},
+		{&u.Nickname, data.Nickname},
+		{&u.Password, data.Password},
+		{&u.Email, data.Email},
+		{&u.Country, data.Country},
+	} {
+		if f.src != "" {
+			*f.dst = f.src
+		}
+	}
+	u.UpdatedAt = time.Now()
+	data.UpdatedAt = u.UpdatedAt
+	return nil
+}
+
+// VerifyEmail marks email of user with id verified at when it is still email
+func (r *userMemoryRepository) VerifyEmail(ctx context.Context, id, email string, at time.Time) error {
+	scope, err := scopeOf(ctx)
+	if err != nil {
+		return errors.Wrap(err, "userMemoryRepository VerifyEmail err")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok || !scope(u) || u.Email != email {
+		return errors.Wrap(ErrRowsAffectedEmpty, "userMemoryRepository VerifyEmail err")
+	}
+	u.EmailVerifiedAt = &at
+	return nil
+}
+
+// SetPassword replaces password of user with id and records it was changed at
+func (r *userMemoryRepository) SetPassword(ctx context.Context, id, password string, at time.Time) error {
+	scope, err := scopeOf(ctx)
+	if err != nil {
+		return errors.Wrap(err, "userMemoryRepository SetPassword err")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok || !scope(u) {
+		return errors.Wrap(ErrRowsAffectedEmpty, "userMemoryRepository SetPassword err")
+	}
+	u.Password = password
+	u.PasswordChangedAt = &at
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+// Get returns copies of users matching all conditions, limit 0 means no limit
+func (r *userMemoryRepository) Get(ctx context.Context, conditions Conditions, limit uint32, offset uint32) ([]*User, error) {
+	if err := conditions.check(); err != nil {
+		return nil, errors.Wrap(err, "userMemoryRepository Get err")
+	}
+	scope, err := scopeOf(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "userMemoryRepository Get err")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var users []*User
+	// offset is 1-based like in database repository, so zero skips everything
+	skip := uint64(offset - 1)
+	for _, id := range r.order {
+		u := r.users[id]
+		if !scope(u) || !conditions.match(u) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		res := *u
+		users = append(users, &res)
+		if limit > 0 && len(users) == int(limit) {
+			break
+		}
+	}
+	return users, nil
+}
+
+// columns are getters of User fields by column names used in Conditions
+var columns = map[string]func(u *User) string{
+	"id":         func(u *User) string { return u.ID },
+	"tenant_id":  func(u *User) string { return u.TenantID },
+	"first_name": func(u *User) string { return u.FirstName },
+	"last_name":  func(u *User) string { return u.LastName },
+	"nickname":   func(u *User) string { return u.Nickname },
+	"password":   func(u *User) string { return u.Password },
+	"email":      func(u *User) string { return u.Email },
+	"country":    func(u *User) string { return u.Country },
+}
+
+// check returns error when condition refers to unknown column
+func (c Conditions) check() error {
+	for key := range c {
+		if _, ok := columns[key]; !ok {
+			return errors.Errorf("unknown column %q", key)
+		}
+	}
+	return nil
+}
+
+// match reports whether u has all condition columns equal to their values
+func (c Conditions) match(u *User) bool {
+	for key, value := range c {
+		if columns[key](u) != fmt.Sprint(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// scopeOf returns filter of users visible in tenant scope of ctx
+func scopeOf(ctx context.Context) (func(u *User) bool, error) {
+	id, all, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return func(u *User) bool {
+		return all || u.TenantID == id
+	}, nil
+}
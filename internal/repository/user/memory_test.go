@@ -0,0 +1,32 @@
+package user_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nakiner/faceit/internal/repository/user"
+	"github.com/nakiner/faceit/internal/repository/user/repotest"
+	userQueue "github.com/nakiner/faceit/pkg/queue/user"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) user.Repository {
+		return user.NewMemoryRepository()
+	})
+}
+
+func TestCachingRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) user.Repository {
+		b := userQueue.NewMemoryBroker(context.Background())
+		t.Cleanup(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			b.Drain(ctx)
+		})
+		repo, err := user.NewCachingRepository(context.Background(), user.NewMemoryRepository(), userQueue.NewMemoryInvalidator(b))
+		require.NoError(t, err)
+		return repo
+	})
+}
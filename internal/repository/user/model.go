@@ -9,6 +9,7 @@ type Conditions map[string]interface{}
 
 type User struct {
 	ID        string    `gorm:"primaryKey,size:64"`
+	TenantID  string    `gorm:"size:64"`
 	FirstName string    `gorm:"size:64"`
 	LastName  string    `gorm:"size:64"`
 	Nickname  string    `gorm:"size:64"`
@@ -17,6 +18,10 @@ type User struct {
 	Country   string    `gorm:"size:64"`
 	CreatedAt time.Time `gorm:"type:timestamp"`
 	UpdatedAt time.Time `gorm:"type:timestamp"`
+	// EmailVerifiedAt is set once user proves owning Email, changing Email resets it
+	EmailVerifiedAt *time.Time `gorm:"type:timestamp"`
+	// PasswordChangedAt is set by password change and reset, sessions issued before it are revoked
+	PasswordChangedAt *time.Time `gorm:"type:timestamp"`
 }
 
 func (User) TableName() string {
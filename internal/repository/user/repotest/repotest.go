@@ -0,0 +1,301 @@
+// Package repotest is a conformance suite every user.Repository implementation should pass.
+package repotest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nakiner/faceit/internal/repository/user"
+	"github.com/nakiner/faceit/tools/auth"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run runs conformance tests against repository returned by newRepo. Repository may be shared between tests
+// and hold other data, every test works with users of its own unique country within tenant "repotest".
+func Run(t *testing.T, newRepo func(t *testing.T) user.Repository) {
+	for _, tc := range []struct {
+		name string
+		fn   func(t *testing.T, repo user.Repository)
+	}{
+		{"Create", testCreate},
+		{"Get", testGet},
+		{"Pagination", testPagination},
+		{"Update", testUpdate},
+		{"Delete", testDelete},
+		{"VerifyEmail", testVerifyEmail},
+		{"SetPassword", testSetPassword},
+		{"Concurrent", testConcurrent},
+		{"TenantIsolation", testTenantIsolation},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, newRepo(t))
+		})
+	}
+}
+
+// newUser returns user of country unique for calling test
+func newUser(country, nickname string) *user.User {
+	return &user.User{
+		FirstName: "first",
+		LastName:  "last",
+		Nickname:  nickname,
+		Password:  "password",
+		Email:     nickname + "@example.com",
+		Country:   country,
+	}
+}
+
+// newContext returns context scoped to tenant shared by tests
+func newContext() context.Context {
+	return tenant.WithContext(context.Background(), "repotest")
+}
+
+func uniqueCountry() string {
+	return uuid.New().String()
+}
+
+func testCreate(t *testing.T, repo user.Repository) {
+	ctx := newContext()
+	u := newUser(uniqueCountry(), "sample")
+	id, err := repo.Create(ctx, u)
+	require.NoError(t, err)
+	assert.NotEmpty(t, id)
+	assert.Equal(t, id, u.ID)
+
+	users, err := repo.Get(ctx, user.Conditions{"id": id}, 50, 1)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	got := users[0]
+	assert.Equal(t, u.Nickname, got.Nickname)
+	assert.Equal(t, u.Email, got.Email)
+	assert.Equal(t, u.Country, got.Country)
+	assert.False(t, got.CreatedAt.IsZero())
+	assert.False(t, got.UpdatedAt.IsZero())
+
+	// returned users are copies
+	got.Nickname = "changed"
+	users, err = repo.Get(ctx, user.Conditions{"id": id}, 50, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "sample", users[0].Nickname)
+}
+
+func testGet(t *testing.T, repo user.Repository) {
+	ctx := newContext()
+	country := uniqueCountry()
+	for _, nickname := range []string{"alice", "bob", "alice"} {
+		_, err := repo.Create(ctx, newUser(country, nickname))
+		require.NoError(t, err)
+	}
+
+	users, err := repo.Get(ctx, user.Conditions{"country": country}, 50, 1)
+	require.NoError(t, err)
+	assert.Len(t, users, 3)
+
+	users, err = repo.Get(ctx, user.Conditions{"country": country, "nickname": "alice"}, 50, 1)
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+	for _, u := range users {
+		assert.Equal(t, "alice", u.Nickname)
+	}
+
+	users, err = repo.Get(ctx, user.Conditions{"country": country, "nickname": "carol"}, 50, 1)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	_, err = repo.Get(ctx, user.Conditions{"unknown": "value"}, 50, 1)
+	assert.Error(t, err)
+}
+
+func testPagination(t *testing.T, repo user.Repository) {
+	ctx := newContext()
+	country := uniqueCountry()
+	created := map[string]bool{}
+	for _, nickname := range []string{"first", "second", "third"} {
+		id, err := repo.Create(ctx, newUser(country, nickname))
+		require.NoError(t, err)
+		created[id] = true
+	}
+
+	// offset is 1-based position of the first returned user
+	seen := map[string]bool{}
+	first, err := repo.Get(ctx, user.Conditions{"country": country}, 2, 1)
+	require.NoError(t, err)
+	assert.Len(t, first, 2)
+	second, err := repo.Get(ctx, user.Conditions{"country": country}, 2, 3)
+	require.NoError(t, err)
+	assert.Len(t, second, 1)
+	for _, u := range append(first, second...) {
+		seen[u.ID] = true
+	}
+	assert.Equal(t, created, seen)
+
+	beyond, err := repo.Get(ctx, user.Conditions{"country": country}, 2, 4)
+	require.NoError(t, err)
+	assert.Empty(t, beyond)
+}
+
+func testUpdate(t *testing.T, repo user.Repository) {
+	ctx := newContext()
+	u := newUser(uniqueCountry(), "sample")
+	id, err := repo.Create(ctx, u)
+	require.NoError(t, err)
+
+	// zero fields are left untouched
+	require.NoError(t, repo.Update(ctx, &user.User{ID: id, Nickname: "renamed"}))
+	users, err := repo.Get(ctx, user.Conditions{"id": id}, 50, 1)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "renamed", users[0].Nickname)
+	assert.Equal(t, u.Email, users[0].Email)
+	assert.False(t, users[0].UpdatedAt.Before(users[0].CreatedAt))
+
+	err = repo.Update(ctx, &user.User{ID: uuid.New().String(), Nickname: "missing"})
+	assert.True(t, errors.Is(err, user.ErrRowsAffectedEmpty), "got %v", err)
+}
+
+func testDelete(t *testing.T, repo user.Repository) {
+	ctx := newContext()
+	id, err := repo.Create(ctx, newUser(uniqueCountry(), "sample"))
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Delete(ctx, id))
+	users, err := repo.Get(ctx, user.Conditions{"id": id}, 50, 1)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	err = repo.Delete(ctx, id)
+	assert.True(t, errors.Is(err, user.ErrRowsAffectedEmpty), "got %v", err)
+}
+
+func testVerifyEmail(t *testing.T, repo user.Repository) {
+	ctx := newContext()
+	u := newUser(uniqueCountry(), "sample")
+	id, err := repo.Create(ctx, u)
+	require.NoError(t, err)
+
+	// email changed after verification was requested is not verified
+	err = repo.VerifyEmail(ctx, id, "other@example.com", time.Now())
+	assert.True(t, errors.Is(err, user.ErrRowsAffectedEmpty), "got %v", err)
+
+	require.NoError(t, repo.VerifyEmail(ctx, id, u.Email, time.Now()))
+	users, err := repo.Get(ctx, user.Conditions{"id": id}, 50, 1)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.NotNil(t, users[0].EmailVerifiedAt)
+
+	// updates keeping email keep it verified, changing it resets verification
+	require.NoError(t, repo.Update(ctx, &user.User{ID: id, Email: u.Email, Nickname: "renamed"}))
+	users, err = repo.Get(ctx, user.Conditions{"id": id}, 50, 1)
+	require.NoError(t, err)
+	assert.NotNil(t, users[0].EmailVerifiedAt)
+	require.NoError(t, repo.Update(ctx, &user.User{ID: id, Email: "changed@example.com"}))
+	users, err = repo.Get(ctx, user.Conditions{"id": id}, 50, 1)
+	require.NoError(t, err)
+	assert.Nil(t, users[0].EmailVerifiedAt)
+}
+
+func testSetPassword(t *testing.T, repo user.Repository) {
+	ctx := newContext()
+	id, err := repo.Create(ctx, newUser(uniqueCountry(), "sample"))
+	require.NoError(t, err)
+	revoker := user.NewRevoker(repo)
+	session := &auth.Principal{Subject: id, Tenant: "repotest", IssuedAt: time.Now().Add(-time.Hour)}
+	revoked, err := revoker.Revoked(ctx, session)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	at := time.Now()
+	require.NoError(t, repo.SetPassword(ctx, id, "changed", at))
+	users, err := repo.Get(ctx, user.Conditions{"id": id}, 50, 1)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "changed", users[0].Password)
+	require.NotNil(t, users[0].PasswordChangedAt)
+
+	// sessions issued before change are revoked, later ones are not
+	revoked, err = revoker.Revoked(ctx, session)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = revoker.Revoked(ctx, &auth.Principal{Subject: id, Tenant: "repotest", IssuedAt: at.Add(time.Second)})
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	err = repo.SetPassword(ctx, uuid.New().String(), "changed", at)
+	assert.True(t, errors.Is(err, user.ErrRowsAffectedEmpty), "got %v", err)
+}
+
+func testConcurrent(t *testing.T, repo user.Repository) {
+	ctx := newContext()
+	country := uniqueCountry()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id, err := repo.Create(ctx, newUser(country, "sample"))
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.NoError(t, repo.Update(ctx, &user.User{ID: id, Nickname: "renamed"}))
+			_, err = repo.Get(ctx, user.Conditions{"country": country}, 50, 1)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	users, err := repo.Get(ctx, user.Conditions{"country": country, "nickname": "renamed"}, 50, 1)
+	require.NoError(t, err)
+	assert.Len(t, users, 20)
+}
+
+func testTenantIsolation(t *testing.T, repo user.Repository) {
+	country := uniqueCountry()
+	ctxA := tenant.WithContext(context.Background(), "repotest-a")
+	ctxB := tenant.WithContext(context.Background(), "repotest-b")
+	u := newUser(country, "sample")
+	id, err := repo.Create(ctxA, u)
+	require.NoError(t, err)
+	assert.Equal(t, "repotest-a", u.TenantID)
+	_, err = repo.Create(ctxB, newUser(country, "sample"))
+	require.NoError(t, err)
+
+	users, err := repo.Get(ctxA, user.Conditions{"country": country}, 50, 1)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, id, users[0].ID)
+	assert.Equal(t, "repotest-a", users[0].TenantID)
+
+	// other tenant neither sees nor changes the user
+	users, err = repo.Get(ctxB, user.Conditions{"id": id}, 50, 1)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+	err = repo.Update(ctxB, &user.User{ID: id, Nickname: "renamed"})
+	assert.True(t, errors.Is(err, user.ErrRowsAffectedEmpty), "got %v", err)
+	err = repo.Delete(ctxB, id)
+	assert.True(t, errors.Is(err, user.ErrRowsAffectedEmpty), "got %v", err)
+
+	// super-admin works across tenants
+	admin := tenant.WithSuperAdmin(context.Background())
+	users, err = repo.Get(admin, user.Conditions{"country": country}, 50, 1)
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+	require.NoError(t, repo.Update(admin, &user.User{ID: id, Nickname: "renamed"}))
+	users, err = repo.Get(ctxA, user.Conditions{"id": id}, 50, 1)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "renamed", users[0].Nickname)
+	assert.Equal(t, "repotest-a", users[0].TenantID)
+
+	// operations without tenant fail
+	_, err = repo.Get(context.Background(), user.Conditions{"id": id}, 50, 1)
+	assert.True(t, errors.Is(err, tenant.ErrMissing), "got %v", err)
+	_, err = repo.Create(admin, newUser(country, "sample"))
+	assert.True(t, errors.Is(err, tenant.ErrMissing), "got %v", err)
+}
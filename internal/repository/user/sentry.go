@@ -2,6 +2,8 @@ package user
 
 import (
 	"context"
+	"time"
+
 	"github.com/getsentry/sentry-go"
 )
 
@@ -69,3 +71,29 @@ func (s *sentryRepository) Get(ctx context.Context, conditions Conditions, limit
 	}()
 	return s.Repository.Get(ctx, conditions, limit, offset)
 }
+
+func (s *sentryRepository) VerifyEmail(ctx context.Context, id, email string, at time.Time) (err error) {
+	defer func() {
+		if err != nil {
+			sentry.ConfigureScope(func(scope *sentry.Scope) {
+				scope.SetTag("repository", "userDBRepository")
+				scope.SetTag("method", "VerifyEmail")
+			})
+			sentry.CaptureException(err)
+		}
+	}()
+	return s.Repository.VerifyEmail(ctx, id, email, at)
+}
+
+func (s *sentryRepository) SetPassword(ctx context.Context, id, password string, at time.Time) (err error) {
+	defer func() {
+		if err != nil {
+			sentry.ConfigureScope(func(scope *sentry.Scope) {
+				scope.SetTag("repository", "userDBRepository")
+				scope.SetTag("method", "SetPassword")
+			})
+			sentry.CaptureException(err)
+		}
+	}()
+	return s.Repository.SetPassword(ctx, id, password, at)
+}
@@ -2,6 +2,8 @@ package user
 
 import (
 	"context"
+	"time"
+
 	"github.com/nakiner/faceit/tools/tracing"
 	"github.com/opentracing/opentracing-go"
 )
@@ -45,3 +47,15 @@ func (r *tracingRepository) Get(ctx context.Context, conditions Conditions, limi
 	defer span.Finish()
 	return r.Repository.Get(ctx, conditions, limit, offset)
 }
+
+func (r *tracingRepository) VerifyEmail(ctx context.Context, id, email string, at time.Time) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, r.tracer, "VerifyEmail")
+	defer span.Finish()
+	return r.Repository.VerifyEmail(ctx, id, email, at)
+}
+
+func (r *tracingRepository) SetPassword(ctx context.Context, id, password string, at time.Time) error {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, r.tracer, "SetPassword")
+	defer span.Finish()
+	return r.Repository.SetPassword(ctx, id, password, at)
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"net/http"
 	"sort"
 	"time"
@@ -9,7 +10,17 @@ import (
 	grpctransport "github.com/go-kit/kit/transport/grpc"
 	"github.com/gorilla/mux"
 	"github.com/nakiner/faceit/configs"
+	"github.com/nakiner/faceit/internal/store/database"
+	"github.com/nakiner/faceit/tools/auth"
+	"github.com/nakiner/faceit/tools/limiting"
+	"github.com/nakiner/faceit/tools/logging"
+	"github.com/nakiner/faceit/tools/metrics"
+	"github.com/nakiner/faceit/tools/requestid"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
 )
 
 // NewServer initializes new server.
@@ -19,6 +30,10 @@ func NewServer(ops ...Option) (svc *Server, err error) {
 	for _, o := range ops {
 		o(svc)
 	}
+	if svc.err != nil {
+		svc.Close()
+		return nil, svc.err
+	}
 
 	return svc, nil
 }
@@ -58,15 +73,91 @@ func SetHandler(handlers map[string]http.Handler) Option {
 	}
 }
 
+// SetOnShutdown sets function called first on shutdown, used to fail readiness.
+func SetOnShutdown(fn func()) Option {
+	return func(s *Server) {
+		s.onShutdown = fn
+	}
+}
+
+// SetTeardown sets phases run in order after transports are stopped, e.g. closing dependencies.
+func SetTeardown(phases ...Phase) Option {
+	return func(s *Server) {
+		s.teardown = append(s.teardown, phases...)
+	}
+}
+
+// SetPriorityAuthenticator sets authenticator of superadmins never shed by concurrency limiter. Limiter runs
+// before authentication and checks credentials only when it is overloaded, so a should not reach storage,
+// e.g. bare JWT verification.
+func SetPriorityAuthenticator(a auth.Authenticator) Option {
+	return func(s *Server) {
+		s.priority = a
+	}
+}
+
+// SetMetrics enables HTTP and GRPC transport metrics, it should precede SetGRPC.
+func SetMetrics(m *metrics.Metrics) Option {
+	return func(s *Server) {
+		s.metrics = m
+	}
+}
+
+// SetAuthenticator enables authentication of HTTP and GRPC requests, it should precede SetGRPC.
+func SetAuthenticator(a auth.Authenticator) Option {
+	return func(s *Server) {
+		s.auth = a
+	}
+}
+
 func SetGRPC(joins ...func(grpc *grpc.Server)) Option {
 	return func(s *Server) {
-		grpcServer := grpc.NewServer(
-			grpc.UnaryInterceptor(grpctransport.Interceptor),
-			grpc.ConnectionTimeout(time.Second*time.Duration(s.cfg.Server.GRPC.TimeoutSec)),
-		)
+		interceptors := []grpc.UnaryServerInterceptor{
+			grpctransport.Interceptor,
+			requestid.UnaryServerInterceptor(logging.WithContext(context.Background(), s.logger)),
+			limiting.ClientIPInterceptor(s.trustedProxies()),
+		}
+		// measured before limiters, so rejected requests are counted too
+		if s.metrics != nil {
+			interceptors = append(interceptors, s.metrics.UnaryServerInterceptor())
+		}
+		// overload and floods are shed before credentials are checked
+		if s.cfg.Concurrency.Enabled {
+			interceptors = append(interceptors, s.getConcurrencyLimiter().UnaryServerInterceptor())
+		}
+		if s.cfg.Limiter.Enabled {
+			interceptors = append(interceptors, s.getLimiter().UnaryServerInterceptor())
+		}
+		if s.auth != nil {
+			interceptors = append(interceptors, auth.UnaryServerInterceptor(s.auth))
+		}
+		if s.cfg.Limiter.Enabled {
+			interceptors = append(interceptors, s.getLimiter().QuotaInterceptor())
+		}
+		interceptors = append(interceptors, tenant.UnaryServerInterceptor(s.cfg.Tenant.Default))
+		if s.cfg.Postgres.ReadYourWrites {
+			interceptors = append(interceptors, database.ReadYourWritesInterceptor)
+		}
+		opts := []grpc.ServerOption{
+			grpc.ChainUnaryInterceptor(interceptors...),
+			grpc.ConnectionTimeout(time.Second * time.Duration(s.cfg.Server.GRPC.TimeoutSec)),
+		}
+		// in single mode grpc is served over http listener and shares its TLS
+		if s.cfg.Server.Mode != ModeSingle {
+			tlsConfig, err := s.serverTLS(s.cfg.Server.GRPC.TLS)
+			if err != nil {
+				s.err = errors.Wrap(err, "grpc tls")
+				return
+			}
+			if tlsConfig != nil {
+				opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+			}
+		}
+		grpcServer := grpc.NewServer(opts...)
 		for _, j := range joins {
 			j(grpcServer)
 		}
+		reflection.Register(grpcServer)
 		s.grpc = grpcServer
 	}
 }
@@ -2,82 +2,167 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/nakiner/faceit/configs"
+	"github.com/nakiner/faceit/internal/store/database"
+	"github.com/nakiner/faceit/tools/auth"
 	"github.com/nakiner/faceit/tools/limiting"
+	"github.com/nakiner/faceit/tools/logging"
+	"github.com/nakiner/faceit/tools/metrics"
+	"github.com/nakiner/faceit/tools/requestid"
 	"github.com/nakiner/faceit/tools/sentry"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/nakiner/faceit/tools/tlsconfig"
 	"github.com/oklog/run"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"google.golang.org/grpc"
 )
 
+const (
+	// ModeSplit serves HTTP and GRPC on separate listeners
+	ModeSplit = "split"
+	// ModeSingle multiplexes GRPC and HTTP on http listener, including h2c for cleartext HTTP/2
+	ModeSingle = "single"
+)
+
 // Server main struct for prm-export service
 type Server struct {
-	cfg     *configs.Config
-	logger  log.Logger
-	handler http.Handler
-	grpc    *grpc.Server
-	group   run.Group
+	cfg         *configs.Config
+	logger      log.Logger
+	handler     http.Handler
+	grpc        *grpc.Server
+	limiter     limiting.Limiter
+	concurrency limiting.ConcurrencyLimiter
+	metrics     *metrics.Metrics
+	auth        auth.Authenticator
+	priority    auth.Authenticator
+	reloaders   []*tlsconfig.Reloader
+	group       run.Group
+	err         error
+
+	cors         atomic.Value
+	httpServers  []*http.Server
+	onShutdown   func()
+	teardown     []Phase
+	shutdownOnce sync.Once
 }
 
 type Option func(*Server)
 
+// Phase is a named step of shutdown sequence run after transports are stopped.
+type Phase struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
 func (s *Server) setGroup(group run.Group) {
 	s.group = group
 }
 
-// Run запускает сервер
+// Run запускает сервер; teardown phases are run in order after transports are stopped
 func (s *Server) Run() error {
-	return s.logger.Log("exit", s.group.Run())
+	err := s.group.Run()
+	s.runTeardown()
+	return s.logger.Log("exit", err)
 }
 
 // Close closes everything that is open and should be closed after server shutdown
 func (s *Server) Close() {
-
+	for _, r := range s.reloaders {
+		r.Close()
+	}
 }
 
 // AddHTTP  http server start when Server.Run()
 func (s *Server) AddHTTP() error {
 	addr := fmt.Sprintf(":%d", s.cfg.Server.HTTP.Port)
+	tlsConfig, err := s.serverTLS(s.cfg.Server.HTTP.TLS)
+	if err != nil {
+		return errors.Wrap(err, "cann't add HTTP transport")
+	}
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
 		return errors.Wrap(err, "cann't add HTTP transport")
 	}
 
-	s.group.Add(func() error {
-		level.Info(s.logger).Log("component", "HTTP server", "addr", addr, "msg", "listening...")
-		if s.cfg.Limiter.Enabled {
-			l := limiting.NewLimiter(context.Background(), s.cfg.Limiter.Limit)
-			s.handler = l.Middleware(s.handler)
-		}
-		if s.cfg.Sentry.Enabled {
-			s.handler = sentry.Middleware(s.handler)
-		}
+	if s.cfg.Sentry.Enabled {
+		s.handler = sentry.Middleware(s.handler)
+	}
+	if s.cfg.Postgres.ReadYourWrites {
+		s.handler = database.ReadYourWritesMiddleware(s.handler)
+	}
+	s.handler = tenant.Middleware(s.cfg.Tenant.Default, s.handler)
+	// quotas are granted to authenticated principals, so they are enforced inside of auth
+	if s.cfg.Limiter.Enabled {
+		s.handler = s.getLimiter().QuotaMiddleware(s.handler)
+	}
+	// tenant is resolved against claim of authenticated caller
+	if s.auth != nil {
+		s.handler = auth.Middleware(s.auth, s.handler)
+	}
+	// overload and floods are shed before credentials are checked
+	if s.cfg.Concurrency.Enabled {
+		s.handler = s.getConcurrencyLimiter().Middleware(s.handler)
+	}
+	if s.cfg.Limiter.Enabled {
+		s.handler = s.getLimiter().Middleware(s.handler)
+	}
+	s.handler = limiting.ClientIPMiddleware(s.trustedProxies(), s.handler)
+	if s.metrics != nil {
+		s.handler = s.metrics.Middleware(s.handler)
+	}
+	// outermost, so rejected requests also carry request id
+	s.handler = requestid.Middleware(logging.WithContext(context.Background(), s.logger), s.handler)
 
-		httpServer := &http.Server{
-			Handler:      accessControl(s.handler),
-			WriteTimeout: time.Second * time.Duration(s.cfg.Server.HTTP.TimeoutSec),
-		}
+	s.cors.Store(newCORS(s.cfg))
+	handler := s.accessControl(s.handler)
+	if s.cfg.Server.Mode == ModeSingle {
+		level.Info(s.logger).Log("component", "GRPC server", "addr", addr, "msg", "multiplexed with HTTP")
+		handler = h2c.NewHandler(grpcHandler(s.grpc, handler), &http2.Server{})
+	}
+
+	httpServer := &http.Server{
+		Handler:      handler,
+		WriteTimeout: time.Second * time.Duration(s.cfg.Server.HTTP.TimeoutSec),
+		TLSConfig:    tlsConfig,
+	}
+	s.httpServers = append(s.httpServers, httpServer)
 
+	s.group.Add(func() error {
+		level.Info(s.logger).Log("component", "HTTP server", "addr", addr, "tls", tlsConfig != nil, "msg", "listening...")
+		if tlsConfig != nil {
+			return httpServer.ServeTLS(listener, "", "")
+		}
 		return httpServer.Serve(listener)
 	}, func(error) {
+		s.shutdown()
 		listener.Close()
 	})
 	return nil
 }
 
 // AddGRPC  grpc server start when Server.Run()
+// In single mode grpc is served by AddHTTP listener, so nothing is added.
 func (s *Server) AddGRPC() error {
+	if s.cfg.Server.Mode == ModeSingle {
+		return nil
+	}
 	addr := fmt.Sprintf(":%d", s.cfg.Server.GRPC.Port)
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
@@ -85,9 +170,10 @@ func (s *Server) AddGRPC() error {
 	}
 
 	s.group.Add(func() error {
-		level.Info(s.logger).Log("component", "GRPC server", "addr", addr, "msg", "listening...")
+		level.Info(s.logger).Log("component", "GRPC server", "addr", addr, "tls", s.cfg.Server.GRPC.TLS.Enabled, "msg", "listening...")
 		return s.grpc.Serve(listener)
 	}, func(error) {
+		s.shutdown()
 		listener.Close()
 	})
 	return nil
@@ -99,15 +185,26 @@ func (s *Server) AddMetrics() error {
 		return nil
 	}
 	addr := fmt.Sprintf(":%d", s.cfg.Metrics.Port)
+	tlsConfig, err := s.serverTLS(s.cfg.Metrics.TLS)
+	if err != nil {
+		return errors.Wrap(err, "cann't add Metrics")
+	}
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
 		return errors.Wrap(err, "cann't add Metrics")
 	}
+	http.Handle("/metrics", promhttp.Handler())
+	metricsServer := &http.Server{Handler: http.DefaultServeMux, TLSConfig: tlsConfig}
+	s.httpServers = append(s.httpServers, metricsServer)
+
 	s.group.Add(func() error {
-		level.Info(s.logger).Log("component", "metrics server", "addr", addr, "msg", "listening...")
-		http.Handle("/metrics", promhttp.Handler())
-		return http.Serve(listener, http.DefaultServeMux)
+		level.Info(s.logger).Log("component", "metrics server", "addr", addr, "tls", tlsConfig != nil, "msg", "listening...")
+		if tlsConfig != nil {
+			return metricsServer.ServeTLS(listener, "", "")
+		}
+		return metricsServer.Serve(listener)
 	}, func(error) {
+		s.shutdown()
 		listener.Close()
 	})
 
@@ -131,11 +228,260 @@ func (s *Server) AddSignalHandler() {
 	})
 }
 
-func accessControl(h http.Handler) http.Handler {
+// AddStartup runs fn in background when Server.Run(), transports are served meanwhile.
+// Server is stopped when fn fails, e.g. dependencies are not reachable within startup deadline.
+func (s *Server) AddStartup(fn func(ctx context.Context) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.group.Add(func() error {
+		if err := fn(ctx); err != nil {
+			return errors.Wrap(err, "startup")
+		}
+		<-ctx.Done()
+		return nil
+	}, func(error) {
+		cancel()
+	})
+}
+
+// Apply applies live settings of reloaded configuration: limiter quota and CORS.
+func (s *Server) Apply(cfg *configs.Config) {
+	if s.limiter != nil {
+		s.limiter.SetLimit(cfg.Limiter.Limit, cfg.Limiter.Burst)
+	}
+	s.cors.Store(newCORS(cfg))
+}
+
+// shutdown fails readiness, waits pre-stop delay and stops transports letting in-flight requests finish
+// until deadline. It is run once by the first interrupted transport.
+func (s *Server) shutdown() {
+	s.shutdownOnce.Do(func() {
+		level.Info(s.logger).Log("component", "shutdown", "msg", "shutting down")
+		if s.onShutdown != nil {
+			s.runPhase(context.Background(), "readiness", func(context.Context) error {
+				s.onShutdown()
+				return nil
+			})
+		}
+		s.runPhase(context.Background(), "pre-stop delay", func(context.Context) error {
+			time.Sleep(time.Second * time.Duration(s.cfg.Shutdown.PreStopDelaySec))
+			return nil
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(s.cfg.Shutdown.TimeoutSec))
+		defer cancel()
+		s.runPhase(ctx, "transports", s.stopTransports)
+	})
+}
+
+// stopTransports gracefully stops http servers and grpc server concurrently, cutting connections on deadline
+func (s *Server) stopTransports(ctx context.Context) error {
+	var wg sync.WaitGroup
+	errs := make(chan error, len(s.httpServers)+1)
+	for _, srv := range s.httpServers {
+		wg.Add(1)
+		go func(srv *http.Server) {
+			defer wg.Done()
+			if err := srv.Shutdown(ctx); err != nil {
+				srv.Close()
+				errs <- errors.Wrap(err, "http shutdown")
+			}
+		}(srv)
+	}
+	// in single mode grpc is served by http server, its streams are drained by http shutdown above
+	if s.grpc != nil && s.cfg.Server.Mode != ModeSingle {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			done := make(chan struct{})
+			go func() {
+				s.grpc.GracefulStop()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-ctx.Done():
+				s.grpc.Stop()
+				errs <- errors.Wrap(ctx.Err(), "grpc graceful stop")
+			}
+		}()
+	}
+	wg.Wait()
+	if s.grpc != nil && s.cfg.Server.Mode == ModeSingle {
+		s.grpc.Stop()
+	}
+	close(errs)
+	return <-errs
+}
+
+// runTeardown runs teardown phases in order, each one limited by teardown timeout
+func (s *Server) runTeardown() {
+	for _, p := range s.teardown {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(s.cfg.Shutdown.TeardownTimeoutSec))
+		s.runPhase(ctx, p.Name, p.Run)
+		cancel()
+	}
+}
+
+// runPhase runs shutdown phase logging its duration and error
+func (s *Server) runPhase(ctx context.Context, name string, fn func(ctx context.Context) error) {
+	begin := time.Now()
+	err := fn(ctx)
+	took := time.Since(begin)
+	if err != nil {
+		level.Error(s.logger).Log("component", "shutdown", "phase", name, "took", took, "err", err)
+		return
+	}
+	level.Info(s.logger).Log("component", "shutdown", "phase", name, "took", took, "msg", "done")
+}
+
+// getLimiter returns limiter shared by HTTP and GRPC transports
+func (s *Server) getLimiter() limiting.Limiter {
+	if s.limiter != nil {
+		return s.limiter
+	}
+	cfg := s.cfg.Limiter
+
+	var policies []limiting.Policy
+	for _, r := range cfg.Routes {
+		policies = append(policies, limiting.Policy{
+			Method: r.Method,
+			Path:   r.Path,
+			Limit:  r.Limit,
+			Burst:  r.Burst,
+		})
+	}
+	s.limiter = limiting.NewLimiter(
+		logging.WithContext(context.Background(), s.logger),
+		cfg.Limit,
+		limiting.SetBurst(cfg.Burst),
+		limiting.SetPolicies(policies),
+		limiting.SetTrustedProxies(s.trustedProxies()),
+		limiting.SetStore(cfg.MaxKeys, time.Second*time.Duration(cfg.IdleTimeoutSec)),
+	)
+	return s.limiter
+}
+
+// trustedProxies returns networks of proxies whose X-Forwarded-For is honored in client ip,
+// they are checked by configs.Validate at startup
+func (s *Server) trustedProxies() []*net.IPNet {
+	proxies, _ := limiting.ParseCIDRs(splitList(s.cfg.Limiter.TrustedProxies))
+	return proxies
+}
+
+// getConcurrencyLimiter returns adaptive concurrency limiter shared by HTTP and GRPC transports
+func (s *Server) getConcurrencyLimiter() limiting.ConcurrencyLimiter {
+	if s.concurrency != nil {
+		return s.concurrency
+	}
+	cfg := s.cfg.Concurrency
+
+	opts := []limiting.AdaptiveOption{
+		limiting.SetLimits(cfg.InitialLimit, cfg.MinLimit, cfg.MaxLimit),
+		limiting.SetLatencyTarget(time.Millisecond * time.Duration(cfg.LatencyTargetMsec)),
+		limiting.SetBackoff(cfg.Backoff),
+		limiting.SetPriority(splitList(cfg.Priority)),
+	}
+	if s.priority != nil {
+		opts = append(opts, limiting.SetPrioritizer(superAdmins{s.priority}))
+	}
+	s.concurrency = limiting.NewAdaptiveLimiter(logging.WithContext(context.Background(), s.logger), opts...)
+	return s.concurrency
+}
+
+// superAdmins recognizes callers with credentials of superadmin, which are internal admin traffic
+type superAdmins struct {
+	auth auth.Authenticator
+}
+
+func (a superAdmins) PriorityRequest(r *http.Request) bool {
+	return a.superAdmin(r.Context(), auth.RequestCredentials(r))
+}
+
+func (a superAdmins) PriorityCall(ctx context.Context) bool {
+	return a.superAdmin(ctx, auth.CallCredentials(ctx))
+}
+
+func (a superAdmins) superAdmin(ctx context.Context, credentials string) bool {
+	if credentials == "" {
+		return false
+	}
+	p, err := auth.Verify(ctx, a.auth, credentials)
+	return err == nil && p.SuperAdmin
+}
+
+// serverTLS returns listener TLS config with certificates reloaded from disk, nil when TLS is disabled
+func (s *Server) serverTLS(cfg tlsconfig.Config) (*tls.Config, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	r, err := tlsconfig.NewReloader(logging.WithContext(context.Background(), s.logger), cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.reloaders = append(s.reloaders, r)
+	return r.ServerConfig()
+}
+
+// splitList splits comma separated config value
+func splitList(value string) []string {
+	var res []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// grpcHandler routes HTTP/2 requests with grpc content type to grpc server and the rest to http handler
+func grpcHandler(grpcServer *grpc.Server, other http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+		other.ServeHTTP(w, r)
+	})
+}
+
+// corsPolicy holds CORS settings, origins are nil when any origin is allowed
+type corsPolicy struct {
+	origins map[string]struct{}
+	methods string
+	headers string
+}
+
+func newCORS(cfg *configs.Config) *corsPolicy {
+	p := &corsPolicy{
+		methods: cfg.CORS.AllowedMethods,
+		headers: cfg.CORS.AllowedHeaders,
+	}
+	for _, o := range splitList(cfg.CORS.AllowedOrigins) {
+		if o == "*" {
+			p.origins = nil
+			break
+		}
+		if p.origins == nil {
+			p.origins = make(map[string]struct{})
+		}
+		p.origins[o] = struct{}{}
+	}
+	return p
+}
+
+func (s *Server) accessControl(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, PUT, DELETE, UPDATE, PATCH")
-		w.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization")
+		p := s.cors.Load().(*corsPolicy)
+		if p.origins == nil {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Add("Vary", "Origin")
+			if _, ok := p.origins[r.Header.Get("Origin")]; ok {
+				w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+			}
+		}
+		w.Header().Set("Access-Control-Allow-Methods", p.methods)
+		w.Header().Set("Access-Control-Allow-Headers", p.headers)
 
 		if r.Method == "OPTIONS" {
 			return
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/nakiner/faceit/configs"
+	"github.com/nakiner/faceit/tools/auth"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestGRPCHandler(t *testing.T) {
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, grpchealth.NewServer())
+
+	rest := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	httpServer := &http.Server{Handler: h2c.NewHandler(grpcHandler(grpcServer, rest), &http2.Server{})}
+	go httpServer.Serve(listener)
+	defer httpServer.Close()
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/liveness")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	res, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.Status)
+}
+
+func TestShutdown(t *testing.T) {
+	cfg := configs.NewConfig()
+	cfg.Shutdown.TimeoutSec = 5
+	cfg.Shutdown.TeardownTimeoutSec = 1
+
+	var mu sync.Mutex
+	var order []string
+	record := func(phase string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, phase)
+	}
+	s, err := NewServer(
+		SetConfig(cfg),
+		SetLogger(log.NewNopLogger()),
+		SetOnShutdown(func() { record("readiness") }),
+		SetTeardown(
+			Phase{Name: "nats", Run: func(context.Context) error {
+				record("nats")
+				return nil
+			}},
+			Phase{Name: "database", Run: func(context.Context) error {
+				record("database")
+				return nil
+			}},
+		),
+	)
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		record("request")
+		w.WriteHeader(http.StatusOK)
+	})}
+	s.httpServers = append(s.httpServers, httpServer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go httpServer.Serve(listener)
+
+	result := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			result <- 0
+			return
+		}
+		resp.Body.Close()
+		result <- resp.StatusCode
+	}()
+	<-started
+
+	// in-flight request completes before transports are reported stopped
+	s.shutdown()
+	assert.Equal(t, http.StatusOK, <-result)
+	s.runTeardown()
+	mu.Lock()
+	assert.Equal(t, []string{"readiness", "request", "nats", "database"}, order)
+	mu.Unlock()
+
+	_, err = http.Get("http://" + listener.Addr().String())
+	assert.Error(t, err)
+}
+
+type tokens map[string]*auth.Principal
+
+func (t tokens) Authenticate(_ context.Context, credentials string) (*auth.Principal, error) {
+	if p, ok := t[credentials]; ok {
+		return p, nil
+	}
+	return nil, auth.ErrUnauthenticated
+}
+
+func TestSuperAdmins(t *testing.T) {
+	priority := superAdmins{tokens{"root": {SuperAdmin: true}, "player": {Subject: "1"}}}
+	call := func(credentials string) bool {
+		r := httptest.NewRequest("GET", "/user", nil)
+		if credentials != "" {
+			r.Header.Set(auth.Header, "Bearer "+credentials)
+		}
+		return priority.PriorityRequest(r)
+	}
+	assert.False(t, call(""))
+	assert.False(t, call("player"))
+	assert.False(t, call("forged"))
+	assert.True(t, call("root"))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(auth.MetadataKey, "Bearer root"))
+	assert.True(t, priority.PriorityCall(ctx))
+	assert.False(t, priority.PriorityCall(context.Background()))
+}
+
+func TestAccessControl(t *testing.T) {
+	cfg := configs.NewConfig()
+	cfg.CORS.AllowedOrigins = "*"
+	cfg.CORS.AllowedMethods = "GET"
+	s := &Server{cfg: cfg}
+	s.cors.Store(newCORS(cfg))
+	h := s.accessControl(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	call := func(origin string) http.Header {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/user", nil)
+		r.Header.Set("Origin", origin)
+		h.ServeHTTP(w, r)
+		return w.Header()
+	}
+	assert.Equal(t, "*", call("https://faceit.com").Get("Access-Control-Allow-Origin"))
+
+	next := configs.NewConfig()
+	next.CORS.AllowedOrigins = "https://faceit.com, https://admin.faceit.com"
+	next.CORS.AllowedMethods = "GET, POST"
+	s.Apply(next)
+
+	header := call("https://admin.faceit.com")
+	assert.Equal(t, "https://admin.faceit.com", header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST", header.Get("Access-Control-Allow-Methods"))
+	assert.Empty(t, call("https://evil.com").Get("Access-Control-Allow-Origin"))
+}
+
+func TestAddStartup(t *testing.T) {
+	cfg := configs.NewConfig()
+	var tornDown int32
+	s, err := NewServer(
+		SetConfig(cfg),
+		SetLogger(log.NewNopLogger()),
+		SetTeardown(Phase{Name: "database", Run: func(context.Context) error {
+			atomic.StoreInt32(&tornDown, 1)
+			return nil
+		}}),
+	)
+	require.NoError(t, err)
+
+	// successful startup keeps server running until another actor stops it
+	s.AddStartup(func(ctx context.Context) error { return nil })
+	s.AddStartup(func(ctx context.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		return errors.New("postgres is not reachable")
+	})
+	s.AddSignalHandler()
+
+	done := make(chan struct{})
+	go func() {
+		s.Run()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server is not stopped after failed startup")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tornDown))
+}
@@ -4,49 +4,101 @@ import (
 	"context"
 	"fmt"
 	"github.com/nakiner/faceit/configs"
+	"github.com/nakiner/faceit/tools/metrics"
 	"github.com/pkg/errors"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"net/url"
+	"strconv"
+	"time"
 )
 
-// Connection for different types of operations could be used read/read-write connections to perform actions
+// Connection for different types of operations could be used read/read-write connections to perform actions.
+// Reads are balanced between healthy replicas and fall back to master when none is healthy.
 type Connection struct {
-	Master  *gorm.DB
-	Replica *gorm.DB
+	Master   *gorm.DB
+	Replicas []*Replica
+
+	maxLag  time.Duration
+	next    uint32
+	metrics *metrics.Metrics
 }
 
-// Connect connects to master and replica and returns database descriptor to access connections
-func Connect(ctx context.Context, cfg *configs.Config) (*Connection, error) {
-	var res Connection
-	var err error
+// NewConnection creates connection routing reads to replicas, every replica is healthy until checked.
+func NewConnection(master *gorm.DB, replicas ...*gorm.DB) *Connection {
+	c := &Connection{Master: master}
+	for i, db := range replicas {
+		c.Replicas = append(c.Replicas, newReplica(fmt.Sprintf("replica-%d", i+1), db))
+	}
+	return c
+}
 
-	res.Master, err = connectPool(ctx, cfg.Postgres.Master)
+// Connect opens pools of master and replicas and returns database descriptor to access connections.
+// Pools connect lazily, so it does not fail while database is down, reachability is checked by pings.
+func Connect(ctx context.Context, cfg *configs.Config) (*Connection, error) {
+	slow := time.Millisecond * time.Duration(cfg.Postgres.SlowQueryThresholdMsec)
+	master, err := connectPool(ctx, "master", cfg.Postgres.Master, slow)
 	if err != nil {
 		return nil, errors.Wrap(err, "Master DB connect")
 	}
 
-	res.Replica, err = connectPool(ctx, cfg.Postgres.Replica)
-	if err != nil {
-		return nil, errors.Wrap(err, "Replica DB connect")
+	var replicas []*gorm.DB
+	for i, node := range cfg.ReplicaNodes() {
+		replica, err := connectPool(ctx, fmt.Sprintf("replica-%d", i+1), node, slow)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Replica %d DB connect", i+1)
+		}
+		replicas = append(replicas, replica)
 	}
 
-	return &res, nil
+	res := NewConnection(master, replicas...)
+	res.maxLag = time.Millisecond * time.Duration(cfg.Postgres.MaxReplicaLagMsec)
+	res.metrics = metrics.Instance(ctx)
+	return res, nil
 }
 
-// connectPool performs a new connection with given configs.Database config
-func connectPool(ctx context.Context, db configs.Database) (conn *gorm.DB, err error) {
+// connectPool performs a new connection with given configs.Database config,
+// statements are instrumented and labeled with pool name, ones slower than slow are logged,
+// statements on tenant-owned tables are scoped to tenant of context
+func connectPool(ctx context.Context, pool string, db configs.Database, slow time.Duration) (conn *gorm.DB, err error) {
 	dsn := url.URL{
-		User:     url.UserPassword(db.User, db.Password),
+		User:     url.UserPassword(db.User, db.Password.Reveal()),
 		Scheme:   "postgres",
 		Host:     fmt.Sprintf("%s:%d", db.Host, db.Port),
 		Path:     db.DatabaseName,
-		RawQuery: (&url.Values{"sslmode": []string{db.Secure}}).Encode(),
+		RawQuery: dsnParams(db).Encode(),
+	}
+
+	conn, err = gorm.Open(postgres.Open(dsn.String()), &gorm.Config{Logger: gormLogger{}, DisableAutomaticPing: true})
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Use(newInstrumentation(ctx, pool, slow)); err != nil {
+		return nil, err
+	}
+	if err := UseTenantScope(conn); err != nil {
+		return nil, err
 	}
 
-	conn, err = gorm.Open(postgres.Open(dsn.String()), &gorm.Config{})
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(db.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(db.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(time.Second * time.Duration(db.ConnMaxLifetimeSec))
+	sqlDB.SetConnMaxIdleTime(time.Second * time.Duration(db.ConnMaxIdleTimeSec))
 
-	return conn, err
+	return conn, nil
+}
+
+// dsnParams returns connection parameters, statement_timeout is passed to server as runtime parameter
+func dsnParams(db configs.Database) *url.Values {
+	params := &url.Values{"sslmode": []string{db.Secure}}
+	if db.StatementTimeoutMsec > 0 {
+		params.Set("statement_timeout", strconv.Itoa(db.StatementTimeoutMsec))
+	}
+	return params
 }
 
 // Close closes all available idle connections to master/replica sets
@@ -60,28 +112,58 @@ func (c *Connection) Close() error {
 		return errors.Wrap(err, "err close conn.Master")
 	}
 
-	db, err = c.Replica.DB()
-	if err != nil {
-		return errors.Wrap(err, "err get conn.Replica")
-	}
-	err = db.Close()
-	if err != nil {
-		return errors.Wrap(err, "err close conn.Replica")
+	for _, r := range c.Replicas {
+		db, err = r.DB.DB()
+		if err != nil {
+			return errors.Wrapf(err, "err get conn %s", r.Name)
+		}
+		err = db.Close()
+		if err != nil {
+			return errors.Wrapf(err, "err close conn %s", r.Name)
+		}
 	}
 
 	return nil
 }
 
-// GetReplicaConn allows applying passed context to active operation and takes active Replica connection
+// GetReplicaConn allows applying passed context to active operation and takes next healthy Replica connection.
+// Master is taken when no replica is healthy or ctx has written with read-your-writes enabled.
 func (c *Connection) GetReplicaConn(ctx context.Context) *gorm.DB {
-	return c.Replica.WithContext(ctx)
+	if pinned(ctx) {
+		return c.Master.WithContext(ctx)
+	}
+	if r := c.nextReplica(); r != nil {
+		return r.DB.WithContext(ctx)
+	}
+	return c.Master.WithContext(ctx)
 }
 
-// GetMasterConn allows applying passed context to active operation and takes active Master connection
+// GetMasterConn allows applying passed context to active operation and takes active Master connection.
+// Further reads of ctx with read-your-writes enabled go to master as well.
 func (c *Connection) GetMasterConn(ctx context.Context) *gorm.DB {
+	pin(ctx)
 	return c.Master.WithContext(ctx)
 }
 
+// PingMaster checks Master connection is alive, used by readiness checks
+func (c *Connection) PingMaster(ctx context.Context) error {
+	return ping(ctx, c.Master)
+}
+
+// PingReplica checks every replica, updates its routing state and fails when no replica is healthy,
+// used by readiness checks which run it periodically
+func (c *Connection) PingReplica(ctx context.Context) error {
+	return c.CheckReplicas(ctx)
+}
+
+func ping(ctx context.Context, conn *gorm.DB) error {
+	db, err := conn.DB()
+	if err != nil {
+		return errors.Wrap(err, "err get conn")
+	}
+	return db.PingContext(ctx)
+}
+
 // CheckConn performs Ping() operation on connections and returns error if database went down
 // In common used in repository to perform Readiness checks and restart service if needed.
 func (c *Connection) CheckConn() error {
@@ -92,12 +174,14 @@ func (c *Connection) CheckConn() error {
 	if err := db.Ping(); err != nil {
 		return err
 	}
-	db, err = c.Replica.DB()
-	if err != nil {
-		return errors.Wrap(err, "err get conn.Replica")
-	}
-	if err := db.Ping(); err != nil {
-		return err
+	for _, r := range c.Replicas {
+		db, err = r.DB.DB()
+		if err != nil {
+			return errors.Wrapf(err, "err get conn %s", r.Name)
+		}
+		if err := db.Ping(); err != nil {
+			return err
+		}
 	}
 
 	return nil
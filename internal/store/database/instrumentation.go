@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/nakiner/faceit/tools/logging"
+	"github.com/nakiner/faceit/tools/metrics"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+const (
+	startKey = "faceit:start"
+	spanKey  = "faceit:span"
+)
+
+// instrumentation is gorm plugin reporting every statement executed on pool: span within active trace,
+// query metrics and slow or failed query log. Reported SQL has placeholders, not argument values.
+type instrumentation struct {
+	pool    string
+	metrics *metrics.Metrics
+	slow    time.Duration
+}
+
+func newInstrumentation(ctx context.Context, pool string, slow time.Duration) *instrumentation {
+	return &instrumentation{
+		pool:    pool,
+		metrics: metrics.Instance(ctx),
+		slow:    slow,
+	}
+}
+
+func (p *instrumentation) Name() string {
+	return "faceit:instrumentation"
+}
+
+func (p *instrumentation) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	// every callback is registered, the first failure is reported
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("faceit:before_create", p.before("insert")),
+		cb.Create().After("gorm:create").Register("faceit:after_create", p.after("insert")),
+		cb.Query().Before("gorm:query").Register("faceit:before_query", p.before("select")),
+		cb.Query().After("gorm:query").Register("faceit:after_query", p.after("select")),
+		cb.Update().Before("gorm:update").Register("faceit:before_update", p.before("update")),
+		cb.Update().After("gorm:update").Register("faceit:after_update", p.after("update")),
+		cb.Delete().Before("gorm:delete").Register("faceit:before_delete", p.before("delete")),
+		cb.Delete().After("gorm:delete").Register("faceit:after_delete", p.after("delete")),
+		cb.Row().Before("gorm:row").Register("faceit:before_row", p.before("row")),
+		cb.Row().After("gorm:row").Register("faceit:after_row", p.after("row")),
+		cb.Raw().Before("gorm:raw").Register("faceit:before_raw", p.before("raw")),
+		cb.Raw().After("gorm:raw").Register("faceit:after_raw", p.after("raw")),
+	} {
+		if err != nil {
+			return errors.Wrap(err, "register instrumentation callback")
+		}
+	}
+	return nil
+}
+
+func (p *instrumentation) before(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		db.InstanceSet(startKey, time.Now())
+		// statements are traced only within a trace, the span knows its tracer
+		if parent := opentracing.SpanFromContext(db.Statement.Context); parent != nil {
+			span := parent.Tracer().StartSpan("sql "+operation, opentracing.ChildOf(parent.Context()), ext.SpanKindRPCClient)
+			db.InstanceSet(spanKey, span)
+		}
+	}
+}
+
+func (p *instrumentation) after(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		begin := v.(time.Time)
+		elapsed := time.Since(begin)
+		query := db.Statement.SQL.String()
+		err := db.Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+
+		if v, ok := db.InstanceGet(spanKey); ok {
+			span := v.(opentracing.Span)
+			ext.DBType.Set(span, "postgresql")
+			ext.DBStatement.Set(span, query)
+			span.SetTag("db.pool", p.pool)
+			span.SetTag("db.rows_affected", db.Statement.RowsAffected)
+			if err != nil {
+				ext.LogError(span, err)
+			}
+			span.Finish()
+		}
+		if p.metrics != nil {
+			p.metrics.ObserveQuery(p.pool, operation, begin, err)
+		}
+
+		logger := logging.FromContext(db.Statement.Context)
+		if err != nil {
+			level.Error(logger).Log("msg", "query failed", "pool", p.pool, "query", query, "elapsed", elapsed, "err", err)
+		} else if p.slow > 0 && elapsed >= p.slow {
+			level.Warn(logger).Log("msg", "slow query", "pool", p.pool, "query", query, "rows", db.Statement.RowsAffected, "elapsed", elapsed)
+		}
+	}
+}
+
+// gormLogger routes gorm messages to logger of context, statements are reported by instrumentation instead.
+type gormLogger struct{}
+
+func (l gormLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface {
+	return l
+}
+
+func (l gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	level.Info(l.logger(ctx)).Log("msg", fmt.Sprintf(msg, args...))
+}
+
+func (l gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	level.Warn(l.logger(ctx)).Log("msg", fmt.Sprintf(msg, args...))
+}
+
+func (l gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	level.Error(l.logger(ctx)).Log("msg", fmt.Sprintf(msg, args...))
+}
+
+func (l gormLogger) Trace(context.Context, time.Time, func() (string, int64), error) {}
+
+func (l gormLogger) logger(ctx context.Context) log.Logger {
+	return log.With(logging.FromContext(ctx), "component", "gorm")
+}
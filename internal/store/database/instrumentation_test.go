@@ -0,0 +1,72 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-kit/kit/log"
+	"github.com/nakiner/faceit/tools/logging"
+	"github.com/nakiner/faceit/tools/metrics"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type user struct {
+	ID       string
+	Password string
+}
+
+func TestInstrumentation(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	registry := stdprometheus.NewRegistry()
+	ctx := metrics.WithContext(context.Background(), metrics.SetRegisterer(registry))
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{Logger: gormLogger{}})
+	require.NoError(t, err)
+	// every statement is slow with nanosecond threshold
+	require.NoError(t, db.Use(newInstrumentation(ctx, "replica", 1)))
+
+	var buf bytes.Buffer
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("Get")
+	reqCtx := opentracing.ContextWithSpan(context.Background(), parent)
+	reqCtx = logging.WithContext(reqCtx, log.With(log.NewLogfmtLogger(&buf), "request_id", "complaint-42"))
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE password = $1`)).
+		WithArgs("secret").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "password"}).AddRow("1", "secret"))
+	var users []user
+	require.NoError(t, db.WithContext(reqCtx).Where("password = ?", "secret").Find(&users).Error)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "sql select", spans[0].OperationName)
+	assert.Equal(t, parent.(*mocktracer.MockSpan).SpanContext.SpanID, spans[0].ParentID)
+	assert.Equal(t, `SELECT * FROM "users" WHERE password = $1`, spans[0].Tag("db.statement"))
+	assert.Equal(t, "replica", spans[0].Tag("db.pool"))
+
+	assert.Contains(t, buf.String(), "slow query")
+	assert.Contains(t, buf.String(), "request_id=complaint-42")
+	assert.NotContains(t, buf.String(), "secret")
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	var found bool
+	for _, f := range families {
+		if f.GetName() == "faceit_db_queries_total" {
+			found = true
+			assert.Equal(t, 1.0, f.GetMetric()[0].GetCounter().GetValue())
+		}
+	}
+	assert.True(t, found)
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/nakiner/faceit/tools/logging"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
+)
+
+// lagQuery returns seconds replica is behind master, zero when it has replayed everything received
+// or is not in recovery at all
+const lagQuery = `SELECT COALESCE(CASE
+	WHEN pg_is_in_recovery() AND pg_last_wal_receive_lsn() IS DISTINCT FROM pg_last_wal_replay_lsn()
+	THEN EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+	ELSE 0 END, 0)`
+
+// Replica is read-only node, reads are routed to it while it is healthy.
+type Replica struct {
+	Name string
+	DB   *gorm.DB
+
+	healthy int32
+	lag     int64
+}
+
+func newReplica(name string, db *gorm.DB) *Replica {
+	return &Replica{Name: name, DB: db, healthy: 1}
+}
+
+// Healthy reports whether replica was reachable and not lagging on last check.
+func (r *Replica) Healthy() bool {
+	return atomic.LoadInt32(&r.healthy) == 1
+}
+
+// Lag returns replication lag measured on last check.
+func (r *Replica) Lag() time.Duration {
+	return time.Duration(atomic.LoadInt64(&r.lag))
+}
+
+// nextReplica returns next healthy replica in round-robin order or nil when none is healthy
+func (c *Connection) nextReplica() *Replica {
+	n := uint32(len(c.Replicas))
+	if n == 0 {
+		return nil
+	}
+	start := atomic.AddUint32(&c.next, 1)
+	for i := uint32(0); i < n; i++ {
+		if r := c.Replicas[(start+i)%n]; r.Healthy() {
+			return r
+		}
+	}
+	return nil
+}
+
+// CheckReplicas pings replicas and measures their lag, replicas which fail or lag longer
+// than allowed are not read from until next successful check. Error is returned when no replica is healthy.
+func (c *Connection) CheckReplicas(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+	var wg sync.WaitGroup
+	for _, r := range c.Replicas {
+		wg.Add(1)
+		go func(r *Replica) {
+			defer wg.Done()
+			lag, err := replicaLag(ctx, r.DB)
+			if err == nil && c.maxLag > 0 && lag > c.maxLag {
+				err = errors.Errorf("lag %s exceeds %s", lag, c.maxLag)
+			}
+			atomic.StoreInt64(&r.lag, int64(lag))
+			healthy := int32(0)
+			if err == nil {
+				healthy = 1
+			}
+			if old := atomic.SwapInt32(&r.healthy, healthy); old != healthy {
+				if err != nil {
+					level.Warn(logger).Log("msg", "replica is excluded from reads", "replica", r.Name, "err", err)
+				} else {
+					level.Info(logger).Log("msg", "replica is back to reads", "replica", r.Name, "lag", lag)
+				}
+			}
+			if c.metrics != nil {
+				c.metrics.SetReplicaState(r.Name, lag, err == nil)
+			}
+		}(r)
+	}
+	wg.Wait()
+
+	if c.nextReplica() == nil {
+		return errors.New("no healthy replica, reading from master")
+	}
+	return nil
+}
+
+func replicaLag(ctx context.Context, conn *gorm.DB) (time.Duration, error) {
+	db, err := conn.DB()
+	if err != nil {
+		return 0, errors.Wrap(err, "err get conn")
+	}
+	var seconds float64
+	if err := db.QueryRowContext(ctx, lagQuery).Scan(&seconds); err != nil {
+		return 0, errors.Wrap(err, "measure replication lag")
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+type pinKey struct{}
+
+// WithReadYourWrites makes reads of ctx go to master once it has written through GetMasterConn,
+// so a request sees its own writes despite replication lag.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, pinKey{}, new(int32))
+}
+
+func pin(ctx context.Context) {
+	if p, ok := ctx.Value(pinKey{}).(*int32); ok {
+		atomic.StoreInt32(p, 1)
+	}
+}
+
+func pinned(ctx context.Context) bool {
+	p, ok := ctx.Value(pinKey{}).(*int32)
+	return ok && atomic.LoadInt32(p) == 1
+}
+
+// ReadYourWritesMiddleware enables read-your-writes for every HTTP request.
+func ReadYourWritesMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(WithReadYourWrites(r.Context())))
+	})
+}
+
+// ReadYourWritesInterceptor enables read-your-writes for every unary gRPC request.
+func ReadYourWritesInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(WithReadYourWrites(ctx), req)
+}
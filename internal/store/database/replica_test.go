@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	require.NoError(t, err)
+	return db, mock
+}
+
+func TestConnection_GetReplicaConn(t *testing.T) {
+	master, _ := newMockDB(t)
+	first, firstMock := newMockDB(t)
+	second, secondMock := newMockDB(t)
+	c := NewConnection(master, first, second)
+	c.maxLag = 5 * time.Second
+	ctx := context.Background()
+
+	// round-robin between healthy replicas
+	seen := map[gorm.ConnPool]int{}
+	for i := 0; i < 4; i++ {
+		seen[c.GetReplicaConn(ctx).Statement.ConnPool]++
+	}
+	assert.Equal(t, map[gorm.ConnPool]int{first.ConnPool: 2, second.ConnPool: 2}, seen)
+
+	// lagging replica is excluded
+	firstMock.ExpectQuery("pg_last_xact_replay_timestamp").WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(10.0))
+	secondMock.ExpectQuery("pg_last_xact_replay_timestamp").WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(0.5))
+	require.NoError(t, c.CheckReplicas(ctx))
+	assert.False(t, c.Replicas[0].Healthy())
+	assert.Equal(t, 10*time.Second, c.Replicas[0].Lag())
+	for i := 0; i < 2; i++ {
+		assert.Same(t, second.ConnPool, c.GetReplicaConn(ctx).Statement.ConnPool)
+	}
+
+	// reads fall back to master when no replica is healthy
+	firstMock.ExpectQuery("pg_last_xact_replay_timestamp").WillReturnError(errors.New("connection refused"))
+	secondMock.ExpectQuery("pg_last_xact_replay_timestamp").WillReturnError(errors.New("connection refused"))
+	assert.Error(t, c.CheckReplicas(ctx))
+	assert.Same(t, master.ConnPool, c.GetReplicaConn(ctx).Statement.ConnPool)
+
+	// recovered replica is read from again
+	firstMock.ExpectQuery("pg_last_xact_replay_timestamp").WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(0.0))
+	secondMock.ExpectQuery("pg_last_xact_replay_timestamp").WillReturnError(errors.New("connection refused"))
+	require.NoError(t, c.CheckReplicas(ctx))
+	assert.Same(t, first.ConnPool, c.GetReplicaConn(ctx).Statement.ConnPool)
+
+	assert.NoError(t, firstMock.ExpectationsWereMet())
+	assert.NoError(t, secondMock.ExpectationsWereMet())
+}
+
+func TestConnection_ReadYourWrites(t *testing.T) {
+	master, _ := newMockDB(t)
+	replica, _ := newMockDB(t)
+	c := NewConnection(master, replica)
+
+	ctx := WithReadYourWrites(context.Background())
+	assert.Same(t, replica.ConnPool, c.GetReplicaConn(ctx).Statement.ConnPool)
+	c.GetMasterConn(ctx)
+	assert.Same(t, master.ConnPool, c.GetReplicaConn(ctx).Statement.ConnPool)
+
+	// requests without read-your-writes are not pinned
+	ctx = context.Background()
+	c.GetMasterConn(ctx)
+	assert.Same(t, replica.ConnPool, c.GetReplicaConn(ctx).Statement.ConnPool)
+}
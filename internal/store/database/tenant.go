@@ -0,0 +1,95 @@
+package database
+
+import (
+	"reflect"
+
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tenantField is field of models owned by tenant, statements on their tables are scoped automatically
+const tenantField = "TenantID"
+
+// tenantScope is gorm plugin limiting every statement on tables of tenant-owned models to tenant of
+// statement context: inserted rows are assigned to it, other statements are filtered by it and may not
+// move rows to other tenant. Super-admin without selected tenant works across tenants.
+type tenantScope struct{}
+
+// UseTenantScope registers tenant scoping on db, pools opened by Connect have it registered.
+func UseTenantScope(db *gorm.DB) error {
+	return db.Use(tenantScope{})
+}
+
+func (tenantScope) Name() string {
+	return "faceit:tenant"
+}
+
+func (p tenantScope) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("faceit:tenant_create", p.assign),
+		cb.Query().Before("gorm:query").Register("faceit:tenant_query", p.filter(false)),
+		cb.Update().Before("gorm:update").Register("faceit:tenant_update", p.filter(true)),
+		cb.Delete().Before("gorm:delete").Register("faceit:tenant_delete", p.filter(false)),
+		cb.Row().Before("gorm:row").Register("faceit:tenant_row", p.filter(false)),
+	} {
+		if err != nil {
+			return errors.Wrap(err, "register tenant callback")
+		}
+	}
+	return nil
+}
+
+// assign sets tenant of inserted rows, super-admin has to set it explicitly
+func (tenantScope) assign(db *gorm.DB) {
+	if db.Statement.Schema == nil {
+		return
+	}
+	field := db.Statement.Schema.LookUpField(tenantField)
+	if field == nil {
+		return
+	}
+	id, all, err := tenant.Scope(db.Statement.Context)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	if all {
+		if v := db.Statement.ReflectValue; v.Kind() == reflect.Struct {
+			if _, zero := field.ValueOf(v); zero {
+				db.AddError(tenant.ErrMissing)
+			}
+		}
+		return
+	}
+	db.Statement.SetColumn(tenantField, id, true)
+}
+
+// filter limits statement to rows of tenant, updates may not change tenant of rows
+func (tenantScope) filter(update bool) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement.Schema == nil {
+			return
+		}
+		field := db.Statement.Schema.LookUpField(tenantField)
+		if field == nil {
+			return
+		}
+		id, all, err := tenant.Scope(db.Statement.Context)
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+		if update {
+			db.Statement.Omits = append(db.Statement.Omits, field.DBName)
+		}
+		if all {
+			return
+		}
+		db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+			clause.Eq{Column: clause.Column{Name: field.DBName}, Value: id},
+		}})
+	}
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type member struct {
+	ID       string
+	TenantID string
+	Nickname string
+}
+
+func TestTenantScope(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{Logger: gormLogger{}, SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	require.NoError(t, UseTenantScope(db))
+
+	// tenant of row may not be changed by update
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "members" SET "nickname"=$1 WHERE "tenant_id" = $2 AND "id" = $3`)).
+		WithArgs("renamed", "brand-a", "1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, db.WithContext(ctx).Model(&member{ID: "1"}).Updates(&member{TenantID: "brand-b", Nickname: "renamed"}).Error)
+
+	// super-admin is not scoped
+	admin := tenant.WithSuperAdmin(context.Background())
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "members" WHERE nickname = $1`)).
+		WithArgs("sample").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "nickname"}).AddRow("1", "brand-a", "sample"))
+	var members []member
+	require.NoError(t, db.WithContext(admin).Where("nickname = ?", "sample").Find(&members).Error)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// statements without tenant fail before reaching database
+	err = db.WithContext(context.Background()).Find(&members).Error
+	assert.ErrorIs(t, err, tenant.ErrMissing)
+	err = db.WithContext(admin).Create(&member{ID: "2"}).Error
+	assert.ErrorIs(t, err, tenant.ErrMissing)
+
+	// tables of models without tenant are not scoped
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "password"}))
+	var users []user
+	require.NoError(t, db.WithContext(context.Background()).Find(&users).Error)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
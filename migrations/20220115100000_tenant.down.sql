@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS "public"."users_tenant_nickname_idx";
+DROP INDEX IF EXISTS "public"."users_tenant_country_idx";
+
+ALTER TABLE "public"."users" DROP CONSTRAINT "users_pkey";
+ALTER TABLE "public"."users" ADD CONSTRAINT "users_pkey" PRIMARY KEY ("id");
+
+ALTER TABLE "public"."users" DROP COLUMN IF EXISTS "tenant_id";
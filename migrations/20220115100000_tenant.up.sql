@@ -0,0 +1,7 @@
+ALTER TABLE "public"."users" ADD COLUMN "tenant_id" varchar(64) COLLATE "pg_catalog"."default" NOT NULL DEFAULT 'default';
+
+ALTER TABLE "public"."users" DROP CONSTRAINT "users_pkey";
+ALTER TABLE "public"."users" ADD CONSTRAINT "users_pkey" PRIMARY KEY ("tenant_id", "id");
+
+CREATE INDEX "users_tenant_country_idx" ON "public"."users" ("tenant_id", "country");
+CREATE INDEX "users_tenant_nickname_idx" ON "public"."users" ("tenant_id", "nickname");
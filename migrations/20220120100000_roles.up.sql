@@ -0,0 +1,10 @@
+CREATE TABLE "public"."user_roles"
+(
+    "tenant_id"  varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "user_id"    varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "role"       varchar(32) COLLATE "pg_catalog"."default" NOT NULL,
+    "created_at" timestamp(6),
+    CONSTRAINT "user_roles_pkey" PRIMARY KEY ("tenant_id", "user_id", "role"),
+    CONSTRAINT "user_roles_user_fkey" FOREIGN KEY ("tenant_id", "user_id")
+        REFERENCES "public"."users" ("tenant_id", "id") ON DELETE CASCADE
+);
@@ -0,0 +1,18 @@
+CREATE TABLE "public"."api_keys"
+(
+    "id"           varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "tenant_id"    varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "name"         varchar(64) COLLATE "pg_catalog"."default",
+    "prefix"       varchar(16) COLLATE "pg_catalog"."default" NOT NULL,
+    "hash"         varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "scopes"       varchar(255) COLLATE "pg_catalog"."default",
+    "rate_limit"   float8 NOT NULL DEFAULT 0,
+    "expires_at"   timestamp(6),
+    "last_used_at" timestamp(6),
+    "revoked_at"   timestamp(6),
+    "created_at"   timestamp(6),
+    CONSTRAINT "api_keys_pkey" PRIMARY KEY ("id")
+);
+
+CREATE UNIQUE INDEX "api_keys_prefix_idx" ON "public"."api_keys" ("prefix");
+CREATE INDEX "api_keys_tenant_idx" ON "public"."api_keys" ("tenant_id", "created_at");
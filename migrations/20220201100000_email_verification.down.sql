@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "public"."user_tokens";
+
+ALTER TABLE "public"."users" DROP COLUMN IF EXISTS "email_verified_at";
@@ -0,0 +1,18 @@
+ALTER TABLE "public"."users" ADD COLUMN "email_verified_at" timestamp(6);
+
+CREATE TABLE "public"."user_tokens"
+(
+    "id"         varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "tenant_id"  varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "user_id"    varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "purpose"    varchar(32) COLLATE "pg_catalog"."default" NOT NULL,
+    "hash"       varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "email"      varchar(64) COLLATE "pg_catalog"."default",
+    "expires_at" timestamp(6) NOT NULL,
+    "used_at"    timestamp(6),
+    "created_at" timestamp(6),
+    CONSTRAINT "user_tokens_pkey" PRIMARY KEY ("id")
+);
+
+CREATE UNIQUE INDEX "user_tokens_hash_idx" ON "public"."user_tokens" ("hash");
+CREATE INDEX "user_tokens_user_idx" ON "public"."user_tokens" ("tenant_id", "user_id", "purpose");
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "public"."user_tokens_issued_idx";
+
+ALTER TABLE "public"."users" DROP COLUMN IF EXISTS "password_changed_at";
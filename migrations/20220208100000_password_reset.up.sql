@@ -0,0 +1,3 @@
+ALTER TABLE "public"."users" ADD COLUMN "password_changed_at" timestamp(6);
+
+CREATE INDEX "user_tokens_issued_idx" ON "public"."user_tokens" ("tenant_id", "user_id", "purpose", "created_at");
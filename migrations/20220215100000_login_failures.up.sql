@@ -0,0 +1,10 @@
+CREATE TABLE "public"."login_failures"
+(
+    "id"         varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "tenant_id"  varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "subject"    varchar(128) COLLATE "pg_catalog"."default" NOT NULL,
+    "created_at" timestamp(6) NOT NULL,
+    CONSTRAINT "login_failures_pkey" PRIMARY KEY ("id")
+);
+
+CREATE INDEX "login_failures_subject_idx" ON "public"."login_failures" ("tenant_id", "subject", "created_at");
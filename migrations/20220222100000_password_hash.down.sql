@@ -0,0 +1 @@
+-- bcrypt hashes can not be reverted to plaintext passwords, they are kept
@@ -0,0 +1,7 @@
+-- passwords are stored as bcrypt hashes, plaintext ones are hashed in place
+CREATE EXTENSION IF NOT EXISTS "pgcrypto";
+
+UPDATE "public"."users"
+SET "password" = crypt("password", gen_salt('bf', 10))
+WHERE "password" IS NOT NULL
+  AND "password" !~ '^\$2[aby]\$';
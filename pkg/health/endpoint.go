@@ -23,7 +23,18 @@ type ReadinessRequest struct {
 
 //easyjson:json
 type ReadinessResponse struct {
-	Status string `json:"status,omitempty"`
+	Status     string      `json:"status,omitempty"`
+	Components []Component `json:"components,omitempty"`
+}
+
+//easyjson:json
+type Component struct {
+	Name      string  `json:"name"`
+	Severity  string  `json:"severity"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+	CheckedAt string  `json:"checkedAt,omitempty"`
 }
 
 //easyjson:json
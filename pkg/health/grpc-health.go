@@ -0,0 +1,33 @@
+package health
+
+import (
+	googlegrpc "google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// JoinGRPCHealth registers standard grpc.health.v1.Health service which reports
+// SERVING or NOT_SERVING for the server and every service registered before it,
+// following Monitor readiness. Check and Watch are provided by grpc health server.
+func JoinGRPCHealth(m *Monitor) func(*googlegrpc.Server) {
+	return func(g *googlegrpc.Server) {
+		srv := grpchealth.NewServer()
+
+		services := []string{""}
+		for name := range g.GetServiceInfo() {
+			services = append(services, name)
+		}
+
+		m.Subscribe(func(ready bool) {
+			status := healthpb.HealthCheckResponse_NOT_SERVING
+			if ready {
+				status = healthpb.HealthCheckResponse_SERVING
+			}
+			for _, name := range services {
+				srv.SetServingStatus(name, status)
+			}
+		})
+
+		healthpb.RegisterHealthServer(g, srv)
+	}
+}
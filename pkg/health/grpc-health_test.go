@@ -0,0 +1,54 @@
+package health
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestJoinGRPCHealth(t *testing.T) {
+	var down int32
+	m := NewMonitor(context.Background())
+	m.Register("master", SeverityCritical, CheckerFunc(func(ctx context.Context) error {
+		if atomic.LoadInt32(&down) == 1 {
+			return errors.New("down")
+		}
+		return nil
+	}))
+	m.CheckAll(context.Background())
+
+	g := grpc.NewServer()
+	JoinGRPC(context.Background(), NewHealthService(m))(g)
+	JoinGRPCHealth(m)(g)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go g.Serve(listener)
+	defer g.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "faceitpb.HealthService"})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	atomic.StoreInt32(&down, 1)
+	m.CheckAll(context.Background())
+
+	resp, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+
+	_, err = NewGRPCClient(conn, nil, nil).Readiness(context.Background(), &ReadinessRequest{})
+	assert.Error(t, err)
+}
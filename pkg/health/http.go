@@ -10,6 +10,8 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/gorilla/schema"
 	"github.com/nakiner/faceit/tools/logging"
+	"github.com/nakiner/faceit/tools/metrics"
+	"github.com/nakiner/faceit/tools/requestid"
 	"github.com/pkg/errors"
 )
 
@@ -18,6 +20,7 @@ func MakeHTTPHandler(ctx context.Context, s Service) http.Handler {
 	logger = log.With(logger, "http handler", "health")
 
 	r := mux.NewRouter()
+	r.Use(metrics.Route)
 
 	options := []httptransport.ServerOption{
 		httptransport.ServerErrorEncoder(encodeError),
@@ -146,14 +149,22 @@ type errorer interface {
 }
 
 // encodeError handles error from business-layer.
-func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
 	w.Header().Set("X-Esp-Error", err.Error())
 	w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
 
 	w.WriteHeader(getHTTPStatusCode(err))
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	if e, ok := err.(*NotReadyError); ok {
+		json.NewEncoder(w).Encode(e.Response)
+		return
+	}
+	body := map[string]interface{}{
 		"error": err.Error(),
-	})
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		body["request_id"] = id
+	}
+	json.NewEncoder(w).Encode(body)
 }
 
 // accessControl is CORS middleware.
@@ -7,6 +7,7 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/nakiner/faceit/tools/logging"
+	"github.com/nakiner/faceit/tools/requestid"
 )
 
 // NewLoggingService returns a new instance of a logging Service.
@@ -111,6 +112,16 @@ func (s *loggingService) Version(ctx context.Context, req *VersionRequest) (resp
 
 func getInfoFromContext(ctx context.Context) []interface{} {
 	m := make([]interface{}, 0)
+	if id := requestid.FromContext(ctx); id != "" {
+		m = append(m, requestid.LogKey, id)
+	}
+	{
+		val := ctx.Value(ContextGRPCKey{})
+		if _, ok := val.(GRPCInfo); ok {
+			m = append(m, "protocol", "GRPC")
+		}
+	}
+
 	{
 		val := ctx.Value(ContextHTTPKey{})
 		if i, ok := val.(HTTPInfo); ok {
@@ -0,0 +1,340 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/nakiner/faceit/tools/logging"
+	"github.com/pkg/errors"
+)
+
+// Severity defines how failing component affects service readiness.
+type Severity string
+
+const (
+	// SeverityCritical failing component makes service not ready.
+	SeverityCritical Severity = "critical"
+	// SeverityDegraded failing component is reported but service stays ready.
+	SeverityDegraded Severity = "degraded"
+)
+
+const (
+	StatusOK       = "OK"
+	StatusDegraded = "DEGRADED"
+	StatusFail     = "FAIL"
+	StatusUnknown  = "UNKNOWN"
+)
+
+// errNotChecked is reported for components which were not probed yet.
+var errNotChecked = errors.New("not checked yet")
+
+// errShuttingDown is reported once service started shutting down.
+var errShuttingDown = errors.New("service is shutting down")
+
+// errStarting is reported until startup waits for dependencies.
+var errStarting = errors.New("service is starting")
+
+const (
+	// shutdownComponent is the name of component reported while draining.
+	shutdownComponent = "shutdown"
+	// startupComponent is the name of component reported while starting.
+	startupComponent = "startup"
+)
+
+// Checker probes single dependency, returned error marks it as failing.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc allows using ordinary functions as Checker.
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+type MonitorOption func(*Monitor)
+
+// SetInterval sets period between probes.
+func SetInterval(d time.Duration) MonitorOption {
+	return func(m *Monitor) {
+		if d > 0 {
+			m.interval = d
+		}
+	}
+}
+
+// SetTimeout sets timeout of a single probe.
+func SetTimeout(d time.Duration) MonitorOption {
+	return func(m *Monitor) {
+		if d > 0 {
+			m.timeout = d
+		}
+	}
+}
+
+// Monitor periodically runs registered checkers concurrently and keeps their last results,
+// so readiness reflects current dependencies state and recovers once they are back.
+type Monitor struct {
+	mu       sync.RWMutex
+	checks   []*check
+	interval time.Duration
+	timeout  time.Duration
+	logger   log.Logger
+	stop     chan struct{}
+	done     chan struct{}
+
+	// notifyMu serializes listener calls so they observe readiness changes in order
+	notifyMu  sync.Mutex
+	listeners []func(ready bool)
+	ready     bool
+	// draining is set on shutdown, readiness stays failed regardless of checks
+	draining bool
+	// starting is set until dependencies are reachable, readiness stays failed regardless of checks
+	starting bool
+}
+
+type check struct {
+	name     string
+	severity Severity
+	checker  Checker
+	result   Component
+}
+
+// NewMonitor creates Monitor, checkers are added with Register and probed after Start.
+func NewMonitor(ctx context.Context, opts ...MonitorOption) *Monitor {
+	logger := logging.FromContext(ctx)
+	logger = log.With(logger, "component", "health monitor")
+	m := &Monitor{
+		interval: 10 * time.Second,
+		timeout:  2 * time.Second,
+		logger:   logger,
+	}
+	for _, o := range opts {
+		o(m)
+	}
+	return m
+}
+
+// Register adds named checker with given severity.
+func (m *Monitor) Register(name string, severity Severity, c Checker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checks = append(m.checks, &check{
+		name:     name,
+		severity: severity,
+		checker:  c,
+		result: Component{
+			Name:     name,
+			Severity: string(severity),
+			Status:   StatusUnknown,
+			Error:    errNotChecked.Error(),
+		},
+	})
+}
+
+// Start probes all checkers once and then keeps probing them every interval until Stop.
+func (m *Monitor) Start(ctx context.Context) {
+	m.CheckAll(ctx)
+
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.CheckAll(ctx)
+			case <-m.stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops periodic probes.
+func (m *Monitor) Stop() {
+	if m.stop == nil {
+		return
+	}
+	close(m.stop)
+	<-m.done
+	m.stop = nil
+}
+
+// CheckAll runs all checkers concurrently and waits for their results.
+func (m *Monitor) CheckAll(ctx context.Context) {
+	m.mu.RLock()
+	checks := make([]*check, len(m.checks))
+	copy(checks, m.checks)
+	m.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c *check) {
+			defer wg.Done()
+			m.probe(ctx, c)
+		}(c)
+	}
+	wg.Wait()
+	m.notify()
+}
+
+// Drain permanently fails readiness, so load balancers stop routing new traffic before shutdown.
+func (m *Monitor) Drain() {
+	m.mu.Lock()
+	m.draining = true
+	m.mu.Unlock()
+	m.notify()
+}
+
+// Starting fails readiness until Started, so no traffic is routed while dependencies are being connected.
+func (m *Monitor) Starting() {
+	m.mu.Lock()
+	m.starting = true
+	m.mu.Unlock()
+	m.notify()
+}
+
+// Started probes all checkers and lets their results define readiness again.
+func (m *Monitor) Started(ctx context.Context) {
+	m.mu.Lock()
+	m.starting = false
+	m.mu.Unlock()
+	m.CheckAll(ctx)
+}
+
+// Subscribe registers listener called with current readiness and then on every readiness change.
+func (m *Monitor) Subscribe(fn func(ready bool)) {
+	m.notifyMu.Lock()
+	defer m.notifyMu.Unlock()
+
+	m.mu.Lock()
+	m.listeners = append(m.listeners, fn)
+	m.mu.Unlock()
+	fn(m.IsReady())
+}
+
+func (m *Monitor) notify() {
+	m.notifyMu.Lock()
+	defer m.notifyMu.Unlock()
+
+	ready := m.IsReady()
+
+	m.mu.Lock()
+	changed := ready != m.ready
+	m.ready = ready
+	listeners := make([]func(bool), len(m.listeners))
+	copy(listeners, m.listeners)
+	m.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	for _, fn := range listeners {
+		fn(ready)
+	}
+}
+
+func (m *Monitor) probe(ctx context.Context, c *check) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	begin := time.Now()
+	err := m.run(ctx, c.checker)
+	took := time.Since(begin)
+
+	res := Component{
+		Name:      c.name,
+		Severity:  string(c.severity),
+		Status:    StatusOK,
+		LatencyMs: float64(took) / float64(time.Millisecond),
+		CheckedAt: begin.UTC().Format(time.RFC3339Nano),
+	}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+
+	m.mu.Lock()
+	prev := c.result.Status
+	c.result = res
+	m.mu.Unlock()
+
+	if prev != res.Status {
+		if err != nil {
+			level.Error(m.logger).Log("check", c.name, "severity", c.severity, "status", res.Status, "err", err)
+		} else {
+			level.Info(m.logger).Log("check", c.name, "severity", c.severity, "status", res.Status)
+		}
+	}
+}
+
+// run calls checker and gives up once probe timeout expires even if checker ignores context.
+func (m *Monitor) run(ctx context.Context, c Checker) error {
+	res := make(chan error, 1)
+	go func() {
+		res <- c.Check(ctx)
+	}()
+	select {
+	case err := <-res:
+		return err
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "check timed out")
+	}
+}
+
+// Report returns overall status and state of every component.
+// Status is FAIL when any critical component fails or was not checked, DEGRADED when
+// only degraded components fail, and OK otherwise.
+func (m *Monitor) Report() (string, []Component) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := StatusOK
+	components := make([]Component, 0, len(m.checks))
+	for _, c := range m.checks {
+		components = append(components, c.result)
+		if c.result.Status == StatusOK {
+			continue
+		}
+		if c.severity == SeverityCritical {
+			status = StatusFail
+		} else if status == StatusOK {
+			status = StatusDegraded
+		}
+	}
+	if m.starting {
+		status = StatusFail
+		components = append(components, Component{
+			Name:     startupComponent,
+			Severity: string(SeverityCritical),
+			Status:   StatusFail,
+			Error:    errStarting.Error(),
+		})
+	}
+	if m.draining {
+		status = StatusFail
+		components = append(components, Component{
+			Name:     shutdownComponent,
+			Severity: string(SeverityCritical),
+			Status:   StatusFail,
+			Error:    errShuttingDown.Error(),
+		})
+	}
+	return status, components
+}
+
+// IsReady reports whether all critical components are healthy.
+func (m *Monitor) IsReady() bool {
+	status, _ := m.Report()
+	return status != StatusFail
+}
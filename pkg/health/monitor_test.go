@@ -0,0 +1,121 @@
+package health
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonitor_Report(t *testing.T) {
+	var masterDown, replicaDown int32
+	m := NewMonitor(context.Background(), SetTimeout(50*time.Millisecond))
+	m.Register("master", SeverityCritical, CheckerFunc(func(ctx context.Context) error {
+		if atomic.LoadInt32(&masterDown) == 1 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}))
+	m.Register("replica", SeverityDegraded, CheckerFunc(func(ctx context.Context) error {
+		if atomic.LoadInt32(&replicaDown) == 1 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}))
+
+	status, components := m.Report()
+	assert.Equal(t, StatusFail, status)
+	assert.Equal(t, StatusUnknown, components[0].Status)
+
+	m.CheckAll(context.Background())
+	status, _ = m.Report()
+	assert.Equal(t, StatusOK, status)
+
+	atomic.StoreInt32(&replicaDown, 1)
+	m.CheckAll(context.Background())
+	status, components = m.Report()
+	assert.Equal(t, StatusDegraded, status)
+	assert.Equal(t, "connection refused", components[1].Error)
+	assert.True(t, m.IsReady())
+
+	atomic.StoreInt32(&masterDown, 1)
+	m.CheckAll(context.Background())
+	assert.False(t, m.IsReady())
+
+	atomic.StoreInt32(&masterDown, 0)
+	atomic.StoreInt32(&replicaDown, 0)
+	m.CheckAll(context.Background())
+	status, _ = m.Report()
+	assert.Equal(t, StatusOK, status)
+}
+
+func TestMonitor_Drain(t *testing.T) {
+	m := NewMonitor(context.Background())
+	m.Register("master", SeverityCritical, CheckerFunc(func(ctx context.Context) error { return nil }))
+	m.CheckAll(context.Background())
+
+	var changes []bool
+	m.Subscribe(func(ready bool) { changes = append(changes, ready) })
+
+	m.Drain()
+	m.CheckAll(context.Background())
+	status, components := m.Report()
+	assert.Equal(t, StatusFail, status)
+	require.Len(t, components, 2)
+	assert.Equal(t, shutdownComponent, components[1].Name)
+	assert.Equal(t, []bool{true, false}, changes)
+}
+
+func TestMonitor_Starting(t *testing.T) {
+	m := NewMonitor(context.Background())
+	m.Register("master", SeverityCritical, CheckerFunc(func(ctx context.Context) error { return nil }))
+
+	var changes []bool
+	m.Subscribe(func(ready bool) { changes = append(changes, ready) })
+
+	m.Starting()
+	m.CheckAll(context.Background())
+	status, components := m.Report()
+	assert.Equal(t, StatusFail, status)
+	require.Len(t, components, 2)
+	assert.Equal(t, startupComponent, components[1].Name)
+
+	m.Started(context.Background())
+	status, components = m.Report()
+	assert.Equal(t, StatusOK, status)
+	assert.Len(t, components, 1)
+	assert.Equal(t, []bool{false, true}, changes)
+}
+
+func TestMonitor_Timeout(t *testing.T) {
+	m := NewMonitor(context.Background(), SetTimeout(10*time.Millisecond))
+	m.Register("slow", SeverityCritical, CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}))
+
+	begin := time.Now()
+	m.CheckAll(context.Background())
+	assert.Less(t, int64(time.Since(begin)), int64(500*time.Millisecond))
+
+	_, components := m.Report()
+	require.Len(t, components, 1)
+	assert.Equal(t, StatusFail, components[0].Status)
+}
+
+func TestHealthService_Readiness(t *testing.T) {
+	m := NewMonitor(context.Background())
+	m.Register("master", SeverityCritical, CheckerFunc(func(ctx context.Context) error {
+		return errors.New("down")
+	}))
+	m.CheckAll(context.Background())
+
+	_, err := NewHealthService(m).Readiness(context.Background(), &ReadinessRequest{})
+	require.Error(t, err)
+	assert.Equal(t, 503, getHTTPStatusCode(err))
+	assert.Equal(t, ErrServiceNotReady, errors.Cause(err))
+}
@@ -2,6 +2,8 @@ package health
 
 import (
 	"context"
+	"net/http"
+
 	"github.com/pkg/errors"
 )
 
@@ -12,19 +14,36 @@ var (
 )
 
 type healthService struct {
-	reps []repo
+	reporter reporter
 }
 
-type repo interface {
-	IsReady() bool
+type reporter interface {
+	Report() (string, []Component)
 }
 
 // ErrServiceNotReady is returned when service doesn`t ready
 var ErrServiceNotReady = errors.New("service not ready")
 
-func NewHealthService(reps ...repo) *healthService {
+// NotReadyError is returned by Readiness when critical component fails and carries components state.
+type NotReadyError struct {
+	Response *ReadinessResponse
+}
+
+func (e *NotReadyError) Error() string {
+	return ErrServiceNotReady.Error()
+}
+
+func (e *NotReadyError) Code() int {
+	return http.StatusServiceUnavailable
+}
+
+func (e *NotReadyError) Cause() error {
+	return ErrServiceNotReady
+}
+
+func NewHealthService(reporter reporter) *healthService {
 	return &healthService{
-		reps: reps,
+		reporter: reporter,
 	}
 }
 
@@ -35,14 +54,15 @@ func (s *healthService) Liveness(ctx context.Context, req *LivenessRequest) (res
 }
 
 func (s *healthService) Readiness(ctx context.Context, req *ReadinessRequest) (resp *ReadinessResponse, err error) {
-	for _, r := range s.reps {
-		if !r.IsReady() {
-			return nil, ErrServiceNotReady
-		}
+	status, components := s.reporter.Report()
+	resp = &ReadinessResponse{
+		Status:     status,
+		Components: components,
 	}
-	return &ReadinessResponse{
-		Status: "OK",
-	}, nil
+	if status == StatusFail {
+		return nil, &NotReadyError{Response: resp}
+	}
+	return resp, nil
 }
 
 func (s *healthService) Version(ctx context.Context, req *VersionRequest) (resp *VersionResponse, err error) {
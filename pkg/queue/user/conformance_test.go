@@ -0,0 +1,47 @@
+package user_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nakiner/faceit/pkg/queue/user"
+	"github.com/nakiner/faceit/pkg/queue/user/queuetest"
+	natsCl "github.com/nakiner/faceit/pkg/store/nats"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryQueue(t *testing.T) {
+	queuetest.Run(t, func(t *testing.T, ctx context.Context) (user.Publisher, user.Subscriber) {
+		b := user.NewMemoryBroker(ctx)
+		t.Cleanup(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			b.Drain(ctx)
+		})
+		return user.NewMemoryPublisher(b), user.NewMemorySubscriber(ctx, b)
+	})
+}
+
+func TestNATSQueue(t *testing.T) {
+	queuetest.Run(t, func(t *testing.T, ctx context.Context) (user.Publisher, user.Subscriber) {
+		opt := natsserver.DefaultTestOptions
+		opt.Port = 4224
+		natsSvr := natsserver.RunServer(&opt)
+		t.Cleanup(natsSvr.Shutdown)
+
+		nc, err := natsCl.NewClient(&natsCl.Config{
+			Host: opt.Host,
+			Port: opt.Port,
+		})
+		require.NoError(t, err)
+		t.Cleanup(nc.Close)
+		ec, err := natsCl.NewEncodedClient(nc)
+		require.NoError(t, err)
+
+		pub, err := user.NewPublisher(ec)
+		require.NoError(t, err)
+		return pub, user.NewSubscriber(ctx, nc)
+	})
+}
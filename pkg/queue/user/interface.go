@@ -1,19 +1,40 @@
 package user
 
 var (
-	Queue             = "user"
+	Queue = "user"
+	// UpdateUserSubject is prefix of per tenant subjects, events of tenant are published to UpdateUserSubject.<tenant>
 	UpdateUserSubject = "faceit-user-updateUser"
+	// InvalidateUserSubject is broadcast to every instance, so they drop cached user
+	InvalidateUserSubject = "faceit-user-invalidate"
+	// UserLockedSubject and UserUnlockedSubject are prefixes of per tenant subjects of lockouts of users
+	UserLockedSubject   = "faceit-user-locked"
+	UserUnlockedSubject = "faceit-user-unlocked"
 )
 
 // User type used to define queue messages
 type User struct {
 	ID        string
+	TenantID  string
 	FirstName string
 	LastName  string
 	Nickname  string
-	Password  string
 	Email     string
 	Country   string
 	CreatedAt string
 	UpdatedAt string
 }
+
+// Lock type used to define messages of users locked after failed credential checks and of users unlocked,
+// LockedUntil is RFC3339 time lockout ends at and UnlockedBy is subject of admin who unlocked user.
+type Lock struct {
+	ID          string
+	TenantID    string
+	Failures    int
+	LockedUntil string
+	UnlockedBy  string
+}
+
+// TenantSubject returns subject of tenant, subject.* matches subjects of all tenants.
+func TenantSubject(subject, tenantID string) string {
+	return subject + "." + tenantID
+}
@@ -0,0 +1,55 @@
+package user
+
+import (
+	"context"
+
+	"github.com/nakiner/faceit/tools/requestid"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/nats-io/nats.go"
+)
+
+// Invalidator broadcasts ids of changed users to every instance, including the publishing one.
+type Invalidator struct {
+	send      func(msg *nats.Msg) error
+	subscribe func(subject, queue string, cb nats.MsgHandler) error
+}
+
+// NewInvalidator creates Invalidator broadcasting through nats.
+func NewInvalidator(nc *nats.Conn) *Invalidator {
+	return &Invalidator{
+		send: nc.PublishMsg,
+		subscribe: func(subject, queue string, cb nats.MsgHandler) error {
+			_, err := nc.QueueSubscribe(subject, queue, cb)
+			return err
+		},
+	}
+}
+
+// NewMemoryInvalidator creates Invalidator broadcasting through in-process broker.
+func NewMemoryInvalidator(b *MemoryBroker) *Invalidator {
+	return &Invalidator{
+		send:      b.publish,
+		subscribe: b.queueSubscribe,
+	}
+}
+
+// Invalidate broadcasts id of changed user, ids are unique across tenants so subject is shared by them.
+func (i *Invalidator) Invalidate(ctx context.Context, id string) error {
+	msg := nats.NewMsg(InvalidateUserSubject)
+	msg.Data = []byte(id)
+	if rid := requestid.FromContext(ctx); rid != "" {
+		msg.Header.Set(requestid.Header, rid)
+	}
+	if tid := tenant.FromContext(ctx); tid != "" {
+		msg.Header.Set(tenant.Header, tid)
+	}
+	return i.send(msg)
+}
+
+// OnInvalidate subscribes fn to ids of changed users, every subscribed instance receives all of them.
+func (i *Invalidator) OnInvalidate(fn func(id string)) error {
+	// no queue group, so message is not balanced between instances
+	return i.subscribe(InvalidateUserSubject, "", func(msg *nats.Msg) {
+		fn(string(msg.Data))
+	})
+}
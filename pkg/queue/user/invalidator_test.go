@@ -0,0 +1,52 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	natsCl "github.com/nakiner/faceit/pkg/store/nats"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBroadcast checks every subscribed instance receives invalidated id
+func testBroadcast(t *testing.T, inv *Invalidator) {
+	ids := make(chan string, 2)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, inv.OnInvalidate(func(id string) { ids <- id }))
+	}
+	require.NoError(t, inv.Invalidate(context.Background(), "sample"))
+	for i := 0; i < 2; i++ {
+		select {
+		case id := <-ids:
+			assert.Equal(t, "sample", id)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("invalidation received by %d of 2 subscribers", i)
+		}
+	}
+}
+
+func TestInvalidator(t *testing.T) {
+	opt := natsserver.DefaultTestOptions
+	opt.Port = port
+	natsSvr := natsserver.RunServer(&opt)
+	defer natsSvr.Shutdown()
+
+	nc, err := natsCl.NewClient(&natsCl.Config{
+		Host: opt.Host,
+		Port: opt.Port,
+	})
+	require.NoError(t, err)
+	defer nc.Close()
+
+	testBroadcast(t, NewInvalidator(nc))
+}
+
+func TestMemoryInvalidator(t *testing.T) {
+	b := NewMemoryBroker(context.Background())
+	defer b.Drain(context.Background())
+
+	testBroadcast(t, NewMemoryInvalidator(b))
+}
@@ -0,0 +1,178 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/nakiner/faceit/tools/logging"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/encoders/builtin"
+	"github.com/pkg/errors"
+)
+
+// pendingLimit is number of messages buffered per subscription, further ones are dropped as nats does for slow consumers
+const pendingLimit = nats.DefaultSubPendingMsgsLimit
+
+// MemoryBroker delivers messages in process instead of nats, for local development and tests.
+// Like nats queue subscriptions every message is delivered to one member of each queue group,
+// subscriptions without queue receive every message, and each subscription handles its messages
+// one by one in publish order. Subscription subjects may have nats wildcards '*' and '>'.
+type MemoryBroker struct {
+	ctx    context.Context
+	mu     sync.RWMutex
+	groups map[string]map[string]*memoryGroup
+	closed bool
+	wg     sync.WaitGroup
+	// plain counts subscriptions without queue, each one is its own group
+	plain int
+}
+
+type memoryGroup struct {
+	subs []chan *nats.Msg
+	next uint32
+}
+
+// NewMemoryBroker creates broker, dropped messages are logged with logger of ctx.
+func NewMemoryBroker(ctx context.Context) *MemoryBroker {
+	return &MemoryBroker{
+		ctx:    ctx,
+		groups: make(map[string]map[string]*memoryGroup),
+	}
+}
+
+// NewMemoryPublisher creates Publisher sending JSON encoded messages through b.
+func NewMemoryPublisher(b *MemoryBroker) Publisher {
+	return &publisher{
+		enc:   &builtin.JsonEncoder{},
+		send:  b.publish,
+		ready: b.IsReady,
+	}
+}
+
+// NewMemorySubscriber creates Subscriber receiving messages from b, handlers context is derived from ctx.
+func NewMemorySubscriber(ctx context.Context, b *MemoryBroker) Subscriber {
+	return &subscriber{
+		ready:     true,
+		subscribe: b.queueSubscribe,
+		ctx:       ctx,
+	}
+}
+
+// IsReady reports whether broker accepts messages.
+func (b *MemoryBroker) IsReady() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return !b.closed
+}
+
+func (b *MemoryBroker) publish(msg *nats.Msg) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return nats.ErrConnectionClosed
+	}
+	for subject, groups := range b.groups {
+		if !matchSubject(subject, msg.Subject) {
+			continue
+		}
+		for queue, g := range groups {
+			sub := g.subs[atomic.AddUint32(&g.next, 1)%uint32(len(g.subs))]
+			select {
+			case sub <- copyMsg(msg):
+			default:
+				level.Error(logging.FromContext(b.ctx)).Log("msg", "message dropped, slow consumer", "subject", msg.Subject, "queue", queue)
+			}
+		}
+	}
+	return nil
+}
+
+// matchSubject reports whether subject matches pattern, where '*' matches one token and trailing '>' the rest
+func matchSubject(pattern, subject string) bool {
+	pt := strings.Split(pattern, ".")
+	st := strings.Split(subject, ".")
+	for i, p := range pt {
+		switch {
+		case p == ">" && i == len(pt)-1:
+			return len(st) > i
+		case i >= len(st):
+			return false
+		case p != "*" && p != st[i]:
+			return false
+		}
+	}
+	return len(pt) == len(st)
+}
+
+func (b *MemoryBroker) queueSubscribe(subject, queue string, cb nats.MsgHandler) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nats.ErrConnectionClosed
+	}
+	if b.groups[subject] == nil {
+		b.groups[subject] = make(map[string]*memoryGroup)
+	}
+	if queue == "" {
+		b.plain++
+		queue = fmt.Sprintf("_plain.%d", b.plain)
+	}
+	g := b.groups[subject][queue]
+	if g == nil {
+		g = &memoryGroup{}
+		b.groups[subject][queue] = g
+	}
+	sub := make(chan *nats.Msg, pendingLimit)
+	g.subs = append(g.subs, sub)
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for msg := range sub {
+			cb(msg)
+		}
+	}()
+	return nil
+}
+
+// Drain stops accepting messages and waits until subscriptions handle delivered ones or ctx is done.
+func (b *MemoryBroker) Drain(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		for _, groups := range b.groups {
+			for _, g := range groups {
+				for _, sub := range g.subs {
+					close(sub)
+				}
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "drain memory broker")
+	}
+}
+
+// copyMsg copies message delivered to subscription, so handlers do not share header
+func copyMsg(msg *nats.Msg) *nats.Msg {
+	res := nats.NewMsg(msg.Subject)
+	res.Data = msg.Data
+	for k, v := range msg.Header {
+		res.Header[k] = append([]string(nil), v...)
+	}
+	return res
+}
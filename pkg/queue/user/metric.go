@@ -0,0 +1,56 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	tool "github.com/nakiner/faceit/tools/metrics"
+)
+
+// NewMetricsPublisher returns an instance of an instrumenting Publisher.
+func NewMetricsPublisher(ctx context.Context, p Publisher) Publisher {
+	return &metricPublisher{tool.Instance(ctx), p}
+}
+
+type metricPublisher struct {
+	metrics *tool.Metrics
+	Publisher
+}
+
+func (p *metricPublisher) UpdateUser(ctx context.Context, u *User) (err error) {
+	defer func(begin time.Time) {
+		p.metrics.ObservePublish(UpdateUserSubject, begin, err)
+	}(time.Now())
+	return p.Publisher.UpdateUser(ctx, u)
+}
+
+func (p *metricPublisher) UserLocked(ctx context.Context, l *Lock) (err error) {
+	defer func(begin time.Time) {
+		p.metrics.ObservePublish(UserLockedSubject, begin, err)
+	}(time.Now())
+	return p.Publisher.UserLocked(ctx, l)
+}
+
+func (p *metricPublisher) UserUnlocked(ctx context.Context, l *Lock) (err error) {
+	defer func(begin time.Time) {
+		p.metrics.ObservePublish(UserUnlockedSubject, begin, err)
+	}(time.Now())
+	return p.Publisher.UserUnlocked(ctx, l)
+}
+
+// NewMetricsSubscriber returns an instance of an instrumenting Subscriber.
+func NewMetricsSubscriber(ctx context.Context, s Subscriber) Subscriber {
+	return &metricSubscriber{tool.Instance(ctx), s}
+}
+
+type metricSubscriber struct {
+	metrics *tool.Metrics
+	Subscriber
+}
+
+func (s *metricSubscriber) UpdateUser(fn UpdateUserHandler) error {
+	return s.Subscriber.UpdateUser(func(ctx context.Context, u *User) {
+		defer s.metrics.ObserveHandle(UpdateUserSubject, time.Now())
+		fn(ctx, u)
+	})
+}
@@ -1,40 +1,105 @@
 package user
 
 import (
+	"context"
+
+	"github.com/nakiner/faceit/tools/requestid"
+	"github.com/nakiner/faceit/tools/tenant"
 	"github.com/nats-io/nats.go"
-	"time"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/pkg/errors"
 )
 
+// publisher encodes messages and sends them over transport, nats connection or in-process broker
 type publisher struct {
-	ready bool
-	ec    *nats.EncodedConn
+	enc   nats.Encoder
+	send  func(msg *nats.Msg) error
+	ready func() bool
 }
 
 type Publisher interface {
 	IsReady() bool
-	UpdateUser(u *User) error
+	UpdateUser(ctx context.Context, u *User) error
+	UserLocked(ctx context.Context, l *Lock) error
+	UserUnlocked(ctx context.Context, l *Lock) error
 }
 
 func NewPublisher(ec *nats.EncodedConn) (Publisher, error) {
-	pub := publisher{
-		ready: true,
-		ec:    ec,
-	}
-	go func() {
-		tic := time.Tick(time.Minute * 1)
-		for range tic {
-			if err := ec.Flush(); err != nil {
-				pub.ready = false
-			}
-		}
-	}()
-	return &pub, nil
+	return &publisher{
+		enc:   ec.Enc,
+		send:  ec.Conn.PublishMsg,
+		ready: ec.Conn.IsConnected,
+	}, nil
 }
 
+// IsReady reports whether underlying connection is currently connected
 func (s *publisher) IsReady() bool {
-	return s.ready
+	return s.ready()
+}
+
+// UpdateUser publishes update to subject of user tenant, tenant of ctx is used when event has none
+func (s *publisher) UpdateUser(ctx context.Context, u *User) error {
+	if u.TenantID == "" {
+		id := tenant.FromContext(ctx)
+		if id == "" {
+			return tenant.ErrMissing
+		}
+		event := *u
+		event.TenantID = id
+		u = &event
+	}
+	return s.publish(ctx, UpdateUserSubject, u.TenantID, u)
+}
+
+// UserLocked publishes lockout to subject of user tenant, tenant of ctx is used when event has none
+func (s *publisher) UserLocked(ctx context.Context, l *Lock) error {
+	return s.publishLock(ctx, UserLockedSubject, l)
+}
+
+// UserUnlocked publishes unlock to subject of user tenant, tenant of ctx is used when event has none
+func (s *publisher) UserUnlocked(ctx context.Context, l *Lock) error {
+	return s.publishLock(ctx, UserUnlockedSubject, l)
+}
+
+func (s *publisher) publishLock(ctx context.Context, subject string, l *Lock) error {
+	if l.TenantID == "" {
+		id := tenant.FromContext(ctx)
+		if id == "" {
+			return tenant.ErrMissing
+		}
+		event := *l
+		event.TenantID = id
+		l = &event
+	}
+	return s.publish(ctx, subject, l.TenantID, l)
 }
 
-func (s *publisher) UpdateUser(u *User) error {
-	return s.ec.Publish(UpdateUserSubject, u)
+// publish encodes v and publishes it to subject of tenant with request id, tenant and span context in message header
+func (s *publisher) publish(ctx context.Context, subject, tenantID string, v interface{}) error {
+	data, err := s.enc.Encode(subject, v)
+	if err != nil {
+		return errors.Wrap(err, "encode message")
+	}
+	msg := nats.NewMsg(TenantSubject(subject, tenantID))
+	msg.Data = data
+	msg.Header.Set(tenant.Header, tenantID)
+	if id := requestid.FromContext(ctx); id != "" {
+		msg.Header.Set(requestid.Header, id)
+	}
+	// message is traced only within a trace, the span knows its tracer
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		span := parent.Tracer().StartSpan("publish "+subject, opentracing.ChildOf(parent.Context()), ext.SpanKindProducer)
+		ext.MessageBusDestination.Set(span, msg.Subject)
+		defer span.Finish()
+		if err := span.Tracer().Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(msg.Header)); err != nil {
+			ext.LogError(span, err)
+		}
+		if err := s.send(msg); err != nil {
+			ext.LogError(span, err)
+			return err
+		}
+		return nil
+	}
+	return s.send(msg)
 }
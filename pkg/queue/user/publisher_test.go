@@ -1,10 +1,13 @@
 package user
 
 import (
+	"context"
 	natsCl "github.com/nakiner/faceit/pkg/store/nats"
-	natsserver "github.com/nats-io/nats-server/test"
+	"github.com/nakiner/faceit/tools/tenant"
+	natsserver "github.com/nats-io/nats-server/v2/test"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 var port = 4223
@@ -76,8 +79,53 @@ func TestPublisher_UpdateUser(t *testing.T) {
 	pub, err := NewPublisher(ec)
 	assert.NoError(t, err)
 
-	err = pub.UpdateUser(&User{
+	err = pub.UpdateUser(tenant.WithContext(context.Background(), "default"), &User{
 		ID: "sample",
 	})
 	assert.NoError(t, err)
+
+	err = pub.UpdateUser(context.Background(), &User{
+		ID: "sample",
+	})
+	assert.Equal(t, tenant.ErrMissing, err)
+}
+
+func TestPublisher_UserLocked(t *testing.T) {
+	opt := natsserver.DefaultTestOptions
+	opt.Port = port
+	natsSvr := natsserver.RunServer(&opt)
+	natsSvr.Start()
+	defer natsSvr.Shutdown()
+
+	nc, err := natsCl.NewClient(&natsCl.Config{
+		Host: opt.Host,
+		Port: opt.Port,
+	})
+	assert.NoError(t, err)
+	defer nc.Close()
+
+	ec, err := natsCl.NewEncodedClient(nc)
+	assert.NoError(t, err)
+	defer ec.Close()
+
+	pub, err := NewPublisher(ec)
+	assert.NoError(t, err)
+
+	locked, err := nc.SubscribeSync(TenantSubject(UserLockedSubject, "*"))
+	assert.NoError(t, err)
+	unlocked, err := nc.SubscribeSync(TenantSubject(UserUnlockedSubject, "*"))
+	assert.NoError(t, err)
+
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	assert.NoError(t, pub.UserLocked(ctx, &Lock{ID: "sample", Failures: 5, LockedUntil: "2022-02-15T10:15:00Z"}))
+	assert.NoError(t, pub.UserUnlocked(ctx, &Lock{ID: "sample", UnlockedBy: "admin"}))
+	assert.Equal(t, tenant.ErrMissing, pub.UserLocked(context.Background(), &Lock{ID: "sample"}))
+
+	msg, err := locked.NextMsg(time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, TenantSubject(UserLockedSubject, "brand-a"), msg.Subject)
+	assert.JSONEq(t, `{"ID":"sample","TenantID":"brand-a","Failures":5,"LockedUntil":"2022-02-15T10:15:00Z","UnlockedBy":""}`, string(msg.Data))
+	msg, err = unlocked.NextMsg(time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "brand-a", msg.Header.Get(tenant.Header))
 }
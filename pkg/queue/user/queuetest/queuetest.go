@@ -0,0 +1,201 @@
+// Package queuetest is a conformance suite every user queue transport should pass.
+package queuetest
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nakiner/faceit/pkg/queue/user"
+	"github.com/nakiner/faceit/tools/requestid"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/nakiner/faceit/tools/tracing"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// timeout is how long a published message may take to be handled
+const timeout = 2 * time.Second
+
+// Factory returns publisher and subscriber sharing one transport, subscriber handlers context is derived from ctx.
+// Transport should be released on test cleanup.
+type Factory func(t *testing.T, ctx context.Context) (user.Publisher, user.Subscriber)
+
+// Run runs conformance tests against transports created by newQueue.
+func Run(t *testing.T, newQueue Factory) {
+	for _, tc := range []struct {
+		name string
+		fn   func(t *testing.T, newQueue Factory)
+	}{
+		{"Delivery", testDelivery},
+		{"RequestID", testRequestID},
+		{"Tracing", testTracing},
+		{"QueueGroup", testQueueGroup},
+		{"Order", testOrder},
+		{"Tenant", testTenant},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, newQueue)
+		})
+	}
+}
+
+// newContext returns context scoped to tenant of published events
+func newContext() context.Context {
+	return tenant.WithContext(context.Background(), "queuetest")
+}
+
+func testDelivery(t *testing.T, newQueue Factory) {
+	pub, sub := newQueue(t, context.Background())
+	assert.True(t, pub.IsReady())
+
+	users := make(chan *user.User, 1)
+	require.NoError(t, sub.UpdateUser(func(ctx context.Context, u *user.User) {
+		users <- u
+	}))
+	sent := &user.User{
+		ID:        "sample",
+		FirstName: "first",
+		LastName:  "last",
+		Nickname:  "nickname",
+		Email:     "email",
+		Country:   "country",
+		TenantID:  "queuetest",
+		CreatedAt: "2021-01-01T00:00:00",
+		UpdatedAt: "2021-01-02T00:00:00",
+	}
+	require.NoError(t, pub.UpdateUser(newContext(), sent))
+
+	select {
+	case u := <-users:
+		assert.Equal(t, sent, u)
+	case <-time.After(timeout):
+		t.Fatal("message was not delivered")
+	}
+}
+
+func testRequestID(t *testing.T, newQueue Factory) {
+	pub, sub := newQueue(t, context.Background())
+
+	ids := make(chan string, 1)
+	require.NoError(t, sub.UpdateUser(func(ctx context.Context, u *user.User) {
+		ids <- requestid.FromContext(ctx)
+	}))
+	require.NoError(t, pub.UpdateUser(requestid.WithContext(newContext(), "complaint-42"), &user.User{ID: "sample"}))
+
+	select {
+	case id := <-ids:
+		assert.Equal(t, "complaint-42", id)
+	case <-time.After(timeout):
+		t.Fatal("message was not delivered")
+	}
+}
+
+func testTracing(t *testing.T, newQueue Factory) {
+	tracer := mocktracer.New()
+	pub, sub := newQueue(t, tracing.WithContext(context.Background(), tracer))
+
+	spans := make(chan *mocktracer.MockSpan, 1)
+	require.NoError(t, sub.UpdateUser(func(ctx context.Context, u *user.User) {
+		spans <- opentracing.SpanFromContext(ctx).(*mocktracer.MockSpan)
+	}))
+	parent := tracer.StartSpan("UpdateUser")
+	require.NoError(t, pub.UpdateUser(opentracing.ContextWithSpan(newContext(), parent), &user.User{ID: "sample"}))
+	parent.Finish()
+
+	select {
+	case span := <-spans:
+		assert.Equal(t, "consume "+user.UpdateUserSubject, span.OperationName)
+		assert.Equal(t, parent.(*mocktracer.MockSpan).SpanContext.TraceID, span.SpanContext.TraceID)
+		published := tracer.FinishedSpans()[0]
+		assert.Equal(t, "publish "+user.UpdateUserSubject, published.OperationName)
+		assert.Equal(t, published.SpanContext.SpanID, span.ParentID)
+	case <-time.After(timeout):
+		t.Fatal("message was not delivered")
+	}
+}
+
+// testQueueGroup checks every message is handled once by one of queue group members
+func testQueueGroup(t *testing.T, newQueue Factory) {
+	pub, sub := newQueue(t, context.Background())
+
+	const count = 10
+	handled := make(chan string, 2*count)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, sub.UpdateUser(func(ctx context.Context, u *user.User) {
+			handled <- u.ID
+		}))
+	}
+	for i := 0; i < count; i++ {
+		require.NoError(t, pub.UpdateUser(newContext(), &user.User{ID: strconv.Itoa(i)}))
+	}
+
+	seen := map[string]int{}
+	deadline := time.After(timeout)
+	for len(seen) < count {
+		select {
+		case id := <-handled:
+			seen[id]++
+		case <-deadline:
+			t.Fatalf("handled %d of %d messages", len(seen), count)
+		}
+	}
+	// duplicates would arrive shortly after
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, handled)
+	for id, n := range seen {
+		assert.Equal(t, 1, n, "message %s handled %d times", id, n)
+	}
+}
+
+// testOrder checks subscription handles messages of one publisher in publish order
+func testOrder(t *testing.T, newQueue Factory) {
+	pub, sub := newQueue(t, context.Background())
+
+	const count = 20
+	handled := make(chan string, count)
+	require.NoError(t, sub.UpdateUser(func(ctx context.Context, u *user.User) {
+		handled <- u.ID
+	}))
+	for i := 0; i < count; i++ {
+		require.NoError(t, pub.UpdateUser(newContext(), &user.User{ID: strconv.Itoa(i)}))
+	}
+
+	for i := 0; i < count; i++ {
+		select {
+		case id := <-handled:
+			assert.Equal(t, strconv.Itoa(i), id)
+		case <-time.After(timeout):
+			t.Fatalf("handled %d of %d messages", i, count)
+		}
+	}
+}
+
+// testTenant checks events of every tenant are delivered with tenant of event in handler context
+func testTenant(t *testing.T, newQueue Factory) {
+	pub, sub := newQueue(t, context.Background())
+
+	type delivery struct {
+		tenant, event string
+	}
+	handled := make(chan delivery, 2)
+	require.NoError(t, sub.UpdateUser(func(ctx context.Context, u *user.User) {
+		handled <- delivery{tenant.FromContext(ctx), u.TenantID}
+	}))
+	require.NoError(t, pub.UpdateUser(tenant.WithContext(context.Background(), "brand-a"), &user.User{ID: "sample"}))
+	require.NoError(t, pub.UpdateUser(context.Background(), &user.User{ID: "sample", TenantID: "brand-b"}))
+	assert.ErrorIs(t, pub.UpdateUser(context.Background(), &user.User{ID: "sample"}), tenant.ErrMissing)
+
+	for _, want := range []string{"brand-a", "brand-b"} {
+		select {
+		case d := <-handled:
+			assert.Equal(t, delivery{want, want}, d)
+		case <-time.After(timeout):
+			t.Fatalf("event of %s was not delivered", want)
+		}
+	}
+}
@@ -3,34 +3,66 @@ package user
 import (
 	"context"
 	"encoding/json"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/nakiner/faceit/tools/logging"
+	"github.com/nakiner/faceit/tools/requestid"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/nakiner/faceit/tools/tracing"
 	"github.com/nats-io/nats.go"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/pkg/errors"
 )
 
+// subscriber decodes messages delivered by transport, nats connection or in-process broker
 type subscriber struct {
-	ready bool
-	nc    *nats.Conn
+	ready     bool
+	subscribe func(subject, queue string, cb nats.MsgHandler) error
+	ctx       context.Context
 }
 
-type UpdateUserHandler func(u *User)
+// UpdateUserHandler handles user update, ctx carries request id of the publishing request, tenant of user,
+// logger annotated with them, span continuing publisher trace and message header.
+type UpdateUserHandler func(ctx context.Context, u *User)
 
 type Subscriber interface {
 	UpdateUser(fn UpdateUserHandler) error
 }
 
-func NewSubscriber(nc *nats.Conn) Subscriber {
+// NewSubscriber creates subscriber, handlers context is derived from ctx.
+func NewSubscriber(ctx context.Context, nc *nats.Conn) Subscriber {
 	return &subscriber{
 		ready: true,
-		nc:    nc,
+		subscribe: func(subject, queue string, cb nats.MsgHandler) error {
+			_, err := nc.QueueSubscribe(subject, queue, cb)
+			return err
+		},
+		ctx: ctx,
 	}
 }
 
+// UpdateUser subscribes fn to updates of users of every tenant
 func (s *subscriber) UpdateUser(fn UpdateUserHandler) error {
-	if _, err := s.nc.QueueSubscribe(UpdateUserSubject, Queue, func(msg *nats.Msg) {
-		dec, err := decodeNATSUserRequest(context.Background(), msg)
-		if err == nil {
-			user := dec.(User)
-			fn(&user)
+	if err := s.subscribe(TenantSubject(UpdateUserSubject, "*"), Queue, func(msg *nats.Msg) {
+		ctx := s.msgContext(msg)
+		span, ctx := s.startSpan(ctx, UpdateUserSubject, msg)
+		defer span.Finish()
+		ctx, err := tenantContext(ctx, msg)
+		if err != nil {
+			ext.LogError(span, err)
+			level.Error(logging.FromContext(ctx)).Log("msg", "resolve message tenant", "subject", msg.Subject, "err", err)
+			return
+		}
+		dec, err := decodeNATSUserRequest(ctx, msg)
+		if err != nil {
+			ext.LogError(span, err)
+			level.Error(logging.FromContext(ctx)).Log("msg", "decode message", "subject", msg.Subject, "err", err)
+			return
 		}
+		user := dec.(User)
+		fn(ctx, &user)
 	}); err != nil {
 		return err
 	}
@@ -38,6 +70,57 @@ func (s *subscriber) UpdateUser(fn UpdateUserHandler) error {
 	return nil
 }
 
+// msgContext returns handler context with message header and request id taken from it or a new one
+func (s *subscriber) msgContext(msg *nats.Msg) context.Context {
+	var id string
+	if msg.Header != nil {
+		id = msg.Header.Get(requestid.Header)
+	}
+	ctx, _ := requestid.Inject(s.ctx, id)
+	return context.WithValue(ctx, headerKey{}, msg.Header)
+}
+
+// tenantContext scopes handler context to tenant of message header, which has to match tenant of subject
+func tenantContext(ctx context.Context, msg *nats.Msg) (context.Context, error) {
+	var id string
+	if msg.Header != nil {
+		id = msg.Header.Get(tenant.Header)
+	}
+	if err := tenant.Validate(id); err != nil {
+		return ctx, err
+	}
+	if !strings.HasSuffix(msg.Subject, "."+id) {
+		return ctx, errors.Errorf("tenant %q does not match subject", id)
+	}
+	return tenant.Resolve(ctx, id, "")
+}
+
+// startSpan starts consumer span named after subject of all tenants, child of publisher span when message header
+// carries its context
+func (s *subscriber) startSpan(ctx context.Context, subject string, msg *nats.Msg) (opentracing.Span, context.Context) {
+	tracer := tracing.FromContext(s.ctx)
+	opts := []opentracing.StartSpanOption{ext.SpanKindConsumer}
+	if msg.Header != nil {
+		if sc, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(msg.Header)); err == nil {
+			opts = append(opts, opentracing.ChildOf(sc))
+		}
+	}
+	span := tracer.StartSpan("consume "+subject, opts...)
+	ext.MessageBusDestination.Set(span, msg.Subject)
+	span.SetTag(requestid.LogKey, requestid.FromContext(ctx))
+	return span, opentracing.ContextWithSpan(ctx, span)
+}
+
+type headerKey struct{}
+
+// HeaderFromContext returns header of message handled with ctx.
+func HeaderFromContext(ctx context.Context) nats.Header {
+	if h, ok := ctx.Value(headerKey{}).(nats.Header); ok {
+		return h
+	}
+	return nats.Header{}
+}
+
 func decodeNATSUserRequest(_ context.Context, msg *nats.Msg) (interface{}, error) {
 	var request User
 	if err := json.Unmarshal(msg.Data, &request); err != nil {
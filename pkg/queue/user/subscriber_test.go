@@ -1,10 +1,16 @@
 package user
 
 import (
+	"context"
 	natsCl "github.com/nakiner/faceit/pkg/store/nats"
-	natsserver "github.com/nats-io/nats-server/test"
+	"github.com/nakiner/faceit/tools/requestid"
+	"github.com/nakiner/faceit/tools/tracing"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestNewSubscriber(t *testing.T) {
@@ -21,7 +27,7 @@ func TestNewSubscriber(t *testing.T) {
 	assert.NoError(t, err)
 	defer nc.Close()
 
-	sub := NewSubscriber(nc)
+	sub := NewSubscriber(context.Background(), nc)
 	assert.NotNil(t, sub)
 }
 
@@ -39,9 +45,85 @@ func TestSubscriber_UpdateUser(t *testing.T) {
 	assert.NoError(t, err)
 	defer nc.Close()
 
-	sub := NewSubscriber(nc)
+	sub := NewSubscriber(context.Background(), nc)
 	assert.NotNil(t, sub)
 
-	err = sub.UpdateUser(func(u *User) {})
+	err = sub.UpdateUser(func(ctx context.Context, u *User) {})
 	assert.NoError(t, err)
 }
+
+func TestSubscriber_RequestID(t *testing.T) {
+	opt := natsserver.DefaultTestOptions
+	opt.Port = port
+	natsSvr := natsserver.RunServer(&opt)
+	defer natsSvr.Shutdown()
+
+	nc, err := natsCl.NewClient(&natsCl.Config{
+		Host: opt.Host,
+		Port: opt.Port,
+	})
+	assert.NoError(t, err)
+	defer nc.Close()
+
+	ec, err := natsCl.NewEncodedClient(nc)
+	assert.NoError(t, err)
+
+	ids := make(chan string, 1)
+	sub := NewSubscriber(context.Background(), nc)
+	assert.NoError(t, sub.UpdateUser(func(ctx context.Context, u *User) {
+		ids <- requestid.FromContext(ctx)
+	}))
+
+	pub, err := NewPublisher(ec)
+	assert.NoError(t, err)
+	assert.NoError(t, pub.UpdateUser(requestid.WithContext(context.Background(), "complaint-42"), &User{ID: "sample", TenantID: "default"}))
+
+	select {
+	case id := <-ids:
+		assert.Equal(t, "complaint-42", id)
+	case <-time.After(2 * time.Second):
+		t.Fatal("message was not delivered")
+	}
+}
+
+func TestSubscriber_Tracing(t *testing.T) {
+	opt := natsserver.DefaultTestOptions
+	opt.Port = port
+	natsSvr := natsserver.RunServer(&opt)
+	defer natsSvr.Shutdown()
+
+	nc, err := natsCl.NewClient(&natsCl.Config{
+		Host: opt.Host,
+		Port: opt.Port,
+	})
+	assert.NoError(t, err)
+	defer nc.Close()
+
+	ec, err := natsCl.NewEncodedClient(nc)
+	assert.NoError(t, err)
+
+	tracer := mocktracer.New()
+	spans := make(chan *mocktracer.MockSpan, 1)
+	sub := NewSubscriber(tracing.WithContext(context.Background(), tracer), nc)
+	assert.NoError(t, sub.UpdateUser(func(ctx context.Context, u *User) {
+		spans <- opentracing.SpanFromContext(ctx).(*mocktracer.MockSpan)
+	}))
+
+	pub, err := NewPublisher(ec)
+	assert.NoError(t, err)
+	parent := tracer.StartSpan("UpdateUser")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+	assert.NoError(t, pub.UpdateUser(ctx, &User{ID: "sample", TenantID: "default"}))
+	parent.Finish()
+
+	select {
+	case span := <-spans:
+		assert.Equal(t, "consume "+UpdateUserSubject, span.OperationName)
+		assert.Equal(t, parent.(*mocktracer.MockSpan).SpanContext.TraceID, span.SpanContext.TraceID)
+		published := tracer.FinishedSpans()[0]
+		assert.Equal(t, "publish "+UpdateUserSubject, published.OperationName)
+		assert.Equal(t, published.SpanContext.SpanID, span.ParentID)
+	case <-time.After(2 * time.Second):
+		t.Fatal("message was not delivered")
+	}
+}
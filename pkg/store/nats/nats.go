@@ -1,8 +1,14 @@
 package nats
 
 import (
+	"context"
 	"fmt"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/nakiner/faceit/tools/retry"
+	"github.com/nakiner/faceit/tools/secrets"
 	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
 	"time"
 )
 
@@ -10,22 +16,96 @@ type Config struct {
 	Host           string
 	Port           int
 	UserName       string
-	Password       string
+	Password       secrets.String
 	RequestTimeOut int `mapstructure:"request_timeout_msec"`
 	RetryLimit     int `mapstructure:"retry_limit"`
 	WaitLimit      int `mapstructure:"reconnect_time_wait_msec"`
 }
 
-func NewClient(cfg *Config) (*nats.Conn, error) {
+// NewClient connects to nats, connection is returned in RECONNECTING state when server is not reachable yet.
+func NewClient(cfg *Config, opts ...nats.Option) (*nats.Conn, error) {
 	return nats.Connect(
 		fmt.Sprintf("nats://%s:%d", cfg.Host, cfg.Port),
-		nats.RetryOnFailedConnect(true),
-		nats.MaxReconnects(cfg.RetryLimit),
-		nats.ReconnectWait(time.Millisecond*time.Duration(cfg.WaitLimit)),
-		nats.UserInfo(cfg.UserName, cfg.Password),
+		append([]nats.Option{
+			nats.RetryOnFailedConnect(true),
+			nats.MaxReconnects(cfg.RetryLimit),
+			nats.ReconnectWait(time.Millisecond * time.Duration(cfg.WaitLimit)),
+			nats.UserInfo(cfg.UserName, cfg.Password.Reveal()),
+		}, opts...)...,
 	)
 }
 
+// LogState returns options logging connection state changes: disconnects, reconnects and close.
+func LogState(logger log.Logger) []nats.Option {
+	logger = log.With(logger, "component", "nats")
+	return []nats.Option{
+		nats.DisconnectErrHandler(func(nc *nats.Conn, err error) {
+			level.Warn(logger).Log("msg", "disconnected", "err", err)
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			level.Info(logger).Log("msg", "connected", "url", nc.ConnectedUrl())
+		}),
+		nats.ClosedHandler(func(nc *nats.Conn) {
+			level.Warn(logger).Log("msg", "connection closed", "err", nc.LastError())
+		}),
+	}
+}
+
+// AwaitConn returns probe failing until connection is CONNECTED, closed connection fails permanently,
+// used to wait for nats on startup
+func AwaitConn(nc *nats.Conn) func(ctx context.Context) error {
+	check := CheckConn(nc)
+	return func(ctx context.Context) error {
+		if nc.IsClosed() {
+			return retry.Permanent(errors.Errorf("nats connection closed, last error: %v", nc.LastError()))
+		}
+		return check(ctx)
+	}
+}
+
 func NewEncodedClient(nc *nats.Conn) (*nats.EncodedConn, error) {
 	return nats.NewEncodedConn(nc, nats.JSON_ENCODER)
 }
+
+// CheckConn returns error when connection is not in CONNECTED state, used by readiness checks
+func CheckConn(nc *nats.Conn) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if status := nc.Status(); status != nats.CONNECTED {
+			if err := nc.LastError(); err != nil {
+				return errors.Wrapf(err, "nats connection %s", status)
+			}
+			return errors.Errorf("nats connection %s", status)
+		}
+		return nil
+	}
+}
+
+// Drain flushes pending publishes, lets subscriptions process delivered messages and closes connection.
+// Connection is closed forcibly when ctx is done first.
+func Drain(ctx context.Context, nc *nats.Conn) error {
+	if nc.IsClosed() {
+		return nil
+	}
+	if nc.IsConnected() {
+		if err := nc.FlushWithContext(ctx); err != nil {
+			nc.Close()
+			return errors.Wrap(err, "flush nats")
+		}
+	}
+	if err := nc.Drain(); err != nil {
+		nc.Close()
+		return errors.Wrap(err, "drain nats")
+	}
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for !nc.IsClosed() {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			nc.Close()
+			return errors.Wrap(ctx.Err(), "drain nats")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package user
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/nakiner/faceit/tools/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// ClientOption configures transport of HTTP and gRPC clients.
+type ClientOption func(*clientOptions)
+
+type clientOptions struct {
+	tls *tls.Config
+}
+
+// ClientTLS enables TLS with given config, set its client certificate for mTLS.
+// Config may be taken from tlsconfig.Reloader.ClientConfig to follow rotated certificates.
+func ClientTLS(cfg *tls.Config) ClientOption {
+	return func(o *clientOptions) {
+		o.tls = cfg
+	}
+}
+
+func newClientOptions(opts []ClientOption) *clientOptions {
+	o := &clientOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *clientOptions) httpClient() *http.Client {
+	if o.tls == nil {
+		return http.DefaultClient
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = o.tls
+	return &http.Client{Transport: transport}
+}
+
+// requestIDToHTTP propagates request id of ctx to outgoing request
+func requestIDToHTTP() httptransport.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		if id := requestid.FromContext(ctx); id != "" {
+			r.Header.Set(requestid.Header, id)
+		}
+		return ctx
+	}
+}
+
+// requestIDToGRPC propagates request id of ctx to outgoing metadata
+func requestIDToGRPC() grpctransport.ClientRequestFunc {
+	return func(ctx context.Context, md *metadata.MD) context.Context {
+		if id := requestid.FromContext(ctx); id != "" {
+			md.Set(requestid.MetadataKey, id)
+		}
+		return ctx
+	}
+}
+
+// DialGRPC connects to gRPC server at target, in plaintext unless ClientTLS is given.
+func DialGRPC(ctx context.Context, target string, opts ...ClientOption) (*grpc.ClientConn, error) {
+	o := newClientOptions(opts)
+	creds := grpc.WithInsecure()
+	if o.tls != nil {
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(o.tls))
+	}
+	return grpc.DialContext(ctx, target, creds)
+}
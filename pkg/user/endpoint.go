@@ -44,6 +44,104 @@ type GetUsersRequest struct {
 //easyjson:json
 type GetUsersResponse []User
 
+//easyjson:json
+type GetRolesRequest struct {
+	Id string `json:"id,omitempty"`
+}
+
+//easyjson:json
+type GetRolesResponse struct {
+	Roles []string `json:"roles"`
+}
+
+//easyjson:json
+type RoleRequest struct {
+	Id   string `json:"id,omitempty"`
+	Role string `json:"role,omitempty"`
+}
+
+//easyjson:json
+type APIKey struct {
+	Id         string   `json:"id,omitempty"`
+	Name       string   `json:"name,omitempty"`
+	Prefix     string   `json:"prefix,omitempty"`
+	Scopes     []string `json:"scopes,omitempty"`
+	RateLimit  float64  `json:"rateLimit,omitempty"`
+	ExpiresAt  string   `json:"expiresAt,omitempty"`
+	LastUsedAt string   `json:"lastUsedAt,omitempty"`
+	RevokedAt  string   `json:"revokedAt,omitempty"`
+	CreatedAt  string   `json:"createdAt,omitempty"`
+}
+
+//easyjson:json
+type CreateAPIKeyRequest struct {
+	Name      string   `json:"name,omitempty"`
+	Scopes    []string `json:"scopes,omitempty"`
+	RateLimit float64  `json:"rateLimit,omitempty"`
+	ExpiresAt string   `json:"expiresAt,omitempty"`
+}
+
+//easyjson:json
+type CreateAPIKeyResponse struct {
+	Key    string  `json:"key,omitempty"`
+	APIKey *APIKey `json:"apiKey,omitempty"`
+}
+
+//easyjson:json
+type ListAPIKeysRequest struct{}
+
+//easyjson:json
+type ListAPIKeysResponse []APIKey
+
+//easyjson:json
+type RevokeAPIKeyRequest struct {
+	Id string `json:"id,omitempty"`
+}
+
+//easyjson:json
+type RotateAPIKeyRequest struct {
+	Id             string `json:"id,omitempty"`
+	GracePeriodSec uint32 `json:"gracePeriodSec,omitempty"`
+}
+
+//easyjson:json
+type SendVerificationEmailRequest struct {
+	Id string `json:"id,omitempty"`
+}
+
+//easyjson:json
+type VerifyEmailRequest struct {
+	Token string `json:"token,omitempty"`
+}
+
+//easyjson:json
+type ChangePasswordRequest struct {
+	Id              string `json:"id,omitempty"`
+	CurrentPassword string `json:"currentPassword,omitempty"`
+	NewPassword     string `json:"newPassword,omitempty"`
+}
+
+//easyjson:json
+type RequestPasswordResetRequest struct {
+	Email string `json:"email,omitempty"`
+}
+
+//easyjson:json
+type ResetPasswordRequest struct {
+	Token       string `json:"token,omitempty"`
+	NewPassword string `json:"newPassword,omitempty"`
+}
+
+//easyjson:json
+type UnlockUserRequest struct {
+	Id string `json:"id,omitempty"`
+}
+
+//easyjson:json
+type UnlockIPRequest struct {
+	Ip string `json:"ip,omitempty"`
+}
+
 //easyjson:json
 type Status struct {
 	Status  bool   `json:"status,omitempty"`
@@ -56,11 +154,12 @@ type User struct {
 	FirstName string `json:"firstName,omitempty"`
 	LastName  string `json:"lastName,omitempty"`
 	Nickname  string `json:"nickname,omitempty"`
-	Password  string `json:"password,omitempty"`
 	Email     string `json:"email,omitempty"`
 	Country   string `json:"country,omitempty"`
 	CreatedAt string `json:"createdAt,omitempty"`
 	UpdatedAt string `json:"updatedAt,omitempty"`
+	// EmailVerifiedAt is empty until user verifies email, it is read-only
+	EmailVerifiedAt string `json:"emailVerifiedAt,omitempty"`
 }
 
 //easyjson:skip
@@ -69,6 +168,35 @@ type endpoints struct {
 	GetUsersEndpoint   endpoint.Endpoint
 	UpdateUserEndpoint endpoint.Endpoint
 	DeleteUserEndpoint endpoint.Endpoint
+	GetRolesEndpoint   endpoint.Endpoint
+	AssignRoleEndpoint endpoint.Endpoint
+	RevokeRoleEndpoint endpoint.Endpoint
+
+	CreateAPIKeyEndpoint endpoint.Endpoint
+	ListAPIKeysEndpoint  endpoint.Endpoint
+	RevokeAPIKeyEndpoint endpoint.Endpoint
+	RotateAPIKeyEndpoint endpoint.Endpoint
+
+	SendVerificationEmailEndpoint endpoint.Endpoint
+	VerifyEmailEndpoint           endpoint.Endpoint
+
+	ChangePasswordEndpoint       endpoint.Endpoint
+	RequestPasswordResetEndpoint endpoint.Endpoint
+	ResetPasswordEndpoint        endpoint.Endpoint
+
+	UnlockUserEndpoint endpoint.Endpoint
+	UnlockIPEndpoint   endpoint.Endpoint
+}
+
+// EndpointMiddleware wraps server endpoint of Service method, e.g. to authorize calls.
+type EndpointMiddleware func(method string, next endpoint.Endpoint) endpoint.Endpoint
+
+// chain applies mws to endpoint of method, first of mws is outermost
+func chain(method string, e endpoint.Endpoint, mws []EndpointMiddleware) endpoint.Endpoint {
+	for i := len(mws) - 1; i >= 0; i-- {
+		e = mws[i](method, e)
+	}
+	return e
 }
 
 func (e endpoints) CreateUser(ctx context.Context, req *CreateUserRequest) (resp *CreateUserResponse, err error) {
@@ -107,6 +235,132 @@ func (e endpoints) DeleteUser(ctx context.Context, req *DeleteUserRequest) (resp
 	return &r, err
 }
 
+func (e endpoints) GetRoles(ctx context.Context, req *GetRolesRequest) (resp *GetRolesResponse, err error) {
+	response, err := e.GetRolesEndpoint(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r := response.(GetRolesResponse)
+	return &r, err
+}
+
+func (e endpoints) AssignRole(ctx context.Context, req *RoleRequest) (resp *Status, err error) {
+	response, err := e.AssignRoleEndpoint(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r := response.(Status)
+	return &r, err
+}
+
+func (e endpoints) RevokeRole(ctx context.Context, req *RoleRequest) (resp *Status, err error) {
+	response, err := e.RevokeRoleEndpoint(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r := response.(Status)
+	return &r, err
+}
+
+func (e endpoints) CreateAPIKey(ctx context.Context, req *CreateAPIKeyRequest) (resp *CreateAPIKeyResponse, err error) {
+	response, err := e.CreateAPIKeyEndpoint(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r := response.(CreateAPIKeyResponse)
+	return &r, err
+}
+
+func (e endpoints) ListAPIKeys(ctx context.Context, req *ListAPIKeysRequest) (resp *ListAPIKeysResponse, err error) {
+	response, err := e.ListAPIKeysEndpoint(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r := response.(ListAPIKeysResponse)
+	return &r, err
+}
+
+func (e endpoints) RevokeAPIKey(ctx context.Context, req *RevokeAPIKeyRequest) (resp *Status, err error) {
+	response, err := e.RevokeAPIKeyEndpoint(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r := response.(Status)
+	return &r, err
+}
+
+func (e endpoints) RotateAPIKey(ctx context.Context, req *RotateAPIKeyRequest) (resp *CreateAPIKeyResponse, err error) {
+	response, err := e.RotateAPIKeyEndpoint(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r := response.(CreateAPIKeyResponse)
+	return &r, err
+}
+
+func (e endpoints) SendVerificationEmail(ctx context.Context, req *SendVerificationEmailRequest) (resp *Status, err error) {
+	response, err := e.SendVerificationEmailEndpoint(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r := response.(Status)
+	return &r, err
+}
+
+func (e endpoints) VerifyEmail(ctx context.Context, req *VerifyEmailRequest) (resp *Status, err error) {
+	response, err := e.VerifyEmailEndpoint(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r := response.(Status)
+	return &r, err
+}
+
+func (e endpoints) ChangePassword(ctx context.Context, req *ChangePasswordRequest) (resp *Status, err error) {
+	response, err := e.ChangePasswordEndpoint(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r := response.(Status)
+	return &r, err
+}
+
+func (e endpoints) RequestPasswordReset(ctx context.Context, req *RequestPasswordResetRequest) (resp *Status, err error) {
+	response, err := e.RequestPasswordResetEndpoint(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r := response.(Status)
+	return &r, err
+}
+
+func (e endpoints) ResetPassword(ctx context.Context, req *ResetPasswordRequest) (resp *Status, err error) {
+	response, err := e.ResetPasswordEndpoint(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r := response.(Status)
+	return &r, err
+}
+
+func (e endpoints) UnlockUser(ctx context.Context, req *UnlockUserRequest) (resp *Status, err error) {
+	response, err := e.UnlockUserEndpoint(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r := response.(Status)
+	return &r, err
+}
+
+func (e endpoints) UnlockIP(ctx context.Context, req *UnlockIPRequest) (resp *Status, err error) {
+	response, err := e.UnlockIPEndpoint(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r := response.(Status)
+	return &r, err
+}
+
 func makeCreateUserEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(CreateUserRequest)
@@ -134,3 +388,101 @@ func makeDeleteUserEndpoint(s Service) endpoint.Endpoint {
 		return s.DeleteUser(ctx, &req)
 	}
 }
+
+func makeGetRolesEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetRolesRequest)
+		return s.GetRoles(ctx, &req)
+	}
+}
+
+func makeAssignRoleEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(RoleRequest)
+		return s.AssignRole(ctx, &req)
+	}
+}
+
+func makeRevokeRoleEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(RoleRequest)
+		return s.RevokeRole(ctx, &req)
+	}
+}
+
+func makeCreateAPIKeyEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CreateAPIKeyRequest)
+		return s.CreateAPIKey(ctx, &req)
+	}
+}
+
+func makeListAPIKeysEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ListAPIKeysRequest)
+		return s.ListAPIKeys(ctx, &req)
+	}
+}
+
+func makeRevokeAPIKeyEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(RevokeAPIKeyRequest)
+		return s.RevokeAPIKey(ctx, &req)
+	}
+}
+
+func makeRotateAPIKeyEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(RotateAPIKeyRequest)
+		return s.RotateAPIKey(ctx, &req)
+	}
+}
+
+func makeSendVerificationEmailEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(SendVerificationEmailRequest)
+		return s.SendVerificationEmail(ctx, &req)
+	}
+}
+
+func makeVerifyEmailEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(VerifyEmailRequest)
+		return s.VerifyEmail(ctx, &req)
+	}
+}
+
+func makeChangePasswordEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ChangePasswordRequest)
+		return s.ChangePassword(ctx, &req)
+	}
+}
+
+func makeRequestPasswordResetEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(RequestPasswordResetRequest)
+		return s.RequestPasswordReset(ctx, &req)
+	}
+}
+
+func makeResetPasswordEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ResetPasswordRequest)
+		return s.ResetPassword(ctx, &req)
+	}
+}
+
+func makeUnlockUserEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(UnlockUserRequest)
+		return s.UnlockUser(ctx, &req)
+	}
+}
+
+func makeUnlockIPEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(UnlockIPRequest)
+		return s.UnlockIP(ctx, &req)
+	}
+}
@@ -3,6 +3,7 @@ package user
 import (
 	"net/http"
 
+	"github.com/nakiner/faceit/tools/tenant"
 	"github.com/pkg/errors"
 )
 
@@ -14,6 +15,12 @@ var (
 	ErrNotFound        = errors.New("not found")
 	errBadRoute        = errors.New("bad route")
 	ErrInvalidRequest  = errors.New("invalid params in request")
+	// ErrUnauthenticated is returned when method requires authenticated caller.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden is returned when caller is not permitted to call method.
+	ErrForbidden = errors.New("forbidden")
+	// ErrTooManyAttempts is returned when credentials are not checked after too many failed checks.
+	ErrTooManyAttempts = errors.New("too many failed attempts")
 )
 
 type ContextHTTPKey struct{}
@@ -48,6 +55,14 @@ func getHTTPStatusCode(err error) int {
 		return http.StatusBadRequest
 	case ErrNotFound:
 		return http.StatusNotFound
+	case tenant.ErrMissing:
+		return http.StatusBadRequest
+	case ErrUnauthenticated:
+		return http.StatusUnauthorized
+	case ErrForbidden:
+		return http.StatusForbidden
+	case ErrTooManyAttempts:
+		return http.StatusTooManyRequests
 	default:
 		return http.StatusInternalServerError
 	}
@@ -20,6 +20,7 @@ func NewGRPCClient(conn *grpc.ClientConn, tracer stdopentracing.Tracer, logger l
 	// global client middlewares
 	options := []grpctransport.ClientOption{
 		grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger)),
+		grpctransport.ClientBefore(requestIDToGRPC()),
 	}
 
 	return endpoints{
@@ -63,6 +64,132 @@ func NewGRPCClient(conn *grpc.ClientConn, tracer stdopentracing.Tracer, logger l
 			pb.Status{},
 			options...,
 		).Endpoint(),
+		GetRolesEndpoint: grpctransport.NewClient(
+			conn,
+			"faceitpb.UserService",
+			"GetRoles",
+			encodeGRPCGetRolesRequest,
+			decodeGRPCGetRolesResponse,
+			pb.GetRolesResponse{},
+			options...,
+		).Endpoint(),
+		AssignRoleEndpoint: grpctransport.NewClient(
+			conn,
+			"faceitpb.UserService",
+			"AssignRole",
+			encodeGRPCRoleRequest,
+			decodeGRPCStatus,
+			pb.Status{},
+			options...,
+		).Endpoint(),
+		RevokeRoleEndpoint: grpctransport.NewClient(
+			conn,
+			"faceitpb.UserService",
+			"RevokeRole",
+			encodeGRPCRoleRequest,
+			decodeGRPCStatus,
+			pb.Status{},
+			options...,
+		).Endpoint(),
+		CreateAPIKeyEndpoint: grpctransport.NewClient(
+			conn,
+			"faceitpb.UserService",
+			"CreateApiKey",
+			encodeGRPCCreateAPIKeyRequest,
+			decodeGRPCCreateAPIKeyResponse,
+			pb.CreateApiKeyResponse{},
+			options...,
+		).Endpoint(),
+		ListAPIKeysEndpoint: grpctransport.NewClient(
+			conn,
+			"faceitpb.UserService",
+			"ListApiKeys",
+			encodeGRPCListAPIKeysRequest,
+			decodeGRPCListAPIKeysResponse,
+			pb.ListApiKeysResponse{},
+			options...,
+		).Endpoint(),
+		RevokeAPIKeyEndpoint: grpctransport.NewClient(
+			conn,
+			"faceitpb.UserService",
+			"RevokeApiKey",
+			encodeGRPCRevokeAPIKeyRequest,
+			decodeGRPCStatus,
+			pb.Status{},
+			options...,
+		).Endpoint(),
+		RotateAPIKeyEndpoint: grpctransport.NewClient(
+			conn,
+			"faceitpb.UserService",
+			"RotateApiKey",
+			encodeGRPCRotateAPIKeyRequest,
+			decodeGRPCCreateAPIKeyResponse,
+			pb.CreateApiKeyResponse{},
+			options...,
+		).Endpoint(),
+		SendVerificationEmailEndpoint: grpctransport.NewClient(
+			conn,
+			"faceitpb.UserService",
+			"SendVerificationEmail",
+			encodeGRPCSendVerificationEmailRequest,
+			decodeGRPCStatus,
+			pb.Status{},
+			options...,
+		).Endpoint(),
+		VerifyEmailEndpoint: grpctransport.NewClient(
+			conn,
+			"faceitpb.UserService",
+			"VerifyEmail",
+			encodeGRPCVerifyEmailRequest,
+			decodeGRPCStatus,
+			pb.Status{},
+			options...,
+		).Endpoint(),
+		ChangePasswordEndpoint: grpctransport.NewClient(
+			conn,
+			"faceitpb.UserService",
+			"ChangePassword",
+			encodeGRPCChangePasswordRequest,
+			decodeGRPCStatus,
+			pb.Status{},
+			options...,
+		).Endpoint(),
+		RequestPasswordResetEndpoint: grpctransport.NewClient(
+			conn,
+			"faceitpb.UserService",
+			"RequestPasswordReset",
+			encodeGRPCRequestPasswordResetRequest,
+			decodeGRPCStatus,
+			pb.Status{},
+			options...,
+		).Endpoint(),
+		ResetPasswordEndpoint: grpctransport.NewClient(
+			conn,
+			"faceitpb.UserService",
+			"ResetPassword",
+			encodeGRPCResetPasswordRequest,
+			decodeGRPCStatus,
+			pb.Status{},
+			options...,
+		).Endpoint(),
+		UnlockUserEndpoint: grpctransport.NewClient(
+			conn,
+			"faceitpb.UserService",
+			"UnlockUser",
+			encodeGRPCUnlockUserRequest,
+			decodeGRPCStatus,
+			pb.Status{},
+			options...,
+		).Endpoint(),
+		UnlockIPEndpoint: grpctransport.NewClient(
+			conn,
+			"faceitpb.UserService",
+			"UnlockIP",
+			encodeGRPCUnlockIPRequest,
+			decodeGRPCStatus,
+			pb.Status{},
+			options...,
+		).Endpoint(),
 	}
 }
 
@@ -93,6 +220,122 @@ func encodeGRPCGetUsersRequest(_ context.Context, request interface{}) (interfac
 	return GetUsersRequestToPB(inReq), nil
 }
 
+func encodeGRPCGetRolesRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*GetRolesRequest)
+	if !ok {
+		return nil, errors.New("encodeGRPCGetRolesRequest wrong request")
+	}
+
+	return GetRolesRequestToPB(inReq), nil
+}
+
+func encodeGRPCRoleRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*RoleRequest)
+	if !ok {
+		return nil, errors.New("encodeGRPCRoleRequest wrong request")
+	}
+
+	return RoleRequestToPB(inReq), nil
+}
+
+func encodeGRPCCreateAPIKeyRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*CreateAPIKeyRequest)
+	if !ok {
+		return nil, errors.New("encodeGRPCCreateAPIKeyRequest wrong request")
+	}
+
+	return CreateAPIKeyRequestToPB(inReq), nil
+}
+
+func encodeGRPCListAPIKeysRequest(_ context.Context, request interface{}) (interface{}, error) {
+	if _, ok := request.(*ListAPIKeysRequest); !ok {
+		return nil, errors.New("encodeGRPCListAPIKeysRequest wrong request")
+	}
+
+	return &pb.ListApiKeysRequest{}, nil
+}
+
+func encodeGRPCRevokeAPIKeyRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*RevokeAPIKeyRequest)
+	if !ok {
+		return nil, errors.New("encodeGRPCRevokeAPIKeyRequest wrong request")
+	}
+
+	return RevokeAPIKeyRequestToPB(inReq), nil
+}
+
+func encodeGRPCRotateAPIKeyRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*RotateAPIKeyRequest)
+	if !ok {
+		return nil, errors.New("encodeGRPCRotateAPIKeyRequest wrong request")
+	}
+
+	return RotateAPIKeyRequestToPB(inReq), nil
+}
+
+func encodeGRPCSendVerificationEmailRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*SendVerificationEmailRequest)
+	if !ok {
+		return nil, errors.New("encodeGRPCSendVerificationEmailRequest wrong request")
+	}
+
+	return SendVerificationEmailRequestToPB(inReq), nil
+}
+
+func encodeGRPCVerifyEmailRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*VerifyEmailRequest)
+	if !ok {
+		return nil, errors.New("encodeGRPCVerifyEmailRequest wrong request")
+	}
+
+	return VerifyEmailRequestToPB(inReq), nil
+}
+
+func encodeGRPCChangePasswordRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*ChangePasswordRequest)
+	if !ok {
+		return nil, errors.New("encodeGRPCChangePasswordRequest wrong request")
+	}
+
+	return ChangePasswordRequestToPB(inReq), nil
+}
+
+func encodeGRPCRequestPasswordResetRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*RequestPasswordResetRequest)
+	if !ok {
+		return nil, errors.New("encodeGRPCRequestPasswordResetRequest wrong request")
+	}
+
+	return RequestPasswordResetRequestToPB(inReq), nil
+}
+
+func encodeGRPCResetPasswordRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*ResetPasswordRequest)
+	if !ok {
+		return nil, errors.New("encodeGRPCResetPasswordRequest wrong request")
+	}
+
+	return ResetPasswordRequestToPB(inReq), nil
+}
+
+func encodeGRPCUnlockUserRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*UnlockUserRequest)
+	if !ok {
+		return nil, errors.New("encodeGRPCUnlockUserRequest wrong request")
+	}
+
+	return UnlockUserRequestToPB(inReq), nil
+}
+
+func encodeGRPCUnlockIPRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*UnlockIPRequest)
+	if !ok {
+		return nil, errors.New("encodeGRPCUnlockIPRequest wrong request")
+	}
+
+	return UnlockIPRequestToPB(inReq), nil
+}
+
 func encodeGRPCUser(_ context.Context, request interface{}) (interface{}, error) {
 	inReq, ok := request.(*User)
 	if !ok {
@@ -124,6 +367,39 @@ func decodeGRPCGetUsersResponse(_ context.Context, response interface{}) (interf
 	return *resp, nil
 }
 
+func decodeGRPCGetRolesResponse(_ context.Context, response interface{}) (interface{}, error) {
+	inResp, ok := response.(*pb.GetRolesResponse)
+	if !ok {
+		return nil, errors.New("decodeGRPCGetRolesResponse wrong response")
+	}
+
+	resp := PBToGetRolesResponse(inResp)
+
+	return *resp, nil
+}
+
+func decodeGRPCCreateAPIKeyResponse(_ context.Context, response interface{}) (interface{}, error) {
+	inResp, ok := response.(*pb.CreateApiKeyResponse)
+	if !ok {
+		return nil, errors.New("decodeGRPCCreateAPIKeyResponse wrong response")
+	}
+
+	resp := PBToCreateAPIKeyResponse(inResp)
+
+	return *resp, nil
+}
+
+func decodeGRPCListAPIKeysResponse(_ context.Context, response interface{}) (interface{}, error) {
+	inResp, ok := response.(*pb.ListApiKeysResponse)
+	if !ok {
+		return nil, errors.New("decodeGRPCListAPIKeysResponse wrong response")
+	}
+
+	resp := PBToListAPIKeysResponse(inResp)
+
+	return *resp, nil
+}
+
 func decodeGRPCStatus(_ context.Context, response interface{}) (interface{}, error) {
 	inResp, ok := response.(*pb.Status)
 	if !ok {
@@ -3,6 +3,7 @@ package user
 import (
 	"context"
 	"errors"
+	"net/http"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/tracing/opentracing"
@@ -12,8 +13,11 @@ import (
 	"github.com/nakiner/faceit/tools/logging"
 	"github.com/nakiner/faceit/tools/tracing"
 	stdopentracing "github.com/opentracing/opentracing-go"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	googlegrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 type grpcServer struct {
@@ -21,14 +25,32 @@ type grpcServer struct {
 	getUsers   grpctransport.Handler
 	updateUser grpctransport.Handler
 	deleteUser grpctransport.Handler
+	getRoles   grpctransport.Handler
+	assignRole grpctransport.Handler
+	revokeRole grpctransport.Handler
+
+	createAPIKey grpctransport.Handler
+	listAPIKeys  grpctransport.Handler
+	revokeAPIKey grpctransport.Handler
+	rotateAPIKey grpctransport.Handler
+
+	sendVerificationEmail grpctransport.Handler
+	verifyEmail           grpctransport.Handler
+
+	changePassword       grpctransport.Handler
+	requestPasswordReset grpctransport.Handler
+	resetPassword        grpctransport.Handler
+
+	unlockUser grpctransport.Handler
+	unlockIP   grpctransport.Handler
 }
 
 type ContextGRPCKey struct{}
 
 type GRPCInfo struct{}
 
-// NewGRPCServer makes a set of endpoints available as a gRPC userServer.
-func NewGRPCServer(ctx context.Context, s Service) pb.UserServiceServer {
+// NewGRPCServer makes a set of endpoints available as a gRPC userServer, endpoints of methods are wrapped with mws.
+func NewGRPCServer(ctx context.Context, s Service, mws ...EndpointMiddleware) pb.UserServiceServer {
 	logger := logging.FromContext(ctx)
 	logger = log.With(logger, "grpc handler", "user")
 	tracer := tracing.FromContext(ctx)
@@ -42,35 +64,119 @@ func NewGRPCServer(ctx context.Context, s Service) pb.UserServiceServer {
 
 	return &grpcServer{
 		createUser: grpctransport.NewServer(
-			makeCreateUserEndpoint(s),
+			chain("CreateUser", makeCreateUserEndpoint(s), mws),
 			decodeGRPCCreateUserRequest,
 			encodeGRPCCreateUserResponse,
 			options...,
 		),
 		getUsers: grpctransport.NewServer(
-			makeGetUsersEndpoint(s),
+			chain("GetUsers", makeGetUsersEndpoint(s), mws),
 			decodeGRPCGetUsersRequest,
 			encodeGRPCGetUsersResponse,
 			options...,
 		),
 		updateUser: grpctransport.NewServer(
-			makeUpdateUserEndpoint(s),
+			chain("UpdateUser", makeUpdateUserEndpoint(s), mws),
 			decodeGRPCUpdateUserRequest,
 			encodeGRPCUpdateUserResponse,
 			options...,
 		),
 		deleteUser: grpctransport.NewServer(
-			makeDeleteUserEndpoint(s),
+			chain("DeleteUser", makeDeleteUserEndpoint(s), mws),
 			decodeGRPCDeleteUserRequest,
 			encodeGRPCDeleteUserResponse,
 			options...,
 		),
+		getRoles: grpctransport.NewServer(
+			chain("GetRoles", makeGetRolesEndpoint(s), mws),
+			decodeGRPCGetRolesRequest,
+			encodeGRPCGetRolesResponse,
+			options...,
+		),
+		assignRole: grpctransport.NewServer(
+			chain("AssignRole", makeAssignRoleEndpoint(s), mws),
+			decodeGRPCRoleRequest,
+			encodeGRPCStatus,
+			options...,
+		),
+		revokeRole: grpctransport.NewServer(
+			chain("RevokeRole", makeRevokeRoleEndpoint(s), mws),
+			decodeGRPCRoleRequest,
+			encodeGRPCStatus,
+			options...,
+		),
+		createAPIKey: grpctransport.NewServer(
+			chain("CreateAPIKey", makeCreateAPIKeyEndpoint(s), mws),
+			decodeGRPCCreateAPIKeyRequest,
+			encodeGRPCCreateAPIKeyResponse,
+			options...,
+		),
+		listAPIKeys: grpctransport.NewServer(
+			chain("ListAPIKeys", makeListAPIKeysEndpoint(s), mws),
+			decodeGRPCListAPIKeysRequest,
+			encodeGRPCListAPIKeysResponse,
+			options...,
+		),
+		revokeAPIKey: grpctransport.NewServer(
+			chain("RevokeAPIKey", makeRevokeAPIKeyEndpoint(s), mws),
+			decodeGRPCRevokeAPIKeyRequest,
+			encodeGRPCStatus,
+			options...,
+		),
+		rotateAPIKey: grpctransport.NewServer(
+			chain("RotateAPIKey", makeRotateAPIKeyEndpoint(s), mws),
+			decodeGRPCRotateAPIKeyRequest,
+			encodeGRPCCreateAPIKeyResponse,
+			options...,
+		),
+		sendVerificationEmail: grpctransport.NewServer(
+			chain("SendVerificationEmail", makeSendVerificationEmailEndpoint(s), mws),
+			decodeGRPCSendVerificationEmailRequest,
+			encodeGRPCStatus,
+			options...,
+		),
+		verifyEmail: grpctransport.NewServer(
+			chain("VerifyEmail", makeVerifyEmailEndpoint(s), mws),
+			decodeGRPCVerifyEmailRequest,
+			encodeGRPCStatus,
+			options...,
+		),
+		changePassword: grpctransport.NewServer(
+			chain("ChangePassword", makeChangePasswordEndpoint(s), mws),
+			decodeGRPCChangePasswordRequest,
+			encodeGRPCStatus,
+			options...,
+		),
+		requestPasswordReset: grpctransport.NewServer(
+			chain("RequestPasswordReset", makeRequestPasswordResetEndpoint(s), mws),
+			decodeGRPCRequestPasswordResetRequest,
+			encodeGRPCStatus,
+			options...,
+		),
+		resetPassword: grpctransport.NewServer(
+			chain("ResetPassword", makeResetPasswordEndpoint(s), mws),
+			decodeGRPCResetPasswordRequest,
+			encodeGRPCStatus,
+			options...,
+		),
+		unlockUser: grpctransport.NewServer(
+			chain("UnlockUser", makeUnlockUserEndpoint(s), mws),
+			decodeGRPCUnlockUserRequest,
+			encodeGRPCStatus,
+			options...,
+		),
+		unlockIP: grpctransport.NewServer(
+			chain("UnlockIP", makeUnlockIPEndpoint(s), mws),
+			decodeGRPCUnlockIPRequest,
+			encodeGRPCStatus,
+			options...,
+		),
 	}
 }
 
-func JoinGRPC(ctx context.Context, s Service) func(*googlegrpc.Server) {
+func JoinGRPC(ctx context.Context, s Service, mws ...EndpointMiddleware) func(*googlegrpc.Server) {
 	return func(g *googlegrpc.Server) {
-		pb.RegisterUserServiceServer(g, NewGRPCServer(ctx, s))
+		pb.RegisterUserServiceServer(g, NewGRPCServer(ctx, s, mws...))
 	}
 }
 
@@ -89,7 +195,7 @@ func closeGRPCTracer() grpc.ServerFinalizerFunc {
 func (s *grpcServer) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
 	_, rep, err := s.createUser.ServeGRPC(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, grpcError(err)
 	}
 	return rep.(*pb.CreateUserResponse), nil
 }
@@ -97,7 +203,7 @@ func (s *grpcServer) CreateUser(ctx context.Context, req *pb.CreateUserRequest)
 func (s *grpcServer) GetUsers(ctx context.Context, req *pb.GetUsersRequest) (*pb.GetUsersResponse, error) {
 	_, rep, err := s.getUsers.ServeGRPC(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, grpcError(err)
 	}
 	return rep.(*pb.GetUsersResponse), nil
 }
@@ -105,7 +211,7 @@ func (s *grpcServer) GetUsers(ctx context.Context, req *pb.GetUsersRequest) (*pb
 func (s *grpcServer) UpdateUser(ctx context.Context, req *pb.User) (*pb.Status, error) {
 	_, rep, err := s.updateUser.ServeGRPC(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, grpcError(err)
 	}
 	return rep.(*pb.Status), nil
 }
@@ -113,11 +219,155 @@ func (s *grpcServer) UpdateUser(ctx context.Context, req *pb.User) (*pb.Status,
 func (s *grpcServer) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*pb.Status, error) {
 	_, rep, err := s.deleteUser.ServeGRPC(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, grpcError(err)
+	}
+	return rep.(*pb.Status), nil
+}
+
+func (s *grpcServer) GetRoles(ctx context.Context, req *pb.GetRolesRequest) (*pb.GetRolesResponse, error) {
+	_, rep, err := s.getRoles.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return rep.(*pb.GetRolesResponse), nil
+}
+
+func (s *grpcServer) AssignRole(ctx context.Context, req *pb.RoleRequest) (*pb.Status, error) {
+	_, rep, err := s.assignRole.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return rep.(*pb.Status), nil
+}
+
+func (s *grpcServer) RevokeRole(ctx context.Context, req *pb.RoleRequest) (*pb.Status, error) {
+	_, rep, err := s.revokeRole.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return rep.(*pb.Status), nil
+}
+
+func (s *grpcServer) CreateApiKey(ctx context.Context, req *pb.CreateApiKeyRequest) (*pb.CreateApiKeyResponse, error) {
+	_, rep, err := s.createAPIKey.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return rep.(*pb.CreateApiKeyResponse), nil
+}
+
+func (s *grpcServer) ListApiKeys(ctx context.Context, req *pb.ListApiKeysRequest) (*pb.ListApiKeysResponse, error) {
+	_, rep, err := s.listAPIKeys.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return rep.(*pb.ListApiKeysResponse), nil
+}
+
+func (s *grpcServer) RevokeApiKey(ctx context.Context, req *pb.RevokeApiKeyRequest) (*pb.Status, error) {
+	_, rep, err := s.revokeAPIKey.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return rep.(*pb.Status), nil
+}
+
+func (s *grpcServer) RotateApiKey(ctx context.Context, req *pb.RotateApiKeyRequest) (*pb.CreateApiKeyResponse, error) {
+	_, rep, err := s.rotateAPIKey.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return rep.(*pb.CreateApiKeyResponse), nil
+}
+
+func (s *grpcServer) SendVerificationEmail(ctx context.Context, req *pb.SendVerificationEmailRequest) (*pb.Status, error) {
+	_, rep, err := s.sendVerificationEmail.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return rep.(*pb.Status), nil
+}
+
+func (s *grpcServer) VerifyEmail(ctx context.Context, req *pb.VerifyEmailRequest) (*pb.Status, error) {
+	_, rep, err := s.verifyEmail.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return rep.(*pb.Status), nil
+}
+
+func (s *grpcServer) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.Status, error) {
+	_, rep, err := s.changePassword.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return rep.(*pb.Status), nil
+}
+
+func (s *grpcServer) RequestPasswordReset(ctx context.Context, req *pb.RequestPasswordResetRequest) (*pb.Status, error) {
+	_, rep, err := s.requestPasswordReset.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return rep.(*pb.Status), nil
+}
+
+func (s *grpcServer) ResetPassword(ctx context.Context, req *pb.ResetPasswordRequest) (*pb.Status, error) {
+	_, rep, err := s.resetPassword.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return rep.(*pb.Status), nil
+}
+
+func (s *grpcServer) UnlockUser(ctx context.Context, req *pb.UnlockUserRequest) (*pb.Status, error) {
+	_, rep, err := s.unlockUser.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return rep.(*pb.Status), nil
+}
+
+func (s *grpcServer) UnlockIP(ctx context.Context, req *pb.UnlockIPRequest) (*pb.Status, error) {
+	_, rep, err := s.unlockIP.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
 	}
 	return rep.(*pb.Status), nil
 }
 
+// grpcError converts authorization, validation and lockout failures to their gRPC codes, so clients may tell them
+// from other errors; violations are sent as BadRequest details
+func grpcError(err error) error {
+	switch getHTTPStatusCode(err) {
+	case http.StatusUnauthorized:
+		return status.Error(codes.Unauthenticated, err.Error())
+	case http.StatusForbidden:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case http.StatusTooManyRequests:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case http.StatusBadRequest:
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			return err
+		}
+		details := &errdetails.BadRequest{}
+		for _, v := range verr.Violations {
+			details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Code + ": " + v.Message,
+			})
+		}
+		st, detailsErr := status.New(codes.InvalidArgument, err.Error()).WithDetails(details)
+		if detailsErr != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		return st.Err()
+	default:
+		return err
+	}
+}
+
 func decodeGRPCCreateUserRequest(_ context.Context, request interface{}) (interface{}, error) {
 	inReq, ok := request.(*pb.CreateUserRequest)
 	if !ok {
@@ -170,6 +420,169 @@ func decodeGRPCDeleteUserRequest(_ context.Context, request interface{}) (interf
 	return *req, nil
 }
 
+func decodeGRPCGetRolesRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*pb.GetRolesRequest)
+	if !ok {
+		return nil, errors.New("decodeGRPCGetRolesRequest wrong request")
+	}
+
+	req := PBToGetRolesRequest(inReq)
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	return *req, nil
+}
+
+func decodeGRPCRoleRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*pb.RoleRequest)
+	if !ok {
+		return nil, errors.New("decodeGRPCRoleRequest wrong request")
+	}
+
+	req := PBToRoleRequest(inReq)
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	return *req, nil
+}
+
+func decodeGRPCCreateAPIKeyRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*pb.CreateApiKeyRequest)
+	if !ok {
+		return nil, errors.New("decodeGRPCCreateAPIKeyRequest wrong request")
+	}
+
+	req := PBToCreateAPIKeyRequest(inReq)
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	return *req, nil
+}
+
+func decodeGRPCListAPIKeysRequest(_ context.Context, request interface{}) (interface{}, error) {
+	if _, ok := request.(*pb.ListApiKeysRequest); !ok {
+		return nil, errors.New("decodeGRPCListAPIKeysRequest wrong request")
+	}
+	return ListAPIKeysRequest{}, nil
+}
+
+func decodeGRPCRevokeAPIKeyRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*pb.RevokeApiKeyRequest)
+	if !ok {
+		return nil, errors.New("decodeGRPCRevokeAPIKeyRequest wrong request")
+	}
+
+	req := PBToRevokeAPIKeyRequest(inReq)
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	return *req, nil
+}
+
+func decodeGRPCRotateAPIKeyRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*pb.RotateApiKeyRequest)
+	if !ok {
+		return nil, errors.New("decodeGRPCRotateAPIKeyRequest wrong request")
+	}
+
+	req := PBToRotateAPIKeyRequest(inReq)
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	return *req, nil
+}
+
+func decodeGRPCSendVerificationEmailRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*pb.SendVerificationEmailRequest)
+	if !ok {
+		return nil, errors.New("decodeGRPCSendVerificationEmailRequest wrong request")
+	}
+
+	req := PBToSendVerificationEmailRequest(inReq)
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	return *req, nil
+}
+
+func decodeGRPCVerifyEmailRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*pb.VerifyEmailRequest)
+	if !ok {
+		return nil, errors.New("decodeGRPCVerifyEmailRequest wrong request")
+	}
+
+	req := PBToVerifyEmailRequest(inReq)
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	return *req, nil
+}
+
+func decodeGRPCChangePasswordRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*pb.ChangePasswordRequest)
+	if !ok {
+		return nil, errors.New("decodeGRPCChangePasswordRequest wrong request")
+	}
+
+	req := PBToChangePasswordRequest(inReq)
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	return *req, nil
+}
+
+func decodeGRPCRequestPasswordResetRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*pb.RequestPasswordResetRequest)
+	if !ok {
+		return nil, errors.New("decodeGRPCRequestPasswordResetRequest wrong request")
+	}
+
+	req := PBToRequestPasswordResetRequest(inReq)
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	return *req, nil
+}
+
+func decodeGRPCResetPasswordRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*pb.ResetPasswordRequest)
+	if !ok {
+		return nil, errors.New("decodeGRPCResetPasswordRequest wrong request")
+	}
+
+	req := PBToResetPasswordRequest(inReq)
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	return *req, nil
+}
+
+func decodeGRPCUnlockUserRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*pb.UnlockUserRequest)
+	if !ok {
+		return nil, errors.New("decodeGRPCUnlockUserRequest wrong request")
+	}
+
+	req := PBToUnlockUserRequest(inReq)
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	return *req, nil
+}
+
+func decodeGRPCUnlockIPRequest(_ context.Context, request interface{}) (interface{}, error) {
+	inReq, ok := request.(*pb.UnlockIPRequest)
+	if !ok {
+		return nil, errors.New("decodeGRPCUnlockIPRequest wrong request")
+	}
+
+	req := PBToUnlockIPRequest(inReq)
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	return *req, nil
+}
+
 func encodeGRPCCreateUserResponse(_ context.Context, response interface{}) (interface{}, error) {
 	inResp, ok := response.(*CreateUserResponse)
 	if !ok {
@@ -206,6 +619,42 @@ func encodeGRPCDeleteUserResponse(_ context.Context, response interface{}) (inte
 	return StatusToPB(inResp), nil
 }
 
+func encodeGRPCGetRolesResponse(_ context.Context, response interface{}) (interface{}, error) {
+	inResp, ok := response.(*GetRolesResponse)
+	if !ok {
+		return nil, errors.New("encodeGRPCGetRolesResponse wrong response")
+	}
+
+	return GetRolesResponseToPB(inResp), nil
+}
+
+func encodeGRPCCreateAPIKeyResponse(_ context.Context, response interface{}) (interface{}, error) {
+	inResp, ok := response.(*CreateAPIKeyResponse)
+	if !ok {
+		return nil, errors.New("encodeGRPCCreateAPIKeyResponse wrong response")
+	}
+
+	return CreateAPIKeyResponseToPB(inResp), nil
+}
+
+func encodeGRPCListAPIKeysResponse(_ context.Context, response interface{}) (interface{}, error) {
+	inResp, ok := response.(*ListAPIKeysResponse)
+	if !ok {
+		return nil, errors.New("encodeGRPCListAPIKeysResponse wrong response")
+	}
+
+	return ListAPIKeysResponseToPB(inResp), nil
+}
+
+func encodeGRPCStatus(_ context.Context, response interface{}) (interface{}, error) {
+	inResp, ok := response.(*Status)
+	if !ok {
+		return nil, errors.New("encodeGRPCStatus wrong response")
+	}
+
+	return StatusToPB(inResp), nil
+}
+
 func CreateUserRequestToPB(d *CreateUserRequest) *pb.CreateUserRequest {
 	if d == nil {
 		return nil
@@ -394,11 +843,12 @@ func UserToPB(d *User) *pb.User {
 		FirstName: d.FirstName,
 		LastName:  d.LastName,
 		Nickname:  d.Nickname,
-		Password:  d.Password,
 		Email:     d.Email,
 		Country:   d.Country,
 		CreatedAt: d.CreatedAt,
 		UpdatedAt: d.UpdatedAt,
+
+		EmailVerifiedAt: d.EmailVerifiedAt,
 	}
 
 	return &resp
@@ -414,11 +864,434 @@ func PBToUser(d *pb.User) *User {
 		FirstName: d.FirstName,
 		LastName:  d.LastName,
 		Nickname:  d.Nickname,
-		Password:  d.Password,
 		Email:     d.Email,
 		Country:   d.Country,
 		CreatedAt: d.CreatedAt,
 		UpdatedAt: d.UpdatedAt,
+
+		EmailVerifiedAt: d.EmailVerifiedAt,
+	}
+
+	return &resp
+}
+
+func GetRolesRequestToPB(d *GetRolesRequest) *pb.GetRolesRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := pb.GetRolesRequest{
+		Id: d.Id,
+	}
+
+	return &resp
+}
+
+func PBToGetRolesRequest(d *pb.GetRolesRequest) *GetRolesRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := GetRolesRequest{
+		Id: d.Id,
+	}
+
+	return &resp
+}
+
+func GetRolesResponseToPB(d *GetRolesResponse) *pb.GetRolesResponse {
+	if d == nil {
+		return nil
+	}
+
+	resp := pb.GetRolesResponse{
+		Roles: d.Roles,
+	}
+
+	return &resp
+}
+
+func PBToGetRolesResponse(d *pb.GetRolesResponse) *GetRolesResponse {
+	if d == nil {
+		return nil
+	}
+
+	resp := GetRolesResponse{
+		Roles: append([]string{}, d.Roles...),
+	}
+
+	return &resp
+}
+
+func RoleRequestToPB(d *RoleRequest) *pb.RoleRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := pb.RoleRequest{
+		Id:   d.Id,
+		Role: d.Role,
+	}
+
+	return &resp
+}
+
+func PBToRoleRequest(d *pb.RoleRequest) *RoleRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := RoleRequest{
+		Id:   d.Id,
+		Role: d.Role,
+	}
+
+	return &resp
+}
+
+func APIKeyToPB(d *APIKey) *pb.ApiKey {
+	if d == nil {
+		return nil
+	}
+
+	resp := pb.ApiKey{
+		Id:         d.Id,
+		Name:       d.Name,
+		Prefix:     d.Prefix,
+		Scopes:     d.Scopes,
+		RateLimit:  d.RateLimit,
+		ExpiresAt:  d.ExpiresAt,
+		LastUsedAt: d.LastUsedAt,
+		RevokedAt:  d.RevokedAt,
+		CreatedAt:  d.CreatedAt,
+	}
+
+	return &resp
+}
+
+func PBToAPIKey(d *pb.ApiKey) *APIKey {
+	if d == nil {
+		return nil
+	}
+
+	resp := APIKey{
+		Id:         d.Id,
+		Name:       d.Name,
+		Prefix:     d.Prefix,
+		Scopes:     d.Scopes,
+		RateLimit:  d.RateLimit,
+		ExpiresAt:  d.ExpiresAt,
+		LastUsedAt: d.LastUsedAt,
+		RevokedAt:  d.RevokedAt,
+		CreatedAt:  d.CreatedAt,
+	}
+
+	return &resp
+}
+
+func CreateAPIKeyRequestToPB(d *CreateAPIKeyRequest) *pb.CreateApiKeyRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := pb.CreateApiKeyRequest{
+		Name:      d.Name,
+		Scopes:    d.Scopes,
+		RateLimit: d.RateLimit,
+		ExpiresAt: d.ExpiresAt,
+	}
+
+	return &resp
+}
+
+func PBToCreateAPIKeyRequest(d *pb.CreateApiKeyRequest) *CreateAPIKeyRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := CreateAPIKeyRequest{
+		Name:      d.Name,
+		Scopes:    d.Scopes,
+		RateLimit: d.RateLimit,
+		ExpiresAt: d.ExpiresAt,
+	}
+
+	return &resp
+}
+
+func CreateAPIKeyResponseToPB(d *CreateAPIKeyResponse) *pb.CreateApiKeyResponse {
+	if d == nil {
+		return nil
+	}
+
+	resp := pb.CreateApiKeyResponse{
+		Key:    d.Key,
+		ApiKey: APIKeyToPB(d.APIKey),
+	}
+
+	return &resp
+}
+
+func PBToCreateAPIKeyResponse(d *pb.CreateApiKeyResponse) *CreateAPIKeyResponse {
+	if d == nil {
+		return nil
+	}
+
+	resp := CreateAPIKeyResponse{
+		Key:    d.Key,
+		APIKey: PBToAPIKey(d.ApiKey),
+	}
+
+	return &resp
+}
+
+func ListAPIKeysResponseToPB(d *ListAPIKeysResponse) *pb.ListApiKeysResponse {
+	if d == nil {
+		return nil
+	}
+
+	resp := pb.ListApiKeysResponse{}
+
+	for _, v := range *d {
+		resp.Data = append(resp.Data, APIKeyToPB(&v))
+	}
+
+	return &resp
+}
+
+func PBToListAPIKeysResponse(d *pb.ListApiKeysResponse) *ListAPIKeysResponse {
+	if d == nil {
+		return nil
+	}
+
+	resp := ListAPIKeysResponse{}
+
+	for _, v := range d.Data {
+		resp = append(resp, *PBToAPIKey(v))
+	}
+
+	return &resp
+}
+
+func RevokeAPIKeyRequestToPB(d *RevokeAPIKeyRequest) *pb.RevokeApiKeyRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := pb.RevokeApiKeyRequest{
+		Id: d.Id,
+	}
+
+	return &resp
+}
+
+func PBToRevokeAPIKeyRequest(d *pb.RevokeApiKeyRequest) *RevokeAPIKeyRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := RevokeAPIKeyRequest{
+		Id: d.Id,
+	}
+
+	return &resp
+}
+
+func RotateAPIKeyRequestToPB(d *RotateAPIKeyRequest) *pb.RotateApiKeyRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := pb.RotateApiKeyRequest{
+		Id:             d.Id,
+		GracePeriodSec: d.GracePeriodSec,
+	}
+
+	return &resp
+}
+
+func PBToRotateAPIKeyRequest(d *pb.RotateApiKeyRequest) *RotateAPIKeyRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := RotateAPIKeyRequest{
+		Id:             d.Id,
+		GracePeriodSec: d.GracePeriodSec,
+	}
+
+	return &resp
+}
+
+func SendVerificationEmailRequestToPB(d *SendVerificationEmailRequest) *pb.SendVerificationEmailRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := pb.SendVerificationEmailRequest{
+		Id: d.Id,
+	}
+
+	return &resp
+}
+
+func PBToSendVerificationEmailRequest(d *pb.SendVerificationEmailRequest) *SendVerificationEmailRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := SendVerificationEmailRequest{
+		Id: d.Id,
+	}
+
+	return &resp
+}
+
+func VerifyEmailRequestToPB(d *VerifyEmailRequest) *pb.VerifyEmailRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := pb.VerifyEmailRequest{
+		Token: d.Token,
+	}
+
+	return &resp
+}
+
+func PBToVerifyEmailRequest(d *pb.VerifyEmailRequest) *VerifyEmailRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := VerifyEmailRequest{
+		Token: d.Token,
+	}
+
+	return &resp
+}
+
+func ChangePasswordRequestToPB(d *ChangePasswordRequest) *pb.ChangePasswordRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := pb.ChangePasswordRequest{
+		Id:              d.Id,
+		CurrentPassword: d.CurrentPassword,
+		NewPassword:     d.NewPassword,
+	}
+
+	return &resp
+}
+
+func PBToChangePasswordRequest(d *pb.ChangePasswordRequest) *ChangePasswordRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := ChangePasswordRequest{
+		Id:              d.Id,
+		CurrentPassword: d.CurrentPassword,
+		NewPassword:     d.NewPassword,
+	}
+
+	return &resp
+}
+
+func RequestPasswordResetRequestToPB(d *RequestPasswordResetRequest) *pb.RequestPasswordResetRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := pb.RequestPasswordResetRequest{
+		Email: d.Email,
+	}
+
+	return &resp
+}
+
+func PBToRequestPasswordResetRequest(d *pb.RequestPasswordResetRequest) *RequestPasswordResetRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := RequestPasswordResetRequest{
+		Email: d.Email,
+	}
+
+	return &resp
+}
+
+func ResetPasswordRequestToPB(d *ResetPasswordRequest) *pb.ResetPasswordRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := pb.ResetPasswordRequest{
+		Token:       d.Token,
+		NewPassword: d.NewPassword,
+	}
+
+	return &resp
+}
+
+func PBToResetPasswordRequest(d *pb.ResetPasswordRequest) *ResetPasswordRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := ResetPasswordRequest{
+		Token:       d.Token,
+		NewPassword: d.NewPassword,
+	}
+
+	return &resp
+}
+
+func UnlockUserRequestToPB(d *UnlockUserRequest) *pb.UnlockUserRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := pb.UnlockUserRequest{
+		Id: d.Id,
+	}
+
+	return &resp
+}
+
+func PBToUnlockUserRequest(d *pb.UnlockUserRequest) *UnlockUserRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := UnlockUserRequest{
+		Id: d.Id,
+	}
+
+	return &resp
+}
+
+func UnlockIPRequestToPB(d *UnlockIPRequest) *pb.UnlockIPRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := pb.UnlockIPRequest{
+		Ip: d.Ip,
+	}
+
+	return &resp
+}
+
+func PBToUnlockIPRequest(d *pb.UnlockIPRequest) *UnlockIPRequest {
+	if d == nil {
+		return nil
+	}
+
+	resp := UnlockIPRequest{
+		Ip: d.Ip,
 	}
 
 	return &resp
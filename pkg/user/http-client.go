@@ -23,10 +23,15 @@ import (
 // remote instance. We expect instance to come from a service discovery system,
 // so likely of the form "host:port". We bake-in certain middlewares,
 // implementing the client library pattern.
-func NewHTTPClient(instance string, tracer stdopentracing.Tracer, logger log.Logger) (Service, error) {
+func NewHTTPClient(instance string, tracer stdopentracing.Tracer, logger log.Logger, opts ...ClientOption) (Service, error) {
+	o := newClientOptions(opts)
 	// Quickly sanitize the instance string.
 	if !strings.HasPrefix(instance, "http") {
-		instance = "http://" + instance
+		if o.tls != nil {
+			instance = "https://" + instance
+		} else {
+			instance = "http://" + instance
+		}
 	}
 	u, err := url.Parse(instance)
 	if err != nil {
@@ -34,7 +39,10 @@ func NewHTTPClient(instance string, tracer stdopentracing.Tracer, logger log.Log
 	}
 
 	// global client middlewares
-	var options []httptransport.ClientOption
+	options := []httptransport.ClientOption{
+		httptransport.SetClient(o.httpClient()),
+		httptransport.ClientBefore(requestIDToHTTP()),
+	}
 	if tracer != nil {
 		options = append(
 			options,
@@ -71,6 +79,104 @@ func NewHTTPClient(instance string, tracer stdopentracing.Tracer, logger log.Log
 			decodeHTTPDeleteUserStatus,
 			options...,
 		).Endpoint(),
+		GetRolesEndpoint: httptransport.NewClient(
+			"GET",
+			copyURL(u, "/user/{id}/roles"),
+			encodeHTTPGetRolesGetRolesRequest,
+			decodeHTTPGetRolesGetRolesResponse,
+			options...,
+		).Endpoint(),
+		AssignRoleEndpoint: httptransport.NewClient(
+			"PUT",
+			copyURL(u, "/user/{id}/roles/{role}"),
+			encodeHTTPRoleRequest("AssignRole"),
+			decodeHTTPRoleStatus,
+			options...,
+		).Endpoint(),
+		RevokeRoleEndpoint: httptransport.NewClient(
+			"DELETE",
+			copyURL(u, "/user/{id}/roles/{role}"),
+			encodeHTTPRoleRequest("RevokeRole"),
+			decodeHTTPRoleStatus,
+			options...,
+		).Endpoint(),
+		CreateAPIKeyEndpoint: httptransport.NewClient(
+			"POST",
+			copyURL(u, "/user/apikeys"),
+			encodeHTTPCreateAPIKeyRequest,
+			decodeHTTPCreateAPIKeyResponse,
+			options...,
+		).Endpoint(),
+		ListAPIKeysEndpoint: httptransport.NewClient(
+			"GET",
+			copyURL(u, "/user/apikeys"),
+			httptransport.EncodeJSONRequest,
+			decodeHTTPListAPIKeysResponse,
+			options...,
+		).Endpoint(),
+		RevokeAPIKeyEndpoint: httptransport.NewClient(
+			"DELETE",
+			copyURL(u, "/user/apikeys/{id}"),
+			encodeHTTPRevokeAPIKeyRequest,
+			decodeHTTPRoleStatus,
+			options...,
+		).Endpoint(),
+		RotateAPIKeyEndpoint: httptransport.NewClient(
+			"POST",
+			copyURL(u, "/user/apikeys/{id}/rotate"),
+			encodeHTTPRotateAPIKeyRequest,
+			decodeHTTPCreateAPIKeyResponse,
+			options...,
+		).Endpoint(),
+		SendVerificationEmailEndpoint: httptransport.NewClient(
+			"POST",
+			copyURL(u, "/user/{id}/verify-email/send"),
+			encodeHTTPSendVerificationEmailRequest,
+			decodeHTTPRoleStatus,
+			options...,
+		).Endpoint(),
+		VerifyEmailEndpoint: httptransport.NewClient(
+			"POST",
+			copyURL(u, "/user/verify-email"),
+			httptransport.EncodeJSONRequest,
+			decodeHTTPRoleStatus,
+			options...,
+		).Endpoint(),
+		ChangePasswordEndpoint: httptransport.NewClient(
+			"POST",
+			copyURL(u, "/user/{id}/password"),
+			encodeHTTPChangePasswordRequest,
+			decodeHTTPRoleStatus,
+			options...,
+		).Endpoint(),
+		RequestPasswordResetEndpoint: httptransport.NewClient(
+			"POST",
+			copyURL(u, "/user/password-reset"),
+			httptransport.EncodeJSONRequest,
+			decodeHTTPRoleStatus,
+			options...,
+		).Endpoint(),
+		ResetPasswordEndpoint: httptransport.NewClient(
+			"POST",
+			copyURL(u, "/user/password-reset/confirm"),
+			httptransport.EncodeJSONRequest,
+			decodeHTTPRoleStatus,
+			options...,
+		).Endpoint(),
+		UnlockUserEndpoint: httptransport.NewClient(
+			"POST",
+			copyURL(u, "/user/{id}/unlock"),
+			encodeHTTPUnlockUserRequest,
+			decodeHTTPRoleStatus,
+			options...,
+		).Endpoint(),
+		UnlockIPEndpoint: httptransport.NewClient(
+			"POST",
+			copyURL(u, "/user/unlock-ip"),
+			httptransport.EncodeJSONRequest,
+			decodeHTTPRoleStatus,
+			options...,
+		).Endpoint(),
 	}, nil
 }
 
@@ -147,6 +253,149 @@ func encodeHTTPDeleteUserDeleteUserRequest(_ context.Context, r *http.Request, r
 	return nil
 }
 
+func encodeHTTPGetRolesGetRolesRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(*GetRolesRequest)
+	rout := mux.NewRouter()
+	rout.Path(r.URL.Path).Name("GetRoles")
+
+	url, err := rout.Get("GetRoles").URL(
+		"id", fmt.Sprint(req.Id),
+	)
+	if err != nil {
+		return err
+	}
+
+	r.URL.Path = url.String()
+
+	return nil
+}
+
+// encodeHTTPRoleRequest encodes requests of AssignRole and RevokeRole, which are carried by route only
+func encodeHTTPRoleRequest(name string) httptransport.EncodeRequestFunc {
+	return func(_ context.Context, r *http.Request, request interface{}) error {
+		req := request.(*RoleRequest)
+		rout := mux.NewRouter()
+		rout.Path(r.URL.Path).Name(name)
+
+		url, err := rout.Get(name).URL(
+			"id", fmt.Sprint(req.Id),
+			"role", fmt.Sprint(req.Role),
+		)
+		if err != nil {
+			return err
+		}
+
+		r.URL.Path = url.String()
+
+		return nil
+	}
+}
+
+func encodeHTTPCreateAPIKeyRequest(_ context.Context, r *http.Request, request interface{}) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(request); err != nil {
+		return errors.Wrap(err, "encode request body")
+	}
+	r.Body = ioutil.NopCloser(&buf)
+
+	return nil
+}
+
+func encodeHTTPRevokeAPIKeyRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(*RevokeAPIKeyRequest)
+	rout := mux.NewRouter()
+	rout.Path(r.URL.Path).Name("RevokeAPIKey")
+
+	url, err := rout.Get("RevokeAPIKey").URL(
+		"id", fmt.Sprint(req.Id),
+	)
+	if err != nil {
+		return err
+	}
+
+	r.URL.Path = url.String()
+
+	return nil
+}
+
+func encodeHTTPRotateAPIKeyRequest(_ context.Context, r *http.Request, request interface{}) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(request); err != nil {
+		return errors.Wrap(err, "encode request body")
+	}
+	r.Body = ioutil.NopCloser(&buf)
+	req := request.(*RotateAPIKeyRequest)
+	rout := mux.NewRouter()
+	rout.Path(r.URL.Path).Name("RotateAPIKey")
+
+	url, err := rout.Get("RotateAPIKey").URL(
+		"id", fmt.Sprint(req.Id),
+	)
+	if err != nil {
+		return err
+	}
+
+	r.URL.Path = url.String()
+
+	return nil
+}
+
+func encodeHTTPSendVerificationEmailRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(*SendVerificationEmailRequest)
+	rout := mux.NewRouter()
+	rout.Path(r.URL.Path).Name("SendVerificationEmail")
+
+	url, err := rout.Get("SendVerificationEmail").URL(
+		"id", fmt.Sprint(req.Id),
+	)
+	if err != nil {
+		return err
+	}
+
+	r.URL.Path = url.String()
+
+	return nil
+}
+
+func encodeHTTPUnlockUserRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(*UnlockUserRequest)
+	rout := mux.NewRouter()
+	rout.Path(r.URL.Path).Name("UnlockUser")
+
+	url, err := rout.Get("UnlockUser").URL(
+		"id", fmt.Sprint(req.Id),
+	)
+	if err != nil {
+		return err
+	}
+
+	r.URL.Path = url.String()
+
+	return nil
+}
+
+func encodeHTTPChangePasswordRequest(_ context.Context, r *http.Request, request interface{}) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(request); err != nil {
+		return errors.Wrap(err, "encode request body")
+	}
+	r.Body = ioutil.NopCloser(&buf)
+	req := request.(*ChangePasswordRequest)
+	rout := mux.NewRouter()
+	rout.Path(r.URL.Path).Name("ChangePassword")
+
+	url, err := rout.Get("ChangePassword").URL(
+		"id", fmt.Sprint(req.Id),
+	)
+	if err != nil {
+		return err
+	}
+
+	r.URL.Path = url.String()
+
+	return nil
+}
+
 func decodeHTTPCreateUserCreateUserResponse(_ context.Context, r *http.Response) (interface{}, error) {
 	if r.StatusCode != http.StatusOK {
 		return nil, errors.New(r.Status)
@@ -190,3 +439,47 @@ func decodeHTTPDeleteUserStatus(_ context.Context, r *http.Response) (interface{
 	}
 	return request, nil
 }
+
+func decodeHTTPGetRolesGetRolesResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		return nil, errors.New(r.Status)
+	}
+	var request GetRolesResponse
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, errors.Wrap(err, "decode request body")
+	}
+	return request, nil
+}
+
+func decodeHTTPRoleStatus(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		return nil, errors.New(r.Status)
+	}
+	var request Status
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, errors.Wrap(err, "decode request body")
+	}
+	return request, nil
+}
+
+func decodeHTTPCreateAPIKeyResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		return nil, errors.New(r.Status)
+	}
+	var request CreateAPIKeyResponse
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, errors.Wrap(err, "decode request body")
+	}
+	return request, nil
+}
+
+func decodeHTTPListAPIKeysResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		return nil, errors.New(r.Status)
+	}
+	var request ListAPIKeysResponse
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, errors.Wrap(err, "decode request body")
+	}
+	return request, nil
+}
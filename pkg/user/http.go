@@ -3,6 +3,7 @@ package user
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 
 	"github.com/go-kit/kit/log"
@@ -11,17 +12,21 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/gorilla/schema"
 	"github.com/nakiner/faceit/tools/logging"
+	"github.com/nakiner/faceit/tools/metrics"
+	"github.com/nakiner/faceit/tools/requestid"
 	"github.com/nakiner/faceit/tools/tracing"
 	stdopentracing "github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 )
 
-func MakeHTTPHandler(ctx context.Context, s Service) http.Handler {
+// MakeHTTPHandler exposes s over HTTP, endpoints of methods are wrapped with mws.
+func MakeHTTPHandler(ctx context.Context, s Service, mws ...EndpointMiddleware) http.Handler {
 	logger := logging.FromContext(ctx)
 	logger = log.With(logger, "http handler", "user")
 	tracer := tracing.FromContext(ctx)
 
 	r := mux.NewRouter()
+	r.Use(metrics.Route)
 
 	options := []httptransport.ServerOption{
 		httptransport.ServerErrorEncoder(encodeError),
@@ -32,33 +37,131 @@ func MakeHTTPHandler(ctx context.Context, s Service) http.Handler {
 	}
 
 	r.Methods("POST").Path("/user").Handler(httptransport.NewServer(
-		makeCreateUserEndpoint(s),
+		chain("CreateUser", makeCreateUserEndpoint(s), mws),
 		decodePOSTCreateUserRequest,
 		encodeCreateUserResponse,
 		options...,
 	))
 
 	r.Methods("GET").Path("/user").Handler(httptransport.NewServer(
-		makeGetUsersEndpoint(s),
+		chain("GetUsers", makeGetUsersEndpoint(s), mws),
 		decodeGETGetUsersRequest,
 		encodeGetUsersResponse,
 		options...,
 	))
 
 	r.Methods("PUT").Path("/user/{id}").Handler(httptransport.NewServer(
-		makeUpdateUserEndpoint(s),
+		chain("UpdateUser", makeUpdateUserEndpoint(s), mws),
 		decodePUTUser,
 		encodeStatus,
 		options...,
 	))
 
 	r.Methods("DELETE").Path("/user/{id}").Handler(httptransport.NewServer(
-		makeDeleteUserEndpoint(s),
+		chain("DeleteUser", makeDeleteUserEndpoint(s), mws),
 		decodeDELETEDeleteUserRequest,
 		encodeStatus,
 		options...,
 	))
 
+	r.Methods("GET").Path("/user/{id}/roles").Handler(httptransport.NewServer(
+		chain("GetRoles", makeGetRolesEndpoint(s), mws),
+		decodeGETGetRolesRequest,
+		encodeGetRolesResponse,
+		options...,
+	))
+
+	r.Methods("PUT").Path("/user/{id}/roles/{role}").Handler(httptransport.NewServer(
+		chain("AssignRole", makeAssignRoleEndpoint(s), mws),
+		decodeRoleRequest,
+		encodeStatus,
+		options...,
+	))
+
+	r.Methods("DELETE").Path("/user/{id}/roles/{role}").Handler(httptransport.NewServer(
+		chain("RevokeRole", makeRevokeRoleEndpoint(s), mws),
+		decodeRoleRequest,
+		encodeStatus,
+		options...,
+	))
+
+	r.Methods("POST").Path("/user/apikeys").Handler(httptransport.NewServer(
+		chain("CreateAPIKey", makeCreateAPIKeyEndpoint(s), mws),
+		decodePOSTCreateAPIKeyRequest,
+		encodeCreateAPIKeyResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/user/apikeys").Handler(httptransport.NewServer(
+		chain("ListAPIKeys", makeListAPIKeysEndpoint(s), mws),
+		decodeGETListAPIKeysRequest,
+		encodeListAPIKeysResponse,
+		options...,
+	))
+
+	r.Methods("DELETE").Path("/user/apikeys/{id}").Handler(httptransport.NewServer(
+		chain("RevokeAPIKey", makeRevokeAPIKeyEndpoint(s), mws),
+		decodeDELETERevokeAPIKeyRequest,
+		encodeStatus,
+		options...,
+	))
+
+	r.Methods("POST").Path("/user/apikeys/{id}/rotate").Handler(httptransport.NewServer(
+		chain("RotateAPIKey", makeRotateAPIKeyEndpoint(s), mws),
+		decodePOSTRotateAPIKeyRequest,
+		encodeCreateAPIKeyResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/user/verify-email").Handler(httptransport.NewServer(
+		chain("VerifyEmail", makeVerifyEmailEndpoint(s), mws),
+		decodePOSTVerifyEmailRequest,
+		encodeStatus,
+		options...,
+	))
+
+	r.Methods("POST").Path("/user/password-reset").Handler(httptransport.NewServer(
+		chain("RequestPasswordReset", makeRequestPasswordResetEndpoint(s), mws),
+		decodePOSTRequestPasswordResetRequest,
+		encodeStatus,
+		options...,
+	))
+
+	r.Methods("POST").Path("/user/password-reset/confirm").Handler(httptransport.NewServer(
+		chain("ResetPassword", makeResetPasswordEndpoint(s), mws),
+		decodePOSTResetPasswordRequest,
+		encodeStatus,
+		options...,
+	))
+
+	r.Methods("POST").Path("/user/unlock-ip").Handler(httptransport.NewServer(
+		chain("UnlockIP", makeUnlockIPEndpoint(s), mws),
+		decodePOSTUnlockIPRequest,
+		encodeStatus,
+		options...,
+	))
+
+	r.Methods("POST").Path("/user/{id}/password").Handler(httptransport.NewServer(
+		chain("ChangePassword", makeChangePasswordEndpoint(s), mws),
+		decodePOSTChangePasswordRequest,
+		encodeStatus,
+		options...,
+	))
+
+	r.Methods("POST").Path("/user/{id}/verify-email/send").Handler(httptransport.NewServer(
+		chain("SendVerificationEmail", makeSendVerificationEmailEndpoint(s), mws),
+		decodePOSTSendVerificationEmailRequest,
+		encodeStatus,
+		options...,
+	))
+
+	r.Methods("POST").Path("/user/{id}/unlock").Handler(httptransport.NewServer(
+		chain("UnlockUser", makeUnlockUserEndpoint(s), mws),
+		decodePOSTUnlockUserRequest,
+		encodeStatus,
+		options...,
+	))
+
 	return accessControl(r)
 }
 
@@ -161,6 +264,231 @@ func decodePUTUser(_ context.Context, r *http.Request) (interface{}, error) {
 	return request, nil
 }
 
+func decodeGETGetRolesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request GetRolesRequest
+	vars := mux.Vars(r)
+
+	{
+		id, ok := vars["id"]
+		if !ok {
+			return nil, errors.WithStack(errBadRoute)
+		}
+		request.Id = id
+	}
+
+	{
+		if err := validate(request); err != nil {
+			return nil, errors.Wrap(ErrInvalidRequest, err.Error())
+		}
+	}
+	return request, nil
+}
+
+// decodeRoleRequest decodes requests of AssignRole and RevokeRole, which are identified by route only
+func decodeRoleRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request RoleRequest
+	vars := mux.Vars(r)
+
+	{
+		id, ok := vars["id"]
+		if !ok {
+			return nil, errors.WithStack(errBadRoute)
+		}
+		request.Id = id
+
+		role, ok := vars["role"]
+		if !ok {
+			return nil, errors.WithStack(errBadRoute)
+		}
+		request.Role = role
+	}
+
+	{
+		if err := validate(request); err != nil {
+			return nil, errors.Wrap(ErrInvalidRequest, err.Error())
+		}
+	}
+	return request, nil
+}
+
+func decodePOSTCreateAPIKeyRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, errors.Wrap(err, "decode request body")
+	}
+	{
+		if err := validate(request); err != nil {
+			return nil, errors.Wrap(ErrInvalidRequest, err.Error())
+		}
+	}
+	return request, nil
+}
+
+func decodeGETListAPIKeysRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return ListAPIKeysRequest{}, nil
+}
+
+func decodeDELETERevokeAPIKeyRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request RevokeAPIKeyRequest
+	vars := mux.Vars(r)
+
+	{
+		id, ok := vars["id"]
+		if !ok {
+			return nil, errors.WithStack(errBadRoute)
+		}
+		request.Id = id
+	}
+
+	{
+		if err := validate(request); err != nil {
+			return nil, errors.Wrap(ErrInvalidRequest, err.Error())
+		}
+	}
+	return request, nil
+}
+
+// decodePOSTRotateAPIKeyRequest decodes optional body with grace period
+func decodePOSTRotateAPIKeyRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request RotateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "decode request body")
+	}
+
+	vars := mux.Vars(r)
+
+	{
+		id, ok := vars["id"]
+		if !ok {
+			return nil, errors.WithStack(errBadRoute)
+		}
+		request.Id = id
+	}
+
+	{
+		if err := validate(request); err != nil {
+			return nil, errors.Wrap(ErrInvalidRequest, err.Error())
+		}
+	}
+	return request, nil
+}
+
+func decodePOSTSendVerificationEmailRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request SendVerificationEmailRequest
+	vars := mux.Vars(r)
+
+	{
+		id, ok := vars["id"]
+		if !ok {
+			return nil, errors.WithStack(errBadRoute)
+		}
+		request.Id = id
+	}
+
+	{
+		if err := validate(request); err != nil {
+			return nil, errors.Wrap(ErrInvalidRequest, err.Error())
+		}
+	}
+	return request, nil
+}
+
+func decodePOSTUnlockUserRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request UnlockUserRequest
+	vars := mux.Vars(r)
+
+	{
+		id, ok := vars["id"]
+		if !ok {
+			return nil, errors.WithStack(errBadRoute)
+		}
+		request.Id = id
+	}
+
+	{
+		if err := validate(request); err != nil {
+			return nil, errors.Wrap(ErrInvalidRequest, err.Error())
+		}
+	}
+	return request, nil
+}
+
+func decodePOSTVerifyEmailRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, errors.Wrap(err, "decode request body")
+	}
+	{
+		if err := validate(request); err != nil {
+			return nil, errors.Wrap(ErrInvalidRequest, err.Error())
+		}
+	}
+	return request, nil
+}
+
+func decodePOSTRequestPasswordResetRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request RequestPasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, errors.Wrap(err, "decode request body")
+	}
+	{
+		if err := validate(request); err != nil {
+			return nil, errors.Wrap(ErrInvalidRequest, err.Error())
+		}
+	}
+	return request, nil
+}
+
+func decodePOSTResetPasswordRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, errors.Wrap(err, "decode request body")
+	}
+	{
+		if err := validate(request); err != nil {
+			return nil, errors.Wrap(ErrInvalidRequest, err.Error())
+		}
+	}
+	return request, nil
+}
+
+func decodePOSTUnlockIPRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request UnlockIPRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, errors.Wrap(err, "decode request body")
+	}
+	{
+		if err := validate(request); err != nil {
+			return nil, errors.Wrap(ErrInvalidRequest, err.Error())
+		}
+	}
+	return request, nil
+}
+
+func decodePOSTChangePasswordRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "decode request body")
+	}
+
+	vars := mux.Vars(r)
+
+	{
+		id, ok := vars["id"]
+		if !ok {
+			return nil, errors.WithStack(errBadRoute)
+		}
+		request.Id = id
+	}
+
+	{
+		if err := validate(request); err != nil {
+			return nil, errors.Wrap(ErrInvalidRequest, err.Error())
+		}
+	}
+	return request, nil
+}
+
 func encodeCreateUserResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	if e, ok := response.(errorer); ok && e.error() != nil {
 		encodeError(ctx, e.error(), w)
@@ -179,6 +507,35 @@ func encodeGetUsersResponse(ctx context.Context, w http.ResponseWriter, response
 	return json.NewEncoder(w).Encode(response)
 }
 
+func encodeGetRolesResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if e, ok := response.(errorer); ok && e.error() != nil {
+		encodeError(ctx, e.error(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(response)
+}
+
+func encodeCreateAPIKeyResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if e, ok := response.(errorer); ok && e.error() != nil {
+		encodeError(ctx, e.error(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// key is shown only once, so it must not be cached on the way
+	w.Header().Set("Cache-Control", "no-store")
+	return json.NewEncoder(w).Encode(response)
+}
+
+func encodeListAPIKeysResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if e, ok := response.(errorer); ok && e.error() != nil {
+		encodeError(ctx, e.error(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(response)
+}
+
 func encodeStatus(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	if e, ok := response.(errorer); ok && e.error() != nil {
 		encodeError(ctx, e.error(), w)
@@ -193,14 +550,22 @@ type errorer interface {
 }
 
 // encodeError handles error from business-layer.
-func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
 	w.Header().Set("X-Esp-Error", err.Error())
 	w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
 
 	w.WriteHeader(getHTTPStatusCode(err))
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	body := map[string]interface{}{
 		"error": err.Error(),
-	})
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		body["request_id"] = id
+	}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		body["violations"] = verr.Violations
+	}
+	json.NewEncoder(w).Encode(body)
 }
 
 // accessControl is CORS middleware.
@@ -20,4 +20,46 @@ type Service interface {
 
 	// DeleteUser Delete existing user
 	DeleteUser(context.Context, *DeleteUserRequest) (*Status, error)
+
+	// GetRoles Get roles assigned to user
+	GetRoles(context.Context, *GetRolesRequest) (*GetRolesResponse, error)
+
+	// AssignRole Assign role to user
+	AssignRole(context.Context, *RoleRequest) (*Status, error)
+
+	// RevokeRole Revoke role of user
+	RevokeRole(context.Context, *RoleRequest) (*Status, error)
+
+	// CreateAPIKey Create API key of service caller, key is returned only once
+	CreateAPIKey(context.Context, *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error)
+
+	// ListAPIKeys List API keys of tenant
+	ListAPIKeys(context.Context, *ListAPIKeysRequest) (*ListAPIKeysResponse, error)
+
+	// RevokeAPIKey Revoke API key
+	RevokeAPIKey(context.Context, *RevokeAPIKeyRequest) (*Status, error)
+
+	// RotateAPIKey Replace API key with a new one, old key stays valid for grace period
+	RotateAPIKey(context.Context, *RotateAPIKeyRequest) (*CreateAPIKeyResponse, error)
+
+	// SendVerificationEmail Send email with verification link to user
+	SendVerificationEmail(context.Context, *SendVerificationEmailRequest) (*Status, error)
+
+	// VerifyEmail Mark email of user verified with token from verification email
+	VerifyEmail(context.Context, *VerifyEmailRequest) (*Status, error)
+
+	// ChangePassword Change password of user, current password is required
+	ChangePassword(context.Context, *ChangePasswordRequest) (*Status, error)
+
+	// RequestPasswordReset Send email with password reset link to user with email
+	RequestPasswordReset(context.Context, *RequestPasswordResetRequest) (*Status, error)
+
+	// ResetPassword Set new password with token from password reset email
+	ResetPassword(context.Context, *ResetPasswordRequest) (*Status, error)
+
+	// UnlockUser Unlock user locked after failed password checks
+	UnlockUser(context.Context, *UnlockUserRequest) (*Status, error)
+
+	// UnlockIP Unlock client ip locked after failed password checks
+	UnlockIP(context.Context, *UnlockIPRequest) (*Status, error)
 }
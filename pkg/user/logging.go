@@ -7,6 +7,7 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/nakiner/faceit/tools/logging"
+	"github.com/nakiner/faceit/tools/requestid"
 )
 
 // NewLoggingService returns a new instance of a logging Service.
@@ -133,8 +134,347 @@ func (s *loggingService) DeleteUser(ctx context.Context, req *DeleteUserRequest)
 	return s.Service.DeleteUser(ctx, req)
 }
 
+func (s *loggingService) GetRoles(ctx context.Context, req *GetRolesRequest) (resp *GetRolesResponse, err error) {
+	defer func(begin time.Time) {
+		m := getInfoFromContext(ctx)
+		m = append(m,
+			"code", getHTTPStatusCode(err),
+			"method", "GetRoles",
+			"took", time.Since(begin),
+		)
+
+		m = append(m, s.getLog(req, resp)...)
+
+		if getHTTPStatusCode(err) == 404 {
+			m = append(m, "msg", err)
+			level.Warn(s.logger).Log(m...)
+		} else if err != nil {
+			m = append(m, "err", err)
+			level.Error(s.logger).Log(m...)
+		} else {
+			level.Info(s.logger).Log(m...)
+		}
+	}(time.Now())
+	return s.Service.GetRoles(ctx, req)
+}
+
+func (s *loggingService) AssignRole(ctx context.Context, req *RoleRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		m := getInfoFromContext(ctx)
+		m = append(m,
+			"code", getHTTPStatusCode(err),
+			"method", "AssignRole",
+			"took", time.Since(begin),
+		)
+
+		m = append(m, s.getLog(req, resp)...)
+
+		if getHTTPStatusCode(err) == 404 {
+			m = append(m, "msg", err)
+			level.Warn(s.logger).Log(m...)
+		} else if err != nil {
+			m = append(m, "err", err)
+			level.Error(s.logger).Log(m...)
+		} else {
+			level.Info(s.logger).Log(m...)
+		}
+	}(time.Now())
+	return s.Service.AssignRole(ctx, req)
+}
+
+func (s *loggingService) RevokeRole(ctx context.Context, req *RoleRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		m := getInfoFromContext(ctx)
+		m = append(m,
+			"code", getHTTPStatusCode(err),
+			"method", "RevokeRole",
+			"took", time.Since(begin),
+		)
+
+		m = append(m, s.getLog(req, resp)...)
+
+		if getHTTPStatusCode(err) == 404 {
+			m = append(m, "msg", err)
+			level.Warn(s.logger).Log(m...)
+		} else if err != nil {
+			m = append(m, "err", err)
+			level.Error(s.logger).Log(m...)
+		} else {
+			level.Info(s.logger).Log(m...)
+		}
+	}(time.Now())
+	return s.Service.RevokeRole(ctx, req)
+}
+
+func (s *loggingService) CreateAPIKey(ctx context.Context, req *CreateAPIKeyRequest) (resp *CreateAPIKeyResponse, err error) {
+	defer func(begin time.Time) {
+		m := getInfoFromContext(ctx)
+		m = append(m,
+			"code", getHTTPStatusCode(err),
+			"method", "CreateAPIKey",
+			"took", time.Since(begin),
+		)
+
+		m = append(m, s.getLog(req, resp)...)
+
+		if getHTTPStatusCode(err) == 404 {
+			m = append(m, "msg", err)
+			level.Warn(s.logger).Log(m...)
+		} else if err != nil {
+			m = append(m, "err", err)
+			level.Error(s.logger).Log(m...)
+		} else {
+			level.Info(s.logger).Log(m...)
+		}
+	}(time.Now())
+	return s.Service.CreateAPIKey(ctx, req)
+}
+
+func (s *loggingService) ListAPIKeys(ctx context.Context, req *ListAPIKeysRequest) (resp *ListAPIKeysResponse, err error) {
+	defer func(begin time.Time) {
+		m := getInfoFromContext(ctx)
+		m = append(m,
+			"code", getHTTPStatusCode(err),
+			"method", "ListAPIKeys",
+			"took", time.Since(begin),
+		)
+
+		m = append(m, s.getLog(req, resp)...)
+
+		if getHTTPStatusCode(err) == 404 {
+			m = append(m, "msg", err)
+			level.Warn(s.logger).Log(m...)
+		} else if err != nil {
+			m = append(m, "err", err)
+			level.Error(s.logger).Log(m...)
+		} else {
+			level.Info(s.logger).Log(m...)
+		}
+	}(time.Now())
+	return s.Service.ListAPIKeys(ctx, req)
+}
+
+func (s *loggingService) RevokeAPIKey(ctx context.Context, req *RevokeAPIKeyRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		m := getInfoFromContext(ctx)
+		m = append(m,
+			"code", getHTTPStatusCode(err),
+			"method", "RevokeAPIKey",
+			"took", time.Since(begin),
+		)
+
+		m = append(m, s.getLog(req, resp)...)
+
+		if getHTTPStatusCode(err) == 404 {
+			m = append(m, "msg", err)
+			level.Warn(s.logger).Log(m...)
+		} else if err != nil {
+			m = append(m, "err", err)
+			level.Error(s.logger).Log(m...)
+		} else {
+			level.Info(s.logger).Log(m...)
+		}
+	}(time.Now())
+	return s.Service.RevokeAPIKey(ctx, req)
+}
+
+func (s *loggingService) RotateAPIKey(ctx context.Context, req *RotateAPIKeyRequest) (resp *CreateAPIKeyResponse, err error) {
+	defer func(begin time.Time) {
+		m := getInfoFromContext(ctx)
+		m = append(m,
+			"code", getHTTPStatusCode(err),
+			"method", "RotateAPIKey",
+			"took", time.Since(begin),
+		)
+
+		m = append(m, s.getLog(req, resp)...)
+
+		if getHTTPStatusCode(err) == 404 {
+			m = append(m, "msg", err)
+			level.Warn(s.logger).Log(m...)
+		} else if err != nil {
+			m = append(m, "err", err)
+			level.Error(s.logger).Log(m...)
+		} else {
+			level.Info(s.logger).Log(m...)
+		}
+	}(time.Now())
+	return s.Service.RotateAPIKey(ctx, req)
+}
+
+func (s *loggingService) SendVerificationEmail(ctx context.Context, req *SendVerificationEmailRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		m := getInfoFromContext(ctx)
+		m = append(m,
+			"code", getHTTPStatusCode(err),
+			"method", "SendVerificationEmail",
+			"took", time.Since(begin),
+		)
+
+		m = append(m, s.getLog(req, resp)...)
+
+		if getHTTPStatusCode(err) == 404 {
+			m = append(m, "msg", err)
+			level.Warn(s.logger).Log(m...)
+		} else if err != nil {
+			m = append(m, "err", err)
+			level.Error(s.logger).Log(m...)
+		} else {
+			level.Info(s.logger).Log(m...)
+		}
+	}(time.Now())
+	return s.Service.SendVerificationEmail(ctx, req)
+}
+
+func (s *loggingService) VerifyEmail(ctx context.Context, req *VerifyEmailRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		m := getInfoFromContext(ctx)
+		m = append(m,
+			"code", getHTTPStatusCode(err),
+			"method", "VerifyEmail",
+			"took", time.Since(begin),
+		)
+
+		m = append(m, s.getLog(req, resp)...)
+
+		if getHTTPStatusCode(err) == 404 {
+			m = append(m, "msg", err)
+			level.Warn(s.logger).Log(m...)
+		} else if err != nil {
+			m = append(m, "err", err)
+			level.Error(s.logger).Log(m...)
+		} else {
+			level.Info(s.logger).Log(m...)
+		}
+	}(time.Now())
+	return s.Service.VerifyEmail(ctx, req)
+}
+
+func (s *loggingService) ChangePassword(ctx context.Context, req *ChangePasswordRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		m := getInfoFromContext(ctx)
+		m = append(m,
+			"code", getHTTPStatusCode(err),
+			"method", "ChangePassword",
+			"took", time.Since(begin),
+		)
+
+		m = append(m, s.getLog(req, resp)...)
+
+		if getHTTPStatusCode(err) == 404 {
+			m = append(m, "msg", err)
+			level.Warn(s.logger).Log(m...)
+		} else if err != nil {
+			m = append(m, "err", err)
+			level.Error(s.logger).Log(m...)
+		} else {
+			level.Info(s.logger).Log(m...)
+		}
+	}(time.Now())
+	return s.Service.ChangePassword(ctx, req)
+}
+
+func (s *loggingService) RequestPasswordReset(ctx context.Context, req *RequestPasswordResetRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		m := getInfoFromContext(ctx)
+		m = append(m,
+			"code", getHTTPStatusCode(err),
+			"method", "RequestPasswordReset",
+			"took", time.Since(begin),
+		)
+
+		m = append(m, s.getLog(req, resp)...)
+
+		if getHTTPStatusCode(err) == 404 {
+			m = append(m, "msg", err)
+			level.Warn(s.logger).Log(m...)
+		} else if err != nil {
+			m = append(m, "err", err)
+			level.Error(s.logger).Log(m...)
+		} else {
+			level.Info(s.logger).Log(m...)
+		}
+	}(time.Now())
+	return s.Service.RequestPasswordReset(ctx, req)
+}
+
+func (s *loggingService) ResetPassword(ctx context.Context, req *ResetPasswordRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		m := getInfoFromContext(ctx)
+		m = append(m,
+			"code", getHTTPStatusCode(err),
+			"method", "ResetPassword",
+			"took", time.Since(begin),
+		)
+
+		m = append(m, s.getLog(req, resp)...)
+
+		if getHTTPStatusCode(err) == 404 {
+			m = append(m, "msg", err)
+			level.Warn(s.logger).Log(m...)
+		} else if err != nil {
+			m = append(m, "err", err)
+			level.Error(s.logger).Log(m...)
+		} else {
+			level.Info(s.logger).Log(m...)
+		}
+	}(time.Now())
+	return s.Service.ResetPassword(ctx, req)
+}
+
+func (s *loggingService) UnlockUser(ctx context.Context, req *UnlockUserRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		m := getInfoFromContext(ctx)
+		m = append(m,
+			"code", getHTTPStatusCode(err),
+			"method", "UnlockUser",
+			"took", time.Since(begin),
+		)
+
+		m = append(m, s.getLog(req, resp)...)
+
+		if getHTTPStatusCode(err) == 404 {
+			m = append(m, "msg", err)
+			level.Warn(s.logger).Log(m...)
+		} else if err != nil {
+			m = append(m, "err", err)
+			level.Error(s.logger).Log(m...)
+		} else {
+			level.Info(s.logger).Log(m...)
+		}
+	}(time.Now())
+	return s.Service.UnlockUser(ctx, req)
+}
+
+func (s *loggingService) UnlockIP(ctx context.Context, req *UnlockIPRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		m := getInfoFromContext(ctx)
+		m = append(m,
+			"code", getHTTPStatusCode(err),
+			"method", "UnlockIP",
+			"took", time.Since(begin),
+		)
+
+		m = append(m, s.getLog(req, resp)...)
+
+		if getHTTPStatusCode(err) == 404 {
+			m = append(m, "msg", err)
+			level.Warn(s.logger).Log(m...)
+		} else if err != nil {
+			m = append(m, "err", err)
+			level.Error(s.logger).Log(m...)
+		} else {
+			level.Info(s.logger).Log(m...)
+		}
+	}(time.Now())
+	return s.Service.UnlockIP(ctx, req)
+}
+
 func getInfoFromContext(ctx context.Context) []interface{} {
 	m := make([]interface{}, 0)
+	if id := requestid.FromContext(ctx); id != "" {
+		m = append(m, requestid.LogKey, id)
+	}
 	{
 		val := ctx.Value(ContextGRPCKey{})
 		if _, ok := val.(GRPCInfo); ok {
@@ -60,3 +60,143 @@ func (s *metricService) DeleteUser(ctx context.Context, req *DeleteUserRequest)
 	}(time.Now())
 	return s.Service.DeleteUser(ctx, req)
 }
+
+func (s *metricService) GetRoles(ctx context.Context, req *GetRolesRequest) (resp *GetRolesResponse, err error) {
+	defer func(begin time.Time) {
+		go func() {
+			s.requestCount.With("service", "user", "handler", "GetRoles", "code", strconv.Itoa(getHTTPStatusCode(err))).Add(1)
+			s.requestLatency.With("service", "user", "handler", "GetRoles", "code", strconv.Itoa(getHTTPStatusCode(err))).Observe(time.Since(begin).Seconds())
+		}()
+	}(time.Now())
+	return s.Service.GetRoles(ctx, req)
+}
+
+func (s *metricService) AssignRole(ctx context.Context, req *RoleRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		go func() {
+			s.requestCount.With("service", "user", "handler", "AssignRole", "code", strconv.Itoa(getHTTPStatusCode(err))).Add(1)
+			s.requestLatency.With("service", "user", "handler", "AssignRole", "code", strconv.Itoa(getHTTPStatusCode(err))).Observe(time.Since(begin).Seconds())
+		}()
+	}(time.Now())
+	return s.Service.AssignRole(ctx, req)
+}
+
+func (s *metricService) RevokeRole(ctx context.Context, req *RoleRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		go func() {
+			s.requestCount.With("service", "user", "handler", "RevokeRole", "code", strconv.Itoa(getHTTPStatusCode(err))).Add(1)
+			s.requestLatency.With("service", "user", "handler", "RevokeRole", "code", strconv.Itoa(getHTTPStatusCode(err))).Observe(time.Since(begin).Seconds())
+		}()
+	}(time.Now())
+	return s.Service.RevokeRole(ctx, req)
+}
+
+func (s *metricService) CreateAPIKey(ctx context.Context, req *CreateAPIKeyRequest) (resp *CreateAPIKeyResponse, err error) {
+	defer func(begin time.Time) {
+		go func() {
+			s.requestCount.With("service", "user", "handler", "CreateAPIKey", "code", strconv.Itoa(getHTTPStatusCode(err))).Add(1)
+			s.requestLatency.With("service", "user", "handler", "CreateAPIKey", "code", strconv.Itoa(getHTTPStatusCode(err))).Observe(time.Since(begin).Seconds())
+		}()
+	}(time.Now())
+	return s.Service.CreateAPIKey(ctx, req)
+}
+
+func (s *metricService) ListAPIKeys(ctx context.Context, req *ListAPIKeysRequest) (resp *ListAPIKeysResponse, err error) {
+	defer func(begin time.Time) {
+		go func() {
+			s.requestCount.With("service", "user", "handler", "ListAPIKeys", "code", strconv.Itoa(getHTTPStatusCode(err))).Add(1)
+			s.requestLatency.With("service", "user", "handler", "ListAPIKeys", "code", strconv.Itoa(getHTTPStatusCode(err))).Observe(time.Since(begin).Seconds())
+		}()
+	}(time.Now())
+	return s.Service.ListAPIKeys(ctx, req)
+}
+
+func (s *metricService) RevokeAPIKey(ctx context.Context, req *RevokeAPIKeyRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		go func() {
+			s.requestCount.With("service", "user", "handler", "RevokeAPIKey", "code", strconv.Itoa(getHTTPStatusCode(err))).Add(1)
+			s.requestLatency.With("service", "user", "handler", "RevokeAPIKey", "code", strconv.Itoa(getHTTPStatusCode(err))).Observe(time.Since(begin).Seconds())
+		}()
+	}(time.Now())
+	return s.Service.RevokeAPIKey(ctx, req)
+}
+
+func (s *metricService) RotateAPIKey(ctx context.Context, req *RotateAPIKeyRequest) (resp *CreateAPIKeyResponse, err error) {
+	defer func(begin time.Time) {
+		go func() {
+			s.requestCount.With("service", "user", "handler", "RotateAPIKey", "code", strconv.Itoa(getHTTPStatusCode(err))).Add(1)
+			s.requestLatency.With("service", "user", "handler", "RotateAPIKey", "code", strconv.Itoa(getHTTPStatusCode(err))).Observe(time.Since(begin).Seconds())
+		}()
+	}(time.Now())
+	return s.Service.RotateAPIKey(ctx, req)
+}
+
+func (s *metricService) SendVerificationEmail(ctx context.Context, req *SendVerificationEmailRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		go func() {
+			s.requestCount.With("service", "user", "handler", "SendVerificationEmail", "code", strconv.Itoa(getHTTPStatusCode(err))).Add(1)
+			s.requestLatency.With("service", "user", "handler", "SendVerificationEmail", "code", strconv.Itoa(getHTTPStatusCode(err))).Observe(time.Since(begin).Seconds())
+		}()
+	}(time.Now())
+	return s.Service.SendVerificationEmail(ctx, req)
+}
+
+func (s *metricService) VerifyEmail(ctx context.Context, req *VerifyEmailRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		go func() {
+			s.requestCount.With("service", "user", "handler", "VerifyEmail", "code", strconv.Itoa(getHTTPStatusCode(err))).Add(1)
+			s.requestLatency.With("service", "user", "handler", "VerifyEmail", "code", strconv.Itoa(getHTTPStatusCode(err))).Observe(time.Since(begin).Seconds())
+		}()
+	}(time.Now())
+	return s.Service.VerifyEmail(ctx, req)
+}
+
+func (s *metricService) ChangePassword(ctx context.Context, req *ChangePasswordRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		go func() {
+			s.requestCount.With("service", "user", "handler", "ChangePassword", "code", strconv.Itoa(getHTTPStatusCode(err))).Add(1)
+			s.requestLatency.With("service", "user", "handler", "ChangePassword", "code", strconv.Itoa(getHTTPStatusCode(err))).Observe(time.Since(begin).Seconds())
+		}()
+	}(time.Now())
+	return s.Service.ChangePassword(ctx, req)
+}
+
+func (s *metricService) RequestPasswordReset(ctx context.Context, req *RequestPasswordResetRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		go func() {
+			s.requestCount.With("service", "user", "handler", "RequestPasswordReset", "code", strconv.Itoa(getHTTPStatusCode(err))).Add(1)
+			s.requestLatency.With("service", "user", "handler", "RequestPasswordReset", "code", strconv.Itoa(getHTTPStatusCode(err))).Observe(time.Since(begin).Seconds())
+		}()
+	}(time.Now())
+	return s.Service.RequestPasswordReset(ctx, req)
+}
+
+func (s *metricService) ResetPassword(ctx context.Context, req *ResetPasswordRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		go func() {
+			s.requestCount.With("service", "user", "handler", "ResetPassword", "code", strconv.Itoa(getHTTPStatusCode(err))).Add(1)
+			s.requestLatency.With("service", "user", "handler", "ResetPassword", "code", strconv.Itoa(getHTTPStatusCode(err))).Observe(time.Since(begin).Seconds())
+		}()
+	}(time.Now())
+	return s.Service.ResetPassword(ctx, req)
+}
+
+func (s *metricService) UnlockUser(ctx context.Context, req *UnlockUserRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		go func() {
+			s.requestCount.With("service", "user", "handler", "UnlockUser", "code", strconv.Itoa(getHTTPStatusCode(err))).Add(1)
+			s.requestLatency.With("service", "user", "handler", "UnlockUser", "code", strconv.Itoa(getHTTPStatusCode(err))).Observe(time.Since(begin).Seconds())
+		}()
+	}(time.Now())
+	return s.Service.UnlockUser(ctx, req)
+}
+
+func (s *metricService) UnlockIP(ctx context.Context, req *UnlockIPRequest) (resp *Status, err error) {
+	defer func(begin time.Time) {
+		go func() {
+			s.requestCount.With("service", "user", "handler", "UnlockIP", "code", strconv.Itoa(getHTTPStatusCode(err))).Add(1)
+			s.requestLatency.With("service", "user", "handler", "UnlockIP", "code", strconv.Itoa(getHTTPStatusCode(err))).Observe(time.Since(begin).Seconds())
+		}()
+	}(time.Now())
+	return s.Service.UnlockIP(ctx, req)
+}
@@ -0,0 +1,222 @@
+package user
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/nakiner/faceit/tools/audit"
+	"github.com/nakiner/faceit/tools/auth"
+	"github.com/pkg/errors"
+)
+
+// Roles callers may be assigned.
+const (
+	// RolePlayer may read and update own record, every authenticated caller has it.
+	RolePlayer = "player"
+	// RoleSupport may read and update records of any user and unlock users locked after failed password checks.
+	RoleSupport = "support"
+	// RoleService is role of other services, they may create, read and update users.
+	RoleService = "service"
+	// RoleAdmin may do everything, including deleting users and managing roles and API keys.
+	RoleAdmin = "admin"
+)
+
+type permission uint
+
+const (
+	permCreate permission = 1 << iota
+	permReadOwn
+	permReadAny
+	permUpdateOwn
+	permUpdateAny
+	permDelete
+	permManageRoles
+	permManageKeys
+	permUnlock
+
+	permAll = permCreate | permReadOwn | permReadAny | permUpdateOwn | permUpdateAny | permDelete | permManageRoles | permManageKeys | permUnlock
+)
+
+var rolePermissions = map[string]permission{
+	RolePlayer:  permReadOwn | permUpdateOwn,
+	RoleSupport: permReadOwn | permReadAny | permUpdateOwn | permUpdateAny | permUnlock,
+	RoleService: permCreate | permReadOwn | permReadAny | permUpdateOwn | permUpdateAny,
+	RoleAdmin:   permAll,
+}
+
+// publicMethods are allowed to anonymous callers, they are authorized by tokens in request
+var publicMethods = map[string]bool{
+	"VerifyEmail":          true,
+	"RequestPasswordReset": true,
+	"ResetPassword":        true,
+}
+
+func knownRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func (p permission) has(perm permission) bool {
+	return p&perm == perm
+}
+
+// RoleStore returns roles assigned to user, role.Repository is one.
+type RoleStore interface {
+	Get(ctx context.Context, userID string) ([]string, error)
+}
+
+// NewAuthorizer returns EndpointMiddleware allowing calls by permissions of roles of authenticated caller.
+// Roles are taken from credentials, callers without them get roles assigned in store, and every caller
+// is a player. Players may only read and update own record and may not change nickname and country.
+// Anonymous callers and unknown methods are denied, except public methods like email verification
+// and password reset. Passwords may only be changed by their owners.
+// Denials are audited.
+func NewAuthorizer(store RoleStore) EndpointMiddleware {
+	return func(method string, next endpoint.Endpoint) endpoint.Endpoint {
+		if publicMethods[method] {
+			return next
+		}
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			p, ok := auth.FromContext(ctx)
+			if !ok {
+				deny(ctx, method, request, "anonymous caller")
+				return nil, ErrUnauthenticated
+			}
+			perms, err := permissionsOf(ctx, store, p)
+			if err != nil {
+				return nil, errors.Wrap(err, "authorize")
+			}
+			if reason := authorize(method, perms, p.Subject, request); reason != "" {
+				deny(ctx, method, request, reason)
+				return nil, errors.Wrap(ErrForbidden, reason)
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+func permissionsOf(ctx context.Context, store RoleStore, p *auth.Principal) (permission, error) {
+	if p.SuperAdmin {
+		return permAll, nil
+	}
+	roles := p.Roles
+	if len(roles) == 0 && store != nil {
+		var err error
+		if roles, err = store.Get(ctx, p.Subject); err != nil {
+			return 0, errors.Wrap(err, "get roles of caller")
+		}
+	}
+	perms := rolePermissions[RolePlayer]
+	for _, role := range roles {
+		perms |= rolePermissions[role]
+	}
+	return perms, nil
+}
+
+// authorize returns reason of denial of method call, empty when it is allowed
+func authorize(method string, perms permission, subject string, request interface{}) string {
+	switch method {
+	case "CreateUser":
+		if perms.has(permCreate) {
+			return ""
+		}
+		return "creating users is not permitted"
+	case "GetUsers":
+		req, _ := request.(GetUsersRequest)
+		if perms.has(permReadAny) || perms.has(permReadOwn) && req.Id == subject {
+			return ""
+		}
+		return "reading other users is not permitted"
+	case "UpdateUser":
+		req, _ := request.(User)
+		if perms.has(permUpdateAny) {
+			return ""
+		}
+		if !perms.has(permUpdateOwn) || req.Id != subject {
+			return "updating other users is not permitted"
+		}
+		if req.Nickname != "" || req.Country != "" {
+			return "changing nickname and country is not permitted"
+		}
+		return ""
+	case "DeleteUser":
+		if perms.has(permDelete) {
+			return ""
+		}
+		return "deleting users is not permitted"
+	case "GetRoles":
+		req, _ := request.(GetRolesRequest)
+		if perms.has(permManageRoles) || perms.has(permReadAny) || perms.has(permReadOwn) && req.Id == subject {
+			return ""
+		}
+		return "reading roles of other users is not permitted"
+	case "AssignRole", "RevokeRole":
+		if perms.has(permManageRoles) {
+			return ""
+		}
+		return "managing roles is not permitted"
+	case "SendVerificationEmail":
+		req, _ := request.(SendVerificationEmailRequest)
+		if perms.has(permUpdateAny) || perms.has(permUpdateOwn) && req.Id == subject {
+			return ""
+		}
+		return "verifying email of other users is not permitted"
+	case "ChangePassword":
+		req, _ := request.(ChangePasswordRequest)
+		if perms.has(permUpdateOwn) && req.Id == subject {
+			return ""
+		}
+		return "changing password of other users is not permitted"
+	case "CreateAPIKey", "ListAPIKeys", "RevokeAPIKey", "RotateAPIKey":
+		if perms.has(permManageKeys) {
+			return ""
+		}
+		return "managing api keys is not permitted"
+	case "UnlockUser", "UnlockIP":
+		if perms.has(permUnlock) {
+			return ""
+		}
+		return "unlocking is not permitted"
+	default:
+		return "method is not permitted"
+	}
+}
+
+func deny(ctx context.Context, method string, request interface{}, reason string) {
+	audit.Record(ctx, audit.Event{
+		Action:  method,
+		Outcome: audit.OutcomeDenied,
+		Target:  targetOf(request),
+		Reason:  reason,
+	})
+}
+
+// targetOf returns id of user or API key request is applied to
+func targetOf(request interface{}) string {
+	switch req := request.(type) {
+	case GetUsersRequest:
+		return req.Id
+	case User:
+		return req.Id
+	case DeleteUserRequest:
+		return req.Id
+	case GetRolesRequest:
+		return req.Id
+	case RoleRequest:
+		return req.Id + "/" + req.Role
+	case RevokeAPIKeyRequest:
+		return req.Id
+	case RotateAPIKeyRequest:
+		return req.Id
+	case SendVerificationEmailRequest:
+		return req.Id
+	case ChangePasswordRequest:
+		return req.Id
+	case UnlockUserRequest:
+		return req.Id
+	case UnlockIPRequest:
+		return req.Ip
+	default:
+		return ""
+	}
+}
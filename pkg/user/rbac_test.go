@@ -0,0 +1,79 @@
+package user
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nakiner/faceit/internal/repository/role"
+	"github.com/nakiner/faceit/tools/auth"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorizer(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	roles := role.NewMemoryRepository()
+	require.NoError(t, roles.Assign(ctx, "support-1", RoleSupport))
+	require.NoError(t, roles.Assign(ctx, "admin-1", RoleAdmin))
+
+	player := &auth.Principal{Subject: "player-1"}
+	service := &auth.Principal{Subject: "matchmaking", Roles: []string{RoleService}}
+	testCases := []struct {
+		name    string
+		caller  *auth.Principal
+		method  string
+		request interface{}
+		err     error
+	}{
+		{name: "anonymous", method: "GetUsers", request: GetUsersRequest{}, err: ErrUnauthenticated},
+		{name: "player reads self", caller: player, method: "GetUsers", request: GetUsersRequest{Id: "player-1"}},
+		{name: "player reads others", caller: player, method: "GetUsers", request: GetUsersRequest{Country: "UK"}, err: ErrForbidden},
+		{name: "player updates self", caller: player, method: "UpdateUser", request: User{Id: "player-1", FirstName: "Alice"}},
+		{name: "player changes nickname", caller: player, method: "UpdateUser", request: User{Id: "player-1", Nickname: "alice"}, err: ErrForbidden},
+		{name: "player updates others", caller: player, method: "UpdateUser", request: User{Id: "player-2"}, err: ErrForbidden},
+		{name: "player creates", caller: player, method: "CreateUser", request: CreateUserRequest{}, err: ErrForbidden},
+		{name: "player reads own roles", caller: player, method: "GetRoles", request: GetRolesRequest{Id: "player-1"}},
+		{name: "player assigns role", caller: player, method: "AssignRole", request: RoleRequest{Id: "player-1", Role: RoleAdmin}, err: ErrForbidden},
+		{name: "support changes nickname", caller: &auth.Principal{Subject: "support-1"}, method: "UpdateUser", request: User{Id: "player-1", Nickname: "alice"}},
+		{name: "support deletes", caller: &auth.Principal{Subject: "support-1"}, method: "DeleteUser", request: DeleteUserRequest{Id: "player-1"}, err: ErrForbidden},
+		{name: "service creates", caller: service, method: "CreateUser", request: CreateUserRequest{}},
+		{name: "service revokes role", caller: service, method: "RevokeRole", request: RoleRequest{Id: "player-1", Role: RoleSupport}, err: ErrForbidden},
+		{name: "admin assigns role", caller: &auth.Principal{Subject: "admin-1"}, method: "AssignRole", request: RoleRequest{Id: "player-1", Role: RoleSupport}},
+		{name: "player sends own verification email", caller: player, method: "SendVerificationEmail", request: SendVerificationEmailRequest{Id: "player-1"}},
+		{name: "player sends verification email of others", caller: player, method: "SendVerificationEmail", request: SendVerificationEmailRequest{Id: "player-2"}, err: ErrForbidden},
+		{name: "anonymous verifies email", method: "VerifyEmail", request: VerifyEmailRequest{Token: "token"}},
+		{name: "player changes own password", caller: player, method: "ChangePassword", request: ChangePasswordRequest{Id: "player-1"}},
+		{name: "support changes password of others", caller: &auth.Principal{Subject: "support-1"}, method: "ChangePassword", request: ChangePasswordRequest{Id: "player-1"}, err: ErrForbidden},
+		{name: "anonymous resets password", method: "ResetPassword", request: ResetPasswordRequest{Token: "token"}},
+		{name: "service creates api key", caller: service, method: "CreateAPIKey", request: CreateAPIKeyRequest{Name: "ci"}, err: ErrForbidden},
+		{name: "admin rotates api key", caller: &auth.Principal{Subject: "admin-1"}, method: "RotateAPIKey", request: RotateAPIKeyRequest{Id: "key-1"}},
+		{name: "player unlocks self", caller: player, method: "UnlockUser", request: UnlockUserRequest{Id: "player-1"}, err: ErrForbidden},
+		{name: "support unlocks user", caller: &auth.Principal{Subject: "support-1"}, method: "UnlockUser", request: UnlockUserRequest{Id: "player-1"}},
+		{name: "service unlocks ip", caller: service, method: "UnlockIP", request: UnlockIPRequest{Ip: "1.2.3.4"}, err: ErrForbidden},
+		{name: "super-admin deletes", caller: &auth.Principal{Subject: "root", SuperAdmin: true}, method: "DeleteUser", request: DeleteUserRequest{Id: "player-1"}},
+		{name: "unknown method", caller: &auth.Principal{Subject: "admin-1"}, method: "DropUsers", request: nil, err: ErrForbidden},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			var called bool
+			e := NewAuthorizer(roles)(c.method, func(context.Context, interface{}) (interface{}, error) {
+				called = true
+				return nil, nil
+			})
+			callCtx := ctx
+			if c.caller != nil {
+				callCtx = auth.WithPrincipal(ctx, c.caller)
+			}
+			_, err := e(callCtx, c.request)
+			if c.err != nil {
+				assert.True(t, errors.Is(err, c.err), "got %v", err)
+				assert.False(t, called)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, called)
+		})
+	}
+}
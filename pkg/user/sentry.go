@@ -94,3 +94,227 @@ func (s *sentryService) DeleteUser(ctx context.Context, req *DeleteUserRequest)
 	}()
 	return s.Service.DeleteUser(ctx, req)
 }
+
+func (s *sentryService) GetRoles(ctx context.Context, req *GetRolesRequest) (resp *GetRolesResponse, err error) {
+	defer func() {
+		if err != nil {
+			log := s.getSentryLog(req, resp)
+			sentry.ConfigureScope(func(scope *sentry.Scope) {
+				scope.SetTag("code", strconv.Itoa(getHTTPStatusCode(err)))
+				scope.SetTag("method", "GetRoles")
+				scope.SetExtra("request", log["request"])
+				scope.SetExtra("response", log["response"])
+			})
+			sentry.CaptureException(err)
+		}
+	}()
+	return s.Service.GetRoles(ctx, req)
+}
+
+func (s *sentryService) AssignRole(ctx context.Context, req *RoleRequest) (resp *Status, err error) {
+	defer func() {
+		if err != nil {
+			log := s.getSentryLog(req, resp)
+			sentry.ConfigureScope(func(scope *sentry.Scope) {
+				scope.SetTag("code", strconv.Itoa(getHTTPStatusCode(err)))
+				scope.SetTag("method", "AssignRole")
+				scope.SetExtra("request", log["request"])
+				scope.SetExtra("response", log["response"])
+			})
+			sentry.CaptureException(err)
+		}
+	}()
+	return s.Service.AssignRole(ctx, req)
+}
+
+func (s *sentryService) RevokeRole(ctx context.Context, req *RoleRequest) (resp *Status, err error) {
+	defer func() {
+		if err != nil {
+			log := s.getSentryLog(req, resp)
+			sentry.ConfigureScope(func(scope *sentry.Scope) {
+				scope.SetTag("code", strconv.Itoa(getHTTPStatusCode(err)))
+				scope.SetTag("method", "RevokeRole")
+				scope.SetExtra("request", log["request"])
+				scope.SetExtra("response", log["response"])
+			})
+			sentry.CaptureException(err)
+		}
+	}()
+	return s.Service.RevokeRole(ctx, req)
+}
+
+func (s *sentryService) CreateAPIKey(ctx context.Context, req *CreateAPIKeyRequest) (resp *CreateAPIKeyResponse, err error) {
+	defer func() {
+		if err != nil {
+			log := s.getSentryLog(req, resp)
+			sentry.ConfigureScope(func(scope *sentry.Scope) {
+				scope.SetTag("code", strconv.Itoa(getHTTPStatusCode(err)))
+				scope.SetTag("method", "CreateAPIKey")
+				scope.SetExtra("request", log["request"])
+				scope.SetExtra("response", log["response"])
+			})
+			sentry.CaptureException(err)
+		}
+	}()
+	return s.Service.CreateAPIKey(ctx, req)
+}
+
+func (s *sentryService) ListAPIKeys(ctx context.Context, req *ListAPIKeysRequest) (resp *ListAPIKeysResponse, err error) {
+	defer func() {
+		if err != nil {
+			log := s.getSentryLog(req, resp)
+			sentry.ConfigureScope(func(scope *sentry.Scope) {
+				scope.SetTag("code", strconv.Itoa(getHTTPStatusCode(err)))
+				scope.SetTag("method", "ListAPIKeys")
+				scope.SetExtra("request", log["request"])
+				scope.SetExtra("response", log["response"])
+			})
+			sentry.CaptureException(err)
+		}
+	}()
+	return s.Service.ListAPIKeys(ctx, req)
+}
+
+func (s *sentryService) RevokeAPIKey(ctx context.Context, req *RevokeAPIKeyRequest) (resp *Status, err error) {
+	defer func() {
+		if err != nil {
+			log := s.getSentryLog(req, resp)
+			sentry.ConfigureScope(func(scope *sentry.Scope) {
+				scope.SetTag("code", strconv.Itoa(getHTTPStatusCode(err)))
+				scope.SetTag("method", "RevokeAPIKey")
+				scope.SetExtra("request", log["request"])
+				scope.SetExtra("response", log["response"])
+			})
+			sentry.CaptureException(err)
+		}
+	}()
+	return s.Service.RevokeAPIKey(ctx, req)
+}
+
+func (s *sentryService) RotateAPIKey(ctx context.Context, req *RotateAPIKeyRequest) (resp *CreateAPIKeyResponse, err error) {
+	defer func() {
+		if err != nil {
+			log := s.getSentryLog(req, resp)
+			sentry.ConfigureScope(func(scope *sentry.Scope) {
+				scope.SetTag("code", strconv.Itoa(getHTTPStatusCode(err)))
+				scope.SetTag("method", "RotateAPIKey")
+				scope.SetExtra("request", log["request"])
+				scope.SetExtra("response", log["response"])
+			})
+			sentry.CaptureException(err)
+		}
+	}()
+	return s.Service.RotateAPIKey(ctx, req)
+}
+
+func (s *sentryService) SendVerificationEmail(ctx context.Context, req *SendVerificationEmailRequest) (resp *Status, err error) {
+	defer func() {
+		if err != nil {
+			log := s.getSentryLog(req, resp)
+			sentry.ConfigureScope(func(scope *sentry.Scope) {
+				scope.SetTag("code", strconv.Itoa(getHTTPStatusCode(err)))
+				scope.SetTag("method", "SendVerificationEmail")
+				scope.SetExtra("request", log["request"])
+				scope.SetExtra("response", log["response"])
+			})
+			sentry.CaptureException(err)
+		}
+	}()
+	return s.Service.SendVerificationEmail(ctx, req)
+}
+
+func (s *sentryService) VerifyEmail(ctx context.Context, req *VerifyEmailRequest) (resp *Status, err error) {
+	defer func() {
+		if err != nil {
+			log := s.getSentryLog(req, resp)
+			sentry.ConfigureScope(func(scope *sentry.Scope) {
+				scope.SetTag("code", strconv.Itoa(getHTTPStatusCode(err)))
+				scope.SetTag("method", "VerifyEmail")
+				scope.SetExtra("request", log["request"])
+				scope.SetExtra("response", log["response"])
+			})
+			sentry.CaptureException(err)
+		}
+	}()
+	return s.Service.VerifyEmail(ctx, req)
+}
+
+func (s *sentryService) ChangePassword(ctx context.Context, req *ChangePasswordRequest) (resp *Status, err error) {
+	defer func() {
+		if err != nil {
+			log := s.getSentryLog(req, resp)
+			sentry.ConfigureScope(func(scope *sentry.Scope) {
+				scope.SetTag("code", strconv.Itoa(getHTTPStatusCode(err)))
+				scope.SetTag("method", "ChangePassword")
+				scope.SetExtra("request", log["request"])
+				scope.SetExtra("response", log["response"])
+			})
+			sentry.CaptureException(err)
+		}
+	}()
+	return s.Service.ChangePassword(ctx, req)
+}
+
+func (s *sentryService) RequestPasswordReset(ctx context.Context, req *RequestPasswordResetRequest) (resp *Status, err error) {
+	defer func() {
+		if err != nil {
+			log := s.getSentryLog(req, resp)
+			sentry.ConfigureScope(func(scope *sentry.Scope) {
+				scope.SetTag("code", strconv.Itoa(getHTTPStatusCode(err)))
+				scope.SetTag("method", "RequestPasswordReset")
+				scope.SetExtra("request", log["request"])
+				scope.SetExtra("response", log["response"])
+			})
+			sentry.CaptureException(err)
+		}
+	}()
+	return s.Service.RequestPasswordReset(ctx, req)
+}
+
+func (s *sentryService) ResetPassword(ctx context.Context, req *ResetPasswordRequest) (resp *Status, err error) {
+	defer func() {
+		if err != nil {
+			log := s.getSentryLog(req, resp)
+			sentry.ConfigureScope(func(scope *sentry.Scope) {
+				scope.SetTag("code", strconv.Itoa(getHTTPStatusCode(err)))
+				scope.SetTag("method", "ResetPassword")
+				scope.SetExtra("request", log["request"])
+				scope.SetExtra("response", log["response"])
+			})
+			sentry.CaptureException(err)
+		}
+	}()
+	return s.Service.ResetPassword(ctx, req)
+}
+
+func (s *sentryService) UnlockUser(ctx context.Context, req *UnlockUserRequest) (resp *Status, err error) {
+	defer func() {
+		if err != nil {
+			log := s.getSentryLog(req, resp)
+			sentry.ConfigureScope(func(scope *sentry.Scope) {
+				scope.SetTag("code", strconv.Itoa(getHTTPStatusCode(err)))
+				scope.SetTag("method", "UnlockUser")
+				scope.SetExtra("request", log["request"])
+				scope.SetExtra("response", log["response"])
+			})
+			sentry.CaptureException(err)
+		}
+	}()
+	return s.Service.UnlockUser(ctx, req)
+}
+
+func (s *sentryService) UnlockIP(ctx context.Context, req *UnlockIPRequest) (resp *Status, err error) {
+	defer func() {
+		if err != nil {
+			log := s.getSentryLog(req, resp)
+			sentry.ConfigureScope(func(scope *sentry.Scope) {
+				scope.SetTag("code", strconv.Itoa(getHTTPStatusCode(err)))
+				scope.SetTag("method", "UnlockIP")
+				scope.SetExtra("request", log["request"])
+				scope.SetExtra("response", log["response"])
+			})
+			sentry.CaptureException(err)
+		}
+	}()
+	return s.Service.UnlockIP(ctx, req)
+}
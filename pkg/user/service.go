@@ -2,31 +2,164 @@ package user
 
 import (
 	"context"
+	"net/url"
+	"strings"
+	"time"
+
 	"github.com/go-kit/log/level"
+	"github.com/nakiner/faceit/internal/repository/apikey"
+	"github.com/nakiner/faceit/internal/repository/attempt"
+	roleRepository "github.com/nakiner/faceit/internal/repository/role"
+	"github.com/nakiner/faceit/internal/repository/token"
 	userRepository "github.com/nakiner/faceit/internal/repository/user"
 	userQueue "github.com/nakiner/faceit/pkg/queue/user"
+	"github.com/nakiner/faceit/tools/audit"
+	"github.com/nakiner/faceit/tools/auth"
+	"github.com/nakiner/faceit/tools/limiting"
+	"github.com/nakiner/faceit/tools/lockout"
 	"github.com/nakiner/faceit/tools/logging"
+	"github.com/nakiner/faceit/tools/mailer"
+	"github.com/nakiner/faceit/tools/metrics"
+	"github.com/nakiner/faceit/tools/password"
+	"github.com/nakiner/faceit/tools/requestid"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/nakiner/faceit/tools/workers"
+	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultRotationGrace      = 24 * time.Hour
+	defaultVerificationTTL    = 24 * time.Hour
+	defaultResetTTL           = time.Hour
+	defaultResetInterval      = time.Minute
+	verificationEmailMessage  = "verify_email"
+	resetPasswordEmailMessage = "reset_password"
 )
 
 type userService struct {
-	repo      userRepository.Repository
-	ncUserPub userQueue.Publisher
+	repo            userRepository.Repository
+	roles           roleRepository.Repository
+	keys            apikey.Repository
+	tokens          token.Repository
+	ncUserPub       userQueue.Publisher
+	workers         *workers.Group
+	mailer          mailer.Mailer
+	templates       *mailer.Templates
+	rotationGrace   time.Duration
+	verificationTTL time.Duration
+	verificationURL string
+	resetTTL        time.Duration
+	resetURL        string
+	resetInterval   time.Duration
+	passwords       *password.Policy
+	attempts        attempt.Repository
+	accountLockout  lockout.Policy
+	ipLockout       lockout.Policy
+	metrics         *metrics.Metrics
+	now             func() time.Time
+}
+
+// ServiceOption configures user service.
+type ServiceOption func(*userService)
+
+// SetRotationGrace sets how long rotated API keys stay valid when request does not set grace period.
+func SetRotationGrace(d time.Duration) ServiceOption {
+	return func(s *userService) {
+		s.rotationGrace = d
+	}
+}
+
+// SetMailer sets mailer and templates of emails sent to users, by default they are written to log.
+func SetMailer(m mailer.Mailer, templates *mailer.Templates) ServiceOption {
+	return func(s *userService) {
+		s.mailer = m
+		s.templates = templates
+	}
 }
 
-func NewUserService(repo userRepository.Repository, ncUserPub userQueue.Publisher) Service {
-	return &userService{
-		repo:      repo,
-		ncUserPub: ncUserPub,
+// SetVerification sets lifetime of email verification tokens and URL of page verifying them,
+// token is appended to it as token query parameter.
+func SetVerification(ttl time.Duration, url string) ServiceOption {
+	return func(s *userService) {
+		s.verificationTTL = ttl
+		s.verificationURL = url
 	}
 }
 
+// SetPasswordReset sets lifetime of password reset tokens, URL of page resetting password, token is appended
+// to it as token query parameter, and how often reset emails may be sent to one user.
+func SetPasswordReset(ttl time.Duration, url string, interval time.Duration) ServiceOption {
+	return func(s *userService) {
+		s.resetTTL = ttl
+		s.resetURL = url
+		s.resetInterval = interval
+	}
+}
+
+// SetPasswordPolicy sets rules new passwords have to satisfy, by default any password is accepted.
+func SetPasswordPolicy(p *password.Policy) ServiceOption {
+	return func(s *userService) {
+		s.passwords = p
+	}
+}
+
+// SetLockout sets policies refusing password checks after failed ones of user account and of client ip,
+// failures are kept in attempts. By default failures are not tracked.
+func SetLockout(account, ip lockout.Policy, attempts attempt.Repository) ServiceOption {
+	return func(s *userService) {
+		s.accountLockout = account
+		s.ipLockout = ip
+		s.attempts = attempts
+	}
+}
+
+// SetMetrics sets metrics failed password checks and lockouts are counted in.
+func SetMetrics(m *metrics.Metrics) ServiceOption {
+	return func(s *userService) {
+		s.metrics = m
+	}
+}
+
+// NewUserService creates user service, events are published in background goroutines tracked by workers
+// so shutdown can wait for pending publishes.
+func NewUserService(repo userRepository.Repository, roles roleRepository.Repository, keys apikey.Repository, tokens token.Repository, ncUserPub userQueue.Publisher, group *workers.Group, opts ...ServiceOption) Service {
+	s := &userService{
+		repo:            repo,
+		roles:           roles,
+		keys:            keys,
+		tokens:          tokens,
+		ncUserPub:       ncUserPub,
+		workers:         group,
+		mailer:          mailer.NewLog(),
+		templates:       mailer.DefaultTemplates(),
+		rotationGrace:   defaultRotationGrace,
+		verificationTTL: defaultVerificationTTL,
+		resetTTL:        defaultResetTTL,
+		resetInterval:   defaultResetInterval,
+		passwords:       &password.Policy{},
+		now:             time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
 func (s *userService) CreateUser(ctx context.Context, req *CreateUserRequest) (resp *CreateUserResponse, err error) {
+	if err = s.checkPassword("password", req.Password, req.Nickname, req.Email); err != nil {
+		return nil, err
+	}
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		return nil, errors.Wrap(err, "userService create user err")
+	}
 	id, err := s.repo.Create(ctx, &userRepository.User{
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
 		Nickname:  req.Nickname,
-		Password:  req.Password,
+		Password:  hash,
 		Email:     req.Email,
 		Country:   req.Country,
 	})
@@ -77,11 +210,12 @@ func (s *userService) GetUsers(ctx context.Context, req *GetUsersRequest) (resp
 			FirstName: user.FirstName,
 			LastName:  user.LastName,
 			Nickname:  user.Nickname,
-			Password:  user.Password,
 			Email:     user.Email,
 			Country:   user.Country,
 			CreatedAt: user.TimeToString(user.CreatedAt),
 			UpdatedAt: user.TimeToString(user.UpdatedAt),
+
+			EmailVerifiedAt: verifiedAt(user),
 		})
 	}
 
@@ -94,7 +228,6 @@ func (s *userService) UpdateUser(ctx context.Context, req *User) (resp *Status,
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
 		Nickname:  req.Nickname,
-		Password:  req.Password,
 		Email:     req.Email,
 		Country:   req.Country,
 	})
@@ -104,28 +237,58 @@ func (s *userService) UpdateUser(ctx context.Context, req *User) (resp *Status,
 	if err != nil {
 		return nil, errors.Wrap(err, "userService update user err")
 	}
-	go func() {
-		if err = s.ncUserPub.UpdateUser(&userQueue.User{
+	// publishing outlives request
+	pubCtx := detach(ctx, s.tenantOf(ctx, req.Id))
+	lg := logging.FromContext(ctx)
+	err = s.workers.Go(func() {
+		if err := s.ncUserPub.UpdateUser(pubCtx, &userQueue.User{
 			ID:        req.Id,
+			TenantID:  tenant.FromContext(pubCtx),
 			FirstName: req.FirstName,
 			LastName:  req.LastName,
 			Nickname:  req.Nickname,
-			Password:  req.Password,
 			Email:     req.Email,
 			Country:   req.Country,
 			CreatedAt: req.CreatedAt,
 			UpdatedAt: req.UpdatedAt,
 		}); err != nil {
-			lg := logging.FromContext(context.Background())
 			level.Error(lg).Log("msg", "could not pub to channel", "err", err)
 		}
-	}()
+	})
+	if err != nil {
+		level.Error(lg).Log("msg", "could not pub to channel", "err", err)
+	}
 	return &Status{
 		Status:  true,
 		Message: "OK",
 	}, nil
 }
 
+// detach returns ctx of work outliving request, only request id, logger and span are carried over
+// and it is limited to tenant
+func detach(ctx context.Context, tenantID string) context.Context {
+	bg := requestid.WithContext(context.Background(), requestid.FromContext(ctx))
+	bg = tenant.WithContext(bg, tenantID)
+	bg = logging.WithContext(bg, logging.FromContext(ctx))
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		bg = opentracing.ContextWithSpan(bg, span)
+	}
+	return bg
+}
+
+// tenantOf returns tenant of user with id, super-admin working across tenants does not select one,
+// so it is looked up
+func (s *userService) tenantOf(ctx context.Context, id string) string {
+	if t := tenant.FromContext(ctx); t != "" {
+		return t
+	}
+	users, err := s.repo.Get(ctx, userRepository.Conditions{"id": id}, 1, 1)
+	if err != nil || len(users) == 0 {
+		return ""
+	}
+	return users[0].TenantID
+}
+
 func (s *userService) DeleteUser(ctx context.Context, req *DeleteUserRequest) (resp *Status, err error) {
 	err = s.repo.Delete(ctx, req.Id)
 	if errors.Is(err, userRepository.ErrRowsAffectedEmpty) {
@@ -139,3 +302,670 @@ func (s *userService) DeleteUser(ctx context.Context, req *DeleteUserRequest) (r
 		Message: "OK",
 	}, nil
 }
+
+func (s *userService) GetRoles(ctx context.Context, req *GetRolesRequest) (resp *GetRolesResponse, err error) {
+	roles, err := s.roles.Get(ctx, req.Id)
+	if err != nil {
+		return nil, errors.Wrap(err, "userService GetRoles err")
+	}
+	return &GetRolesResponse{Roles: append([]string{}, roles...)}, nil
+}
+
+func (s *userService) AssignRole(ctx context.Context, req *RoleRequest) (resp *Status, err error) {
+	ctx, err = s.userContext(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.roles.Assign(ctx, req.Id, req.Role); err != nil {
+		return nil, errors.Wrap(err, "userService AssignRole err")
+	}
+	audit.Record(ctx, audit.Event{Action: "AssignRole", Outcome: audit.OutcomeAllowed, Target: req.Id + "/" + req.Role})
+	return &Status{
+		Status:  true,
+		Message: "OK",
+	}, nil
+}
+
+func (s *userService) RevokeRole(ctx context.Context, req *RoleRequest) (resp *Status, err error) {
+	ctx, err = s.userContext(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	err = s.roles.Revoke(ctx, req.Id, req.Role)
+	if errors.Is(err, roleRepository.ErrRowsAffectedEmpty) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "userService RevokeRole err")
+	}
+	audit.Record(ctx, audit.Event{Action: "RevokeRole", Outcome: audit.OutcomeAllowed, Target: req.Id + "/" + req.Role})
+	return &Status{
+		Status:  true,
+		Message: "OK",
+	}, nil
+}
+
+// userContext checks user with id exists and returns ctx limited to its tenant, so roles of super-admin
+// working across tenants are assigned in tenant of user
+func (s *userService) userContext(ctx context.Context, id string) (context.Context, error) {
+	ctx, _, err := s.findUser(ctx, id)
+	return ctx, err
+}
+
+// findUser returns user with id and ctx limited to its tenant
+func (s *userService) findUser(ctx context.Context, id string) (context.Context, *userRepository.User, error) {
+	users, err := s.repo.Get(ctx, userRepository.Conditions{"id": id}, 1, 1)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "userService get user err")
+	}
+	if len(users) == 0 {
+		return nil, nil, ErrNotFound
+	}
+	if tenant.FromContext(ctx) == "" {
+		ctx = tenant.WithContext(ctx, users[0].TenantID)
+	}
+	return ctx, users[0], nil
+}
+
+func verifiedAt(u *userRepository.User) string {
+	if u.EmailVerifiedAt == nil {
+		return ""
+	}
+	return u.TimeToString(*u.EmailVerifiedAt)
+}
+
+func (s *userService) CreateAPIKey(ctx context.Context, req *CreateAPIKeyRequest) (resp *CreateAPIKeyResponse, err error) {
+	if _, all, _ := tenant.Scope(ctx); all {
+		return nil, errors.Wrap(ErrBadRequest, "select tenant of api key")
+	}
+	key := &apikey.Key{
+		Name:      req.Name,
+		Scopes:    strings.Join(req.Scopes, " "),
+		RateLimit: req.RateLimit,
+	}
+	if req.ExpiresAt != "" {
+		// validated to be RFC3339
+		at, _ := time.Parse(time.RFC3339, req.ExpiresAt)
+		key.ExpiresAt = &at
+	}
+	secret, err := s.issueKey(key, func() error { return s.keys.Create(ctx, key) })
+	if err != nil {
+		return nil, errors.Wrap(err, "userService CreateAPIKey err")
+	}
+	audit.Record(ctx, audit.Event{Action: "CreateAPIKey", Outcome: audit.OutcomeAllowed, Target: key.ID})
+	return &CreateAPIKeyResponse{Key: secret, APIKey: apiKeyOf(key)}, nil
+}
+
+func (s *userService) ListAPIKeys(ctx context.Context, _ *ListAPIKeysRequest) (resp *ListAPIKeysResponse, err error) {
+	keys, err := s.keys.List(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "userService ListAPIKeys err")
+	}
+	data := ListAPIKeysResponse{}
+	for i := range keys {
+		data = append(data, *apiKeyOf(&keys[i]))
+	}
+	return &data, nil
+}
+
+func (s *userService) RevokeAPIKey(ctx context.Context, req *RevokeAPIKeyRequest) (resp *Status, err error) {
+	err = s.keys.Revoke(ctx, req.Id, s.now())
+	if errors.Is(err, apikey.ErrRowsAffectedEmpty) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "userService RevokeAPIKey err")
+	}
+	audit.Record(ctx, audit.Event{Action: "RevokeAPIKey", Outcome: audit.OutcomeAllowed, Target: req.Id})
+	return &Status{
+		Status:  true,
+		Message: "OK",
+	}, nil
+}
+
+// RotateAPIKey issues key replacing key with id, which keeps working for grace period so callers can switch
+func (s *userService) RotateAPIKey(ctx context.Context, req *RotateAPIKeyRequest) (resp *CreateAPIKeyResponse, err error) {
+	old, err := s.keys.Get(ctx, req.Id)
+	if errors.Is(err, apikey.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "userService RotateAPIKey err")
+	}
+	now := s.now()
+	if !old.Active(now) {
+		return nil, errors.Wrap(ErrBadRequest, "api key is revoked or expired")
+	}
+	if tenant.FromContext(ctx) == "" {
+		ctx = tenant.WithContext(ctx, old.TenantID)
+	}
+	grace := s.rotationGrace
+	if req.GracePeriodSec > 0 {
+		grace = time.Duration(req.GracePeriodSec) * time.Second
+	}
+	next := &apikey.Key{
+		TenantID:  old.TenantID,
+		Name:      old.Name,
+		Scopes:    old.Scopes,
+		RateLimit: old.RateLimit,
+		ExpiresAt: old.ExpiresAt,
+	}
+	secret, err := s.issueKey(next, func() error { return s.keys.Rotate(ctx, old.ID, next, now.Add(grace)) })
+	if errors.Is(err, apikey.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "userService RotateAPIKey err")
+	}
+	audit.Record(ctx, audit.Event{Action: "RotateAPIKey", Outcome: audit.OutcomeAllowed, Target: old.ID + "/" + next.ID})
+	return &CreateAPIKeyResponse{Key: secret, APIKey: apiKeyOf(next)}, nil
+}
+
+// issueKey generates secret of key and stores it with store, secret is returned to caller once and never stored
+func (s *userService) issueKey(key *apikey.Key, store func() error) (string, error) {
+	secret, prefix, hash, err := apikey.Generate()
+	if err != nil {
+		return "", err
+	}
+	key.Prefix, key.Hash = prefix, hash
+	if err = store(); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+func apiKeyOf(k *apikey.Key) *APIKey {
+	key := &APIKey{
+		Id:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     strings.Fields(k.Scopes),
+		RateLimit:  k.RateLimit,
+		ExpiresAt:  formatTime(k.ExpiresAt),
+		LastUsedAt: formatTime(k.LastUsedAt),
+		RevokedAt:  formatTime(k.RevokedAt),
+	}
+	if !k.CreatedAt.IsZero() {
+		key.CreatedAt = k.CreatedAt.Format(time.RFC3339)
+	}
+	return key
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// SendVerificationEmail sends link with single-use token to email of user, links sent earlier stop working
+func (s *userService) SendVerificationEmail(ctx context.Context, req *SendVerificationEmailRequest) (resp *Status, err error) {
+	ctx, u, err := s.findUser(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if u.Email == "" {
+		return nil, errors.Wrap(ErrBadRequest, "user has no email")
+	}
+	if u.EmailVerifiedAt != nil {
+		return nil, errors.Wrap(ErrBadRequest, "email is already verified")
+	}
+	msg, err := s.issueToken(ctx, u, token.PurposeVerifyEmail, s.verificationTTL, s.verificationURL, verificationEmailMessage)
+	if err != nil {
+		return nil, errors.Wrap(err, "userService SendVerificationEmail err")
+	}
+	if err = s.mailer.Send(ctx, msg); err != nil {
+		return nil, errors.Wrap(err, "userService SendVerificationEmail err")
+	}
+	return &Status{
+		Status:  true,
+		Message: "OK",
+	}, nil
+}
+
+// VerifyEmail marks email token was sent to verified, unless user changed email since.
+// Invalid tokens count as failed attempts of client ip, see SetLockout.
+func (s *userService) VerifyEmail(ctx context.Context, req *VerifyEmailRequest) (resp *Status, err error) {
+	now := s.now()
+	reserved, err := s.reserveAttempt(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	tokenCtx, t, err := s.consumeToken(ctx, token.PurposeVerifyEmail, req.Token, now)
+	if errors.Is(err, ErrBadRequest) {
+		s.failAttempt(ctx, "VerifyEmail", nil, reserved)
+		return nil, errors.Wrap(err, "userService VerifyEmail err")
+	}
+	if err != nil {
+		s.releaseAttempts(ctx, reserved)
+		return nil, errors.Wrap(err, "userService VerifyEmail err")
+	}
+	s.clearAttempts(ctx, reserved)
+	ctx = tokenCtx
+	err = s.repo.VerifyEmail(ctx, t.UserID, t.Email, now)
+	if errors.Is(err, userRepository.ErrRowsAffectedEmpty) {
+		return nil, errors.Wrap(ErrBadRequest, "email was changed, verify the new one")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "userService VerifyEmail err")
+	}
+	audit.Record(ctx, audit.Event{Action: "VerifyEmail", Outcome: audit.OutcomeAllowed, Target: t.UserID})
+	return &Status{
+		Status:  true,
+		Message: "OK",
+	}, nil
+}
+
+// issueToken stores token of purpose sent to email of u, earlier tokens of purpose stop working,
+// and returns email with link to url carrying token
+func (s *userService) issueToken(ctx context.Context, u *userRepository.User, purpose string, ttl time.Duration, url, message string) (*mailer.Message, error) {
+	now := s.now()
+	if err := s.tokens.Revoke(ctx, u.ID, purpose, now); err != nil {
+		return nil, err
+	}
+	plain, hash, err := token.Generate()
+	if err != nil {
+		return nil, err
+	}
+	err = s.tokens.Create(ctx, &token.Token{
+		TenantID:  u.TenantID,
+		UserID:    u.ID,
+		Purpose:   purpose,
+		Hash:      hash,
+		Email:     u.Email,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.templates.Render(message, u.Email, map[string]string{
+		"Nickname": u.Nickname,
+		"Email":    u.Email,
+		"Link":     linkTo(url, plain),
+		"TTL":      formatTTL(ttl),
+	})
+}
+
+// consumeToken uses token of purpose and returns it with ctx limited to its tenant. Callers are not
+// authenticated, token alone identifies user and its tenant.
+func (s *userService) consumeToken(ctx context.Context, purpose, plain string, now time.Time) (context.Context, *token.Token, error) {
+	t, err := s.tokens.Consume(anyTenant(ctx), purpose, token.Hash(plain), now)
+	if errors.Is(err, token.ErrNotFound) {
+		return nil, nil, errors.Wrap(ErrBadRequest, "token is invalid or expired")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return tenant.WithContext(ctx, t.TenantID), t, nil
+}
+
+// anyTenant returns ctx looking up data across tenants
+func anyTenant(ctx context.Context) context.Context {
+	return tenant.WithSuperAdmin(tenant.WithContext(ctx, ""))
+}
+
+// checkPassword returns ValidationError listing rules of password policy password of field breaks,
+// personal are values of user it may not contain
+func (s *userService) checkPassword(field, pw string, personal ...string) error {
+	violations := s.passwords.Check(pw, personal...)
+	if len(violations) == 0 {
+		return nil
+	}
+	verr := &ValidationError{}
+	for _, v := range violations {
+		verr.Violations = append(verr.Violations, Violation{Field: field, Code: v.Code, Message: v.Message})
+	}
+	return verr
+}
+
+// ChangePassword replaces password of user after checking current one, sessions issued before are revoked.
+// Checks are refused for a while after failed ones of user or client ip, see SetLockout, successful one
+// forgets failures of user, failures of client ip age out of window.
+func (s *userService) ChangePassword(ctx context.Context, req *ChangePasswordRequest) (resp *Status, err error) {
+	ctx, u, err := s.findUser(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	reserved, err := s.reserveAttempt(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(req.CurrentPassword)) != nil {
+		audit.Record(ctx, audit.Event{Action: "ChangePassword", Outcome: audit.OutcomeDenied, Target: u.ID, Reason: "wrong current password"})
+		s.failAttempt(ctx, "ChangePassword", u, reserved)
+		return nil, errors.Wrap(ErrForbidden, "current password is wrong")
+	}
+	s.clearAttempts(ctx, reserved)
+	if err = s.checkPassword("newPassword", req.NewPassword, u.Nickname, u.Email); err != nil {
+		return nil, err
+	}
+	if err = s.setPassword(ctx, u.ID, req.NewPassword); err != nil {
+		return nil, errors.Wrap(err, "userService ChangePassword err")
+	}
+	audit.Record(ctx, audit.Event{Action: "ChangePassword", Outcome: audit.OutcomeAllowed, Target: u.ID})
+	return &Status{
+		Status:  true,
+		Message: "OK",
+	}, nil
+}
+
+// attemptGuard is lockout policy of subject failed password checks are counted for, kind is account or ip
+type attemptGuard struct {
+	kind    string
+	subject string
+	policy  *lockout.Policy
+}
+
+// guardsOf returns guards of credential check of user, or of token when userID is empty.
+// Client ip is guarded when it is known, failures are kept in tenant of ctx, nothing is guarded without it.
+func (s *userService) guardsOf(ctx context.Context, userID string) []attemptGuard {
+	if s.attempts == nil || tenant.FromContext(ctx) == "" {
+		return nil
+	}
+	var guards []attemptGuard
+	if userID != "" {
+		guards = append(guards, attemptGuard{kind: "account", subject: attempt.Account(userID), policy: &s.accountLockout})
+	}
+	if ip := limiting.ClientIPFromContext(ctx); ip != "" {
+		guards = append(guards, attemptGuard{kind: "ip", subject: attempt.IP(ip), policy: &s.ipLockout})
+	}
+	return guards
+}
+
+// reservation is attempt of credential check reserved with guard, failures were counted in window before it
+type reservation struct {
+	attemptGuard
+	id       string
+	failures int
+}
+
+// reserveAttempt reserves check of credentials of user, or token when userID is empty, with each guard before
+// it is made, so concurrent checks count each other. It returns ErrTooManyAttempts when check may not be made
+// yet, because of failed checks of user or client ip. Reservations count as failures until they are released,
+// by clearAttempts, failAttempt or releaseAttempts.
+func (s *userService) reserveAttempt(ctx context.Context, userID string) ([]reservation, error) {
+	now := s.now()
+	var reserved []reservation
+	for _, g := range s.guardsOf(ctx, userID) {
+		id, failures, last, err := s.attempts.Reserve(ctx, g.subject, now, now.Add(-g.policy.Window))
+		if err != nil {
+			s.releaseAttempts(ctx, reserved)
+			return nil, errors.Wrap(err, "userService reserve attempt err")
+		}
+		reserved = append(reserved, reservation{attemptGuard: g, id: id, failures: failures})
+		retryAt := g.policy.RetryAt(failures, last)
+		if !now.Before(retryAt) {
+			continue
+		}
+		s.releaseAttempts(ctx, reserved)
+		reason := "delayed"
+		if g.policy.Locked(failures) {
+			reason = "locked"
+		}
+		if s.metrics != nil {
+			s.metrics.ObserveRefusedLogin(reason)
+		}
+		return nil, errors.Wrapf(ErrTooManyAttempts, "%s is %s, retry after %s", g.kind, reason, retryAt.UTC().Format(time.RFC3339))
+	}
+	return reserved, nil
+}
+
+// failAttempt keeps reserved attempts of check of credentials of u, or of token when u is nil, by method as
+// failures. As checks are refused while subject is locked, failure reaching lockout threshold locks it, lockouts
+// of users are published.
+func (s *userService) failAttempt(ctx context.Context, method string, u *userRepository.User, reserved []reservation) {
+	if s.metrics != nil {
+		s.metrics.ObserveFailedLogin(method)
+	}
+	now := s.now()
+	for _, r := range reserved {
+		failures := r.failures + 1
+		if !r.policy.Locked(failures) {
+			continue
+		}
+		until := now.Add(r.policy.Duration)
+		if s.metrics != nil {
+			s.metrics.ObserveLockout(r.kind)
+		}
+		audit.Record(ctx, audit.Event{Action: method, Outcome: audit.OutcomeDenied, Target: r.subject, Reason: "locked after failed attempts"})
+		if r.kind != "account" {
+			continue
+		}
+		lock := &userQueue.Lock{
+			ID:          u.ID,
+			TenantID:    u.TenantID,
+			Failures:    failures,
+			LockedUntil: until.UTC().Format(time.RFC3339),
+		}
+		s.publish(ctx, u.TenantID, func(ctx context.Context) error {
+			return s.ncUserPub.UserLocked(ctx, lock)
+		})
+	}
+}
+
+// clearAttempts forgets failures of user after successful check, reserved attempts of client ip are released
+// and its earlier failures age out of window. Errors are logged, as check succeeded anyway.
+func (s *userService) clearAttempts(ctx context.Context, reserved []reservation) {
+	for _, r := range reserved {
+		if r.kind != "account" {
+			s.releaseAttempts(ctx, []reservation{r})
+			continue
+		}
+		if err := s.attempts.Clear(ctx, r.subject); err != nil {
+			level.Error(logging.FromContext(ctx)).Log("msg", "could not clear failed attempts", "err", err)
+		}
+	}
+}
+
+// releaseAttempts forgets reserved attempts when check was refused or could not be made. Errors are logged,
+// released attempts age out of window anyway.
+func (s *userService) releaseAttempts(ctx context.Context, reserved []reservation) {
+	for _, r := range reserved {
+		if err := s.attempts.Release(ctx, r.subject, r.id); err != nil {
+			level.Error(logging.FromContext(ctx)).Log("msg", "could not release attempt", "err", err)
+		}
+	}
+}
+
+// publish runs publishing of event in background, it outlives request
+func (s *userService) publish(ctx context.Context, tenantID string, fn func(ctx context.Context) error) {
+	pubCtx := detach(ctx, tenantID)
+	lg := logging.FromContext(ctx)
+	err := s.workers.Go(func() {
+		if err := fn(pubCtx); err != nil {
+			level.Error(lg).Log("msg", "could not pub to channel", "err", err)
+		}
+	})
+	if err != nil {
+		level.Error(lg).Log("msg", "could not pub to channel", "err", err)
+	}
+}
+
+// UnlockUser forgets failed password checks of user, so it is not locked or delayed anymore
+func (s *userService) UnlockUser(ctx context.Context, req *UnlockUserRequest) (resp *Status, err error) {
+	ctx, u, err := s.findUser(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if s.attempts != nil {
+		if err = s.attempts.Clear(ctx, attempt.Account(u.ID)); err != nil {
+			return nil, errors.Wrap(err, "userService UnlockUser err")
+		}
+	}
+	audit.Record(ctx, audit.Event{Action: "UnlockUser", Outcome: audit.OutcomeAllowed, Target: u.ID})
+	lock := &userQueue.Lock{
+		ID:       u.ID,
+		TenantID: u.TenantID,
+	}
+	if p, ok := auth.FromContext(ctx); ok {
+		lock.UnlockedBy = p.Subject
+	}
+	s.publish(ctx, u.TenantID, func(ctx context.Context) error {
+		return s.ncUserPub.UserUnlocked(ctx, lock)
+	})
+	return &Status{
+		Status:  true,
+		Message: "OK",
+	}, nil
+}
+
+// UnlockIP forgets failed password checks from client ip in tenant of caller, in every tenant for super-admin
+func (s *userService) UnlockIP(ctx context.Context, req *UnlockIPRequest) (resp *Status, err error) {
+	if s.attempts != nil {
+		if err = s.attempts.Clear(ctx, attempt.IP(req.Ip)); err != nil {
+			return nil, errors.Wrap(err, "userService UnlockIP err")
+		}
+	}
+	audit.Record(ctx, audit.Event{Action: "UnlockIP", Outcome: audit.OutcomeAllowed, Target: req.Ip})
+	return &Status{
+		Status:  true,
+		Message: "OK",
+	}, nil
+}
+
+// RequestPasswordReset emails link with single-use token to user with email. It succeeds whether there is
+// such user or not, user is looked up and email is sent in background, so callers may not tell registered
+// emails, and users get at most one email per reset interval.
+func (s *userService) RequestPasswordReset(ctx context.Context, req *RequestPasswordResetRequest) (resp *Status, err error) {
+	// email is looked up after answering, so response time does not tell registered emails apart
+	workCtx := detach(ctx, tenant.FromContext(ctx))
+	if tenant.IsSuperAdmin(ctx) {
+		workCtx = tenant.WithSuperAdmin(workCtx)
+	}
+	err = s.workers.Go(func() {
+		if err := s.sendPasswordReset(workCtx, req.Email); err != nil {
+			level.Error(logging.FromContext(workCtx)).Log("msg", "could not send password reset email", "err", err)
+		}
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "userService RequestPasswordReset err")
+	}
+	return &Status{
+		Status:  true,
+		Message: "OK",
+	}, nil
+}
+
+// sendPasswordReset emails reset link to user with email, unless one was sent within reset interval
+func (s *userService) sendPasswordReset(ctx context.Context, email string) error {
+	users, err := s.repo.Get(ctx, userRepository.Conditions{"email": email}, 1, 1)
+	if err != nil {
+		return errors.Wrap(err, "look up user")
+	}
+	if len(users) == 0 {
+		audit.Record(ctx, audit.Event{Action: "RequestPasswordReset", Outcome: audit.OutcomeDenied, Reason: "unknown email"})
+		return nil
+	}
+	u := users[0]
+	if tenant.FromContext(ctx) == "" {
+		ctx = tenant.WithContext(ctx, u.TenantID)
+	}
+	last, err := s.tokens.LastIssued(ctx, u.ID, token.PurposeResetPassword)
+	if err != nil {
+		return errors.Wrap(err, "look up last reset")
+	}
+	if !last.IsZero() && s.now().Sub(last) < s.resetInterval {
+		audit.Record(ctx, audit.Event{Action: "RequestPasswordReset", Outcome: audit.OutcomeDenied, Target: u.ID, Reason: "reset was requested recently"})
+		return nil
+	}
+	msg, err := s.issueToken(ctx, u, token.PurposeResetPassword, s.resetTTL, s.resetURL, resetPasswordEmailMessage)
+	if err != nil {
+		return errors.Wrap(err, "issue token")
+	}
+	audit.Record(ctx, audit.Event{Action: "RequestPasswordReset", Outcome: audit.OutcomeAllowed, Target: u.ID})
+	return s.mailer.Send(ctx, msg)
+}
+
+// ResetPassword sets password of user token from reset email was sent to, unless user changed email since.
+// Sessions issued before and other reset links are revoked. Invalid tokens count as failed attempts of
+// client ip, see SetLockout.
+func (s *userService) ResetPassword(ctx context.Context, req *ResetPasswordRequest) (resp *Status, err error) {
+	now := s.now()
+	reserved, err := s.reserveAttempt(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	// token is used once new password is accepted, so rejected passwords do not use it up
+	t, err := s.tokens.Find(anyTenant(ctx), token.PurposeResetPassword, token.Hash(req.Token), now)
+	if errors.Is(err, token.ErrNotFound) {
+		s.failAttempt(ctx, "ResetPassword", nil, reserved)
+		return nil, errors.Wrap(ErrBadRequest, "token is invalid or expired")
+	}
+	if err != nil {
+		s.releaseAttempts(ctx, reserved)
+		return nil, errors.Wrap(err, "userService ResetPassword err")
+	}
+	s.clearAttempts(ctx, reserved)
+	ctx, u, err := s.findUser(tenant.WithContext(ctx, t.TenantID), t.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u.Email != t.Email {
+		return nil, errors.Wrap(ErrBadRequest, "email was changed, request reset again")
+	}
+	if err = s.checkPassword("newPassword", req.NewPassword, u.Nickname, u.Email); err != nil {
+		return nil, err
+	}
+	if ctx, _, err = s.consumeToken(ctx, token.PurposeResetPassword, req.Token, now); err != nil {
+		return nil, errors.Wrap(err, "userService ResetPassword err")
+	}
+	if err = s.setPassword(ctx, u.ID, req.NewPassword); err != nil {
+		return nil, errors.Wrap(err, "userService ResetPassword err")
+	}
+	audit.Record(ctx, audit.Event{Action: "ResetPassword", Outcome: audit.OutcomeAllowed, Target: u.ID})
+	return &Status{
+		Status:  true,
+		Message: "OK",
+	}, nil
+}
+
+// setPassword replaces password of user, sessions issued before and reset links sent earlier stop working
+func (s *userService) setPassword(ctx context.Context, id, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	err = s.repo.SetPassword(ctx, id, hash, now)
+	if errors.Is(err, userRepository.ErrRowsAffectedEmpty) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.tokens.Revoke(ctx, id, token.PurposeResetPassword, now)
+}
+
+// hashPassword returns bcrypt hash of password, which is stored instead of password itself
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", errors.Wrap(err, "hash password")
+	}
+	return string(hash), nil
+}
+
+// linkTo returns base URL with token query parameter, token alone when there is no URL
+func linkTo(base, secret string) string {
+	if base == "" {
+		return secret
+	}
+	sep := "?"
+	if strings.Contains(base, "?") {
+		sep = "&"
+	}
+	return base + sep + "token=" + url.QueryEscape(secret)
+}
+
+// formatTTL formats d without zero minutes and seconds, e.g. 24h
+func formatTTL(d time.Duration) string {
+	str := d.String()
+	if strings.HasSuffix(str, "m0s") {
+		str = strings.TrimSuffix(str, "0s")
+	}
+	if strings.HasSuffix(str, "h0m") {
+		str = strings.TrimSuffix(str, "0m")
+	}
+	return str
+}
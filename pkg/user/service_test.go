@@ -0,0 +1,445 @@
+package user
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nakiner/faceit/internal/repository/apikey"
+	"github.com/nakiner/faceit/internal/repository/attempt"
+	"github.com/nakiner/faceit/internal/repository/role"
+	"github.com/nakiner/faceit/internal/repository/token"
+	userRepository "github.com/nakiner/faceit/internal/repository/user"
+	userQueue "github.com/nakiner/faceit/pkg/queue/user"
+	"github.com/nakiner/faceit/tools/limiting"
+	"github.com/nakiner/faceit/tools/lockout"
+	"github.com/nakiner/faceit/tools/mailer"
+	"github.com/nakiner/faceit/tools/password"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/nakiner/faceit/tools/workers"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []*mailer.Message
+}
+
+func (m *recordingMailer) Send(_ context.Context, msg *mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *recordingMailer) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sent)
+}
+
+// tokenOf returns token of link in last sent message
+func (m *recordingMailer) tokenOf(t *testing.T) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	require.NotEmpty(t, m.sent)
+	for _, line := range strings.Split(m.sent[len(m.sent)-1].Text, "\n") {
+		if u, err := url.Parse(line); err == nil && u.Query().Get("token") != "" {
+			return u.Query().Get("token")
+		}
+	}
+	t.Fatal("message has no link with token")
+	return ""
+}
+
+func newTestService(t *testing.T, opts ...ServiceOption) (Service, userRepository.Repository) {
+	repo := userRepository.NewMemoryRepository()
+	s := NewUserService(repo, role.NewMemoryRepository(), apikey.NewMemoryRepository(), token.NewMemoryRepository(), nil, workers.NewGroup(), opts...)
+	return s, repo
+}
+
+func TestUserService_VerifyEmail(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	m := &recordingMailer{}
+	s, repo := newTestService(t,
+		SetMailer(m, mailer.DefaultTemplates()),
+		SetVerification(time.Hour, "https://example.com/verify?lang=en"),
+	)
+	id, err := repo.Create(ctx, &userRepository.User{Nickname: "alice", Email: "alice@example.com"})
+	require.NoError(t, err)
+
+	_, err = s.SendVerificationEmail(ctx, &SendVerificationEmailRequest{Id: id})
+	require.NoError(t, err)
+	require.Len(t, m.sent, 1)
+	assert.Equal(t, "alice@example.com", m.sent[0].To)
+	assert.Contains(t, m.sent[0].Text, "expires in 1h")
+	first := m.tokenOf(t)
+
+	// new email invalidates link sent before
+	_, err = s.SendVerificationEmail(ctx, &SendVerificationEmailRequest{Id: id})
+	require.NoError(t, err)
+	second := m.tokenOf(t)
+	_, err = s.VerifyEmail(context.Background(), &VerifyEmailRequest{Token: first})
+	assert.True(t, errors.Is(err, ErrBadRequest), "got %v", err)
+
+	// link works without tenant and only once
+	_, err = s.VerifyEmail(context.Background(), &VerifyEmailRequest{Token: second})
+	require.NoError(t, err)
+	_, err = s.VerifyEmail(context.Background(), &VerifyEmailRequest{Token: second})
+	assert.True(t, errors.Is(err, ErrBadRequest), "got %v", err)
+
+	users, err := s.GetUsers(ctx, &GetUsersRequest{Id: id})
+	require.NoError(t, err)
+	require.Len(t, *users, 1)
+	assert.NotEmpty(t, (*users)[0].EmailVerifiedAt)
+	_, err = s.SendVerificationEmail(ctx, &SendVerificationEmailRequest{Id: id})
+	assert.True(t, errors.Is(err, ErrBadRequest), "got %v", err)
+}
+
+func TestUserService_VerifyChangedEmail(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	m := &recordingMailer{}
+	s, repo := newTestService(t, SetMailer(m, mailer.DefaultTemplates()), SetVerification(time.Hour, "https://example.com/verify"))
+	id, err := repo.Create(ctx, &userRepository.User{Nickname: "alice", Email: "alice@example.com"})
+	require.NoError(t, err)
+
+	_, err = s.SendVerificationEmail(ctx, &SendVerificationEmailRequest{Id: id})
+	require.NoError(t, err)
+	require.NoError(t, repo.Update(ctx, &userRepository.User{ID: id, Email: "eve@example.com"}))
+
+	_, err = s.VerifyEmail(context.Background(), &VerifyEmailRequest{Token: m.tokenOf(t)})
+	assert.True(t, errors.Is(err, ErrBadRequest), "got %v", err)
+	users, err := repo.Get(ctx, userRepository.Conditions{"id": id}, 1, 1)
+	require.NoError(t, err)
+	assert.Nil(t, users[0].EmailVerifiedAt)
+}
+
+func TestUserService_ResetPassword(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	m := &recordingMailer{}
+	s, repo := newTestService(t, SetMailer(m, mailer.DefaultTemplates()), SetPasswordReset(time.Hour, "https://example.com/reset", time.Minute))
+	id, err := repo.Create(ctx, &userRepository.User{Nickname: "alice", Email: "alice@example.com", Password: hashed(t, "old")})
+	require.NoError(t, err)
+
+	// unknown emails are not told apart
+	_, err = s.RequestPasswordReset(ctx, &RequestPasswordResetRequest{Email: "eve@example.com"})
+	require.NoError(t, err)
+	_, err = s.RequestPasswordReset(ctx, &RequestPasswordResetRequest{Email: "alice@example.com"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return m.count() == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, "alice@example.com", m.sent[0].To)
+	tok := m.tokenOf(t)
+
+	// repeated request within interval does not issue new token, so first one keeps working
+	_, err = s.RequestPasswordReset(ctx, &RequestPasswordResetRequest{Email: "alice@example.com"})
+	require.NoError(t, err)
+
+	_, err = s.ResetPassword(context.Background(), &ResetPasswordRequest{Token: tok, NewPassword: "new"})
+	require.NoError(t, err)
+	users, err := repo.Get(ctx, userRepository.Conditions{"id": id}, 1, 1)
+	require.NoError(t, err)
+	assertPassword(t, "new", users[0].Password)
+	assert.NotNil(t, users[0].PasswordChangedAt)
+	assert.Equal(t, 1, m.count())
+
+	_, err = s.ResetPassword(context.Background(), &ResetPasswordRequest{Token: tok, NewPassword: "other"})
+	assert.True(t, errors.Is(err, ErrBadRequest), "got %v", err)
+}
+
+// blockingRepository holds lookups of users until release is closed
+type blockingRepository struct {
+	userRepository.Repository
+	release chan struct{}
+}
+
+func (r *blockingRepository) Get(ctx context.Context, conditions userRepository.Conditions, limit, offset uint32) ([]*userRepository.User, error) {
+	<-r.release
+	return r.Repository.Get(ctx, conditions, limit, offset)
+}
+
+func TestUserService_RequestPasswordResetInBackground(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	m := &recordingMailer{}
+	repo := &blockingRepository{Repository: userRepository.NewMemoryRepository(), release: make(chan struct{})}
+	s := NewUserService(repo, role.NewMemoryRepository(), apikey.NewMemoryRepository(), token.NewMemoryRepository(), nil, workers.NewGroup(),
+		SetMailer(m, mailer.DefaultTemplates()), SetPasswordReset(time.Hour, "https://example.com/reset", time.Minute))
+	_, err := repo.Create(ctx, &userRepository.User{Nickname: "alice", Email: "alice@example.com", Password: hashed(t, "old")})
+	require.NoError(t, err)
+
+	// answer does not wait for lookup, so its time does not tell registered emails apart
+	_, err = s.RequestPasswordReset(ctx, &RequestPasswordResetRequest{Email: "alice@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, m.count())
+	close(repo.release)
+	require.Eventually(t, func() bool { return m.count() == 1 }, time.Second, time.Millisecond)
+}
+
+func TestUserService_ChangePassword(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	m := &recordingMailer{}
+	s, repo := newTestService(t, SetMailer(m, mailer.DefaultTemplates()), SetPasswordReset(time.Hour, "https://example.com/reset", time.Minute))
+	id, err := repo.Create(ctx, &userRepository.User{Nickname: "alice", Email: "alice@example.com", Password: hashed(t, "old")})
+	require.NoError(t, err)
+	_, err = s.RequestPasswordReset(ctx, &RequestPasswordResetRequest{Email: "alice@example.com"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return m.count() == 1 }, time.Second, time.Millisecond)
+
+	_, err = s.ChangePassword(ctx, &ChangePasswordRequest{Id: id, CurrentPassword: "wrong", NewPassword: "new"})
+	assert.True(t, errors.Is(err, ErrForbidden), "got %v", err)
+	_, err = s.ChangePassword(ctx, &ChangePasswordRequest{Id: id, CurrentPassword: "old", NewPassword: "new"})
+	require.NoError(t, err)
+	users, err := repo.Get(ctx, userRepository.Conditions{"id": id}, 1, 1)
+	require.NoError(t, err)
+	assertPassword(t, "new", users[0].Password)
+
+	// reset link sent before change stops working
+	_, err = s.ResetPassword(context.Background(), &ResetPasswordRequest{Token: m.tokenOf(t), NewPassword: "other"})
+	assert.True(t, errors.Is(err, ErrBadRequest), "got %v", err)
+}
+
+func TestUserService_CreateUserHashesPassword(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	s, repo := newTestService(t)
+	resp, err := s.CreateUser(ctx, &CreateUserRequest{Nickname: "alice", Email: "alice@example.com", Password: "battery-staple"})
+	require.NoError(t, err)
+
+	users, err := repo.Get(ctx, userRepository.Conditions{"id": resp.Id}, 1, 1)
+	require.NoError(t, err)
+	assert.NotEqual(t, "battery-staple", users[0].Password)
+	assertPassword(t, "battery-staple", users[0].Password)
+}
+
+// hashed returns stored form of password
+func hashed(t *testing.T, password string) string {
+	hash, err := hashPassword(password)
+	require.NoError(t, err)
+	return hash
+}
+
+// assertPassword asserts hash is stored form of password
+func assertPassword(t *testing.T, password, hash string) {
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)), "password does not match")
+}
+
+type breachedList []string
+
+func (b breachedList) Contains(pw string) bool {
+	for _, v := range b {
+		if v == pw {
+			return true
+		}
+	}
+	return false
+}
+
+// violationsOf returns field and code of violations of validation error err
+func violationsOf(t *testing.T, err error) []string {
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr), "got %v", err)
+	assert.True(t, errors.Is(err, ErrBadRequest))
+	var res []string
+	for _, v := range verr.Violations {
+		res = append(res, v.Field+":"+v.Code)
+	}
+	return res
+}
+
+func TestUserService_PasswordPolicy(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	m := &recordingMailer{}
+	s, repo := newTestService(t,
+		SetMailer(m, mailer.DefaultTemplates()),
+		SetPasswordReset(time.Hour, "https://example.com/reset", time.Minute),
+		SetPasswordPolicy(&password.Policy{MinLength: 10, MaxLength: 64, ForbidPersonal: true, Breached: breachedList{"correcthorse"}}),
+	)
+
+	_, err := s.CreateUser(ctx, &CreateUserRequest{Nickname: "alice", Email: "alice@example.com", Password: "alice1"})
+	assert.Equal(t, []string{"password:too_short", "password:personal_info"}, violationsOf(t, err))
+	resp, err := s.CreateUser(ctx, &CreateUserRequest{Nickname: "alice", Email: "alice@example.com", Password: "battery-staple"})
+	require.NoError(t, err)
+
+	_, err = s.ChangePassword(ctx, &ChangePasswordRequest{Id: resp.Id, CurrentPassword: "battery-staple", NewPassword: "alice-staple-2"})
+	assert.Equal(t, []string{"newPassword:personal_info"}, violationsOf(t, err))
+
+	_, err = s.RequestPasswordReset(ctx, &RequestPasswordResetRequest{Email: "alice@example.com"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return m.count() == 1 }, time.Second, time.Millisecond)
+	tok := m.tokenOf(t)
+
+	// rejected password does not use reset link up
+	_, err = s.ResetPassword(context.Background(), &ResetPasswordRequest{Token: tok, NewPassword: "correcthorse"})
+	assert.Equal(t, []string{"newPassword:breached"}, violationsOf(t, err))
+	_, err = s.ResetPassword(context.Background(), &ResetPasswordRequest{Token: tok, NewPassword: "purple-monkey-dishwasher"})
+	require.NoError(t, err)
+	users, err := repo.Get(ctx, userRepository.Conditions{"id": resp.Id}, 1, 1)
+	require.NoError(t, err)
+	assertPassword(t, "purple-monkey-dishwasher", users[0].Password)
+}
+
+type recordingPublisher struct {
+	userQueue.Publisher
+	mu     sync.Mutex
+	events []string
+}
+
+func (p *recordingPublisher) UserLocked(_ context.Context, l *userQueue.Lock) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, "locked "+l.ID+" until "+l.LockedUntil)
+	return nil
+}
+
+func (p *recordingPublisher) UserUnlocked(_ context.Context, l *userQueue.Lock) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, "unlocked "+l.ID+" by "+l.UnlockedBy)
+	return nil
+}
+
+func (p *recordingPublisher) published() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.events...)
+}
+
+func TestUserService_Lockout(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	fromIP := limiting.WithClientIP(ctx, "1.2.3.4")
+	repo := userRepository.NewMemoryRepository()
+	pub := &recordingPublisher{}
+	attempts := attempt.NewMemoryRepository()
+	svc := NewUserService(repo, role.NewMemoryRepository(), apikey.NewMemoryRepository(), token.NewMemoryRepository(), pub, workers.NewGroup(),
+		SetLockout(
+			lockout.Policy{Window: time.Hour, Threshold: 3, Duration: 15 * time.Minute, Delay: time.Second, MaxDelay: 2 * time.Second},
+			lockout.Policy{Window: time.Hour, Threshold: 4, Duration: time.Hour},
+			attempts,
+		),
+	)
+	now := time.Date(2022, 2, 15, 10, 0, 0, 0, time.UTC)
+	svc.(*userService).now = func() time.Time { return now }
+	alice, err := repo.Create(ctx, &userRepository.User{Nickname: "alice", Email: "alice@example.com", Password: hashed(t, "old")})
+	require.NoError(t, err)
+	bob, err := repo.Create(ctx, &userRepository.User{Nickname: "bob", Email: "bob@example.com", Password: hashed(t, "old")})
+	require.NoError(t, err)
+	carol, err := repo.Create(ctx, &userRepository.User{Nickname: "carol", Email: "carol@example.com", Password: hashed(t, "old")})
+	require.NoError(t, err)
+	dave, err := repo.Create(ctx, &userRepository.User{Nickname: "dave", Email: "dave@example.com", Password: hashed(t, "old")})
+	require.NoError(t, err)
+	change := func(ctx context.Context, id, current string) error {
+		_, err := svc.ChangePassword(ctx, &ChangePasswordRequest{Id: id, CurrentPassword: current, NewPassword: current + "-new"})
+		return err
+	}
+
+	assert.ErrorIs(t, change(fromIP, alice, "wrong"), ErrForbidden)
+	// attempts are delayed after failure, even with right password
+	assert.ErrorIs(t, change(fromIP, alice, "old"), ErrTooManyAttempts)
+	now = now.Add(time.Second)
+	assert.ErrorIs(t, change(fromIP, alice, "wrong"), ErrForbidden)
+	now = now.Add(2 * time.Second)
+	assert.ErrorIs(t, change(fromIP, alice, "wrong"), ErrForbidden)
+	require.Eventually(t, func() bool { return len(pub.published()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, "locked "+alice+" until 2022-02-15T10:15:03Z", pub.published()[0])
+
+	now = now.Add(time.Minute)
+	err = change(ctx, alice, "old")
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+	assert.Contains(t, err.Error(), "account is locked")
+	_, err = svc.UnlockUser(ctx, &UnlockUserRequest{Id: alice})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(pub.published()) == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, "unlocked "+alice+" by ", pub.published()[1])
+	require.NoError(t, change(fromIP, alice, "old"))
+	// successful check is not counted and does not forget failures of ip, next failure locks it
+	assert.ErrorIs(t, change(fromIP, dave, "wrong"), ErrForbidden)
+	err = change(fromIP, alice, "old-new")
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+	assert.Contains(t, err.Error(), "ip is locked")
+	_, err = svc.UnlockIP(ctx, &UnlockIPRequest{Ip: "1.2.3.4"})
+	require.NoError(t, err)
+
+	// failures from ip are counted across accounts
+	assert.ErrorIs(t, change(fromIP, bob, "wrong"), ErrForbidden)
+	assert.ErrorIs(t, change(fromIP, carol, "wrong"), ErrForbidden)
+	now = now.Add(time.Second)
+	assert.ErrorIs(t, change(fromIP, bob, "wrong"), ErrForbidden)
+	assert.ErrorIs(t, change(fromIP, carol, "wrong"), ErrForbidden)
+	err = change(fromIP, alice, "old-new")
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+	assert.Contains(t, err.Error(), "ip is locked")
+	require.NoError(t, change(limiting.WithClientIP(ctx, "5.6.7.8"), alice, "old-new"))
+	_, err = svc.UnlockIP(ctx, &UnlockIPRequest{Ip: "1.2.3.4"})
+	require.NoError(t, err)
+	require.NoError(t, change(fromIP, alice, "old-new-new"))
+	// only lockouts of users are published
+	assert.Len(t, pub.published(), 2)
+}
+
+func TestUserService_LockoutConcurrent(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	repo := userRepository.NewMemoryRepository()
+	s := NewUserService(repo, role.NewMemoryRepository(), apikey.NewMemoryRepository(), token.NewMemoryRepository(), &recordingPublisher{}, workers.NewGroup(),
+		SetLockout(lockout.Policy{Window: time.Hour, Threshold: 3, Duration: time.Hour}, lockout.Policy{}, attempt.NewMemoryRepository()),
+	)
+	alice, err := repo.Create(ctx, &userRepository.User{Nickname: "alice", Email: "alice@example.com", Password: hashed(t, "old")})
+	require.NoError(t, err)
+
+	// concurrent checks count each other, so no more than threshold of them are made
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		checked int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.ChangePassword(ctx, &ChangePasswordRequest{Id: alice, CurrentPassword: "wrong", NewPassword: "purple-monkey-dishwasher"})
+			if errors.Is(err, ErrForbidden) {
+				mu.Lock()
+				checked++
+				mu.Unlock()
+				return
+			}
+			assert.ErrorIs(t, err, ErrTooManyAttempts)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 3, checked)
+}
+
+func TestUserService_TokenLockout(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), "brand-a")
+	fromIP := limiting.WithClientIP(ctx, "1.2.3.4")
+	m := &recordingMailer{}
+	s, repo := newTestService(t,
+		SetMailer(m, mailer.DefaultTemplates()),
+		SetPasswordReset(time.Hour, "https://example.com/reset", time.Minute),
+		SetLockout(lockout.Policy{}, lockout.Policy{Window: time.Hour, Threshold: 3, Duration: time.Hour}, attempt.NewMemoryRepository()),
+	)
+	_, err := repo.Create(ctx, &userRepository.User{Nickname: "alice", Email: "alice@example.com", Password: hashed(t, "old")})
+	require.NoError(t, err)
+	_, err = s.RequestPasswordReset(ctx, &RequestPasswordResetRequest{Email: "alice@example.com"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return m.count() == 1 }, time.Second, time.Millisecond)
+	reset := func(ctx context.Context, tok string) error {
+		_, err := s.ResetPassword(ctx, &ResetPasswordRequest{Token: tok, NewPassword: "purple-monkey-dishwasher"})
+		return err
+	}
+
+	// invalid reset and verification tokens count as failures of ip
+	assert.ErrorIs(t, reset(fromIP, "guess-1"), ErrBadRequest)
+	_, err = s.VerifyEmail(fromIP, &VerifyEmailRequest{Token: "guess-2"})
+	assert.ErrorIs(t, err, ErrBadRequest)
+	assert.ErrorIs(t, reset(fromIP, "guess-3"), ErrBadRequest)
+	err = reset(fromIP, m.tokenOf(t))
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+	assert.Contains(t, err.Error(), "ip is locked")
+
+	require.NoError(t, reset(limiting.WithClientIP(ctx, "5.6.7.8"), m.tokenOf(t)))
+}
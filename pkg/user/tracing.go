@@ -62,3 +62,101 @@ func (s *tracingService) DeleteUser(ctx context.Context, req *DeleteUserRequest)
 	defer span.Finish()
 	return s.Service.DeleteUser(ctx, req)
 }
+
+func (s *tracingService) GetRoles(ctx context.Context, req *GetRolesRequest) (resp *GetRolesResponse, err error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "GetRoles")
+	span.LogFields(log.Object("tracingService", s.getTrace(req, resp)))
+	defer span.Finish()
+	return s.Service.GetRoles(ctx, req)
+}
+
+func (s *tracingService) AssignRole(ctx context.Context, req *RoleRequest) (resp *Status, err error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "AssignRole")
+	span.LogFields(log.Object("tracingService", s.getTrace(req, resp)))
+	defer span.Finish()
+	return s.Service.AssignRole(ctx, req)
+}
+
+func (s *tracingService) RevokeRole(ctx context.Context, req *RoleRequest) (resp *Status, err error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "RevokeRole")
+	span.LogFields(log.Object("tracingService", s.getTrace(req, resp)))
+	defer span.Finish()
+	return s.Service.RevokeRole(ctx, req)
+}
+
+func (s *tracingService) CreateAPIKey(ctx context.Context, req *CreateAPIKeyRequest) (resp *CreateAPIKeyResponse, err error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "CreateAPIKey")
+	span.LogFields(log.Object("tracingService", s.getTrace(req, resp)))
+	defer span.Finish()
+	return s.Service.CreateAPIKey(ctx, req)
+}
+
+func (s *tracingService) ListAPIKeys(ctx context.Context, req *ListAPIKeysRequest) (resp *ListAPIKeysResponse, err error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "ListAPIKeys")
+	span.LogFields(log.Object("tracingService", s.getTrace(req, resp)))
+	defer span.Finish()
+	return s.Service.ListAPIKeys(ctx, req)
+}
+
+func (s *tracingService) RevokeAPIKey(ctx context.Context, req *RevokeAPIKeyRequest) (resp *Status, err error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "RevokeAPIKey")
+	span.LogFields(log.Object("tracingService", s.getTrace(req, resp)))
+	defer span.Finish()
+	return s.Service.RevokeAPIKey(ctx, req)
+}
+
+func (s *tracingService) RotateAPIKey(ctx context.Context, req *RotateAPIKeyRequest) (resp *CreateAPIKeyResponse, err error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "RotateAPIKey")
+	span.LogFields(log.Object("tracingService", s.getTrace(req, resp)))
+	defer span.Finish()
+	return s.Service.RotateAPIKey(ctx, req)
+}
+
+func (s *tracingService) SendVerificationEmail(ctx context.Context, req *SendVerificationEmailRequest) (resp *Status, err error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "SendVerificationEmail")
+	span.LogFields(log.Object("tracingService", s.getTrace(req, resp)))
+	defer span.Finish()
+	return s.Service.SendVerificationEmail(ctx, req)
+}
+
+func (s *tracingService) VerifyEmail(ctx context.Context, req *VerifyEmailRequest) (resp *Status, err error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "VerifyEmail")
+	span.LogFields(log.Object("tracingService", s.getTrace(req, resp)))
+	defer span.Finish()
+	return s.Service.VerifyEmail(ctx, req)
+}
+
+func (s *tracingService) ChangePassword(ctx context.Context, req *ChangePasswordRequest) (resp *Status, err error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "ChangePassword")
+	span.LogFields(log.Object("tracingService", s.getTrace(req, resp)))
+	defer span.Finish()
+	return s.Service.ChangePassword(ctx, req)
+}
+
+func (s *tracingService) RequestPasswordReset(ctx context.Context, req *RequestPasswordResetRequest) (resp *Status, err error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "RequestPasswordReset")
+	span.LogFields(log.Object("tracingService", s.getTrace(req, resp)))
+	defer span.Finish()
+	return s.Service.RequestPasswordReset(ctx, req)
+}
+
+func (s *tracingService) ResetPassword(ctx context.Context, req *ResetPasswordRequest) (resp *Status, err error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "ResetPassword")
+	span.LogFields(log.Object("tracingService", s.getTrace(req, resp)))
+	defer span.Finish()
+	return s.Service.ResetPassword(ctx, req)
+}
+
+func (s *tracingService) UnlockUser(ctx context.Context, req *UnlockUserRequest) (resp *Status, err error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UnlockUser")
+	span.LogFields(log.Object("tracingService", s.getTrace(req, resp)))
+	defer span.Finish()
+	return s.Service.UnlockUser(ctx, req)
+}
+
+func (s *tracingService) UnlockIP(ctx context.Context, req *UnlockIPRequest) (resp *Status, err error) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "UnlockIP")
+	span.LogFields(log.Object("tracingService", s.getTrace(req, resp)))
+	defer span.Finish()
+	return s.Service.UnlockIP(ctx, req)
+}
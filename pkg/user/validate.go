@@ -1,9 +1,43 @@
 package user
 
 import (
+	"net"
+	"strings"
+	"time"
+
 	"github.com/pkg/errors"
 )
 
+// Violation is field of request breaking validation rule.
+type Violation struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every violation of request, it is reported as bad request with violations in
+// response body of HTTP and BadRequest details of gRPC status.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.Field+" "+v.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Cause makes validation errors bad requests.
+func (e *ValidationError) Cause() error {
+	return ErrBadRequest
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrBadRequest
+}
+
 type validator interface {
 	Validate() error
 }
@@ -44,3 +78,120 @@ func (r GetUsersRequest) Validate() error {
 	}
 	return nil
 }
+
+func (r GetRolesRequest) Validate() error {
+	if len(r.Id) < 1 {
+		return errors.Wrap(ErrBadRequest, "id cannot be empty")
+	}
+	return nil
+}
+
+func (r RoleRequest) Validate() error {
+	if len(r.Id) < 1 {
+		return errors.Wrap(ErrBadRequest, "id cannot be empty")
+	}
+	if !knownRole(r.Role) {
+		return errors.Wrapf(ErrBadRequest, "unknown role %q", r.Role)
+	}
+	return nil
+}
+
+func (r CreateAPIKeyRequest) Validate() error {
+	if len(r.Name) < 1 || len(r.Name) > 64 {
+		return errors.Wrap(ErrBadRequest, "name should be 1-64 characters long")
+	}
+	if len(r.Scopes) < 1 {
+		return errors.Wrap(ErrBadRequest, "scopes cannot be empty")
+	}
+	for _, scope := range r.Scopes {
+		if !knownRole(scope) {
+			return errors.Wrapf(ErrBadRequest, "unknown scope %q", scope)
+		}
+	}
+	if r.RateLimit < 0 {
+		return errors.Wrap(ErrBadRequest, "rateLimit should not be negative")
+	}
+	if r.ExpiresAt != "" {
+		if _, err := time.Parse(time.RFC3339, r.ExpiresAt); err != nil {
+			return errors.Wrap(ErrBadRequest, "expiresAt should be RFC 3339 time")
+		}
+	}
+	return nil
+}
+
+func (r RevokeAPIKeyRequest) Validate() error {
+	if len(r.Id) < 1 {
+		return errors.Wrap(ErrBadRequest, "id cannot be empty")
+	}
+	return nil
+}
+
+func (r RotateAPIKeyRequest) Validate() error {
+	if len(r.Id) < 1 {
+		return errors.Wrap(ErrBadRequest, "id cannot be empty")
+	}
+	return nil
+}
+
+func (r SendVerificationEmailRequest) Validate() error {
+	if len(r.Id) < 1 {
+		return errors.Wrap(ErrBadRequest, "id cannot be empty")
+	}
+	return nil
+}
+
+func (r VerifyEmailRequest) Validate() error {
+	if len(r.Token) < 1 {
+		return errors.Wrap(ErrBadRequest, "token cannot be empty")
+	}
+	return nil
+}
+
+func (r ChangePasswordRequest) Validate() error {
+	if len(r.Id) < 1 {
+		return errors.Wrap(ErrBadRequest, "id cannot be empty")
+	}
+	if len(r.CurrentPassword) < 1 {
+		return errors.Wrap(ErrBadRequest, "currentPassword cannot be empty")
+	}
+	if r.NewPassword == r.CurrentPassword {
+		return errors.Wrap(ErrBadRequest, "newPassword should differ from current one")
+	}
+	return validatePassword(r.NewPassword)
+}
+
+func (r RequestPasswordResetRequest) Validate() error {
+	if len(r.Email) < 1 {
+		return errors.Wrap(ErrBadRequest, "email cannot be empty")
+	}
+	return nil
+}
+
+func (r ResetPasswordRequest) Validate() error {
+	if len(r.Token) < 1 {
+		return errors.Wrap(ErrBadRequest, "token cannot be empty")
+	}
+	return validatePassword(r.NewPassword)
+}
+
+func (r UnlockUserRequest) Validate() error {
+	if len(r.Id) < 1 {
+		return errors.Wrap(ErrBadRequest, "id cannot be empty")
+	}
+	return nil
+}
+
+func (r UnlockIPRequest) Validate() error {
+	if net.ParseIP(r.Ip) == nil {
+		return errors.Wrap(ErrBadRequest, "ip should be IPv4 or IPv6 address")
+	}
+	return nil
+}
+
+// validatePassword checks new password fits password column
+func validatePassword(password string) error {
+	if len(password) < 1 || len(password) > 64 {
+		return errors.Wrap(ErrBadRequest, "newPassword should be 1-64 characters long")
+	}
+	return nil
+}
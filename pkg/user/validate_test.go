@@ -0,0 +1,45 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidationError(t *testing.T) {
+	err := errors.Wrap(&ValidationError{Violations: []Violation{
+		{Field: "password", Code: "too_short", Message: "must be at least 10 characters long"},
+		{Field: "password", Code: "breached", Message: "is known from data breaches, choose another one"},
+	}}, "userService CreateUser err")
+	assert.Equal(t, http.StatusBadRequest, getHTTPStatusCode(err))
+
+	w := httptest.NewRecorder()
+	encodeError(context.Background(), err, w)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var body struct {
+		Violations []Violation `json:"violations"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, []Violation{
+		{Field: "password", Code: "too_short", Message: "must be at least 10 characters long"},
+		{Field: "password", Code: "breached", Message: "is known from data breaches, choose another one"},
+	}, body.Violations)
+
+	st := status.Convert(grpcError(err))
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 1)
+	details, ok := st.Details()[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+	require.Len(t, details.FieldViolations, 2)
+	assert.Equal(t, "password", details.FieldViolations[0].Field)
+	assert.Equal(t, "too_short: must be at least 10 characters long", details.FieldViolations[0].Description)
+}
@@ -6,7 +6,9 @@ package integration
 import (
 	"context"
 	"github.com/nakiner/faceit/internal/repository/user"
+	"github.com/nakiner/faceit/internal/repository/user/repotest"
 	"github.com/nakiner/faceit/internal/store/database"
+	"github.com/nakiner/faceit/tools/tenant"
 	"log"
 	"testing"
 
@@ -15,7 +17,7 @@ import (
 )
 
 func TestDatabaseUserServiceCreateUser(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(tenant.WithContext(context.Background(), "default"))
 	defer cancel()
 
 	cfg := configs.NewConfig()
@@ -37,7 +39,7 @@ func TestDatabaseUserServiceCreateUser(t *testing.T) {
 }
 
 func TestDatabaseUserServiceUpdateUser(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(tenant.WithContext(context.Background(), "default"))
 	defer cancel()
 
 	cfg := configs.NewConfig()
@@ -65,7 +67,7 @@ func TestDatabaseUserServiceUpdateUser(t *testing.T) {
 }
 
 func TestDatabaseUserServiceDeleteUser(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(tenant.WithContext(context.Background(), "default"))
 	defer cancel()
 
 	cfg := configs.NewConfig()
@@ -90,7 +92,7 @@ func TestDatabaseUserServiceDeleteUser(t *testing.T) {
 }
 
 func TestDatabaseUserServiceGetUsers(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(tenant.WithContext(context.Background(), "default"))
 	defer cancel()
 
 	cfg := configs.NewConfig()
@@ -117,3 +119,19 @@ func TestDatabaseUserServiceGetUsers(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEmpty(t, users)
 }
+
+func TestDatabaseUserRepositoryConformance(t *testing.T) {
+	cfg := configs.NewConfig()
+	err := cfg.Read()
+	require.NoError(t, err)
+
+	db, err := database.Connect(context.Background(), cfg)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// master only, so reads see writes regardless of replication lag
+	master := database.NewConnection(db.Master)
+	repotest.Run(t, func(t *testing.T) user.Repository {
+		return user.NewRepository(master)
+	})
+}
@@ -4,9 +4,11 @@
 package integration
 
 import (
+	"context"
 	"fmt"
 	"github.com/nakiner/faceit/configs"
 	"github.com/nakiner/faceit/pkg/queue/user"
+	"github.com/nakiner/faceit/tools/tenant"
 	"log"
 	"testing"
 	"time"
@@ -43,7 +45,7 @@ func TestNatsPublisherUserServiceUpdateUser(t *testing.T) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	err = p.UpdateUser(&user.User{Nickname: "sample"})
+	err = p.UpdateUser(tenant.WithContext(context.Background(), "default"), &user.User{Nickname: "sample"})
 	require.NoError(t, err)
 }
 
@@ -64,8 +66,8 @@ func TestNatsSubscriberUserServiceUpdateUser(t *testing.T) {
 	defer nc.Close()
 
 	ch := make(chan string)
-	s := user.NewSubscriber(nc)
-	err = s.UpdateUser(func(u *user.User) {
+	s := user.NewSubscriber(context.Background(), nc)
+	err = s.UpdateUser(func(ctx context.Context, u *user.User) {
 		ch <- u.ID
 	})
 	require.NoError(t, err)
@@ -84,7 +86,7 @@ func TestNatsSubscriberUserServiceUpdateUser(t *testing.T) {
 	assert.NoError(t, err)
 
 	for i := 0; i < 5; i++ {
-		err = p.UpdateUser(&us)
+		err = p.UpdateUser(tenant.WithContext(context.Background(), "default"), &us)
 		assert.NoError(t, err)
 	}
 
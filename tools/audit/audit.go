@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/nakiner/faceit/tools/auth"
+	"github.com/nakiner/faceit/tools/requestid"
+	"github.com/nakiner/faceit/tools/tenant"
+)
+
+const (
+	// OutcomeAllowed is outcome of performed action.
+	OutcomeAllowed = "allowed"
+	// OutcomeDenied is outcome of action rejected by access control.
+	OutcomeDenied = "denied"
+)
+
+var auditLogger atomic.Value
+
+func init() {
+	SetLogger(log.With(log.NewJSONLogger(log.NewSyncWriter(os.Stdout)), "ts", log.DefaultTimestampUTC))
+}
+
+// SetLogger sets logger audit log is written to, JSON logger of stdout by default. Audit log must not be
+// filtered by logger.level, so it is kept apart from application logger.
+func SetLogger(logger log.Logger) {
+	auditLogger.Store(&logger)
+}
+
+// Event is security relevant action of caller.
+type Event struct {
+	// Action is operation caller attempted, e.g. method name.
+	Action string
+	// Outcome is OutcomeAllowed or OutcomeDenied.
+	Outcome string
+	// Target identifies resource action was applied to.
+	Target string
+	// Reason explains outcome.
+	Reason string
+}
+
+// Record writes event to audit log with audit=true, annotated with caller, tenant and request id.
+// Denials are logged as warnings.
+func Record(ctx context.Context, e Event) {
+	keyvals := []interface{}{"audit", true, "action", e.Action, "outcome", e.Outcome}
+	if p, ok := auth.FromContext(ctx); ok {
+		keyvals = append(keyvals, "actor", p.Subject)
+	} else {
+		keyvals = append(keyvals, "actor", "anonymous")
+	}
+	if id := tenant.FromContext(ctx); id != "" {
+		keyvals = append(keyvals, tenant.LogKey, id)
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		keyvals = append(keyvals, requestid.LogKey, id)
+	}
+	if e.Target != "" {
+		keyvals = append(keyvals, "target", e.Target)
+	}
+	if e.Reason != "" {
+		keyvals = append(keyvals, "reason", e.Reason)
+	}
+
+	logger := *auditLogger.Load().(*log.Logger)
+	if e.Outcome == OutcomeDenied {
+		logger = level.Warn(logger)
+	} else {
+		logger = level.Info(logger)
+	}
+	logger.Log(keyvals...)
+}
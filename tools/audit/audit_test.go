@@ -0,0 +1,30 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/nakiner/faceit/tools/auth"
+	"github.com/nakiner/faceit/tools/logging"
+	"github.com/nakiner/faceit/tools/requestid"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecord(t *testing.T) {
+	var buf bytes.Buffer
+	SetLogger(log.NewLogfmtLogger(&buf))
+	// application logger level does not filter audit log
+	ctx := logging.WithContext(context.Background(), level.NewFilter(log.NewNopLogger(), level.AllowError()))
+	Record(ctx, Event{Action: "DeleteUser", Outcome: OutcomeDenied, Target: "42", Reason: "missing permission"})
+	assert.Equal(t, `level=warn audit=true action=DeleteUser outcome=denied actor=anonymous target=42 reason="missing permission"`+"\n", buf.String())
+
+	buf.Reset()
+	ctx = auth.WithPrincipal(tenant.WithContext(ctx, "brand-a"), &auth.Principal{Subject: "admin-1"})
+	ctx = requestid.WithContext(ctx, "req-1")
+	Record(ctx, Event{Action: "AssignRole", Outcome: OutcomeAllowed, Target: "42"})
+	assert.Equal(t, "level=info audit=true action=AssignRole outcome=allowed actor=admin-1 tenant=brand-a request_id=req-1 target=42\n", buf.String())
+}
@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/nakiner/faceit/tools/limiting"
+	"github.com/nakiner/faceit/tools/logging"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Header carries credentials of HTTP request as "Bearer <token>".
+	Header = "Authorization"
+	// MetadataKey carries credentials of gRPC request.
+	MetadataKey = "authorization"
+	// APIKeyHeader carries API key of HTTP request, keys may also be sent as bearer credentials.
+	APIKeyHeader = "X-API-Key"
+	// APIKeyMetadataKey carries API key of gRPC request.
+	APIKeyMetadataKey = "x-api-key"
+	// LogKey is the key subject of authenticated caller is logged with.
+	LogKey = "subject"
+
+	bearer = "bearer "
+)
+
+var (
+	// ErrUnauthenticated is returned for missing, malformed, expired or otherwise invalid credentials.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// errUnavailable answers requests whose credentials could not be checked, e.g. when storage is down.
+	errUnavailable = errors.New("authentication is unavailable")
+)
+
+// Principal is authenticated caller.
+type Principal struct {
+	// Subject identifies caller, for players it is their user id.
+	Subject string
+	// Tenant caller belongs to, requests of caller are limited to it.
+	Tenant string
+	// SuperAdmin callers may work across tenants.
+	SuperAdmin bool
+	// Roles granted by credentials, callers without them get roles assigned in storage.
+	Roles []string
+	// RateLimit is requests per second allowed to caller, 0 keeps limiter policies.
+	RateLimit float64
+	// IssuedAt is when credentials were issued, zero when unknown.
+	IssuedAt time.Time
+}
+
+// Authenticator verifies credentials and returns caller they belong to. Invalid credentials are reported
+// by ErrUnauthenticated, other errors are failures to check them.
+type Authenticator interface {
+	Authenticate(ctx context.Context, credentials string) (*Principal, error)
+}
+
+type principalKey struct{}
+
+// WithPrincipal stores authenticated caller in ctx.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns authenticated caller of ctx, false for anonymous ones.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// Inject authenticates credentials and stores caller in ctx together with tenant claim, limiter principal
+// and logger annotated with subject. Empty credentials leave ctx anonymous, so authorization decides
+// whether anonymous call is allowed.
+func Inject(ctx context.Context, a Authenticator, credentials string) (context.Context, error) {
+	if credentials == "" {
+		return ctx, nil
+	}
+	p, err := Verify(ctx, a, credentials)
+	if err != nil {
+		return ctx, err
+	}
+	ctx = WithPrincipal(ctx, p)
+	// callers without tenant are kept to fallback one
+	ctx = tenant.WithClaim(ctx, p.Tenant)
+	if p.SuperAdmin {
+		ctx = tenant.WithSuperAdmin(ctx)
+	}
+	ctx = limiting.WithPrincipal(ctx, p.Subject)
+	if p.RateLimit > 0 {
+		ctx = limiting.WithQuota(ctx, p.RateLimit)
+	}
+	return logging.WithContext(ctx, log.With(logging.FromContext(ctx), LogKey, p.Subject)), nil
+}
+
+// Verify authenticates bearer credentials, e.g. returned by RequestCredentials, without storing caller in ctx.
+func Verify(ctx context.Context, a Authenticator, credentials string) (*Principal, error) {
+	if len(credentials) <= len(bearer) || !strings.EqualFold(credentials[:len(bearer)], bearer) {
+		return nil, errors.Wrap(ErrUnauthenticated, "bearer credentials expected")
+	}
+	return a.Authenticate(ctx, strings.TrimSpace(credentials[len(bearer):]))
+}
+
+// RequestCredentials returns bearer credentials of Authorization header, or X-API-Key header
+// sent as bearer credentials, empty for anonymous requests.
+func RequestCredentials(r *http.Request) string {
+	credentials := r.Header.Get(Header)
+	if key := r.Header.Get(APIKeyHeader); credentials == "" && key != "" {
+		credentials = bearer + key
+	}
+	return credentials
+}
+
+// CallCredentials is gRPC counterpart of RequestCredentials using authorization and x-api-key metadata.
+func CallCredentials(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if v := md.Get(MetadataKey); len(v) > 0 {
+		return v[0]
+	}
+	if v := md.Get(APIKeyMetadataKey); len(v) > 0 && v[0] != "" {
+		return bearer + v[0]
+	}
+	return ""
+}
+
+// Route returns Authenticator passing credentials starting with prefix to keys and other credentials to tokens.
+func Route(prefix string, keys, tokens Authenticator) Authenticator {
+	return router{prefix: prefix, keys: keys, tokens: tokens}
+}
+
+type router struct {
+	prefix       string
+	keys, tokens Authenticator
+}
+
+func (r router) Authenticate(ctx context.Context, credentials string) (*Principal, error) {
+	if strings.HasPrefix(credentials, r.prefix) {
+		return r.keys.Authenticate(ctx, credentials)
+	}
+	return r.tokens.Authenticate(ctx, credentials)
+}
+
+// Revoker reports whether credentials of caller were revoked before they expired, e.g. by password reset.
+type Revoker interface {
+	Revoked(ctx context.Context, p *Principal) (bool, error)
+}
+
+// WithRevocation returns Authenticator rejecting callers authenticated by a whose credentials are revoked.
+func WithRevocation(a Authenticator, r Revoker) Authenticator {
+	return revocation{next: a, revoker: r}
+}
+
+type revocation struct {
+	next    Authenticator
+	revoker Revoker
+}
+
+func (r revocation) Authenticate(ctx context.Context, credentials string) (*Principal, error) {
+	p, err := r.next.Authenticate(ctx, credentials)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := r.revoker.Revoked(ctx, p)
+	if err != nil {
+		return nil, errors.Wrap(err, "check revocation")
+	}
+	if revoked {
+		return nil, errors.Wrap(ErrUnauthenticated, "credentials are revoked")
+	}
+	return p, nil
+}
+
+// Middleware authenticates Authorization or X-API-Key header of request, requests with invalid credentials
+// are rejected with 401 and ones whose credentials could not be checked with 503.
+func Middleware(a Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := Inject(r.Context(), a, RequestCredentials(r))
+		switch {
+		case errors.Is(err, ErrUnauthenticated):
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, ErrUnauthenticated.Error(), http.StatusUnauthorized)
+			return
+		case err != nil:
+			level.Error(logging.FromContext(r.Context())).Log("msg", "could not authenticate", "err", err)
+			http.Error(w, errUnavailable.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// UnaryServerInterceptor is gRPC counterpart of Middleware using authorization and x-api-key metadata.
+func UnaryServerInterceptor(a Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := Inject(ctx, a, CallCredentials(ctx))
+		switch {
+		case errors.Is(err, ErrUnauthenticated):
+			return nil, status.Error(codes.Unauthenticated, ErrUnauthenticated.Error())
+		case err != nil:
+			level.Error(logging.FromContext(ctx)).Log("msg", "could not authenticate", "err", err)
+			return nil, status.Error(codes.Unavailable, errUnavailable.Error())
+		}
+		return handler(ctx, req)
+	}
+}
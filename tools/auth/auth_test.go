@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nakiner/faceit/tools/limiting"
+	"github.com/nakiner/faceit/tools/tenant"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestMiddleware(t *testing.T) {
+	a := NewJWT(secret, SetClock(func() time.Time { return now }))
+	var ctx context.Context
+	h := Middleware(a, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	}))
+	call := func(credentials string) int {
+		ctx = nil
+		r := httptest.NewRequest(http.MethodGet, "/user", nil)
+		if credentials != "" {
+			r.Header.Set(Header, credentials)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// anonymous requests are left to authorization
+	assert.Equal(t, http.StatusOK, call(""))
+	_, ok := FromContext(ctx)
+	assert.False(t, ok)
+
+	assert.Equal(t, http.StatusOK, call("Bearer "+sign(t, "HS256", secret, claims(map[string]interface{}{"scope": ScopeSuperAdmin}))))
+	p, ok := FromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, "player-1", p.Subject)
+	principal, _ := limiting.PrincipalFromContext(ctx)
+	assert.Equal(t, "player-1", principal)
+	assert.True(t, tenant.IsSuperAdmin(ctx))
+	resolved, err := tenant.Resolve(ctx, "", "default")
+	require.NoError(t, err)
+	assert.Equal(t, "brand-a", tenant.FromContext(resolved))
+
+	assert.Equal(t, http.StatusUnauthorized, call("Bearer "+sign(t, "HS256", []byte("other"), claims(nil))))
+	assert.Equal(t, http.StatusUnauthorized, call("Basic dXNlcjpwYXNz"))
+	assert.Nil(t, ctx)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(NewJWT(secret, SetClock(func() time.Time { return now })))
+	var subject string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		if p, ok := FromContext(ctx); ok {
+			subject = p.Subject
+		}
+		return nil, nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataKey, "Bearer "+sign(t, "HS256", secret, claims(nil))))
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "player-1", subject)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataKey, "Bearer token"))
+	_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+type staticKeys map[string]*Principal
+
+func (s staticKeys) Authenticate(_ context.Context, credentials string) (*Principal, error) {
+	if p, ok := s[credentials]; ok {
+		return p, nil
+	}
+	return nil, ErrUnauthenticated
+}
+
+func TestRoute(t *testing.T) {
+	keys := staticKeys{"fk_0123abcd_secret": {Subject: "fk_0123abcd", Tenant: "brand-a", Roles: []string{"service"}, RateLimit: 5}}
+	a := Route("fk_", keys, NewJWT(secret, SetClock(func() time.Time { return now })))
+	var ctx context.Context
+	h := Middleware(a, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	}))
+	call := func(header, value string) int {
+		r := httptest.NewRequest(http.MethodGet, "/user", nil)
+		r.Header.Set(header, value)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call(APIKeyHeader, "fk_0123abcd_secret"))
+	p, ok := FromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, "fk_0123abcd", p.Subject)
+	quota, ok := limiting.QuotaFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, 5.0, quota)
+
+	assert.Equal(t, http.StatusOK, call(Header, "Bearer fk_0123abcd_secret"))
+	assert.Equal(t, http.StatusOK, call(Header, "Bearer "+sign(t, "HS256", secret, claims(nil))))
+	p, _ = FromContext(ctx)
+	assert.Equal(t, "player-1", p.Subject)
+	assert.Equal(t, http.StatusUnauthorized, call(APIKeyHeader, "fk_0123abcd_other"))
+}
+
+type failingAuthenticator struct{}
+
+func (failingAuthenticator) Authenticate(context.Context, string) (*Principal, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestMiddleware_Unavailable(t *testing.T) {
+	h := Middleware(failingAuthenticator{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	r := httptest.NewRequest(http.MethodGet, "/user", nil)
+	r.Header.Set(APIKeyHeader, "fk_0123abcd_secret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(APIKeyMetadataKey, "fk_0123abcd_secret"))
+	_, err := UnaryServerInterceptor(failingAuthenticator{})(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+type revokedBefore time.Time
+
+func (r revokedBefore) Revoked(_ context.Context, p *Principal) (bool, error) {
+	return p.IssuedAt.Before(time.Time(r)), nil
+}
+
+func TestWithRevocation(t *testing.T) {
+	a := WithRevocation(NewJWT(secret, SetClock(func() time.Time { return now })), revokedBefore(now.Add(-time.Minute)))
+
+	p, err := a.Authenticate(context.Background(), sign(t, "HS256", secret, claims(map[string]interface{}{"iat": now.Unix()})))
+	require.NoError(t, err)
+	assert.Equal(t, "player-1", p.Subject)
+
+	_, err = a.Authenticate(context.Background(), sign(t, "HS256", secret, claims(map[string]interface{}{"iat": now.Add(-time.Hour).Unix()})))
+	assert.True(t, errors.Is(err, ErrUnauthenticated), "got %v", err)
+	_, err = a.Authenticate(context.Background(), sign(t, "HS256", secret, claims(nil)))
+	assert.True(t, errors.Is(err, ErrUnauthenticated), "got %v", err)
+}
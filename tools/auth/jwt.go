@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ScopeSuperAdmin in scope claim of token grants access to every tenant.
+const ScopeSuperAdmin = "superadmin"
+
+type jwtOptions struct {
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+type Option func(*jwtOptions)
+
+// SetIssuer requires iss claim of tokens to be equal to issuer.
+func SetIssuer(issuer string) Option {
+	return func(o *jwtOptions) {
+		o.issuer = issuer
+	}
+}
+
+// SetAudience requires aud claim of tokens to contain audience.
+func SetAudience(audience string) Option {
+	return func(o *jwtOptions) {
+		o.audience = audience
+	}
+}
+
+// SetLeeway sets allowed clock skew of exp and nbf claims.
+func SetLeeway(leeway time.Duration) Option {
+	return func(o *jwtOptions) {
+		o.leeway = leeway
+	}
+}
+
+// SetClock replaces time source, for tests.
+func SetClock(now func() time.Time) Option {
+	return func(o *jwtOptions) {
+		o.now = now
+	}
+}
+
+type jwtAuthenticator struct {
+	secret []byte
+	o      *jwtOptions
+}
+
+// NewJWT creates Authenticator of JWT bearer tokens signed with HS256 and secret.
+// Tokens must have sub and exp claims, tenant and space separated scope claims are optional.
+func NewJWT(secret []byte, opts ...Option) Authenticator {
+	o := &jwtOptions{now: time.Now}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &jwtAuthenticator{secret: secret, o: o}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+}
+
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+	IssuedAt  int64    `json:"iat"`
+	Tenant    string   `json:"tenant"`
+	Scope     string   `json:"scope"`
+}
+
+// audience is aud claim, which is either a string or an array of them
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (j *jwtAuthenticator) Authenticate(_ context.Context, token string) (*Principal, error) {
+	claims, err := j.verify(token)
+	if err != nil {
+		return nil, errors.Wrap(ErrUnauthenticated, err.Error())
+	}
+	p := &Principal{Subject: claims.Subject, Tenant: claims.Tenant}
+	if claims.IssuedAt != 0 {
+		p.IssuedAt = time.Unix(claims.IssuedAt, 0)
+	}
+	for _, scope := range strings.Fields(claims.Scope) {
+		if scope == ScopeSuperAdmin {
+			p.SuperAdmin = true
+		}
+	}
+	return p, nil
+}
+
+func (j *jwtAuthenticator) verify(token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.Wrap(err, "decode header")
+	}
+	// algorithm is fixed, so tokens may not downgrade it to none or switch to other key type
+	if header.Alg != "HS256" {
+		return nil, errors.Errorf("unsupported algorithm %q", header.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "decode signature")
+	}
+	mac := hmac.New(sha256.New, j.secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, errors.New("invalid signature")
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errors.Wrap(err, "decode claims")
+	}
+	now := j.o.now()
+	switch {
+	case claims.Subject == "":
+		return nil, errors.New("sub claim is required")
+	case claims.ExpiresAt == 0:
+		return nil, errors.New("exp claim is required")
+	case now.After(time.Unix(claims.ExpiresAt, 0).Add(j.o.leeway)):
+		return nil, errors.New("token is expired")
+	case claims.NotBefore != 0 && now.Before(time.Unix(claims.NotBefore, 0).Add(-j.o.leeway)):
+		return nil, errors.New("token is not valid yet")
+	case j.o.issuer != "" && claims.Issuer != j.o.issuer:
+		return nil, errors.Errorf("unexpected issuer %q", claims.Issuer)
+	case j.o.audience != "" && !claims.Audience.contains(j.o.audience):
+		return nil, errors.New("token is not issued for this service")
+	}
+	return &claims, nil
+}
+
+func (a audience) contains(v string) bool {
+	for _, s := range a {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
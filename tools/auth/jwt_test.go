@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	secret = []byte("0123456789abcdef0123456789abcdef")
+	now    = time.Unix(1640995200, 0)
+)
+
+// sign returns token with claims signed by key using alg
+func sign(t *testing.T, alg string, key []byte, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func claims(extra map[string]interface{}) map[string]interface{} {
+	c := map[string]interface{}{
+		"sub":    "player-1",
+		"tenant": "brand-a",
+		"exp":    now.Add(time.Hour).Unix(),
+		"iss":    "faceit-auth",
+		"aud":    []string{"faceit"},
+	}
+	for k, v := range extra {
+		if v == nil {
+			delete(c, k)
+			continue
+		}
+		c[k] = v
+	}
+	return c
+}
+
+func TestJWT_Authenticate(t *testing.T) {
+	a := NewJWT(secret, SetIssuer("faceit-auth"), SetAudience("faceit"), SetLeeway(time.Minute), SetClock(func() time.Time { return now }))
+
+	testCases := []struct {
+		name  string
+		token string
+		want  *Principal
+	}{
+		{name: "valid", token: sign(t, "HS256", secret, claims(nil)), want: &Principal{Subject: "player-1", Tenant: "brand-a"}},
+		{name: "super-admin", token: sign(t, "HS256", secret, claims(map[string]interface{}{"scope": "users superadmin"})), want: &Principal{Subject: "player-1", Tenant: "brand-a", SuperAdmin: true}},
+		{name: "audience string", token: sign(t, "HS256", secret, claims(map[string]interface{}{"aud": "faceit"})), want: &Principal{Subject: "player-1", Tenant: "brand-a"}},
+		{name: "issued at", token: sign(t, "HS256", secret, claims(map[string]interface{}{"iat": now.Add(-time.Minute).Unix()})), want: &Principal{Subject: "player-1", Tenant: "brand-a", IssuedAt: now.Add(-time.Minute)}},
+		{name: "within leeway", token: sign(t, "HS256", secret, claims(map[string]interface{}{"exp": now.Add(-30 * time.Second).Unix()})), want: &Principal{Subject: "player-1", Tenant: "brand-a"}},
+		{name: "expired", token: sign(t, "HS256", secret, claims(map[string]interface{}{"exp": now.Add(-2 * time.Minute).Unix()}))},
+		{name: "not yet valid", token: sign(t, "HS256", secret, claims(map[string]interface{}{"nbf": now.Add(time.Hour).Unix()}))},
+		{name: "without exp", token: sign(t, "HS256", secret, claims(map[string]interface{}{"exp": nil}))},
+		{name: "without sub", token: sign(t, "HS256", secret, claims(map[string]interface{}{"sub": nil}))},
+		{name: "other issuer", token: sign(t, "HS256", secret, claims(map[string]interface{}{"iss": "other"}))},
+		{name: "other audience", token: sign(t, "HS256", secret, claims(map[string]interface{}{"aud": "other"}))},
+		{name: "other key", token: sign(t, "HS256", []byte("other"), claims(nil))},
+		{name: "other algorithm", token: sign(t, "none", secret, claims(nil))},
+		{name: "malformed", token: "token"},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			p, err := a.Authenticate(context.Background(), c.token)
+			if c.want == nil {
+				assert.True(t, errors.Is(err, ErrUnauthenticated), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, c.want, p)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Eviction reasons passed to eviction listener.
+const (
+	EvictedCapacity    = "capacity"
+	EvictedExpired     = "expired"
+	EvictedInvalidated = "invalidated"
+)
+
+// LRU is thread-safe cache bounded by number of entries, least recently used entry is evicted
+// when it is full and entries expire after TTL.
+type LRU struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	ll      *list.List
+	items   map[string]*list.Element
+	now     func() time.Time
+	onEvict func(reason string)
+}
+
+type entry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+type Option func(*LRU)
+
+// SetOnEvict sets listener called with reason of every eviction, e.g. to count them.
+func SetOnEvict(fn func(reason string)) Option {
+	return func(c *LRU) {
+		c.onEvict = fn
+	}
+}
+
+// SetClock replaces time source, used in tests.
+func SetClock(now func() time.Time) Option {
+	return func(c *LRU) {
+		c.now = now
+	}
+}
+
+// NewLRU creates cache holding up to size entries for ttl each.
+func NewLRU(size int, ttl time.Duration, opts ...Option) *LRU {
+	c := &LRU{
+		size:    size,
+		ttl:     ttl,
+		ll:      list.New(),
+		items:   make(map[string]*list.Element),
+		now:     time.Now,
+		onEvict: func(string) {},
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// Get returns value of key unless it is missing or expired.
+func (c *LRU) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if c.now().After(e.expires) {
+		c.remove(el, EvictedExpired)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return e.value, true
+}
+
+// Set stores value of key, least recently used entry is evicted when cache is full.
+func (c *LRU) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := c.now().Add(c.ttl)
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry)
+		e.value = value
+		e.expires = expires
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&entry{key: key, value: value, expires: expires})
+	for c.ll.Len() > c.size {
+		c.remove(c.ll.Back(), EvictedCapacity)
+	}
+}
+
+// Delete removes key, reported as invalidation when it was cached.
+func (c *LRU) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.remove(el, EvictedInvalidated)
+	}
+}
+
+// Len returns number of entries including expired ones not evicted yet.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *LRU) remove(el *list.Element, reason string) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*entry).key)
+	c.onEvict(reason)
+}
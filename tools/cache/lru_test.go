@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	now := time.Now()
+	evicted := map[string]int{}
+	c := NewLRU(2, time.Minute,
+		SetClock(func() time.Time { return now }),
+		SetOnEvict(func(reason string) { evicted[reason]++ }),
+	)
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	// a becomes recently used, so b is evicted by c
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	c.Set("c", 3)
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+	assert.Equal(t, 2, c.Len())
+
+	c.Delete("c")
+	_, ok = c.Get("c")
+	assert.False(t, ok)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+
+	assert.Equal(t, map[string]int{
+		EvictedCapacity:    1,
+		EvictedInvalidated: 1,
+		EvictedExpired:     1,
+	}, evicted)
+}
+
+func TestLRU_SetRefreshes(t *testing.T) {
+	now := time.Now()
+	c := NewLRU(2, time.Minute, SetClock(func() time.Time { return now }))
+	c.Set("a", 1)
+	now = now.Add(50 * time.Second)
+	c.Set("a", 2)
+	now = now.Add(50 * time.Second)
+
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 2, v)
+	assert.Equal(t, 1, c.Len())
+}
@@ -0,0 +1,49 @@
+package features
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type flagsKey struct{}
+
+// Flags holds feature flags which can be replaced at runtime.
+type Flags struct {
+	current atomic.Value
+}
+
+// New creates Flags with given state, unknown flags are disabled.
+func New(flags map[string]bool) *Flags {
+	f := &Flags{}
+	f.Set(flags)
+	return f
+}
+
+// Set replaces all flags.
+func (f *Flags) Set(flags map[string]bool) {
+	copied := make(map[string]bool, len(flags))
+	for k, v := range flags {
+		copied[k] = v
+	}
+	f.current.Store(copied)
+}
+
+// Enabled reports whether flag is on.
+func (f *Flags) Enabled(name string) bool {
+	if f == nil {
+		return false
+	}
+	return f.current.Load().(map[string]bool)[name]
+}
+
+func WithContext(ctx context.Context, flags *Flags) context.Context {
+	return context.WithValue(ctx, flagsKey{}, flags)
+}
+
+// FromContext returns flags stored in context, nil Flags report every flag as disabled.
+func FromContext(ctx context.Context) *Flags {
+	if flags, ok := ctx.Value(flagsKey{}).(*Flags); ok {
+		return flags
+	}
+	return nil
+}
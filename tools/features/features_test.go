@@ -0,0 +1,26 @@
+package features
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlags(t *testing.T) {
+	state := map[string]bool{"new_matchmaking": true}
+	f := New(state)
+	state["new_matchmaking"] = false
+	assert.True(t, f.Enabled("new_matchmaking"))
+	assert.False(t, f.Enabled("unknown"))
+
+	f.Set(map[string]bool{"new_matchmaking": false})
+	assert.False(t, f.Enabled("new_matchmaking"))
+}
+
+func TestFromContext(t *testing.T) {
+	assert.False(t, FromContext(context.Background()).Enabled("any"))
+
+	f := New(map[string]bool{"any": true})
+	assert.True(t, FromContext(WithContext(context.Background(), f)).Enabled("any"))
+}
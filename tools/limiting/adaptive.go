@@ -0,0 +1,251 @@
+package limiting
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/nakiner/faceit/tools/logging"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ConcurrencyLimiter sheds requests exceeding adaptive concurrency limit.
+type ConcurrencyLimiter interface {
+	Middleware(next http.Handler) http.Handler
+	UnaryServerInterceptor() grpc.UnaryServerInterceptor
+	Limit() int
+}
+
+type AdaptiveOption func(*adaptiveLimiter)
+
+// SetLimits sets initial limit and bounds it can move within.
+func SetLimits(initial, min, max int) AdaptiveOption {
+	return func(l *adaptiveLimiter) {
+		if min > 0 {
+			l.min = float64(min)
+		}
+		if max > 0 {
+			l.max = float64(max)
+		}
+		if initial > 0 {
+			l.limit = float64(initial)
+		}
+		l.limit = math.Max(l.min, math.Min(l.max, l.limit))
+	}
+}
+
+// SetLatencyTarget sets latency above which limit is decreased.
+func SetLatencyTarget(d time.Duration) AdaptiveOption {
+	return func(l *adaptiveLimiter) {
+		if d > 0 {
+			l.target = d
+		}
+	}
+}
+
+// SetBackoff sets multiplier applied to limit on overload, between 0 and 1.
+func SetBackoff(ratio float64) AdaptiveOption {
+	return func(l *adaptiveLimiter) {
+		if ratio > 0 && ratio < 1 {
+			l.backoff = ratio
+		}
+	}
+}
+
+// SetPriority sets path prefixes (or gRPC full method prefixes) which are never shed.
+func SetPriority(prefixes []string) AdaptiveOption {
+	return func(l *adaptiveLimiter) {
+		l.priority = prefixes
+	}
+}
+
+// Prioritizer recognizes callers which are never shed, e.g. internal admin traffic. Limiter runs before
+// authentication, so Prioritizer checks credentials of request itself, it is asked only when limit is exceeded.
+type Prioritizer interface {
+	PriorityRequest(r *http.Request) bool
+	PriorityCall(ctx context.Context) bool
+}
+
+// SetPrioritizer sets check of callers which are never shed.
+func SetPrioritizer(p Prioritizer) AdaptiveOption {
+	return func(l *adaptiveLimiter) {
+		l.prioritizer = p
+	}
+}
+
+// NewAdaptiveLimiter returns AIMD concurrency limiter: limit grows by one per
+// limit of requests served within latency target and is multiplied by backoff
+// when latency exceeds target. Limit is decreased once per overload, by requests
+// started after previous decrease, so burst of slow requests backs off only once.
+func NewAdaptiveLimiter(ctx context.Context, opts ...AdaptiveOption) ConcurrencyLimiter {
+	logger := logging.FromContext(ctx)
+	logger = log.With(logger, "component", "concurrency limiter")
+	l := &adaptiveLimiter{
+		limit:   20,
+		min:     1,
+		max:     1000,
+		target:  250 * time.Millisecond,
+		backoff: 0.9,
+		logger:  logger,
+		metrics: getAdaptiveMetrics(),
+	}
+	for _, o := range opts {
+		o(l)
+	}
+	l.metrics.limit.Set(l.limit)
+	return l
+}
+
+type adaptiveLimiter struct {
+	mu       sync.Mutex
+	limit    float64
+	min      float64
+	max      float64
+	inflight int
+	target   time.Duration
+	backoff  float64
+	priority []string
+	logger   log.Logger
+	metrics  *adaptiveMetrics
+	// decreased is when limit was decreased last
+	decreased   time.Time
+	prioritizer Prioritizer
+}
+
+func (l *adaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+func (l *adaptiveLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.isPriority(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !l.acquire() {
+			if l.prioritizer != nil && l.prioritizer.PriorityRequest(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			l.metrics.rejected.With("transport", "http").Add(1)
+			level.Debug(l.logger).Log(
+				"code", http.StatusServiceUnavailable,
+				"msg", "concurrency limit exceeded",
+				"limit", l.Limit(),
+			)
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		begin := time.Now()
+		defer func() { l.release(begin, time.Now()) }()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *adaptiveLimiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if l.isPriority(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		if !l.acquire() {
+			if l.prioritizer != nil && l.prioritizer.PriorityCall(ctx) {
+				return handler(ctx, req)
+			}
+			l.metrics.rejected.With("transport", "grpc").Add(1)
+			level.Debug(l.logger).Log(
+				"code", codes.Unavailable,
+				"msg", "concurrency limit exceeded",
+				"limit", l.Limit(),
+			)
+			return nil, status.Error(codes.Unavailable, "concurrency limit exceeded")
+		}
+		begin := time.Now()
+		defer func() { l.release(begin, time.Now()) }()
+		return handler(ctx, req)
+	}
+}
+
+func (l *adaptiveLimiter) isPriority(path string) bool {
+	for _, p := range l.priority {
+		if p != "" && strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *adaptiveLimiter) acquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if float64(l.inflight) >= math.Floor(l.limit) {
+		return false
+	}
+	l.inflight++
+	l.metrics.inflight.Set(float64(l.inflight))
+	return true
+}
+
+// release ends request served from begin to end
+func (l *adaptiveLimiter) release(begin, end time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inflight--
+	if end.Sub(begin) > l.target {
+		// requests in flight during decrease already saw the same overload
+		if begin.After(l.decreased) {
+			l.limit = math.Max(l.min, l.limit*l.backoff)
+			l.decreased = end
+		}
+	} else {
+		l.limit = math.Min(l.max, l.limit+1/l.limit)
+	}
+	l.metrics.inflight.Set(float64(l.inflight))
+	l.metrics.limit.Set(l.limit)
+}
+
+type adaptiveMetrics struct {
+	limit    metrics.Gauge
+	inflight metrics.Gauge
+	rejected metrics.Counter
+}
+
+var (
+	adaptiveOnce   sync.Once
+	adaptiveMetric *adaptiveMetrics
+)
+
+func getAdaptiveMetrics() *adaptiveMetrics {
+	adaptiveOnce.Do(func() {
+		adaptiveMetric = &adaptiveMetrics{
+			limit: kitprometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+				Namespace: "faceit",
+				Name:      "concurrency_limit",
+				Help:      "Current adaptive concurrency limit.",
+			}, []string{}),
+			inflight: kitprometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+				Namespace: "faceit",
+				Name:      "concurrency_inflight",
+				Help:      "Number of requests being served under concurrency limit.",
+			}, []string{}),
+			rejected: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "faceit",
+				Name:      "concurrency_rejected_total",
+				Help:      "Number of requests shed by concurrency limiter.",
+			}, []string{"transport"}),
+		}
+	})
+	return adaptiveMetric
+}
@@ -0,0 +1,138 @@
+package limiting
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAdaptiveLimiter_Shed(t *testing.T) {
+	l := NewAdaptiveLimiter(context.Background(), SetLimits(1, 1, 1), SetPriority([]string{"/liveness"}))
+	release := make(chan struct{})
+	started := make(chan struct{})
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/user" {
+			close(started)
+			<-release
+		}
+		w.WriteHeader(200)
+	}))
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/user", nil))
+		done <- w.Code
+	}()
+	<-started
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/other", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/liveness", nil))
+	assert.Equal(t, 200, w.Code)
+
+	close(release)
+	assert.Equal(t, 200, <-done)
+}
+
+func TestAdaptiveLimiter_AIMD(t *testing.T) {
+	l := NewAdaptiveLimiter(context.Background(), SetLimits(10, 2, 11), SetLatencyTarget(time.Second), SetBackoff(0.5)).(*adaptiveLimiter)
+	now := time.Now()
+	serve := func(took time.Duration) {
+		assert.True(t, l.acquire())
+		begin := now
+		now = now.Add(took)
+		l.release(begin, now)
+	}
+
+	for i := 0; i < 10; i++ {
+		serve(time.Millisecond)
+	}
+	assert.Equal(t, 10, l.Limit())
+	serve(time.Millisecond)
+	assert.Equal(t, 11, l.Limit())
+
+	serve(2 * time.Second)
+	assert.Equal(t, 5, l.Limit())
+
+	for i := 0; i < 3; i++ {
+		serve(2 * time.Second)
+	}
+	assert.Equal(t, 2, l.Limit())
+}
+
+func TestAdaptiveLimiter_ConcurrentSlow(t *testing.T) {
+	const n = 5
+	l := NewAdaptiveLimiter(context.Background(), SetLimits(10, 1, 10), SetLatencyTarget(time.Millisecond), SetBackoff(0.5))
+	var started sync.WaitGroup
+	started.Add(n)
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started.Done()
+		started.Wait()
+		time.Sleep(10 * time.Millisecond)
+		w.WriteHeader(200)
+	}))
+
+	var done sync.WaitGroup
+	for i := 0; i < n; i++ {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user", nil))
+		}()
+	}
+	done.Wait()
+	// one overload backs off once, not once per slow request
+	assert.Equal(t, 5, l.Limit())
+}
+
+type headerPrioritizer string
+
+func (h headerPrioritizer) PriorityRequest(r *http.Request) bool {
+	return r.Header.Get(string(h)) != ""
+}
+
+func (h headerPrioritizer) PriorityCall(ctx context.Context) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return len(md.Get(string(h))) > 0
+}
+
+func TestAdaptiveLimiter_Prioritizer(t *testing.T) {
+	l := NewAdaptiveLimiter(context.Background(), SetLimits(1, 1, 1), SetPrioritizer(headerPrioritizer("x-admin"))).(*adaptiveLimiter)
+	assert.True(t, l.acquire())
+
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/user", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/user", nil)
+	r.Header.Set("X-Admin", "1")
+	h.ServeHTTP(w, r)
+	assert.Equal(t, 200, w.Code)
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/faceitpb.UserService/GetUsers"}
+	_, err := l.UnaryServerInterceptor()(context.Background(), nil, info, handler)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-admin", "1"))
+	_, err = l.UnaryServerInterceptor()(ctx, nil, info, handler)
+	assert.NoError(t, err)
+}
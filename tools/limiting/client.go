@@ -0,0 +1,47 @@
+package limiting
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+type clientIPKey struct{}
+
+// WithClientIP stores ip of client request came from.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIPFromContext returns client ip stored by ClientIPMiddleware or ClientIPInterceptor, empty when there is none.
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// ClientIPMiddleware stores client ip of requests in their context, X-Forwarded-For is honored
+// for requests of trusted proxies as limiter does.
+func ClientIPMiddleware(trusted []*net.IPNet, next http.Handler) http.Handler {
+	k := &keyer{trustedProxies: trusted}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := k.clientIP(r.RemoteAddr, r.Header.Values("X-Forwarded-For"))
+		next.ServeHTTP(w, r.WithContext(WithClientIP(r.Context(), ip)))
+	})
+}
+
+// ClientIPInterceptor is ClientIPMiddleware of gRPC calls.
+func ClientIPInterceptor(trusted []*net.IPNet) grpc.UnaryServerInterceptor {
+	k := &keyer{trustedProxies: trusted}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		var remote string
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			remote = p.Addr.String()
+		}
+		return handler(WithClientIP(ctx, k.clientIP(remote, md.Get("x-forwarded-for"))), req)
+	}
+}
@@ -0,0 +1,61 @@
+package limiting
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor limits gRPC calls per client ip with the same policies as Middleware.
+func (l *limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		p, name := l.match("", info.FullMethod)
+		if err := l.allowGRPC(ctx, name+"|"+l.keyer.grpcKey(ctx), p, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// QuotaInterceptor limits gRPC calls of authenticated principals having quota, others pass through.
+func (l *limiter) QuotaInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if p, key, ok := quotaFor(ctx); ok {
+			if err := l.allowGRPC(ctx, key, p, info.FullMethod); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// allowGRPC takes token from bucket of key and sets ratelimit headers, or returns ResourceExhausted
+// when bucket is empty.
+func (l *limiter) allowGRPC(ctx context.Context, key string, p Policy, method string) error {
+	q := l.store.take(key, &p, time.Now())
+
+	md := metadata.Pairs(
+		"ratelimit-limit", strconv.Itoa(q.limit),
+		"ratelimit-remaining", strconv.Itoa(q.remaining),
+		"ratelimit-reset", strconv.Itoa(seconds(q.reset)),
+	)
+	if !q.allowed {
+		level.Debug(l.logger).Log(
+			"code", codes.ResourceExhausted,
+			"msg", "rate limit exceeded",
+			"key", key,
+			"method", method,
+		)
+		md.Set("retry-after", strconv.Itoa(seconds(q.retryAfter)))
+		grpc.SetHeader(ctx, md)
+		return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	grpc.SetHeader(ctx, md)
+	return nil
+}
@@ -0,0 +1,121 @@
+package limiting
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+type principalKey struct{}
+
+// WithPrincipal stores authenticated principal, its quota is limited per principal.
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns principal stored by WithPrincipal.
+func PrincipalFromContext(ctx context.Context) (string, bool) {
+	principal, ok := ctx.Value(principalKey{}).(string)
+	return principal, ok && principal != ""
+}
+
+type quotaKey struct{}
+
+// WithQuota stores requests per second granted to principal, it is enforced on top of route policies.
+func WithQuota(ctx context.Context, limit float64) context.Context {
+	return context.WithValue(ctx, quotaKey{}, limit)
+}
+
+// QuotaFromContext returns quota stored by WithQuota.
+func QuotaFromContext(ctx context.Context) (float64, bool) {
+	limit, ok := ctx.Value(quotaKey{}).(float64)
+	return limit, ok && limit > 0
+}
+
+// keyer resolves client key of HTTP requests and gRPC calls, their client ip. Limiter runs before
+// authentication, so request contents like api keys are never used, clients could pick a fresh one per request.
+type keyer struct {
+	trustedProxies []*net.IPNet
+}
+
+func (k *keyer) httpKey(r *http.Request) string {
+	return "ip:" + k.clientIP(r.RemoteAddr, r.Header.Values("X-Forwarded-For"))
+}
+
+func (k *keyer) grpcKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var remote string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remote = p.Addr.String()
+	}
+	return "ip:" + k.clientIP(remote, md.Get("x-forwarded-for"))
+}
+
+// clientIP returns remote ip, or when request came through trusted proxy,
+// the right-most untrusted address from X-Forwarded-For. When every hop is trusted
+// remote ip is returned, as the left-most hop is set by client and may be spoofed.
+func (k *keyer) clientIP(remoteAddr string, forwarded []string) string {
+	ip := remoteAddr
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		ip = host
+	}
+	if !k.trusted(ip) {
+		return ip
+	}
+
+	var hops []string
+	for _, v := range forwarded {
+		for _, hop := range strings.Split(v, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if !k.trusted(hops[i]) {
+			return hops[i]
+		}
+	}
+	return ip
+}
+
+func (k *keyer) trusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range k.trustedProxies {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseCIDRs parses list of CIDRs or plain ip addresses.
+func ParseCIDRs(values []string) ([]*net.IPNet, error) {
+	var res []*net.IPNet
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if !strings.Contains(v, "/") {
+			if ip := net.ParseIP(v); ip != nil && ip.To4() != nil {
+				v += "/32"
+			} else {
+				v += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, n)
+	}
+	return res, nil
+}
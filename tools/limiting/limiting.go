@@ -2,42 +2,215 @@ package limiting
 
 import (
 	"context"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/nakiner/faceit/tools/logging"
-	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
 )
 
+// Limiter limits requests per client ip before authentication, Middleware and UnaryServerInterceptor
+// must run outside of auth so unauthenticated floods are rejected cheaply. QuotaMiddleware and
+// QuotaInterceptor run inside of auth and additionally enforce quota of authenticated principal.
 type Limiter interface {
 	Middleware(next http.Handler) http.Handler
+	UnaryServerInterceptor() grpc.UnaryServerInterceptor
+	QuotaMiddleware(next http.Handler) http.Handler
+	QuotaInterceptor() grpc.UnaryServerInterceptor
+	// SetLimit changes default quota at runtime, tracked clients are moved to it on their next request.
+	SetLimit(limit float64, burst int)
 }
 
-func NewLimiter(ctx context.Context, limit float64) Limiter {
+// Policy defines quota for requests matching Method and Path prefix, "*" segment of Path matches any
+// segment, e.g. /user/*/password. Empty Method matches any method, for gRPC Path is matched against
+// full method name.
+type Policy struct {
+	Method string
+	Path   string
+	Limit  float64
+	Burst  int
+}
+
+type Option func(*limiter)
+
+// SetBurst sets burst of default policy.
+func SetBurst(burst int) Option {
+	return func(l *limiter) {
+		if burst > 0 {
+			l.policy.Burst = burst
+		}
+	}
+}
+
+// SetPolicies sets per-route policies, the longest matching path wins.
+func SetPolicies(policies []Policy) Option {
+	return func(l *limiter) {
+		for _, p := range policies {
+			if p.Burst < 1 {
+				p.Burst = defaultBurst(p.Limit)
+			}
+			l.policies = append(l.policies, p)
+		}
+	}
+}
+
+// SetTrustedProxies sets networks whose X-Forwarded-For is honored.
+func SetTrustedProxies(nets []*net.IPNet) Option {
+	return func(l *limiter) {
+		l.keyer.trustedProxies = nets
+	}
+}
+
+// SetStore sets LRU size and idle timeout after which client keys are evicted.
+func SetStore(maxKeys int, idleTimeout time.Duration) Option {
+	return func(l *limiter) {
+		l.store = newStore(maxKeys, idleTimeout)
+	}
+}
+
+func NewLimiter(ctx context.Context, limit float64, opts ...Option) Limiter {
 	logger := logging.FromContext(ctx)
 	logger = log.With(logger, "component", "limiter")
-	return &limiter{
-		limiter: rate.NewLimiter(rate.Limit(limit), 1),
-		logger:  logger,
+	l := &limiter{
+		policy: Policy{Limit: limit, Burst: defaultBurst(limit)},
+		keyer:  &keyer{},
+		store:  newStore(10000, 10*time.Minute),
+		logger: logger,
+	}
+	for _, o := range opts {
+		o(l)
 	}
+	return l
 }
 
 type limiter struct {
-	limiter *rate.Limiter
-	logger  log.Logger
+	mu       sync.RWMutex
+	policy   Policy
+	policies []Policy
+	keyer    *keyer
+	store    *store
+	logger   log.Logger
 }
 
 func (l *limiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if l.limiter.Allow() == false {
-			level.Debug(l.logger).Log(
-				"code", http.StatusTooManyRequests,
-				"msg", http.StatusText(http.StatusTooManyRequests),
-			)
-			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
-			return
-		}
-		next.ServeHTTP(w, r)
+		p, name := l.match(r.Method, r.URL.Path)
+		if l.allowHTTP(w, name+"|"+l.keyer.httpKey(r), p, name) {
+			next.ServeHTTP(w, r)
+		}
 	})
 }
+
+// QuotaMiddleware limits authenticated principals having quota, others pass through.
+func (l *limiter) QuotaMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, key, ok := quotaFor(r.Context())
+		if !ok || l.allowHTTP(w, key, p, "quota") {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// allowHTTP takes token from bucket of key and writes RateLimit headers, or 429 when bucket is empty.
+func (l *limiter) allowHTTP(w http.ResponseWriter, key string, p Policy, name string) bool {
+	q := l.store.take(key, &p, time.Now())
+
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(q.limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(q.remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(seconds(q.reset)))
+	if !q.allowed {
+		level.Debug(l.logger).Log(
+			"code", http.StatusTooManyRequests,
+			"msg", http.StatusText(http.StatusTooManyRequests),
+			"key", key,
+			"policy", name,
+		)
+		w.Header().Set("Retry-After", strconv.Itoa(seconds(q.retryAfter)))
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+	}
+	return q.allowed
+}
+
+func (l *limiter) SetLimit(limit float64, burst int) {
+	if burst < 1 {
+		burst = defaultBurst(limit)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.policy.Limit = limit
+	l.policy.Burst = burst
+}
+
+// quotaFor returns quota policy of principal of ctx and its bucket key, ok is false when it has none.
+func quotaFor(ctx context.Context) (Policy, string, bool) {
+	limit, ok := QuotaFromContext(ctx)
+	if !ok {
+		return Policy{}, "", false
+	}
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return Policy{}, "", false
+	}
+	return Policy{Limit: limit, Burst: defaultBurst(limit)}, "quota|" + principal, true
+}
+
+// match returns policy for request and its name used to separate buckets.
+func (l *limiter) match(method, path string) (Policy, string) {
+	var found *Policy
+	for i := range l.policies {
+		p := &l.policies[i]
+		if p.Method != "" && !strings.EqualFold(p.Method, method) {
+			continue
+		}
+		if !hasPathPrefix(path, p.Path) {
+			continue
+		}
+		if found == nil || len(p.Path) > len(found.Path) || (len(p.Path) == len(found.Path) && p.Method != "") {
+			found = p
+		}
+	}
+	if found == nil {
+		l.mu.RLock()
+		defer l.mu.RUnlock()
+		return l.policy, ""
+	}
+	return *found, found.Method + " " + found.Path
+}
+
+// hasPathPrefix reports whether path starts with prefix, "*" segments of prefix match any segment
+func hasPathPrefix(path, prefix string) bool {
+	if !strings.Contains(prefix, "*") {
+		return strings.HasPrefix(path, prefix)
+	}
+	want := strings.Split(prefix, "/")
+	got := strings.Split(path, "/")
+	if len(got) < len(want) {
+		return false
+	}
+	last := len(want) - 1
+	for i, seg := range want[:last] {
+		if seg != "*" && seg != got[i] {
+			return false
+		}
+	}
+	return want[last] == "*" || strings.HasPrefix(got[last], want[last])
+}
+
+func defaultBurst(limit float64) int {
+	if limit < 1 {
+		return 1
+	}
+	return int(limit)
+}
+
+// seconds rounds duration up to whole seconds as required by RateLimit-Reset and Retry-After.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
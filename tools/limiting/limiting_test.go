@@ -9,6 +9,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestLimiter(t *testing.T) {
@@ -37,3 +40,211 @@ func TestLimiter(t *testing.T) {
 		ts.Close()
 	}
 }
+
+func TestLimiter_PerClient(t *testing.T) {
+	l := NewLimiter(context.Background(), 1)
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+
+	call := func(remote, apiKey string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/user", nil)
+		r.RemoteAddr = remote
+		if apiKey != "" {
+			r.Header.Set("X-API-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, 200, call("10.0.0.1:1000", "").Code)
+	w := call("10.0.0.1:1001", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	assert.Equal(t, 200, call("10.0.0.2:1000", "").Code)
+	// api keys are not verified yet, fresh key per request must not grant fresh bucket
+	assert.Equal(t, http.StatusTooManyRequests, call("10.0.0.1:1000", "key").Code)
+}
+
+func TestLimiter_Policies(t *testing.T) {
+	l := NewLimiter(context.Background(), 100, SetPolicies([]Policy{
+		{Method: "POST", Path: "/user", Limit: 1, Burst: 2},
+	}))
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+
+	var codes []int
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/user", nil))
+		codes = append(codes, w.Code)
+	}
+	assert.Equal(t, []int{200, 200, http.StatusTooManyRequests}, codes)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/user", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "100", w.Header().Get("RateLimit-Limit"))
+}
+
+func TestHasPathPrefix(t *testing.T) {
+	for _, c := range []struct {
+		path, prefix string
+		want         bool
+	}{
+		{"/user/42", "/user", true},
+		{"/users", "/user", true},
+		{"/user/42/password", "/user/*/password", true},
+		{"/user/42/password/", "/user/*/password", true},
+		{"/user/42/roles", "/user/*/password", false},
+		{"/user/42", "/user/*/password", false},
+		{"/user/42", "/user/*", true},
+		{"/faceitpb.UserService/ChangePassword", "/faceitpb.UserService/ChangePassword", true},
+	} {
+		assert.Equal(t, c.want, hasPathPrefix(c.path, c.prefix), "%s %s", c.path, c.prefix)
+	}
+}
+
+func TestLimiter_Quota(t *testing.T) {
+	l := NewLimiter(context.Background(), 100, SetPolicies([]Policy{
+		{Method: "POST", Path: "/user", Limit: 1, Burst: 1},
+	}))
+	h := l.Middleware(l.QuotaMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})))
+	call := func(method, remote, principal string, quota float64) *httptest.ResponseRecorder {
+		ctx := WithPrincipal(context.Background(), principal)
+		if quota > 0 {
+			ctx = WithQuota(ctx, quota)
+		}
+		r := httptest.NewRequest(method, "/user", nil).WithContext(ctx)
+		r.RemoteAddr = remote
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := call("GET", "10.0.0.1:1000", "fk_00000001", 1)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	// quota follows principal across client ips
+	assert.Equal(t, http.StatusTooManyRequests, call("GET", "10.0.0.2:1000", "fk_00000001", 1).Code)
+	// principals without quota keep default policy
+	w = call("GET", "10.0.0.3:1000", "fk_00000002", 0)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "100", w.Header().Get("RateLimit-Limit"))
+
+	// quota does not lift route policy
+	assert.Equal(t, 200, call("POST", "10.0.0.4:1000", "fk_00000003", 1000).Code)
+	assert.Equal(t, http.StatusTooManyRequests, call("POST", "10.0.0.4:1000", "fk_00000003", 1000).Code)
+}
+
+func TestLimiter_SetLimit(t *testing.T) {
+	l := NewLimiter(context.Background(), 1)
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	call := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/user", nil))
+		return w
+	}
+
+	assert.Equal(t, 200, call().Code)
+	assert.Equal(t, http.StatusTooManyRequests, call().Code)
+
+	l.SetLimit(1000, 0)
+	time.Sleep(10 * time.Millisecond)
+	w := call()
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "1000", w.Header().Get("RateLimit-Limit"))
+}
+
+func TestKeyer_ClientIP(t *testing.T) {
+	proxies, err := ParseCIDRs([]string{"10.0.0.0/8", "192.168.1.1"})
+	require.NoError(t, err)
+	k := &keyer{trustedProxies: proxies}
+
+	testCases := []struct {
+		remote    string
+		forwarded []string
+		out       string
+	}{
+		{"1.2.3.4:80", []string{"5.6.7.8"}, "1.2.3.4"},
+		{"10.0.0.1:80", []string{"5.6.7.8"}, "5.6.7.8"},
+		{"10.0.0.1:80", []string{"9.9.9.9, 5.6.7.8, 192.168.1.1"}, "5.6.7.8"},
+		{"10.0.0.1:80", nil, "10.0.0.1"},
+		{"10.0.0.1:80", []string{"192.168.1.1, 10.0.0.2"}, "10.0.0.1"},
+	}
+	for _, c := range testCases {
+		assert.Equal(t, c.out, k.clientIP(c.remote, c.forwarded))
+	}
+}
+
+func TestClientIPMiddleware(t *testing.T) {
+	proxies, err := ParseCIDRs([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	var got string
+	h := ClientIPMiddleware(proxies, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = ClientIPFromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest("POST", "/user/1/password", nil)
+	r.RemoteAddr = "10.0.0.1:80"
+	r.Header.Set("X-Forwarded-For", "5.6.7.8")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "5.6.7.8", got)
+
+	r.RemoteAddr = "1.2.3.4:80"
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "1.2.3.4", got)
+}
+
+func TestStore_Evict(t *testing.T) {
+	s := newStore(2, time.Minute)
+	p := &Policy{Limit: 1, Burst: 1}
+	now := time.Now()
+
+	s.take("a", p, now)
+	s.take("b", p, now)
+	s.take("c", p, now)
+	assert.Equal(t, 2, s.len())
+	assert.True(t, s.take("a", p, now).allowed)
+
+	s.take("d", p, now.Add(2*time.Minute))
+	assert.Equal(t, 1, s.len())
+}
+
+func TestLimiter_UnaryServerInterceptor(t *testing.T) {
+	l := NewLimiter(context.Background(), 1)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/faceitpb.UserService/GetUsers"}
+
+	_, err := l.UnaryServerInterceptor()(context.Background(), nil, info, handler)
+	assert.NoError(t, err)
+	_, err = l.UnaryServerInterceptor()(context.Background(), nil, info, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestLimiter_QuotaInterceptor(t *testing.T) {
+	l := NewLimiter(context.Background(), 100)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/faceitpb.UserService/GetUsers"}
+	ctx := WithQuota(WithPrincipal(context.Background(), "fk_00000001"), 1)
+
+	_, err := l.QuotaInterceptor()(ctx, nil, info, handler)
+	assert.NoError(t, err)
+	_, err = l.QuotaInterceptor()(ctx, nil, info, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	_, err = l.QuotaInterceptor()(context.Background(), nil, info, handler)
+	assert.NoError(t, err)
+}
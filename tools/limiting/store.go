@@ -0,0 +1,130 @@
+package limiting
+
+import (
+	"container/list"
+	"math"
+	"sync"
+	"time"
+)
+
+// bucket is a token bucket refilled with limit tokens per second up to burst.
+type bucket struct {
+	limit  float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// quota describes bucket state after a take attempt, used to fill RateLimit-* headers.
+type quota struct {
+	allowed    bool
+	limit      int
+	remaining  int
+	reset      time.Duration
+	retryAfter time.Duration
+}
+
+func newBucket(limit float64, burst int, now time.Time) *bucket {
+	return &bucket{
+		limit:  limit,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now,
+	}
+}
+
+func (b *bucket) take(now time.Time) quota {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.limit)
+	}
+	b.last = now
+
+	q := quota{limit: int(b.burst)}
+	if b.tokens >= 1 {
+		b.tokens--
+		q.allowed = true
+	} else {
+		q.retryAfter = b.durationFor(1 - b.tokens)
+	}
+	q.remaining = int(math.Floor(b.tokens))
+	q.reset = b.durationFor(b.burst - b.tokens)
+	return q
+}
+
+// setPolicy applies changed limit and burst keeping accumulated tokens within new burst.
+func (b *bucket) setPolicy(p *Policy) {
+	b.limit = p.Limit
+	b.burst = float64(p.Burst)
+	b.tokens = math.Min(b.tokens, b.burst)
+}
+
+func (b *bucket) durationFor(tokens float64) time.Duration {
+	if b.limit <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / b.limit * float64(time.Second))
+}
+
+type entry struct {
+	key    string
+	bucket *bucket
+	seen   time.Time
+}
+
+// store keeps buckets per client key and evicts least recently used keys
+// once maxKeys is reached or when a key was idle longer than idleTimeout.
+type store struct {
+	mu          sync.Mutex
+	maxKeys     int
+	idleTimeout time.Duration
+	ll          *list.List
+	items       map[string]*list.Element
+}
+
+func newStore(maxKeys int, idleTimeout time.Duration) *store {
+	return &store{
+		maxKeys:     maxKeys,
+		idleTimeout: idleTimeout,
+		ll:          list.New(),
+		items:       make(map[string]*list.Element),
+	}
+}
+
+func (s *store) take(key string, p *Policy, now time.Time) quota {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var e *entry
+	if el, ok := s.items[key]; ok {
+		s.ll.MoveToFront(el)
+		e = el.Value.(*entry)
+	} else {
+		e = &entry{key: key, bucket: newBucket(p.Limit, p.Burst, now)}
+		s.items[key] = s.ll.PushFront(e)
+	}
+	e.seen = now
+	e.bucket.setPolicy(p)
+	q := e.bucket.take(now)
+
+	s.evict(now)
+	return q
+}
+
+func (s *store) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ll.Len()
+}
+
+func (s *store) evict(now time.Time) {
+	for el := s.ll.Back(); el != nil; el = s.ll.Back() {
+		e := el.Value.(*entry)
+		overflow := s.maxKeys > 0 && s.ll.Len() > s.maxKeys
+		idle := s.idleTimeout > 0 && now.Sub(e.seen) > s.idleTimeout
+		if !overflow && !idle {
+			return
+		}
+		s.ll.Remove(el)
+		delete(s.items, e.key)
+	}
+}
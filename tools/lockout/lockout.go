@@ -0,0 +1,53 @@
+// Package lockout decides when failed credential checks refuse further attempts, by progressive delays
+// and temporary lockouts.
+package lockout
+
+import "time"
+
+// Policy refuses attempts of subject, e.g. account or client ip, by its failures counted in sliding window.
+// After each failure next attempt is delayed, and once failures reach threshold subject is locked.
+// Zero Policy allows every attempt.
+type Policy struct {
+	// Window is period failures are counted in, they are forgotten once it passes.
+	Window time.Duration
+	// Threshold is number of failures in window locking subject, 0 disables lockout.
+	Threshold int
+	// Duration is how long subject stays locked after its latest failure. As earlier failures are still
+	// in window, failure after lockout passes locks subject again.
+	Duration time.Duration
+	// Delay is how long attempts are refused after the first failure, it doubles with every further one
+	// up to MaxDelay, it stays the same when MaxDelay is lower. 0 disables delays.
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+// Locked reports whether subject with failures in window is locked.
+func (p *Policy) Locked(failures int) bool {
+	return p.Threshold > 0 && failures >= p.Threshold
+}
+
+// RetryAt returns when next attempt of subject with failures in window, the latest one at last,
+// is allowed, zero time when it is allowed at any time.
+func (p *Policy) RetryAt(failures int, last time.Time) time.Time {
+	if failures < 1 {
+		return time.Time{}
+	}
+	if p.Locked(failures) {
+		return last.Add(p.Duration)
+	}
+	if p.Delay <= 0 {
+		return time.Time{}
+	}
+	return last.Add(p.delay(failures))
+}
+
+// delay returns delay after failures, doubled per failure up to MaxDelay
+func (p *Policy) delay(failures int) time.Duration {
+	d := p.Delay
+	for i := 1; i < failures && d < p.MaxDelay; i++ {
+		if d *= 2; d > p.MaxDelay {
+			d = p.MaxDelay
+		}
+	}
+	return d
+}
@@ -0,0 +1,35 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_RetryAt(t *testing.T) {
+	p := &Policy{Window: time.Hour, Threshold: 5, Duration: 15 * time.Minute, Delay: time.Second, MaxDelay: 5 * time.Second}
+	last := time.Date(2022, 2, 15, 10, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		failures int
+		want     time.Duration
+		locked   bool
+	}{
+		{1, time.Second, false},
+		{2, 2 * time.Second, false},
+		{3, 4 * time.Second, false},
+		{4, 5 * time.Second, false},
+		{5, 15 * time.Minute, true},
+		{7, 15 * time.Minute, true},
+	} {
+		assert.Equal(t, last.Add(c.want), p.RetryAt(c.failures, last), "failures %d", c.failures)
+		assert.Equal(t, c.locked, p.Locked(c.failures), "failures %d", c.failures)
+	}
+	assert.True(t, p.RetryAt(0, time.Time{}).IsZero())
+
+	// zero policy allows every attempt
+	assert.True(t, (&Policy{}).RetryAt(100, last).IsZero())
+	assert.False(t, (&Policy{}).Locked(100))
+	// delay stays the same without higher max delay
+	assert.Equal(t, last.Add(time.Second), (&Policy{Delay: time.Second}).RetryAt(60, last))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sync/atomic"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
@@ -32,15 +33,40 @@ func FromContext(ctx context.Context) log.Logger {
 	return fallbackLogger
 }
 
-func NewLogger(lvl, format string) (log.Logger, error) {
+// Logger is a JSON logger which level can be changed at runtime.
+type Logger struct {
+	base    log.Logger
+	current atomic.Value
+}
+
+func NewLogger(lvl, format string) (*Logger, error) {
+	l := &Logger{base: log.NewJSONLogger(log.NewSyncWriter(os.Stdout))}
+	if err := l.SetLevel(lvl); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Logger) Log(keyvals ...interface{}) error {
+	return l.current.Load().(log.Logger).Log(keyvals...)
+}
+
+// SetLevel switches level of logger and all loggers derived from it with log.With.
+func (l *Logger) SetLevel(lvl string) error {
 	levelOption, err := getLevel(lvl)
 	if err != nil {
-		return nil, errors.Wrap(err, "get level")
+		return errors.Wrap(err, "get level")
 	}
-	logger := log.NewJSONLogger(log.NewSyncWriter(os.Stdout))
-	logger = level.NewFilter(logger, levelOption)
+	logger := level.NewFilter(l.base, levelOption)
 	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
-	return logger, nil
+	l.current.Store(logger)
+	return nil
+}
+
+// CheckLevel returns error when lvl is not a known level name.
+func CheckLevel(lvl string) error {
+	_, err := getLevel(lvl)
+	return err
 }
 
 func getLevel(lvl string) (level.Option, error) {
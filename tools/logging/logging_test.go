@@ -1,8 +1,10 @@
 package logging
 
 import (
+	"bytes"
 	"context"
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -20,3 +22,21 @@ func TestWithContext(t *testing.T) {
 	actual := FromContext(ctx)
 	assert.Equal(t, expected, actual)
 }
+
+func TestLogger_SetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewLogger("err", "")
+	assert.NoError(t, err)
+	logger.base = log.NewJSONLogger(&buf)
+	assert.NoError(t, logger.SetLevel("err"))
+	derived := log.With(logger, "component", "test")
+
+	level.Info(derived).Log("msg", "hidden")
+	assert.Empty(t, buf.String())
+
+	assert.NoError(t, logger.SetLevel("debug"))
+	level.Info(derived).Log("msg", "shown")
+	assert.Contains(t, buf.String(), "shown")
+
+	assert.Error(t, logger.SetLevel("verbose"))
+}
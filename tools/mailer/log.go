@@ -0,0 +1,21 @@
+package mailer
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/nakiner/faceit/tools/logging"
+)
+
+type logMailer struct{}
+
+// NewLog returns mailer writing messages to log of context instead of sending them, for local development.
+// Messages carry links with secret tokens, so it should not be used in production.
+func NewLog() Mailer {
+	return logMailer{}
+}
+
+func (logMailer) Send(ctx context.Context, msg *Message) error {
+	level.Info(logging.FromContext(ctx)).Log("msg", "email is not sent, mailer is log-only", "to", msg.To, "subject", msg.Subject, "text", msg.Text)
+	return nil
+}
@@ -0,0 +1,122 @@
+package mailer
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplates(t *testing.T) {
+	tmpl, err := LoadTemplates("")
+	require.NoError(t, err)
+	msg, err := tmpl.Render("verify_email", "alice@example.com", map[string]interface{}{
+		"Nickname": "alice",
+		"Email":    "alice@example.com",
+		"Link":     "https://example.com/verify?token=abc",
+		"TTL":      "24h0m0s",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", msg.To)
+	assert.Equal(t, "Confirm your email address", msg.Subject)
+	assert.True(t, strings.HasPrefix(msg.Text, "Hi alice,"), msg.Text)
+	assert.Contains(t, msg.Text, "https://example.com/verify?token=abc")
+
+	_, err = tmpl.Render("unknown", "alice@example.com", nil)
+	assert.Error(t, err)
+
+	// templates in dir replace built-in ones
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "verify_email.tmpl"),
+		[]byte(`{{define "subject"}}Bitte bestätigen{{end}}{{define "text"}}{{.Link}}{{end}}`), 0600))
+	tmpl, err = LoadTemplates(dir)
+	require.NoError(t, err)
+	msg, err = tmpl.Render("verify_email", "alice@example.com", map[string]string{"Link": "link"})
+	require.NoError(t, err)
+	assert.Equal(t, "Bitte bestätigen", msg.Subject)
+	assert.Equal(t, "link", msg.Text)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "broken.tmpl"), []byte(`{{define "text"}}{{end}}`), 0600))
+	_, err = LoadTemplates(dir)
+	assert.Error(t, err)
+}
+
+func TestOutbox(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	m, err := NewOutbox(dir, "Faceit <noreply@example.com>")
+	require.NoError(t, err)
+	require.NoError(t, m.Send(context.Background(), &Message{To: "alice@example.com", Subject: "Hello", Text: "line\nlink=https://example.com"}))
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, os.FileMode(0600), files[0].Mode().Perm())
+	b, err := ioutil.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "From: Faceit <noreply@example.com>\r\nTo: alice@example.com\r\nSubject: Hello\r\n")
+	assert.Contains(t, string(b), "\r\n\r\nline\r\nlink=3Dhttps://example.com")
+
+	// headers may not be injected
+	err = m.Send(context.Background(), &Message{To: "alice@example.com\r\nBcc: eve@example.com", Subject: "Hello"})
+	assert.Error(t, err)
+	err = m.Send(context.Background(), &Message{To: "alice@example.com", Subject: "Hello\r\nBcc: eve@example.com"})
+	assert.Error(t, err)
+}
+
+func TestSMTP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		var lines []string
+		tp.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			lines = append(lines, line)
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				tp.PrintfLine("250 localhost")
+			case line == "DATA":
+				tp.PrintfLine("354 go ahead")
+				data, _ := tp.ReadDotLines()
+				lines = append(lines, data...)
+				tp.PrintfLine("250 queued")
+			case line == "QUIT":
+				tp.PrintfLine("221 bye")
+				received <- lines
+				return
+			default:
+				tp.PrintfLine("250 ok")
+			}
+		}
+	}()
+
+	m, err := NewSMTP(l.Addr().String(), "Faceit <noreply@example.com>", SetTimeout(time.Second))
+	require.NoError(t, err)
+	require.NoError(t, m.Send(context.Background(), &Message{To: "Alice <alice@example.com>", Subject: "Hello", Text: "Hi"}))
+
+	lines := <-received
+	assert.Contains(t, lines, "MAIL FROM:<noreply@example.com>")
+	assert.Contains(t, lines, "RCPT TO:<alice@example.com>")
+	assert.Contains(t, lines, "Subject: Hello")
+	assert.Equal(t, "Hi", lines[len(lines)-2])
+}
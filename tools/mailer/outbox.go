@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type outboxMailer struct {
+	dir  string
+	from string
+}
+
+// NewOutbox returns mailer writing messages as .eml files to dir, so they can be inspected offline.
+func NewOutbox(dir, from string) (Mailer, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "create outbox dir")
+	}
+	return &outboxMailer{dir: dir, from: from}, nil
+}
+
+func (m *outboxMailer) Send(_ context.Context, msg *Message) error {
+	now := time.Now()
+	b, err := format(m.from, msg, now)
+	if err != nil {
+		return errors.Wrap(err, "format message")
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return errors.Wrap(err, "name message")
+	}
+	// names sort in order messages were sent
+	name := strconv.FormatInt(now.UnixNano(), 10) + "-" + hex.EncodeToString(suffix) + ".eml"
+	if err := ioutil.WriteFile(filepath.Join(m.dir, name), b, 0600); err != nil {
+		return errors.Wrap(err, "write message to outbox")
+	}
+	return nil
+}
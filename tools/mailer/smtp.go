@@ -0,0 +1,120 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type smtpMailer struct {
+	addr     string
+	host     string
+	from     string
+	sender   string
+	auth     smtp.Auth
+	timeout  time.Duration
+	startTLS bool
+}
+
+type SMTPOption func(*smtpMailer)
+
+// SetAuth sets credentials of PLAIN authentication, they are sent over TLS only.
+func SetAuth(username, password string) SMTPOption {
+	return func(m *smtpMailer) {
+		m.auth = smtp.PlainAuth("", username, password, m.host)
+	}
+}
+
+// SetTimeout sets how long sending of a message may take when context has no deadline.
+func SetTimeout(d time.Duration) SMTPOption {
+	return func(m *smtpMailer) {
+		m.timeout = d
+	}
+}
+
+// SetStartTLS sets whether connection is upgraded to TLS when server supports it, it is by default.
+func SetStartTLS(enabled bool) SMTPOption {
+	return func(m *smtpMailer) {
+		m.startTLS = enabled
+	}
+}
+
+// NewSMTP returns mailer sending messages from from through SMTP server at addr (host:port).
+func NewSMTP(addr, from string, opts ...SMTPOption) (Mailer, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse smtp address")
+	}
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid sender %q", from)
+	}
+	m := &smtpMailer{addr: addr, host: host, from: from, sender: sender.Address, timeout: 10 * time.Second, startTLS: true}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
+}
+
+func (m *smtpMailer) Send(ctx context.Context, msg *Message) error {
+	b, err := format(m.from, msg, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "format message")
+	}
+	// recipient is validated by format
+	to, _ := mail.ParseAddress(msg.To)
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return errors.Wrap(err, "dial smtp server")
+	}
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return errors.Wrap(err, "set smtp deadline")
+	}
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return errors.Wrap(err, "start smtp session")
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok && m.startTLS {
+		if err := c.StartTLS(&tls.Config{ServerName: m.host, MinVersion: tls.VersionTLS12}); err != nil {
+			return errors.Wrap(err, "starttls")
+		}
+	}
+	if m.auth != nil {
+		if err := c.Auth(m.auth); err != nil {
+			return errors.Wrap(err, "smtp auth")
+		}
+	}
+	if err := c.Mail(m.sender); err != nil {
+		return errors.Wrap(err, "smtp mail from")
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return errors.Wrap(err, "smtp rcpt to")
+	}
+	w, err := c.Data()
+	if err != nil {
+		return errors.Wrap(err, "smtp data")
+	}
+	if _, err := w.Write(b); err != nil {
+		return errors.Wrap(err, "write message")
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrap(err, "send message")
+	}
+	return c.Quit()
+}
@@ -0,0 +1,86 @@
+package mailer
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+//go:embed templates/*.tmpl
+var defaults embed.FS
+
+const ext = ".tmpl"
+
+// Templates render messages. Template file <name>.tmpl defines "subject" and "text" of message name.
+type Templates struct {
+	byName map[string]*template.Template
+}
+
+// LoadTemplates returns built-in templates, ones found in dir replace built-in templates of the same name.
+// Empty dir leaves built-in templates only.
+func LoadTemplates(dir string) (*Templates, error) {
+	t := &Templates{byName: make(map[string]*template.Template)}
+	if err := t.load(defaults, "templates"); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := t.load(os.DirFS(dir), "."); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// DefaultTemplates returns built-in templates, they are checked by tests so it panics only on broken build.
+func DefaultTemplates() *Templates {
+	t, err := LoadTemplates("")
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func (t *Templates) load(fsys fs.FS, dir string) error {
+	files, err := fs.Glob(fsys, path.Join(dir, "*"+ext))
+	if err != nil {
+		return errors.Wrap(err, "list templates")
+	}
+	for _, file := range files {
+		tmpl, err := template.ParseFS(fsys, file)
+		if err != nil {
+			return errors.Wrapf(err, "parse template %s", file)
+		}
+		for _, part := range []string{"subject", "text"} {
+			if tmpl.Lookup(part) == nil {
+				return errors.Errorf("template %s does not define %q", file, part)
+			}
+		}
+		t.byName[strings.TrimSuffix(path.Base(file), ext)] = tmpl
+	}
+	return nil
+}
+
+// Render returns message name to to, rendered with data.
+func (t *Templates) Render(name, to string, data interface{}) (*Message, error) {
+	tmpl, ok := t.byName[name]
+	if !ok {
+		return nil, errors.Errorf("unknown template %q", name)
+	}
+	var subject, text strings.Builder
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, errors.Wrapf(err, "render subject of %s", name)
+	}
+	if err := tmpl.ExecuteTemplate(&text, "text", data); err != nil {
+		return nil, errors.Wrapf(err, "render text of %s", name)
+	}
+	return &Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimLeft(text.String(), "\n"),
+	}, nil
+}
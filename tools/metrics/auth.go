@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+type authMetrics struct {
+	failures *stdprometheus.CounterVec
+	lockouts *stdprometheus.CounterVec
+	refused  *stdprometheus.CounterVec
+}
+
+func newAuthMetrics(registerer stdprometheus.Registerer) *authMetrics {
+	m := &authMetrics{
+		failures: stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "auth",
+			Name:      "failed_logins_total",
+			Help:      "Number of failed credential checks by method checking them.",
+		}, []string{"method"}),
+		lockouts: stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "auth",
+			Name:      "lockouts_total",
+			Help:      "Number of lockouts after failed credential checks by subject: account or ip.",
+		}, []string{"subject"}),
+		refused: stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "auth",
+			Name:      "refused_logins_total",
+			Help:      "Number of credential checks refused before checking by reason: delayed or locked.",
+		}, []string{"reason"}),
+	}
+	registerer.MustRegister(m.failures, m.lockouts, m.refused)
+	return m
+}
+
+// ObserveFailedLogin records failed credential check of method.
+func (m *Metrics) ObserveFailedLogin(method string) {
+	m.auth.failures.WithLabelValues(method).Inc()
+}
+
+// ObserveLockout records lockout of subject kind, account or ip.
+func (m *Metrics) ObserveLockout(subject string) {
+	m.auth.lockouts.WithLabelValues(subject).Inc()
+}
+
+// ObserveRefusedLogin records credential check refused for reason, delayed or locked.
+func (m *Metrics) ObserveRefusedLogin(reason string) {
+	m.auth.refused.WithLabelValues(reason).Inc()
+}
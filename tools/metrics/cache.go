@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+type cacheMetrics struct {
+	requests  *stdprometheus.CounterVec
+	evictions *stdprometheus.CounterVec
+}
+
+func newCacheMetrics(registerer stdprometheus.Registerer) *cacheMetrics {
+	m := &cacheMetrics{
+		requests: stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "requests_total",
+			Help:      "Number of cache lookups by result, hit or miss.",
+		}, []string{"cache", "result"}),
+		evictions: stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "evictions_total",
+			Help:      "Number of evicted cache entries by reason: capacity, expired or invalidated.",
+		}, []string{"cache", "reason"}),
+	}
+	registerer.MustRegister(m.requests, m.evictions)
+	return m
+}
+
+// ObserveCacheLookup records hit or miss of named cache.
+func (m *Metrics) ObserveCacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cache.requests.WithLabelValues(cache, result).Inc()
+}
+
+// ObserveCacheEviction records entry of named cache evicted for reason.
+func (m *Metrics) ObserveCacheEviction(cache, reason string) {
+	m.cache.evictions.WithLabelValues(cache, reason).Inc()
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"time"
+
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+type dbMetrics struct {
+	queries  *stdprometheus.CounterVec
+	duration *stdprometheus.HistogramVec
+	lag      *stdprometheus.GaugeVec
+	healthy  *stdprometheus.GaugeVec
+}
+
+func newDBMetrics(registerer stdprometheus.Registerer, buckets []float64) *dbMetrics {
+	m := &dbMetrics{
+		queries: stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "queries_total",
+			Help:      "Number of executed database statements.",
+		}, []string{"pool", "operation", "status"}),
+		duration: stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "Duration of database statements in seconds.",
+			Buckets:   buckets,
+		}, []string{"pool", "operation"}),
+		lag: stdprometheus.NewGaugeVec(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "replica_lag_seconds",
+			Help:      "Replication lag of replica measured on last check.",
+		}, []string{"pool"}),
+		healthy: stdprometheus.NewGaugeVec(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "replica_healthy",
+			Help:      "Whether replica is read from, 1 or 0.",
+		}, []string{"pool"}),
+	}
+	registerer.MustRegister(m.queries, m.duration, m.lag, m.healthy)
+	return m
+}
+
+// ObserveQuery records statement of operation (select, insert, ...) executed on pool since begin.
+func (m *Metrics) ObserveQuery(pool, operation string, begin time.Time, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	m.db.queries.WithLabelValues(pool, operation, status).Inc()
+	m.db.duration.WithLabelValues(pool, operation).Observe(time.Since(begin).Seconds())
+}
+
+// SetReplicaState records lag and routing state of replica pool measured by health check.
+func (m *Metrics) SetReplicaState(pool string, lag time.Duration, healthy bool) {
+	m.db.lag.WithLabelValues(pool).Set(lag.Seconds())
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+	m.db.healthy.WithLabelValues(pool).Set(value)
+}
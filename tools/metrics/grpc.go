@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+type grpcMetrics struct {
+	handled      *stdprometheus.CounterVec
+	duration     *stdprometheus.HistogramVec
+	inFlight     stdprometheus.Gauge
+	requestSize  *stdprometheus.HistogramVec
+	responseSize *stdprometheus.HistogramVec
+}
+
+func newGRPCMetrics(registerer stdprometheus.Registerer, buckets []float64) *grpcMetrics {
+	labels := []string{"method", "code"}
+	m := &grpcMetrics{
+		handled: stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "requests_total",
+			Help:      "Number of gRPC requests handled.",
+		}, labels),
+		duration: stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of gRPC requests in seconds.",
+			Buckets:   buckets,
+		}, labels),
+		inFlight: stdprometheus.NewGauge(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "requests_in_flight",
+			Help:      "Number of gRPC requests being handled.",
+		}),
+		requestSize: stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "request_size_bytes",
+			Help:      "Size of gRPC request messages in bytes.",
+			Buckets:   sizeBuckets,
+		}, labels),
+		responseSize: stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "response_size_bytes",
+			Help:      "Size of gRPC response messages in bytes.",
+			Buckets:   sizeBuckets,
+		}, labels),
+	}
+	registerer.MustRegister(m.handled, m.duration, m.inFlight, m.requestSize, m.responseSize)
+	return m
+}
+
+// UnaryServerInterceptor measures unary gRPC requests.
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	m.init()
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		begin := time.Now()
+		m.grpc.inFlight.Inc()
+		defer m.grpc.inFlight.Dec()
+
+		resp, err := handler(ctx, req)
+
+		labels := stdprometheus.Labels{"method": info.FullMethod, "code": status.Code(err).String()}
+		m.grpc.handled.With(labels).Inc()
+		m.grpc.duration.With(labels).Observe(time.Since(begin).Seconds())
+		if msg, ok := req.(proto.Message); ok {
+			m.grpc.requestSize.With(labels).Observe(float64(proto.Size(msg)))
+		}
+		if msg, ok := resp.(proto.Message); ok && err == nil {
+			m.grpc.responseSize.With(labels).Observe(float64(proto.Size(msg)))
+		}
+		return resp, err
+	}
+}
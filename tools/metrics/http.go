@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+// unmatchedRoute labels requests not reaching a route, e.g. not found or rejected by limiter
+const unmatchedRoute = "unmatched"
+
+type httpMetrics struct {
+	requests     *stdprometheus.CounterVec
+	duration     *stdprometheus.HistogramVec
+	inFlight     stdprometheus.Gauge
+	requestSize  *stdprometheus.HistogramVec
+	responseSize *stdprometheus.HistogramVec
+}
+
+func newHTTPMetrics(registerer stdprometheus.Registerer, buckets []float64) *httpMetrics {
+	labels := []string{"route", "method", "code"}
+	m := &httpMetrics{
+		requests: stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of HTTP requests served.",
+		}, labels),
+		duration: stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of HTTP requests in seconds.",
+			Buckets:   buckets,
+		}, labels),
+		inFlight: stdprometheus.NewGauge(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "Number of HTTP requests being served.",
+		}),
+		requestSize: stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_size_bytes",
+			Help:      "Size of HTTP request bodies in bytes.",
+			Buckets:   sizeBuckets,
+		}, labels),
+		responseSize: stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "response_size_bytes",
+			Help:      "Size of HTTP response bodies in bytes.",
+			Buckets:   sizeBuckets,
+		}, labels),
+	}
+	registerer.MustRegister(m.requests, m.duration, m.inFlight, m.requestSize, m.responseSize)
+	return m
+}
+
+type routeKey struct{}
+
+// Route is mux middleware recording template of matched route, so HTTP metrics are labeled
+// with e.g. /user/{id} instead of raw path.
+func Route(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route, ok := r.Context().Value(routeKey{}).(*string); ok {
+			if current := mux.CurrentRoute(r); current != nil {
+				if tpl, err := current.GetPathTemplate(); err == nil {
+					*route = tpl
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Middleware measures HTTP requests served by next.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	m.init()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		begin := time.Now()
+		m.http.inFlight.Inc()
+		defer m.http.inFlight.Dec()
+
+		route := unmatchedRoute
+		r = r.WithContext(context.WithValue(r.Context(), routeKey{}, &route))
+		rw := &responseWriter{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		labels := stdprometheus.Labels{"route": route, "method": r.Method, "code": strconv.Itoa(rw.code)}
+		m.http.requests.With(labels).Inc()
+		m.http.duration.With(labels).Observe(time.Since(begin).Seconds())
+		if r.ContentLength > 0 {
+			m.http.requestSize.With(labels).Observe(float64(r.ContentLength))
+		}
+		m.http.responseSize.With(labels).Observe(float64(rw.size))
+	})
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	code        int
+	size        int
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.code = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
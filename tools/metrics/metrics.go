@@ -2,42 +2,153 @@ package metrics
 
 import (
 	"context"
+	"database/sql"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/go-kit/kit/metrics"
 	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/pkg/errors"
 	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
-var once = sync.Once{}
+const namespace = "faceit"
 
+// DefaultBuckets are latency buckets in seconds used unless SetBuckets is given.
+var DefaultBuckets = stdprometheus.DefBuckets
+
+// sizeBuckets are message size buckets in bytes, from 64B to 16MB
+var sizeBuckets = stdprometheus.ExponentialBuckets(64, 4, 10)
+
+// Metrics holds collectors registered on first use.
 type Metrics struct {
 	Counter    metrics.Counter
 	Histogramm metrics.Histogram
+
+	buckets    []float64
+	registerer stdprometheus.Registerer
+	once       sync.Once
+
+	http  *httpMetrics
+	grpc  *grpcMetrics
+	nats  *natsMetrics
+	db    *dbMetrics
+	cache *cacheMetrics
+	auth  *authMetrics
+}
+
+type Option func(*Metrics)
+
+// SetBuckets replaces latency buckets of histograms, values are in seconds.
+func SetBuckets(buckets []float64) Option {
+	return func(m *Metrics) {
+		m.buckets = buckets
+	}
+}
+
+// SetRegisterer replaces default prometheus registerer, e.g. with a fresh registry in tests.
+func SetRegisterer(registerer stdprometheus.Registerer) Option {
+	return func(m *Metrics) {
+		m.registerer = registerer
+	}
 }
 
 type metricKey struct{}
 
-func (m *Metrics) Get() (metrics.Counter, metrics.Histogram) {
-	once.Do(func() {
+// New creates metrics, collectors are registered on first use.
+func New(opts ...Option) *Metrics {
+	m := &Metrics{
+		buckets:    DefaultBuckets,
+		registerer: stdprometheus.DefaultRegisterer,
+	}
+	for _, o := range opts {
+		o(m)
+	}
+	return m
+}
+
+func (m *Metrics) init() {
+	m.once.Do(func() {
 		fieldKeys := []string{"handler", "code", "service"}
-		m.Counter = kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
-			Namespace: "faceit",
+		counter := stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+			Namespace: namespace,
 			Name:      "requests_total",
 			Help:      "Number of requests received.",
 		}, fieldKeys)
-		m.Histogramm = kitprometheus.NewSummaryFrom(stdprometheus.SummaryOpts{
-			Namespace: "faceit",
-			Name:      "request_latency_microseconds",
-			Help:      "Total duration of requests in microseconds.",
+		latency := stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Duration of requests in seconds.",
+			Buckets:   m.buckets,
 		}, fieldKeys)
+		m.registerer.MustRegister(counter, latency)
+		m.Counter = kitprometheus.NewCounter(counter)
+		m.Histogramm = kitprometheus.NewHistogram(latency)
+
+		m.http = newHTTPMetrics(m.registerer, m.buckets)
+		m.grpc = newGRPCMetrics(m.registerer, m.buckets)
+		m.nats = newNATSMetrics(m.registerer, m.buckets)
+		m.db = newDBMetrics(m.registerer, m.buckets)
+		m.cache = newCacheMetrics(m.registerer)
+		m.auth = newAuthMetrics(m.registerer)
 	})
+}
 
+func (m *Metrics) Get() (metrics.Counter, metrics.Histogram) {
+	m.init()
 	return m.Counter, m.Histogramm
 }
 
-func WithContext(ctx context.Context) context.Context {
-	return context.WithValue(ctx, metricKey{}, new(Metrics))
+// RegisterDB exports connection pool stats of db labeled with pool name, e.g. master or replica.
+func (m *Metrics) RegisterDB(pool string, db *sql.DB) error {
+	return m.registerer.Register(collectors.NewDBStatsCollector(db, pool))
+}
+
+// RegisterBuildInfo exports constant build_info gauge labeled with build details.
+func (m *Metrics) RegisterBuildInfo(version, commit, buildTime string) error {
+	info := stdprometheus.NewGauge(stdprometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "build_info",
+		Help:      "Build details of running binary, value is always 1.",
+		ConstLabels: stdprometheus.Labels{
+			"version":    version,
+			"commit":     commit,
+			"build_time": buildTime,
+			"goversion":  runtime.Version(),
+		},
+	})
+	info.Set(1)
+	return m.registerer.Register(info)
+}
+
+// ParseBuckets parses comma separated bucket bounds in increasing order.
+func ParseBuckets(s string) ([]float64, error) {
+	var buckets []float64
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		b, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "bucket %q", part)
+		}
+		if len(buckets) > 0 && b <= buckets[len(buckets)-1] {
+			return nil, errors.Errorf("buckets must be in increasing order, got %v after %v", b, buckets[len(buckets)-1])
+		}
+		buckets = append(buckets, b)
+	}
+	if len(buckets) == 0 {
+		return nil, errors.New("no buckets given")
+	}
+	return buckets, nil
+}
+
+func WithContext(ctx context.Context, opts ...Option) context.Context {
+	return context.WithValue(ctx, metricKey{}, New(opts...))
 }
 
 func FromContext(ctx context.Context) (metrics.Counter, metrics.Histogram) {
@@ -46,3 +157,12 @@ func FromContext(ctx context.Context) (metrics.Counter, metrics.Histogram) {
 	}
 	return nil, nil
 }
+
+// Instance returns metrics of ctx or nil when metrics are disabled.
+func Instance(ctx context.Context) *Metrics {
+	if metric, ok := ctx.Value(metricKey{}).(*Metrics); ok {
+		metric.init()
+		return metric
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestParseBuckets(t *testing.T) {
+	buckets, err := ParseBuckets("0.1, 0.5,1")
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0.1, 0.5, 1}, buckets)
+
+	_, err = ParseBuckets("0.5,0.1")
+	assert.Error(t, err)
+	_, err = ParseBuckets("fast")
+	assert.Error(t, err)
+	_, err = ParseBuckets("")
+	assert.Error(t, err)
+}
+
+func TestMetrics_Middleware(t *testing.T) {
+	registry := stdprometheus.NewRegistry()
+	m := New(SetRegisterer(registry), SetBuckets([]float64{0.1, 1}))
+
+	r := mux.NewRouter()
+	r.Use(Route)
+	r.Methods("PUT").Path("/user/{id}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	})
+	h := m.Middleware(r)
+
+	for _, id := range []string{"1", "2"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/user/"+id, strings.NewReader("{}")))
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/unknown", nil))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.http.requests.WithLabelValues("/user/{id}", "PUT", "404")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.http.requests.WithLabelValues(unmatchedRoute, "GET", "404")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.http.inFlight))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.http.duration))
+}
+
+func TestMetrics_UnaryServerInterceptor(t *testing.T) {
+	registry := stdprometheus.NewRegistry()
+	m := New(SetRegisterer(registry))
+	interceptor := m.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/faceitpb.UserService/GetUsers"}
+
+	_, err := interceptor(context.Background(), wrapperspb.String("req"), info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return wrapperspb.String("resp"), nil
+	})
+	require.NoError(t, err)
+	_, err = interceptor(context.Background(), wrapperspb.String("req"), info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "not found")
+	})
+	require.Error(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.grpc.handled.WithLabelValues(info.FullMethod, "OK")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.grpc.handled.WithLabelValues(info.FullMethod, "NotFound")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.grpc.requestSize))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.grpc.responseSize))
+}
+
+func TestMetrics_RegisterBuildInfo(t *testing.T) {
+	registry := stdprometheus.NewRegistry()
+	m := New(SetRegisterer(registry))
+	require.NoError(t, m.RegisterBuildInfo("1.0.0", "abc123", "2021-12-10"))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+	assert.Equal(t, "faceit_build_info", families[0].GetName())
+	assert.Equal(t, 1.0, families[0].GetMetric()[0].GetGauge().GetValue())
+}
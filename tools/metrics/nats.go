@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/nats-io/nats.go"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+type natsMetrics struct {
+	published       *stdprometheus.CounterVec
+	publishDuration *stdprometheus.HistogramVec
+	received        *stdprometheus.CounterVec
+	handleDuration  *stdprometheus.HistogramVec
+}
+
+func newNATSMetrics(registerer stdprometheus.Registerer, buckets []float64) *natsMetrics {
+	m := &natsMetrics{
+		published: stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "nats",
+			Name:      "published_total",
+			Help:      "Number of messages published to NATS.",
+		}, []string{"subject", "status"}),
+		publishDuration: stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "nats",
+			Name:      "publish_duration_seconds",
+			Help:      "Duration of publishing messages to NATS in seconds.",
+			Buckets:   buckets,
+		}, []string{"subject"}),
+		received: stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "nats",
+			Name:      "received_total",
+			Help:      "Number of messages received from NATS.",
+		}, []string{"subject"}),
+		handleDuration: stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "nats",
+			Name:      "handle_duration_seconds",
+			Help:      "Duration of handling messages received from NATS in seconds.",
+			Buckets:   buckets,
+		}, []string{"subject"}),
+	}
+	registerer.MustRegister(m.published, m.publishDuration, m.received, m.handleDuration)
+	return m
+}
+
+// ObservePublish records message published to subject, err is publish result.
+func (m *Metrics) ObservePublish(subject string, begin time.Time, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	m.nats.published.WithLabelValues(subject, status).Inc()
+	m.nats.publishDuration.WithLabelValues(subject).Observe(time.Since(begin).Seconds())
+}
+
+// ObserveHandle records message received from subject and handled since begin.
+func (m *Metrics) ObserveHandle(subject string, begin time.Time) {
+	m.nats.received.WithLabelValues(subject).Inc()
+	m.nats.handleDuration.WithLabelValues(subject).Observe(time.Since(begin).Seconds())
+}
+
+// RegisterNATS exports connection statistics of nc: reconnects and traffic.
+func (m *Metrics) RegisterNATS(nc *nats.Conn) error {
+	stat := func(name, help string, value func(s nats.Statistics) uint64) stdprometheus.Collector {
+		return stdprometheus.NewCounterFunc(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "nats",
+			Name:      name,
+			Help:      help,
+		}, func() float64 {
+			return float64(value(nc.Stats()))
+		})
+	}
+	for _, c := range []stdprometheus.Collector{
+		stat("reconnects_total", "Number of NATS reconnects.", func(s nats.Statistics) uint64 { return s.Reconnects }),
+		stat("in_msgs_total", "Number of messages received by NATS connection.", func(s nats.Statistics) uint64 { return s.InMsgs }),
+		stat("out_msgs_total", "Number of messages sent by NATS connection.", func(s nats.Statistics) uint64 { return s.OutMsgs }),
+		stat("in_bytes_total", "Bytes received by NATS connection.", func(s nats.Statistics) uint64 { return s.InBytes }),
+		stat("out_bytes_total", "Bytes sent by NATS connection.", func(s nats.Statistics) uint64 { return s.OutBytes }),
+	} {
+		if err := m.registerer.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
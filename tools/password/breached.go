@@ -0,0 +1,141 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Breached is set of passwords known from data breaches.
+type Breached interface {
+	Contains(password string) bool
+}
+
+// LoadBreached reads SHA-1 hashes of breached passwords from file at path, one hex hash per line optionally
+// followed by ":count", as in Have I Been Pwned downloads ordered by hash. Only hashes are read, so the list
+// may be shared without passwords themselves. With falsePositiveRate 0 hashes are kept in memory, 20 bytes
+// each; otherwise they are added to bloom filter of that rate, about 1.2 bytes per hash at 0.01, which
+// rejects that share of acceptable passwords.
+func LoadBreached(path string, falsePositiveRate float64) (Breached, error) {
+	if falsePositiveRate < 0 || falsePositiveRate >= 1 {
+		return nil, errors.Errorf("false positive rate must be in [0, 1), got %v", falsePositiveRate)
+	}
+	if falsePositiveRate == 0 {
+		var list hashList
+		if err := readHashes(path, func(h [sha1.Size]byte) { list = append(list, h) }); err != nil {
+			return nil, err
+		}
+		sort.Slice(list, func(i, j int) bool { return bytes.Compare(list[i][:], list[j][:]) < 0 })
+		return list, nil
+	}
+
+	// filter is sized by number of hashes, so file is read twice
+	var n int
+	if err := readHashes(path, func([sha1.Size]byte) { n++ }); err != nil {
+		return nil, err
+	}
+	f := newBloom(n, falsePositiveRate)
+	if err := readHashes(path, f.add); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Len returns number of hashes in b, for bloom filters it is number of hashes filter was built of.
+func Len(b Breached) int {
+	switch b := b.(type) {
+	case hashList:
+		return len(b)
+	case *bloom:
+		return b.n
+	default:
+		return 0
+	}
+}
+
+func readHashes(path string, fn func([sha1.Size]byte)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "open breached passwords")
+	}
+	defer file.Close()
+
+	s := bufio.NewScanner(file)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if i := strings.IndexByte(text, ':'); i >= 0 {
+			text = text[:i]
+		}
+		var h [sha1.Size]byte
+		if len(text) != hex.EncodedLen(sha1.Size) {
+			return errors.Errorf("breached passwords line %d: expected SHA-1 hex hash", line)
+		}
+		if _, err := hex.Decode(h[:], []byte(text)); err != nil {
+			return errors.Wrapf(err, "breached passwords line %d", line)
+		}
+		fn(h)
+	}
+	return errors.Wrap(s.Err(), "read breached passwords")
+}
+
+// hashList is sorted list of hashes
+type hashList [][sha1.Size]byte
+
+func (l hashList) Contains(password string) bool {
+	h := sha1.Sum([]byte(password))
+	i := sort.Search(len(l), func(i int) bool { return bytes.Compare(l[i][:], h[:]) >= 0 })
+	return i < len(l) && l[i] == h
+}
+
+// bloom is bloom filter of hashes, its k indexes are derived from hash itself by double hashing
+type bloom struct {
+	bits []uint64
+	m    uint64
+	k    uint64
+	n    int
+}
+
+func newBloom(n int, rate float64) *bloom {
+	if n < 1 {
+		n = 1
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(rate) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	return &bloom{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+func (b *bloom) add(h [sha1.Size]byte) {
+	b.n++
+	h1, h2 := split(h)
+	for i := uint64(0); i < b.k; i++ {
+		idx := (h1 + i*h2) % b.m
+		b.bits[idx/64] |= 1 << (idx % 64)
+	}
+}
+
+func (b *bloom) Contains(password string) bool {
+	h1, h2 := split(sha1.Sum([]byte(password)))
+	for i := uint64(0); i < b.k; i++ {
+		idx := (h1 + i*h2) % b.m
+		if b.bits[idx/64]&(1<<(idx%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func split(h [sha1.Size]byte) (uint64, uint64) {
+	// second hash is odd so indexes do not repeat
+	return binary.BigEndian.Uint64(h[:8]), binary.BigEndian.Uint64(h[8:16]) | 1
+}
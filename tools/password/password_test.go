@@ -0,0 +1,87 @@
+package password
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func codes(violations []Violation) []string {
+	var res []string
+	for _, v := range violations {
+		res = append(res, v.Code)
+	}
+	return res
+}
+
+func TestPolicy_Check(t *testing.T) {
+	p := &Policy{MinLength: 8, MaxLength: 16, MinClasses: 3, ForbidPersonal: true}
+	for _, c := range []struct {
+		password string
+		want     []string
+	}{
+		{"Correct-Horse7", nil},
+		{"Sh0rt!", []string{CodeTooShort}},
+		{"Much-Too-Long-Passw0rd", []string{CodeTooLong}},
+		{"lowercaseonly", []string{CodeClasses}},
+		{"Пароль-Ёжик", nil},
+		{"xAlice-2022", []string{CodePersonal}},
+		{"Wonderland-99!", []string{CodePersonal}},
+	} {
+		assert.Equal(t, c.want, codes(p.Check(c.password, "alice", "wonderland@example.com")), c.password)
+	}
+	assert.Empty(t, (&Policy{}).Check(""))
+	assert.Empty(t, p.Check("Al-12345x", "Al"), "short personal values are not checked")
+}
+
+func writeHashes(t *testing.T, passwords ...string) string {
+	lines := []string{"# breached passwords"}
+	for i, p := range passwords {
+		h := sha1.Sum([]byte(p))
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(h[:])), i+1))
+	}
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600))
+	return path
+}
+
+func TestLoadBreached(t *testing.T) {
+	var passwords []string
+	for i := 0; i < 1000; i++ {
+		passwords = append(passwords, fmt.Sprintf("password%d", i))
+	}
+	path := writeHashes(t, passwords...)
+
+	for _, rate := range []float64{0, 0.01} {
+		b, err := LoadBreached(path, rate)
+		require.NoError(t, err)
+		assert.Equal(t, 1000, Len(b))
+		for _, p := range passwords {
+			require.True(t, b.Contains(p), p)
+		}
+		var falsePositives int
+		for i := 0; i < 1000; i++ {
+			if b.Contains(fmt.Sprintf("Correct-Horse-%d", i)) {
+				falsePositives++
+			}
+		}
+		assert.LessOrEqual(t, falsePositives, int(rate*1000*3), "rate %v", rate)
+
+		p := &Policy{Breached: b}
+		assert.Equal(t, []string{CodeBreached}, codes(p.Check("password42")))
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.txt")
+	require.NoError(t, ioutil.WriteFile(bad, []byte("password\n"), 0600))
+	_, err := LoadBreached(bad, 0)
+	assert.EqualError(t, err, "breached passwords line 1: expected SHA-1 hex hash")
+	_, err = LoadBreached(path, 1)
+	assert.Error(t, err)
+}
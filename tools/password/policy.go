@@ -0,0 +1,96 @@
+// Package password checks passwords against policy and lists of breached passwords.
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Codes of violations.
+const (
+	CodeTooShort = "too_short"
+	CodeTooLong  = "too_long"
+	CodeClasses  = "character_classes"
+	CodePersonal = "personal_info"
+	CodeBreached = "breached"
+)
+
+// minPersonalLength is length personal values are checked from, shorter ones match too many passwords
+const minPersonalLength = 3
+
+// Violation is rule password breaks.
+type Violation struct {
+	Code    string
+	Message string
+}
+
+// Policy is rules passwords have to satisfy, zero Policy accepts any password.
+type Policy struct {
+	// MinLength and MaxLength bound number of characters, 0 disables the bound.
+	MinLength int
+	MaxLength int
+	// MinClasses is how many of lowercase, uppercase, digit and other characters password has to contain.
+	MinClasses int
+	// ForbidPersonal rejects passwords containing personal values, like nickname or email, ignoring case.
+	ForbidPersonal bool
+	// Breached rejects passwords known from breaches, nil skips screening.
+	Breached Breached
+}
+
+// Check returns rules password breaks, none when it is acceptable. Personal values are user data password
+// may not contain, emails are checked together with their local part.
+func (p *Policy) Check(password string, personal ...string) []Violation {
+	var violations []Violation
+	length := utf8.RuneCountInString(password)
+	if p.MinLength > 0 && length < p.MinLength {
+		violations = append(violations, Violation{CodeTooShort, fmt.Sprintf("must be at least %d characters long", p.MinLength)})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, Violation{CodeTooLong, fmt.Sprintf("must be at most %d characters long", p.MaxLength)})
+	}
+	if p.MinClasses > 0 && classesOf(password) < p.MinClasses {
+		violations = append(violations, Violation{CodeClasses, fmt.Sprintf("must contain %d of lowercase letters, uppercase letters, digits and other characters", p.MinClasses)})
+	}
+	if p.ForbidPersonal && containsPersonal(password, personal) {
+		violations = append(violations, Violation{CodePersonal, "must not contain nickname or email"})
+	}
+	if p.Breached != nil && p.Breached.Contains(password) {
+		violations = append(violations, Violation{CodeBreached, "is known from data breaches, choose another one"})
+	}
+	return violations
+}
+
+func classesOf(password string) int {
+	var lower, upper, digit, other int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+	return lower + upper + digit + other
+}
+
+func containsPersonal(password string, personal []string) bool {
+	password = strings.ToLower(password)
+	for _, value := range personal {
+		values := []string{value}
+		if i := strings.LastIndex(value, "@"); i > 0 {
+			values = append(values, value[:i])
+		}
+		for _, v := range values {
+			if utf8.RuneCountInString(v) >= minPersonalLength && strings.Contains(password, strings.ToLower(v)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/nakiner/faceit/tools/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// Header carries request id in HTTP requests, responses and NATS messages.
+	Header = "X-Request-ID"
+	// MetadataKey carries request id in gRPC metadata.
+	MetadataKey = "x-request-id"
+	// LogKey is the key request id is logged with.
+	LogKey = "request_id"
+
+	maxLength = 128
+)
+
+type requestIDKey struct{}
+
+func WithContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// FromContext returns request id stored in context or empty string.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// New generates random request id.
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// Inject stores id (or a new one when id is not acceptable) in context together with
+// logger from ctx annotated with it, so logging.FromContext logs it.
+func Inject(ctx context.Context, id string) (context.Context, string) {
+	if !valid(id) {
+		id = New()
+	}
+	ctx = WithContext(ctx, id)
+	ctx = logging.WithContext(ctx, log.With(logging.FromContext(ctx), LogKey, id))
+	return ctx, id
+}
+
+// Middleware accepts X-Request-ID of incoming request or generates it, echoes it in response
+// and stores it in request context with logger taken from ctx.
+func Middleware(ctx context.Context, next http.Handler) http.Handler {
+	logger := logging.FromContext(ctx)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqCtx := logging.WithContext(r.Context(), logger)
+		reqCtx, id := Inject(reqCtx, r.Header.Get(Header))
+		r.Header.Set(Header, id)
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(reqCtx))
+	})
+}
+
+// UnaryServerInterceptor is gRPC counterpart of Middleware using x-request-id metadata.
+func UnaryServerInterceptor(ctx context.Context) grpc.UnaryServerInterceptor {
+	logger := logging.FromContext(ctx)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var id string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md.Get(MetadataKey); len(v) > 0 {
+				id = v[0]
+			}
+		}
+		ctx, id = Inject(logging.WithContext(ctx, logger), id)
+		grpc.SetHeader(ctx, metadata.Pairs(MetadataKey, id))
+		return handler(ctx, req)
+	}
+}
+
+// valid accepts ids of reasonable length made of printable ASCII, so they are safe to log and echo.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
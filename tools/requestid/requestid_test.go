@@ -0,0 +1,63 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/nakiner/faceit/tools/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := logging.WithContext(context.Background(), log.NewLogfmtLogger(&buf))
+
+	var seen string
+	h := Middleware(ctx, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = FromContext(r.Context())
+		logging.FromContext(r.Context()).Log("msg", "handled")
+	}))
+
+	call := func(id string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/user", nil)
+		if id != "" {
+			r.Header.Set(Header, id)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := call("complaint-42")
+	assert.Equal(t, "complaint-42", w.Header().Get(Header))
+	assert.Equal(t, "complaint-42", seen)
+	assert.Contains(t, buf.String(), "request_id=complaint-42")
+
+	w = call("")
+	assert.Len(t, w.Header().Get(Header), 32)
+	assert.Equal(t, w.Header().Get(Header), seen)
+
+	w = call("bad id\n" + strings.Repeat("x", 200))
+	assert.Len(t, w.Header().Get(Header), 32)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(context.Background())
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataKey, "complaint-42"))
+
+	var seen string
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		seen = FromContext(ctx)
+		return nil, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "complaint-42", seen)
+}
@@ -0,0 +1,93 @@
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/nakiner/faceit/tools/logging"
+	"github.com/pkg/errors"
+)
+
+type options struct {
+	initial time.Duration
+	max     time.Duration
+}
+
+type Option func(*options)
+
+// SetBackoff sets delay before the second attempt and the upper bound it doubles up to.
+func SetBackoff(initial, max time.Duration) Option {
+	return func(o *options) {
+		if initial > 0 {
+			o.initial = initial
+		}
+		if max > 0 {
+			o.max = max
+		}
+	}
+}
+
+type permanent struct {
+	error
+}
+
+func (p permanent) Cause() error {
+	return p.error
+}
+
+// Permanent marks err as not worth retrying, Do returns it immediately.
+func Permanent(err error) error {
+	return permanent{err}
+}
+
+// Do calls fn until it succeeds, returns permanent error or ctx is done, the total deadline is taken from ctx.
+// Delay between attempts doubles from initial to max backoff and is jittered, so instances started together
+// do not retry in lockstep. Every failed attempt is logged with the name of dependency.
+func Do(ctx context.Context, name string, fn func(ctx context.Context) error, opts ...Option) error {
+	o := &options{
+		initial: 250 * time.Millisecond,
+		max:     5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	logger := logging.FromContext(ctx)
+
+	begin := time.Now()
+	backoff := o.initial
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			level.Info(logger).Log("msg", "dependency is reachable", "dependency", name, "attempt", attempt, "elapsed", time.Since(begin))
+			return nil
+		}
+		if p, ok := err.(permanent); ok {
+			return errors.Wrap(p.error, name)
+		}
+
+		delay := jitter(backoff)
+		level.Warn(logger).Log("msg", "dependency is not reachable, retrying", "dependency", name, "attempt", attempt, "retry_in", delay, "err", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Wrapf(err, "%s is not reachable after %d attempts in %s", name, attempt, time.Since(begin).Round(time.Millisecond))
+		}
+
+		if backoff *= 2; backoff > o.max {
+			backoff = o.max
+		}
+	}
+}
+
+// jitter returns random delay within [d/2, d)
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
@@ -0,0 +1,56 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDo(t *testing.T) {
+	attempts := 0
+	err := Do(context.Background(), "postgres", func(ctx context.Context) error {
+		if attempts++; attempts < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}, SetBackoff(time.Millisecond, 2*time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestDo_Deadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	begin := time.Now()
+	err := Do(ctx, "postgres", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}, SetBackoff(5*time.Millisecond, 10*time.Millisecond))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "postgres is not reachable")
+	assert.Contains(t, err.Error(), "connection refused")
+	assert.Less(t, int64(time.Since(begin)), int64(time.Second))
+}
+
+func TestDo_Permanent(t *testing.T) {
+	attempts := 0
+	cause := errors.New("connection closed")
+	err := Do(context.Background(), "nats", func(ctx context.Context) error {
+		attempts++
+		return Permanent(cause)
+	})
+	assert.Equal(t, 1, attempts)
+	assert.Equal(t, cause, errors.Cause(err))
+}
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		d := jitter(time.Second)
+		assert.GreaterOrEqual(t, int64(d), int64(500*time.Millisecond))
+		assert.Less(t, int64(d), int64(time.Second))
+	}
+}
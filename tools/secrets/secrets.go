@@ -0,0 +1,34 @@
+package secrets
+
+import "encoding/json"
+
+// Redacted is printed instead of secret values.
+const Redacted = "******"
+
+// String is a configuration value which is never printed: it is redacted when formatted,
+// marshaled to JSON (config printing, sentry breadcrumbs, JSON logs) or logged.
+type String string
+
+// Reveal returns actual secret value, use it only to pass secret to its consumer.
+func (s String) Reveal() string {
+	return string(s)
+}
+
+func (s String) String() string {
+	if s == "" {
+		return ""
+	}
+	return Redacted
+}
+
+func (s String) GoString() string {
+	return `"` + s.String() + `"`
+}
+
+func (s String) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s String) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
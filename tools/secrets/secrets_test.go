@@ -0,0 +1,23 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestString(t *testing.T) {
+	s := String("postgres")
+	assert.Equal(t, "postgres", s.Reveal())
+	assert.Equal(t, Redacted, fmt.Sprint(s))
+	assert.Equal(t, `"******"`, fmt.Sprintf("%#v", s))
+
+	b, err := json.Marshal(struct{ Password String }{s})
+	require.NoError(t, err)
+	assert.Equal(t, `{"Password":"******"}`, string(b))
+
+	assert.Equal(t, "", fmt.Sprint(String("")))
+}
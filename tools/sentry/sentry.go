@@ -17,7 +17,7 @@ func NewSentry(cfg *configs.Config) error {
 		debug = true
 	}
 	if err := sentry.Init(sentry.ClientOptions{
-		Dsn:              cfg.Sentry.Dsn,
+		Dsn:              cfg.Sentry.Dsn.Reveal(),
 		Debug:            debug,
 		AttachStacktrace: true,
 		Environment:      cfg.Sentry.Environment,
@@ -0,0 +1,157 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/nakiner/faceit/tools/logging"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Header selects tenant of HTTP request and carries it in NATS messages.
+	Header = "X-Tenant-ID"
+	// MetadataKey selects tenant of gRPC request.
+	MetadataKey = "x-tenant-id"
+	// LogKey is the key tenant is logged with.
+	LogKey = "tenant"
+
+	maxLength = 64
+)
+
+var (
+	// ErrMissing is returned by tenant-scoped operations when context has no tenant.
+	ErrMissing = errors.New("tenant is not set")
+	// ErrInvalid is returned for tenant ids which are not usable as NATS subject token.
+	ErrInvalid = errors.New("tenant id must be 1-64 letters, digits, '-' or '_'")
+	// ErrForbidden is returned when request selects tenant other than its authenticated one.
+	ErrForbidden = errors.New("access to tenant is forbidden")
+)
+
+type (
+	tenantKey     struct{}
+	superAdminKey struct{}
+	claimKey      struct{}
+)
+
+// WithContext scopes ctx to tenant id.
+func WithContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext returns tenant ctx is scoped to or empty string.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(tenantKey{}).(string)
+	return id
+}
+
+// WithClaim stores tenant of authenticated caller, requests may not select other tenants unless super-admin.
+// Authentication sets it before tenant is resolved, empty for callers without tenant, which get fallback one.
+func WithClaim(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, claimKey{}, id)
+}
+
+// WithSuperAdmin grants ctx access to every tenant. Authentication sets it for callers with super-admin scope.
+func WithSuperAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, superAdminKey{}, true)
+}
+
+// IsSuperAdmin reports whether ctx may access every tenant.
+func IsSuperAdmin(ctx context.Context) bool {
+	v, _ := ctx.Value(superAdminKey{}).(bool)
+	return v
+}
+
+// Scope returns tenant operations of ctx are limited to. All is true for super-admin without selected tenant,
+// whose operations span every tenant. ErrMissing is returned when ctx has neither.
+func Scope(ctx context.Context) (id string, all bool, err error) {
+	if id = FromContext(ctx); id != "" {
+		return id, false, nil
+	}
+	if IsSuperAdmin(ctx) {
+		return "", true, nil
+	}
+	return "", false, ErrMissing
+}
+
+// Validate checks id is 1-64 letters, digits, '-' or '_', so it is safe in logs and NATS subjects.
+func Validate(id string) error {
+	if id == "" || len(id) > maxLength {
+		return ErrInvalid
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return ErrInvalid
+		}
+	}
+	return nil
+}
+
+// Resolve scopes ctx to tenant of authenticated claim, requested one or fallback in this order.
+// Authenticated callers may request tenant other than claimed one, or any when they have no claim,
+// only as super-admin. Empty fallback leaves ctx unscoped, so tenant-scoped operations fail unless tenant is requested.
+func Resolve(ctx context.Context, requested, fallback string) (context.Context, error) {
+	id := fallback
+	claim, authenticated := ctx.Value(claimKey{}).(string)
+	if claim != "" {
+		id = claim
+	}
+	if requested != "" {
+		if err := Validate(requested); err != nil {
+			return ctx, err
+		}
+		if authenticated && requested != claim && !IsSuperAdmin(ctx) {
+			return ctx, ErrForbidden
+		}
+		id = requested
+	} else if IsSuperAdmin(ctx) && claim == "" {
+		// super-admin without selected tenant works across tenants
+		id = ""
+	}
+	if id == "" {
+		return ctx, nil
+	}
+	ctx = WithContext(ctx, id)
+	return logging.WithContext(ctx, log.With(logging.FromContext(ctx), LogKey, id)), nil
+}
+
+// Middleware resolves tenant of request from X-Tenant-ID header, requests without tenant use fallback.
+func Middleware(fallback string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := Resolve(r.Context(), r.Header.Get(Header), fallback)
+		switch {
+		case errors.Is(err, ErrForbidden):
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// UnaryServerInterceptor is gRPC counterpart of Middleware using x-tenant-id metadata.
+func UnaryServerInterceptor(fallback string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var requested string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md.Get(MetadataKey); len(v) > 0 {
+				requested = v[0]
+			}
+		}
+		ctx, err := Resolve(ctx, requested, fallback)
+		switch {
+		case errors.Is(err, ErrForbidden):
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		case err != nil:
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,104 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestResolve(t *testing.T) {
+	testCases := []struct {
+		name       string
+		ctx        context.Context
+		requested  string
+		fallback   string
+		tenant     string
+		superAdmin bool
+		err        error
+	}{
+		{name: "fallback", ctx: context.Background(), fallback: "default", tenant: "default"},
+		{name: "requested", ctx: context.Background(), requested: "brand-a", fallback: "default", tenant: "brand-a"},
+		{name: "unscoped", ctx: context.Background()},
+		{name: "invalid", ctx: context.Background(), requested: "brand.a", err: ErrInvalid},
+		{name: "claim", ctx: WithClaim(context.Background(), "brand-a"), fallback: "default", tenant: "brand-a"},
+		{name: "same as claim", ctx: WithClaim(context.Background(), "brand-a"), requested: "brand-a", tenant: "brand-a"},
+		{name: "other than claim", ctx: WithClaim(context.Background(), "brand-a"), requested: "brand-b", err: ErrForbidden},
+		{name: "no claim", ctx: WithClaim(context.Background(), ""), fallback: "default", tenant: "default"},
+		{name: "no claim requests tenant", ctx: WithClaim(context.Background(), ""), requested: "brand-b", fallback: "default", err: ErrForbidden},
+		{name: "super-admin selects tenant", ctx: WithSuperAdmin(WithClaim(context.Background(), "brand-a")), requested: "brand-b", tenant: "brand-b"},
+		{name: "super-admin across tenants", ctx: WithSuperAdmin(context.Background()), fallback: "default", superAdmin: true},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx, err := Resolve(c.ctx, c.requested, c.fallback)
+			assert.Equal(t, c.err, err)
+			assert.Equal(t, c.tenant, FromContext(ctx))
+
+			id, all, err := Scope(ctx)
+			switch {
+			case c.tenant != "":
+				assert.NoError(t, err)
+				assert.Equal(t, c.tenant, id)
+			case c.superAdmin:
+				assert.True(t, all)
+			default:
+				assert.Equal(t, ErrMissing, err)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var got string
+	h := Middleware("default", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/user", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "default", got)
+
+	req := httptest.NewRequest(http.MethodGet, "/user", nil)
+	req.Header.Set(Header, "brand-a")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, "brand-a", got)
+
+	req = httptest.NewRequest(http.MethodGet, "/user", nil)
+	req.Header.Set(Header, "brand a")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/user", nil)
+	req.Header.Set(Header, "brand-b")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req.WithContext(WithClaim(req.Context(), "brand-a")))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor("default")
+	var got string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got = FromContext(ctx)
+		return nil, nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataKey, "brand-a"))
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "brand-a", got)
+
+	ctx = metadata.NewIncomingContext(WithClaim(context.Background(), "brand-b"), metadata.Pairs(MetadataKey, "brand-a"))
+	_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,241 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/nakiner/faceit/tools/logging"
+	"github.com/pkg/errors"
+)
+
+// Config describes TLS settings of a single listener or client.
+type Config struct {
+	Enabled    bool
+	CertFile   string `mapstructure:"cert_file"`
+	KeyFile    string `mapstructure:"key_file"`
+	CAFile     string `mapstructure:"ca_file"`
+	MinVersion string `mapstructure:"min_version"`
+	ClientAuth string `mapstructure:"client_auth"`
+	ServerName string `mapstructure:"server_name"`
+}
+
+var versions = map[string]uint16{
+	"":    tls.VersionTLS12,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// clientAuthTypes never accept unverified certificates when one is required, "require" is an alias
+// of "require_and_verify"
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                   tls.NoClientCert,
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAndVerifyClientCert,
+	"verify_if_given":    tls.VerifyClientCertIfGiven,
+	"require_and_verify": tls.RequireAndVerifyClientCert,
+}
+
+// debounce groups burst of file events (e.g. kubernetes secret symlink swap) into single reload
+const debounce = 100 * time.Millisecond
+
+// Reloader keeps certificate and CA pool loaded from disk and reloads them when files change,
+// so rotated certificates are picked up by new connections without restart.
+type Reloader struct {
+	cfg        Config
+	minVersion uint16
+	clientAuth tls.ClientAuthType
+	logger     log.Logger
+
+	mu     sync.RWMutex
+	cert   *tls.Certificate
+	caPool *x509.CertPool
+
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+}
+
+// NewReloader loads files described by cfg and starts watching them for changes.
+func NewReloader(ctx context.Context, cfg Config) (*Reloader, error) {
+	logger := logging.FromContext(ctx)
+	logger = log.With(logger, "component", "tls reloader")
+
+	minVersion, ok := versions[cfg.MinVersion]
+	if !ok {
+		return nil, errors.Errorf("unsupported tls min version %q", cfg.MinVersion)
+	}
+	clientAuth, ok := clientAuthTypes[strings.ToLower(cfg.ClientAuth)]
+	if !ok {
+		return nil, errors.Errorf("unsupported tls client auth %q", cfg.ClientAuth)
+	}
+
+	r := &Reloader{
+		cfg:        cfg,
+		minVersion: minVersion,
+		clientAuth: clientAuth,
+		logger:     logger,
+		done:       make(chan struct{}),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	if err := r.watch(); err != nil {
+		return nil, errors.Wrap(err, "watch tls files")
+	}
+	return r, nil
+}
+
+// Reload reads certificate, key and CA from disk, keeping previous ones on failure.
+func (r *Reloader) Reload() error {
+	var cert *tls.Certificate
+	if r.cfg.CertFile != "" || r.cfg.KeyFile != "" {
+		c, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+		if err != nil {
+			return errors.Wrap(err, "load key pair")
+		}
+		cert = &c
+	}
+
+	var pool *x509.CertPool
+	if r.cfg.CAFile != "" {
+		b, err := ioutil.ReadFile(r.cfg.CAFile)
+		if err != nil {
+			return errors.Wrap(err, "read ca file")
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return errors.Errorf("no certificates found in %s", r.cfg.CAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = cert
+	r.caPool = pool
+	r.mu.Unlock()
+	return nil
+}
+
+// ServerConfig returns config for listeners, certificate and client CA are resolved per handshake.
+func (r *Reloader) ServerConfig() (*tls.Config, error) {
+	if r.cfg.CertFile == "" {
+		return nil, errors.New("tls cert_file is required for server")
+	}
+	if r.clientAuth >= tls.VerifyClientCertIfGiven && r.cfg.CAFile == "" {
+		return nil, errors.New("tls ca_file is required to verify client certificates")
+	}
+	base := &tls.Config{
+		MinVersion: r.minVersion,
+		ClientAuth: r.clientAuth,
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	// GetCertificate is consulted by http.Server.ServeTLS to check certificate presence
+	base.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return r.cert, nil
+	}
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		c := base.Clone()
+		c.GetConfigForClient = nil
+		c.GetCertificate = nil
+		c.Certificates = []tls.Certificate{*r.cert}
+		c.ClientCAs = r.caPool
+		return c, nil
+	}
+	return base, nil
+}
+
+// ClientConfig returns config for outgoing connections, client certificate is resolved
+// per handshake and CA pool is the one loaded at call time.
+func (r *Reloader) ClientConfig() (*tls.Config, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c := &tls.Config{
+		MinVersion: r.minVersion,
+		RootCAs:    r.caPool,
+		ServerName: r.cfg.ServerName,
+	}
+	if r.cert != nil {
+		c.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return r.cert, nil
+		}
+	}
+	return c, nil
+}
+
+// Close stops watching files.
+func (r *Reloader) Close() error {
+	if r.watcher == nil {
+		return nil
+	}
+	err := r.watcher.Close()
+	<-r.done
+	return err
+}
+
+// watch subscribes to directories of configured files: kubernetes replaces mounted
+// secrets by swapping symlinks, which is not visible when watching files themselves.
+func (r *Reloader) watch() error {
+	dirs := make(map[string]struct{})
+	for _, f := range []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.CAFile} {
+		if f != "" {
+			dirs[filepath.Dir(f)] = struct{}{}
+		}
+	}
+	if len(dirs) == 0 {
+		close(r.done)
+		return nil
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	for d := range dirs {
+		if err := w.Add(d); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	r.watcher = w
+
+	go func() {
+		defer close(r.done)
+		var timer <-chan time.Time
+		for {
+			select {
+			case _, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				timer = time.After(debounce)
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				level.Error(r.logger).Log("msg", "watch tls files", "err", err)
+			case <-timer:
+				timer = nil
+				if err := r.Reload(); err != nil {
+					level.Error(r.logger).Log("msg", "reload tls files, keeping previous", "err", err)
+					continue
+				}
+				level.Info(r.logger).Log("msg", "tls files reloaded", "cert", r.cfg.CertFile)
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,178 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue writes certificate signed by ca with given serial into dir/name.crt and dir/name.key
+func (ca *testCA) issue(t *testing.T, dir, name string, serial int64) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name+".key"),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name+".crt"),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ca.crt"), ca.pem, 0600))
+	ca.issue(t, dir, "server", 2)
+	ca.issue(t, dir, "client", 3)
+
+	server, err := NewReloader(context.Background(), Config{
+		CertFile:   filepath.Join(dir, "server.crt"),
+		KeyFile:    filepath.Join(dir, "server.key"),
+		CAFile:     filepath.Join(dir, "ca.crt"),
+		ClientAuth: "require_and_verify",
+	})
+	require.NoError(t, err)
+	defer server.Close()
+	serverConfig, err := server.ServerConfig()
+	require.NoError(t, err)
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	ts.TLS = serverConfig
+	ts.StartTLS()
+	defer ts.Close()
+
+	client, err := NewReloader(context.Background(), Config{
+		CertFile: filepath.Join(dir, "client.crt"),
+		KeyFile:  filepath.Join(dir, "client.key"),
+		CAFile:   filepath.Join(dir, "ca.crt"),
+	})
+	require.NoError(t, err)
+	defer client.Close()
+	clientConfig, err := client.ClientConfig()
+	require.NoError(t, err)
+
+	resp, err := (&http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}).Get(ts.URL)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "client", string(body))
+
+	// client without certificate is rejected
+	anonymous := &tls.Config{RootCAs: clientConfig.RootCAs}
+	_, err = (&http.Client{Transport: &http.Transport{TLSClientConfig: anonymous}}).Get(ts.URL)
+	assert.Error(t, err)
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	ca.issue(t, dir, "server", 2)
+
+	r, err := NewReloader(context.Background(), Config{
+		CertFile: filepath.Join(dir, "server.crt"),
+		KeyFile:  filepath.Join(dir, "server.key"),
+	})
+	require.NoError(t, err)
+	defer r.Close()
+	cfg, err := r.ServerConfig()
+	require.NoError(t, err)
+
+	serial := func() int64 {
+		c, err := cfg.GetConfigForClient(&tls.ClientHelloInfo{})
+		require.NoError(t, err)
+		leaf, err := x509.ParseCertificate(c.Certificates[0].Certificate[0])
+		require.NoError(t, err)
+		return leaf.SerialNumber.Int64()
+	}
+	assert.Equal(t, int64(2), serial())
+
+	ca.issue(t, dir, "server", 5)
+	assert.Eventually(t, func() bool { return serial() == 5 }, 5*time.Second, 20*time.Millisecond)
+
+	// broken files keep previous certificate
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "server.crt"), []byte("garbage"), 0600))
+	assert.Error(t, r.Reload())
+	assert.Equal(t, int64(5), serial())
+}
+
+func TestNewReloaderErrors(t *testing.T) {
+	_, err := NewReloader(context.Background(), Config{MinVersion: "0.9"})
+	assert.Error(t, err)
+
+	_, err = NewReloader(context.Background(), Config{MinVersion: "1.1"})
+	assert.Error(t, err)
+
+	_, err = NewReloader(context.Background(), Config{ClientAuth: "sometimes"})
+	assert.Error(t, err)
+
+	_, err = NewReloader(context.Background(), Config{CertFile: "/nonexistent.crt", KeyFile: "/nonexistent.key"})
+	assert.Error(t, err)
+
+	r, err := NewReloader(context.Background(), Config{})
+	require.NoError(t, err)
+	_, err = r.ServerConfig()
+	assert.Error(t, err)
+	assert.NoError(t, r.Close())
+
+	r, err = NewReloader(context.Background(), Config{ClientAuth: "require"})
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, r.clientAuth)
+	assert.NoError(t, r.Close())
+}
@@ -0,0 +1,141 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/nakiner/faceit/tools/logging"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	otbridge "go.opentelemetry.io/otel/bridge/opentracing"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+// Tracer providers selectable by config.
+const (
+	ProviderJaeger = "jaeger"
+	ProviderOTLP   = "otlp"
+)
+
+// shutdownTimeout bounds flushing of buffered spans on close
+const shutdownTimeout = 5 * time.Second
+
+// Propagator propagates trace context and baggage in W3C traceparent, tracestate and baggage headers.
+var Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// SetInsecure disables TLS of OTLP exporter.
+func SetInsecure(insecure bool) Option {
+	return func(o *options) {
+		o.insecure = insecure
+	}
+}
+
+// NewOTelTracer creates OpenTelemetry tracer exporting spans over OTLP/gRPC to endpoint.
+// Tracer is exposed through OpenTracing bridge, so existing decorators and transport options keep working.
+// Root spans are sampled by sampler given with SetSampler (every trace by default), child spans follow their parent.
+func NewOTelTracer(ctx context.Context, endpoint, name string, opts ...Option) (opentracing.Tracer, io.Closer, error) {
+	logger := logging.FromContext(ctx)
+	logger = log.With(logger, "component", "tracer")
+	o := newOptions(opts)
+
+	exporter := o.exporter
+	if exporter == nil {
+		clientOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+		if o.insecure {
+			clientOpts = append(clientOpts, otlptracegrpc.WithInsecure())
+		}
+		exp, err := otlptracegrpc.New(ctx, clientOpts...)
+		if err != nil {
+			return nil, ioutil.NopCloser(nil), errors.Wrap(err, "otlp exporter")
+		}
+		exporter = exp
+	}
+
+	var sampler sdktrace.Sampler = sdktrace.AlwaysSample()
+	if o.sampler != nil {
+		sampler = o.sampler
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(name))),
+	)
+
+	bridge, wrapper := otbridge.NewTracerPair(provider.Tracer(name))
+	bridge.SetTextMapPropagator(Propagator)
+	// bridge warns once that SDK sets up span context itself, which is expected
+	bridge.SetWarningHandler(func(msg string) {
+		level.Debug(logger).Log("msg", msg)
+	})
+	// native OpenTelemetry instrumentation shares provider and propagation with bridge
+	otel.SetTracerProvider(wrapper)
+	otel.SetTextMapPropagator(Propagator)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		level.Error(logger).Log("err", err)
+	}))
+
+	closer := closerFunc(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return provider.Shutdown(ctx)
+	})
+	return &otelTracer{bridge}, closer, nil
+}
+
+// otelTracer extends bridge, which supports only http.Header carrier, to any text map carrier such as gRPC metadata.
+type otelTracer struct {
+	*otbridge.BridgeTracer
+}
+
+func (t *otelTracer) Inject(sc opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	writer, ok := carrier.(opentracing.TextMapWriter)
+	if !ok || !isTextMap(format) {
+		return t.BridgeTracer.Inject(sc, format, carrier)
+	}
+	header := http.Header{}
+	if err := t.BridgeTracer.Inject(sc, opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header)); err != nil {
+		return err
+	}
+	for k, values := range header {
+		for _, v := range values {
+			writer.Set(k, v)
+		}
+	}
+	return nil
+}
+
+func (t *otelTracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	reader, ok := carrier.(opentracing.TextMapReader)
+	if !ok || !isTextMap(format) {
+		return t.BridgeTracer.Extract(format, carrier)
+	}
+	header := http.Header{}
+	err := reader.ForeachKey(func(k, v string) error {
+		header.Add(k, v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t.BridgeTracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header))
+}
+
+func isTextMap(format interface{}) bool {
+	return format == opentracing.HTTPHeaders || format == opentracing.TextMap
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"github.com/uber/jaeger-client-go"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Sampler is a probabilistic sampler which rate can be changed at runtime.
+// It implements both jaeger and OpenTelemetry samplers, so either tracer can use it.
+type Sampler struct {
+	current atomic.Value
+}
+
+type samplers struct {
+	jaeger *jaeger.ProbabilisticSampler
+	otel   sdktrace.Sampler
+}
+
+// NewSampler creates Sampler sampling given ratio of traces, rate is between 0 and 1.
+func NewSampler(rate float64) (*Sampler, error) {
+	s := &Sampler{}
+	if err := s.SetRate(rate); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetRate changes ratio of sampled traces.
+func (s *Sampler) SetRate(rate float64) error {
+	if rate < 0 || rate > 1 {
+		return errors.Errorf("sample rate %v is out of [0, 1]", rate)
+	}
+	sampler, err := jaeger.NewProbabilisticSampler(rate)
+	if err != nil {
+		return err
+	}
+	s.current.Store(samplers{jaeger: sampler, otel: sdktrace.TraceIDRatioBased(rate)})
+	return nil
+}
+
+// Rate returns current ratio of sampled traces.
+func (s *Sampler) Rate() float64 {
+	return s.load().jaeger.SamplingRate()
+}
+
+func (s *Sampler) IsSampled(id jaeger.TraceID, operation string) (bool, []jaeger.Tag) {
+	return s.load().jaeger.IsSampled(id, operation)
+}
+
+func (s *Sampler) Close() {}
+
+func (s *Sampler) Equal(other jaeger.Sampler) bool {
+	return other == s
+}
+
+func (s *Sampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return s.load().otel.ShouldSample(p)
+}
+
+func (s *Sampler) Description() string {
+	return s.load().otel.Description()
+}
+
+func (s *Sampler) load() samplers {
+	return s.current.Load().(samplers)
+}
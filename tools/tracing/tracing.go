@@ -13,6 +13,7 @@ import (
 	"github.com/opentracing/opentracing-go"
 	"github.com/uber/jaeger-client-go"
 	jaegercfg "github.com/uber/jaeger-client-go/config"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 type tracerKey struct{}
@@ -28,9 +29,44 @@ func FromContext(ctx context.Context) opentracing.Tracer {
 	return opentracing.GlobalTracer()
 }
 
-func NewJaegerTracer(ctx context.Context, addr, name string) (opentracing.Tracer, io.Closer, error) {
+// Option configures tracer.
+type Option func(*options)
+
+type options struct {
+	sampler  *Sampler
+	insecure bool
+	exporter sdktrace.SpanExporter
+}
+
+// SetSampler replaces default sampler recording every trace.
+func SetSampler(sampler *Sampler) Option {
+	return func(o *options) {
+		o.sampler = sampler
+	}
+}
+
+// SetExporter replaces OTLP exporter of OpenTelemetry tracer, e.g. with in-memory one in tests.
+func SetExporter(exporter sdktrace.SpanExporter) Option {
+	return func(o *options) {
+		o.exporter = exporter
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func NewJaegerTracer(ctx context.Context, addr, name string, opts ...Option) (opentracing.Tracer, io.Closer, error) {
 	logger := logging.FromContext(ctx)
 	logger = log.With(logger, "component", "tracer")
+	cfgOpts := []jaegercfg.Option{jaegercfg.Logger(&jaegerLoggerAdapter{logger})}
+	if o := newOptions(opts); o.sampler != nil {
+		cfgOpts = append(cfgOpts, jaegercfg.Sampler(o.sampler))
+	}
 	tracer, closer, err := jaegercfg.Configuration{
 		ServiceName: name,
 		Sampler: &jaegercfg.SamplerConfig{
@@ -43,9 +79,7 @@ func NewJaegerTracer(ctx context.Context, addr, name string) (opentracing.Tracer
 			LogSpans:            true,
 			BufferFlushInterval: 1 * time.Second,
 		},
-	}.NewTracer(
-		jaegercfg.Logger(&jaegerLoggerAdapter{logger}),
-	)
+	}.NewTracer(cfgOpts...)
 	if err != nil {
 		return nil, ioutil.NopCloser(nil), err
 	}
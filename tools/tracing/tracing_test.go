@@ -2,9 +2,15 @@ package tracing
 
 import (
 	"context"
+	"net/http"
+	"strings"
+	"testing"
+
 	"github.com/opentracing/opentracing-go"
 	"github.com/stretchr/testify/assert"
-	"testing"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-client-go"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestFromContext(t *testing.T) {
@@ -20,3 +26,90 @@ func TestWithContext(t *testing.T) {
 	actual := FromContext(ctx)
 	assert.Equal(t, expected, actual)
 }
+
+func TestSampler_SetRate(t *testing.T) {
+	s, err := NewSampler(0)
+	assert.NoError(t, err)
+	sampled, _ := s.IsSampled(jaeger.TraceID{Low: 1}, "op")
+	assert.False(t, sampled)
+
+	assert.NoError(t, s.SetRate(1))
+	sampled, _ = s.IsSampled(jaeger.TraceID{Low: 1}, "op")
+	assert.True(t, sampled)
+	assert.Equal(t, 1.0, s.Rate())
+
+	assert.Error(t, s.SetRate(2))
+	assert.Equal(t, 1.0, s.Rate())
+}
+
+func TestNewOTelTracer_Propagation(t *testing.T) {
+	exporter := keepSpans{tracetest.NewInMemoryExporter()}
+	sampler, err := NewSampler(0)
+	require.NoError(t, err)
+	tracer, closer, err := NewOTelTracer(context.Background(), "", "test", SetExporter(exporter), SetSampler(sampler))
+	require.NoError(t, err)
+
+	// remote sampled parent is followed even though root spans are not sampled
+	parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	incoming := http.Header{}
+	incoming.Set("traceparent", parent)
+	incoming.Set("baggage", "tenant=faceit")
+	sc, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(incoming))
+	require.NoError(t, err)
+	span := tracer.StartSpan("http server", opentracing.ChildOf(sc))
+	assert.Equal(t, "faceit", span.BaggageItem("tenant"))
+
+	// gRPC metadata is a plain text map carrier
+	md := opentracing.TextMapCarrier{}
+	require.NoError(t, tracer.Inject(span.Context(), opentracing.HTTPHeaders, md))
+	assert.True(t, strings.HasPrefix(md["Traceparent"], "00-4bf92f3577b34da6a3ce929d0e0e4736-"))
+	assert.Contains(t, md, "Baggage")
+
+	sc, err = tracer.Extract(opentracing.TextMap, md)
+	require.NoError(t, err)
+	child := tracer.StartSpan("grpc server", opentracing.ChildOf(sc))
+	assert.Equal(t, "faceit", child.BaggageItem("tenant"))
+	child.Finish()
+	span.Finish()
+
+	root := tracer.StartSpan("root")
+	root.Finish()
+
+	require.NoError(t, closer.Close())
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	for _, s := range spans {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", s.SpanContext.TraceID().String())
+	}
+}
+
+func TestNewOTelTracer_Sampling(t *testing.T) {
+	exporter := keepSpans{tracetest.NewInMemoryExporter()}
+	sampler, err := NewSampler(1)
+	require.NoError(t, err)
+	tracer, closer, err := NewOTelTracer(context.Background(), "", "test", SetExporter(exporter), SetSampler(sampler))
+	require.NoError(t, err)
+
+	span, ctx := opentracing.StartSpanFromContextWithTracer(context.Background(), tracer, "CreateUser")
+	child, _ := opentracing.StartSpanFromContextWithTracer(ctx, tracer, "Create")
+	child.Finish()
+	span.Finish()
+
+	require.NoError(t, sampler.SetRate(0))
+	tracer.StartSpan("GetUsers").Finish()
+
+	require.NoError(t, closer.Close())
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "Create", spans[0].Name)
+	assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID())
+}
+
+// keepSpans keeps exported spans on shutdown which in-memory exporter resets
+type keepSpans struct {
+	*tracetest.InMemoryExporter
+}
+
+func (keepSpans) Shutdown(context.Context) error {
+	return nil
+}
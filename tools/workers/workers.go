@@ -0,0 +1,57 @@
+package workers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrClosed is returned when work is submitted after Wait has been called.
+var ErrClosed = errors.New("workers group is closed")
+
+// Group tracks background goroutines, so shutdown can wait for them to finish.
+type Group struct {
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+	closed bool
+}
+
+// NewGroup creates empty Group.
+func NewGroup() *Group {
+	return &Group{}
+}
+
+// Go runs fn in a new goroutine unless group is closed.
+func (g *Group) Go(fn func()) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return ErrClosed
+	}
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		fn()
+	}()
+	return nil
+}
+
+// Wait closes group for new work and blocks until running goroutines finish or ctx is done.
+func (g *Group) Wait(ctx context.Context) error {
+	g.mu.Lock()
+	g.closed = true
+	g.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "wait for workers")
+	}
+}
@@ -0,0 +1,37 @@
+package workers
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroup(t *testing.T) {
+	g := NewGroup()
+	var done int32
+	for i := 0; i < 3; i++ {
+		require.NoError(t, g.Go(func() {
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&done, 1)
+		}))
+	}
+
+	require.NoError(t, g.Wait(context.Background()))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&done))
+	assert.Equal(t, ErrClosed, g.Go(func() {}))
+}
+
+func TestGroupWaitDeadline(t *testing.T) {
+	g := NewGroup()
+	release := make(chan struct{})
+	defer close(release)
+	require.NoError(t, g.Go(func() { <-release }))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, g.Wait(ctx), context.DeadlineExceeded)
+}